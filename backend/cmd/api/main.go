@@ -12,12 +12,14 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	authMock "github.com/joe_shih/slot-factory/internal/adapter/auth/mock"
+	adapterAudit "github.com/joe_shih/slot-factory/internal/adapter/audit"
 	internalHTTP "github.com/joe_shih/slot-factory/internal/adapter/http"
+	operatorAdapter "github.com/joe_shih/slot-factory/internal/adapter/operator"
+	walletBonus "github.com/joe_shih/slot-factory/internal/adapter/wallet/bonus"
 	walletMock "github.com/joe_shih/slot-factory/internal/adapter/wallet/mock"
 	walletProxy "github.com/joe_shih/slot-factory/internal/adapter/wallet/proxy"
+	"github.com/joe_shih/slot-factory/internal/application/audit"
 	"github.com/joe_shih/slot-factory/internal/application/gamecenter"
-	"github.com/joe_shih/slot-factory/internal/application/login"
 	"github.com/joe_shih/slot-factory/internal/application/wallet"
 	"github.com/joe_shih/slot-factory/internal/config"
 	"github.com/redis/go-redis/v9"
@@ -43,6 +45,9 @@ func main() {
 
 	// API 預設 Port 8081 (避免與 wsserver 8080 衝突)
 	port := 8081
+	if appCfg.API.Port != 0 {
+		port = appCfg.API.Port
+	}
 	envPort := os.Getenv("API_PORT")
 	if envPort != "" {
 		port, _ = strconv.Atoi(envPort)
@@ -73,34 +78,101 @@ func main() {
 		logger.Info("connected to redis", "addr", appCfg.Redis.Addr)
 	}
 
-	// Auth (Mock) - API 服務這裡暫時用 Mock，實際上可能需要驗證管理員 Token
-	// 如果需要 Real Auth，可從 appCfg.Auth.Mode 判斷
-	authClient := authMock.NewAuthClient()
+	// Operators (多營運商，每個營運商有各自的錢包端點)
+	operatorList := appCfg.Operators.List
+	if appCfg.Operators.Source == "db" {
+		if db == nil {
+			logger.Error("operators source db requires a database driver", "driver", appCfg.Database.Driver)
+			os.Exit(1)
+		}
+		operatorList, err = operatorAdapter.NewGormStore(db).List(ctx)
+		if err != nil {
+			logger.Error("failed to load operators", "error", err)
+			os.Exit(1)
+		}
+	}
+	operatorConfigs, operators, err := appCfg.ResolveOperators(operatorList)
+	if err != nil {
+		logger.Error("invalid operator config", "error", err)
+		os.Exit(1)
+	}
 
 	// External Wallet (Proxy)
-	// 如果 DB Driver 是 proxy，則需要初始化 ProxyPayment
-	var payment wallet.Payment
+	// 如果 DB Driver 是 proxy，則需要為每個營運商初始化 ProxyPayment
+	payments := make(map[string]wallet.Payment, len(operatorConfigs))
+	for _, oc := range operatorConfigs {
+		if appCfg.Database.Driver == "proxy" {
+			p, err := walletProxy.NewPayment(db, oc.ID, oc.Wallet, logger.With("component", "wallet"))
+			if err != nil {
+				logger.Error("failed to init proxy wallet", "operator", oc.ID, "error", err)
+				os.Exit(1)
+			}
+			payments[oc.ID] = p
+		} else {
+			p, err := walletMock.NewPayment(appCfg.Database.Mock)
+			if err != nil {
+				logger.Error("failed to init mock wallet", "operator", oc.ID, "error", err)
+				os.Exit(1)
+			}
+			payments[oc.ID] = p
+		}
+	}
 	if appCfg.Database.Driver == "proxy" {
-		payment = walletProxy.NewPayment(db, appCfg.External.Wallet.BaseURL, appCfg.External.Wallet.APIKey)
 		logger.Info("using PROXY (External API + Local Log) adapter")
-	} else {
-		payment = walletMock.NewPayment()
 	}
+	var payment wallet.Payment = wallet.NewOperatorRouter(operators.DefaultID(), payments)
 
 	// 初始化 Services
-	loginService := login.NewService(authClient)
-	walletService := wallet.NewService(logger, payment)
-	gameCenterService := gamecenter.NewService(*loginService, logger.With("component", "game_center"), rdb)
+	currencies, err := appCfg.Currencies.Registry()
+	if err != nil {
+		logger.Error("invalid currency config", "error", err)
+		os.Exit(1)
+	}
+	// 紅利帳本 (有資料庫時使用 DB 儲存，讓多個實體共用)
+	var bonusLedger *wallet.BonusLedger
+	if appCfg.Bonus.Enabled {
+		var bonusStore wallet.BonusStore = walletBonus.NewMemoryStore()
+		if db != nil {
+			bonusStore = walletBonus.NewGormStore(db)
+		}
+		bonusLedger = wallet.NewBonusLedger(bonusStore, wallet.SpendOrder(appCfg.Bonus.SpendOrder), currencies, operators.DefaultID())
+	}
+	walletService := wallet.NewService(logger, payment, bonusLedger)
+	// API 服務不處理玩家登入，管理員的驗證由下方的 Authenticator 負責
+	// 遊戲目錄由 wsserver 註冊遊戲時寫入 Redis，API 服務只負責讀取與發布管理指令，不監聽控制頻道
+	catalog := gamecenter.NewCatalog(rdb, currencies, operators)
+	adminService := gamecenter.NewAdmin(rdb, catalog, logger.With("component", "game_admin"))
 
 	// 設定 Gin
 	engine := gin.Default()
-	handler := internalHTTP.NewHandler(gameCenterService, gameCenterService, walletService)
+	handler := internalHTTP.NewHandler(adminService, adminService, walletService, walletService, operators)
+
+	// REST API 驗證與稽核
+	authenticator, err := internalHTTP.NewAuthenticator(appCfg.API.Auth)
+	if err != nil {
+		logger.Error("invalid api auth config", "error", err)
+		os.Exit(1)
+	}
+	if !appCfg.API.Auth.Enabled {
+		logger.Warn("api authentication is disabled, all requests are treated as admin")
+	}
+	var auditStore audit.Store = adapterAudit.NewLogStore(logger.With("component", "audit"))
+	if db != nil {
+		auditStore = adapterAudit.NewGormStore(db)
+	}
 
-	apiV1 := engine.Group("/api/v1")
+	apiV1 := engine.Group("/api/v1", authenticator.Authenticate())
 	{
-		apiV1.GET("/games", handler.HandleGetGames)
-		apiV1.GET("/history", handler.HandleGetHistory)
-		apiV1.POST("/admin/kick_all", handler.HandleKickAll)
+		apiV1.GET("/games", internalHTTP.RequireRole(internalHTTP.RoleViewer), handler.HandleGetGames)
+		apiV1.GET("/history", internalHTTP.RequireRole(internalHTTP.RoleSupport), handler.HandleGetHistory)
+		apiV1.GET("/maintenance", internalHTTP.RequireRole(internalHTTP.RoleViewer), handler.HandleListMaintenance)
+
+		admin := apiV1.Group("/admin", internalHTTP.Audit(auditStore, logger), internalHTTP.RequireRole(internalHTTP.RoleAdmin))
+		admin.POST("/kick_all", handler.HandleKickAll)
+		admin.POST("/bonus", handler.HandleGrantBonus)
+		admin.POST("/games/:id/status", handler.HandleSetGameStatus)
+		admin.POST("/maintenance", handler.HandleScheduleMaintenance)
+		admin.DELETE("/maintenance/:id", handler.HandleCancelMaintenance)
 	}
 
 	srv := &http.Server{
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	walletProxy "github.com/joe_shih/slot-factory/internal/adapter/wallet/proxy"
+	"github.com/joe_shih/slot-factory/internal/config"
+	"github.com/joe_shih/slot-factory/internal/mockplatform"
+	"github.com/redis/go-redis/v9"
+)
+
+const configPath = "./configs"
+
+// mockplatform 是本地開發與整合測試用的模擬錢包平台，
+// 實作 Proxy 錢包串接的 /balance、/debit、/credit、/spin 與 /rollback。
+func main() {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	env := os.Getenv("APP_ENV")
+	if env == "" {
+		env = "local"
+	}
+
+	appCfg, err := config.LoadConfig[config.AppConfig](configPath, env)
+	if err != nil {
+		logger.Error("failed to load app config", "error", err)
+		os.Exit(1)
+	}
+	cfg := appCfg.MockPlatform
+
+	port := cfg.Port
+	if envPort := os.Getenv("MOCK_PLATFORM_PORT"); envPort != "" {
+		port, _ = strconv.Atoi(envPort)
+	}
+	if port == 0 {
+		port = 8000
+	}
+
+	starting, err := mockplatform.ParseStartingBalances(cfg.StartingBalances)
+	if err != nil {
+		logger.Error("invalid starting balances", "error", err)
+		os.Exit(1)
+	}
+
+	var store mockplatform.Store
+	switch cfg.Storage {
+	case "", "memory":
+		store = mockplatform.NewMemoryStore(starting)
+		logger.Info("using MEMORY storage")
+	case "sqlite":
+		s, err := mockplatform.NewSQLiteStore(cfg.SQLitePath, starting)
+		if err != nil {
+			logger.Error("failed to open sqlite storage", "error", err)
+			os.Exit(1)
+		}
+		store = s
+		logger.Info("using SQLITE storage", "path", cfg.SQLitePath)
+	default:
+		logger.Error("unknown storage", "storage", cfg.Storage)
+		os.Exit(1)
+	}
+
+	// 有 Redis 時以 Redis 記錄已使用的請求 Nonce，多個模擬平台實體之間也能拒絕重放
+	var nonces walletProxy.NonceStore
+	if appCfg.Redis.Addr != "" {
+		nonces = walletProxy.NewRedisNonceStore(redis.NewClient(&redis.Options{
+			Addr:     appCfg.Redis.Addr,
+			Password: appCfg.Redis.Password,
+			DB:       appCfg.Redis.DB,
+		}))
+		logger.Info("using REDIS nonce store", "addr", appCfg.Redis.Addr)
+	}
+
+	server, err := mockplatform.NewServer(store, cfg, appCfg.External.Wallet, nonces, logger.With("component", "mock_platform"))
+	if err != nil {
+		logger.Error("failed to init mock platform", "error", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: server.Handler(),
+	}
+
+	go func() {
+		logger.Info("Mock wallet platform starting", "port", port, "faults", server.Faults())
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("mock platform failed", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	<-ctx.Done()
+	logger.Info("Shutting down mock platform...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("mock platform shutdown failed", "error", err)
+		os.Exit(1)
+	}
+}
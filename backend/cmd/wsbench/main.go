@@ -0,0 +1,182 @@
+// wsbench 是 WebSocket 伺服器的壓測工具。
+//
+// 它建立大量同時在線的連線，每條連線登入後以固定間隔下注，並統計下注到收到結果的延遲與整體吞吐量。
+// 伺服器建議使用 mock 驗證與 mock 錢包 (database.driver: "mock")，避免壓測結果受外部服務影響。
+//
+// 用法：
+//
+//	go run ./cmd/wsbench -url ws://localhost:8080/ws -conns 10000 -duration 60s -interval 1s
+//
+// 一萬條以上的連線需要調高壓測端與伺服器的檔案描述符上限 (ulimit -n)。
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// stats 彙整所有連線的壓測結果。
+type stats struct {
+	connected atomic.Int64
+	active    atomic.Int64
+	failed    atomic.Int64
+	sent      atomic.Int64
+	received  atomic.Int64
+	errors    atomic.Int64
+
+	mu        sync.Mutex
+	latencies []time.Duration
+}
+
+func (s *stats) observe(d time.Duration) {
+	s.mu.Lock()
+	s.latencies = append(s.latencies, d)
+	s.mu.Unlock()
+}
+
+// percentile 回傳延遲的百分位數，呼叫前 latencies 必須已排序。
+func (s *stats) percentile(p float64) time.Duration {
+	if len(s.latencies) == 0 {
+		return 0
+	}
+	idx := int(float64(len(s.latencies)-1) * p)
+	return s.latencies[idx]
+}
+
+func main() {
+	url := flag.String("url", "ws://localhost:8080/ws", "WebSocket 端點")
+	conns := flag.Int("conns", 1000, "同時在線的連線數")
+	duration := flag.Duration("duration", 30*time.Second, "下注階段的持續時間")
+	interval := flag.Duration("interval", time.Second, "每條連線的下注間隔")
+	rampUp := flag.Duration("ramp", 10*time.Second, "建立所有連線所花的時間")
+	gameID := flag.Int("game", 1000, "登入的遊戲 ID")
+	bet := flag.String("bet", "1", "每次下注金額")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	st := &stats{}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	logger.Info("connecting", "conns", *conns, "ramp", *rampUp)
+	step := *rampUp / time.Duration(max(*conns, 1))
+	for i := 0; i < *conns; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			runClient(n, *url, *gameID, *bet, *interval, st, stop)
+		}(i)
+		time.Sleep(step)
+	}
+	logger.Info("all clients started", "connected", st.connected.Load(), "failed", st.failed.Load())
+
+	start := time.Now()
+	ticker := time.NewTicker(5 * time.Second)
+	timer := time.NewTimer(*duration)
+	for running := true; running; {
+		select {
+		case <-ticker.C:
+			logger.Info("progress", "active", st.active.Load(), "sent", st.sent.Load(), "received", st.received.Load(), "errors", st.errors.Load())
+		case <-timer.C:
+			running = false
+		}
+	}
+	ticker.Stop()
+	close(stop)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	sort.Slice(st.latencies, func(i, j int) bool { return st.latencies[i] < st.latencies[j] })
+	fmt.Printf("connections: %d connected, %d failed\n", st.connected.Load(), st.failed.Load())
+	fmt.Printf("plays:       %d sent, %d results, %d errors\n", st.sent.Load(), st.received.Load(), st.errors.Load())
+	fmt.Printf("throughput:  %.1f results/s\n", float64(st.received.Load())/elapsed.Seconds())
+	fmt.Printf("latency:     p50 %v, p95 %v, p99 %v, max %v\n",
+		st.percentile(0.50), st.percentile(0.95), st.percentile(0.99), st.percentile(1))
+}
+
+// runClient 建立一條連線、登入並持續下注，直到 stop 被關閉或連線中斷。
+func runClient(n int, url string, gameID int, bet string, interval time.Duration, st *stats, stop <-chan struct{}) {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		st.failed.Add(1)
+		return
+	}
+	defer conn.Close()
+
+	login, _ := json.Marshal(map[string]any{
+		"action": "login",
+		"data":   map[string]any{"sid": fmt.Sprintf("bench-%d", n), "gameId": gameID},
+	})
+	if err := conn.WriteMessage(websocket.TextMessage, login); err != nil {
+		st.failed.Add(1)
+		return
+	}
+	st.connected.Add(1)
+	st.active.Add(1)
+	defer st.active.Add(-1)
+
+	// 每次下注帶上 requestId，以伺服器回傳的 requestId 對應下注時間與結果
+	var mu sync.Mutex
+	pending := make(map[string]time.Time)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var env struct {
+				Action    string `json:"action"`
+				RequestID string `json:"requestId"`
+				Payload   struct {
+					Success bool `json:"success"`
+				} `json:"payload"`
+			}
+			if json.Unmarshal(data, &env) != nil || env.Action != "play_result" {
+				continue
+			}
+			mu.Lock()
+			if sentAt, ok := pending[env.RequestID]; ok {
+				st.observe(time.Since(sentAt))
+				delete(pending, env.RequestID)
+			}
+			mu.Unlock()
+			if env.Payload.Success {
+				st.received.Add(1)
+			} else {
+				st.errors.Add(1)
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for seq := 0; ; seq++ {
+		select {
+		case <-stop:
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+			requestID := fmt.Sprintf("%d-%d", n, seq)
+			play, _ := json.Marshal(map[string]any{"action": "play", "requestId": requestID, "data": map[string]any{"betAmount": bet}})
+			mu.Lock()
+			pending[requestID] = time.Now()
+			mu.Unlock()
+			if err := conn.WriteMessage(websocket.TextMessage, play); err != nil {
+				return
+			}
+			st.sent.Add(1)
+		}
+	}
+}
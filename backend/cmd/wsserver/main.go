@@ -12,19 +12,29 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	authJWT "github.com/joe_shih/slot-factory/internal/adapter/auth/jwt"
 	authMock "github.com/joe_shih/slot-factory/internal/adapter/auth/mock"
 	authReal "github.com/joe_shih/slot-factory/internal/adapter/auth/real"
+	authRouter "github.com/joe_shih/slot-factory/internal/adapter/auth/router"
+	internalHTTP "github.com/joe_shih/slot-factory/internal/adapter/http"
+	operatorAdapter "github.com/joe_shih/slot-factory/internal/adapter/operator"
 
+	sessionAdapter "github.com/joe_shih/slot-factory/internal/adapter/session"
+	walletBonus "github.com/joe_shih/slot-factory/internal/adapter/wallet/bonus"
 	walletMock "github.com/joe_shih/slot-factory/internal/adapter/wallet/mock"
 	walletProxy "github.com/joe_shih/slot-factory/internal/adapter/wallet/proxy"
 	"github.com/joe_shih/slot-factory/internal/adapter/ws"
 	"github.com/joe_shih/slot-factory/internal/application/gamecenter"
 	"github.com/joe_shih/slot-factory/internal/application/login"
+	"github.com/joe_shih/slot-factory/internal/application/session"
 	"github.com/joe_shih/slot-factory/internal/application/wallet"
 	"github.com/joe_shih/slot-factory/internal/config"
+	"github.com/joe_shih/slot-factory/internal/domain/game"
 	"github.com/joe_shih/slot-factory/internal/gameImp/game1000"
 	"github.com/joe_shih/slot-factory/internal/gameImp/game1001"
+	"github.com/joe_shih/slot-factory/pkg/codec"
 	"github.com/joe_shih/slot-factory/pkg/wss"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -68,26 +78,7 @@ func main() {
 	defer stop()
 
 	// 4. 根據設定檔初始化底層 Adapters
-	var authClient login.AuthClient
-	var payment wallet.Payment
-
-	// --- Auth Adapter ---
-	authMode := cfg.Auth.Mode
-	if envAuthMode := os.Getenv("AUTH_MODE"); envAuthMode != "" {
-		logger.Info("using auth mode from environment variable", "mode", envAuthMode)
-		authMode = config.AdapterMode(envAuthMode)
-	}
-
-	switch authMode {
-	case config.ModeReal:
-		authClient = authReal.NewAuthClient()
-		logger.Info("using REAL auth adapter")
-	default:
-		authClient = authMock.NewAuthClient()
-		logger.Info("using MOCK auth adapter")
-	}
-
-	// --- Wallet Adapter (Decoupled & Proxy Support) ---
+	// --- Database ---
 	dbDriver := cfg.Database.Driver
 	if envDbDriver := os.Getenv("DB_DRIVER"); envDbDriver != "" {
 		logger.Info("using database driver from environment variable", "driver", envDbDriver)
@@ -105,17 +96,77 @@ func main() {
 		db = d
 	}
 
-	switch dbDriver {
-	case "proxy":
-		payment = walletProxy.NewPayment(db, cfg.External.Wallet.BaseURL, cfg.External.Wallet.APIKey)
+	// --- Operators (多營運商，每個營運商有各自的驗證方式與錢包端點) ---
+	// AUTH_MODE 覆寫全域驗證方式，未個別設定驗證方式的營運商會沿用
+	if envAuthMode := os.Getenv("AUTH_MODE"); envAuthMode != "" {
+		logger.Info("using auth mode from environment variable", "mode", envAuthMode)
+		cfg.Auth.Mode = config.AdapterMode(envAuthMode)
+	}
+	operatorList := cfg.Operators.List
+	if cfg.Operators.Source == "db" {
+		if db == nil {
+			logger.Error("operators source db requires a database driver", "driver", dbDriver)
+			os.Exit(1)
+		}
+		operatorList, err = operatorAdapter.NewGormStore(db).List(ctx)
+		if err != nil {
+			logger.Error("failed to load operators", "error", err)
+			os.Exit(1)
+		}
+	}
+	operatorConfigs, operators, err := cfg.ResolveOperators(operatorList)
+	if err != nil {
+		logger.Error("invalid operator config", "error", err)
+		os.Exit(1)
+	}
+
+	// --- Auth Adapter ---
+	authClients := make(map[string]login.AuthClient, len(operatorConfigs))
+	for _, oc := range operatorConfigs {
+		c, err := newAuthClient(oc.Auth)
+		if err != nil {
+			logger.Error("failed to init auth adapter", "operator", oc.ID, "mode", oc.Auth.Mode, "error", err)
+			os.Exit(1)
+		}
+		authClients[oc.ID] = c
+		logger.Info("auth adapter initialized", "operator", oc.ID, "mode", oc.Auth.Mode)
+	}
+	authClient, err := authRouter.NewAuthClient(operators.DefaultID(), authClients)
+	if err != nil {
+		logger.Error("failed to init auth router", "error", err)
+		os.Exit(1)
+	}
+
+	// --- Wallet Adapter (Decoupled & Proxy Support) ---
+	payments := make(map[string]wallet.Payment, len(operatorConfigs))
+	for _, oc := range operatorConfigs {
+		switch dbDriver {
+		case "proxy":
+			p, err := walletProxy.NewPayment(db, oc.ID, oc.Wallet, logger.With("component", "wallet"))
+			if err != nil {
+				logger.Error("failed to init proxy wallet", "operator", oc.ID, "error", err)
+				os.Exit(1)
+			}
+			payments[oc.ID] = p
+		case "mock":
+			p, err := walletMock.NewPayment(cfg.Database.Mock)
+			if err != nil {
+				logger.Error("failed to init mock wallet", "operator", oc.ID, "error", err)
+				os.Exit(1)
+			}
+			payments[oc.ID] = p
+		default:
+			logger.Error("unknown database driver", "driver", dbDriver)
+			os.Exit(1)
+		}
+	}
+	if dbDriver == "proxy" {
 		logger.Info("using PROXY (External API + Local Log) adapter")
-	case "mock":
-		payment = walletMock.NewPayment()
+	} else {
 		logger.Info("using MOCK (in-memory) adapter")
-	default:
-		logger.Error("unknown database driver", "driver", dbDriver)
-		os.Exit(1)
 	}
+	// 依玩家所屬營運商路由每一筆錢包呼叫
+	var payment wallet.Payment = wallet.NewOperatorRouter(operators.DefaultID(), payments)
 
 	// --- Redis Adapter ---
 	var rdb *redis.Client
@@ -128,24 +179,137 @@ func main() {
 		logger.Info("connected to redis", "addr", cfg.Redis.Addr)
 	}
 
-	// 5. 建立 Application Services (核心業務邏輯)
-	loginService := login.NewService(authClient)
-	walletService := wallet.NewService(logger, payment)
-	gameCenterService := gamecenter.NewService(*loginService, logger.With("component", "game_center"), rdb)
-
-	// 6. 註冊所有遊戲實例到 Game Center
-	gameCenterService.RegisterGame(game1000.NewGame(logger, walletService))
-	gameCenterService.RegisterGame(game1001.NewGame(logger, walletService))
-
-	// 7. 建立 WebSocket 伺服器
+	// 5. 建立 WebSocket 伺服器，遊戲與遊戲中心透過它向群組廣播訊息
+	rateLimitPolicy := cfg.RateLimit.Policy
+	if rateLimitPolicy == "" {
+		rateLimitPolicy = string(wss.RateLimitDrop)
+	}
+	switch wss.OverflowPolicy(cfg.SendOverflowPolicy) {
+	case "", wss.OverflowDisconnect, wss.OverflowDropOldest, wss.OverflowDropNewest:
+	default:
+		logger.Error("unknown send overflow policy", "policy", cfg.SendOverflowPolicy)
+		os.Exit(1)
+	}
+	switch wss.RateLimitPolicy(rateLimitPolicy) {
+	case wss.RateLimitDrop, wss.RateLimitWarn, wss.RateLimitKick:
+	default:
+		logger.Error("unknown rate limit policy", "policy", rateLimitPolicy)
+		os.Exit(1)
+	}
+	if err := wss.ValidateAllowedOrigins(cfg.Admission.AllowedOrigins); err != nil {
+		logger.Error("invalid allowed origins", "error", err)
+		os.Exit(1)
+	}
+	if len(cfg.Admission.AllowedOrigins) == 0 {
+		logger.Warn("no allowed origins configured, accepting websocket connections from any origin")
+	}
 	wssConfig := &wss.Config{
 		WriteWait:       time.Duration(cfg.WriteWaitSec) * time.Second,
 		PongWait:        time.Duration(cfg.PongWaitSec) * time.Second,
 		MaxMessageSize:  cfg.MaxMessageSize,
 		ReadBufferSize:  cfg.ReadBufferSize,
 		WriteBufferSize: cfg.WriteBufferSize,
+		SendBufferSize:  cfg.SendBufferSize,
+		OverflowPolicy:  wss.OverflowPolicy(cfg.SendOverflowPolicy),
+		Subprotocols:    codec.Subprotocols(),
+
+		EnableCompression:    cfg.Compression.Enabled,
+		CompressionLevel:     cfg.Compression.Level,
+		CompressionThreshold: cfg.Compression.ThresholdBytes,
+
+		AllowedOrigins:      cfg.Admission.AllowedOrigins,
+		MaxConnections:      cfg.Admission.MaxConnections,
+		MaxConnectionsPerIP: cfg.Admission.MaxConnectionsPerIP,
+		ClientIPHeader:      cfg.Admission.ClientIPHeader,
+		TrustedProxyHops:    cfg.Admission.TrustedProxyHops,
+
+		MessagesPerSecond: cfg.RateLimit.MessagesPerSec,
+		MessageBurst:      cfg.RateLimit.MessageBurst,
+		BytesPerSecond:    cfg.RateLimit.BytesPerSec,
+		ByteBurst:         cfg.RateLimit.ByteBurst,
+		RateLimitPolicy:   wss.RateLimitPolicy(rateLimitPolicy),
+	}
+	// 連線的生命週期與中斷信號分開：收到信號後先排空連線，等進行中的局結算後才關閉
+	connCtx, closeConnections := context.WithCancel(context.Background())
+	defer closeConnections()
+	wsServer := wss.NewServer(connCtx, wssConfig, logger.With("component", "wss"))
+	broadcaster := ws.NewBroadcaster(wsServer)
+
+	// 6. 建立 Application Services (核心業務邏輯)
+	currencies, err := cfg.Currencies.Registry()
+	if err != nil {
+		logger.Error("invalid currency config", "error", err)
+		os.Exit(1)
+	}
+	loginService := login.NewService(authClient, currencies, operators)
+	// 紅利帳本 (有資料庫時使用 DB 儲存，讓多個實體共用)
+	var bonusLedger *wallet.BonusLedger
+	if cfg.Bonus.Enabled {
+		var bonusStore wallet.BonusStore = walletBonus.NewMemoryStore()
+		if db != nil {
+			bonusStore = walletBonus.NewGormStore(db)
+		}
+		bonusLedger = wallet.NewBonusLedger(bonusStore, wallet.SpendOrder(cfg.Bonus.SpendOrder), currencies, operators.DefaultID())
+	}
+	walletService := wallet.NewService(logger, payment, bonusLedger)
+	// Session Token (有 Redis 時跨實體共用，讓玩家可以重連到任何一個實體)
+	var sessionStore session.Store = sessionAdapter.NewMemoryStore()
+	if rdb != nil {
+		sessionStore = sessionAdapter.NewRedisStore(rdb)
+	}
+	sessionService := session.NewService(sessionStore, time.Duration(cfg.Session.TTLSec)*time.Second)
+	catalog := gamecenter.NewCatalog(rdb, currencies, operators)
+	gameCenterService := gamecenter.NewService(*loginService, logger.With("component", "game_center"), rdb, sessionService, catalog, broadcaster)
+	// 單一連線限制 (跨實體，需要 Redis)
+	if policy := cfg.Session.SingleSession; rdb != nil && policy != "off" {
+		if policy == "" {
+			policy = string(gamecenter.SessionKickOld)
+		}
+		err := gameCenterService.EnableSingleSession(gamecenter.SessionPolicy(policy), time.Duration(cfg.Session.RegistryTTLSec)*time.Second)
+		if err != nil {
+			logger.Error("failed to enable single session", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("single session per player enabled", "policy", policy)
+	}
+	// 各操作的頻率限制，避免單一客戶端以大量下注壓垮錢包平台
+	if len(cfg.RateLimit.Actions) > 0 {
+		actionPolicy := cfg.RateLimit.ActionPolicy
+		if actionPolicy == "" {
+			actionPolicy = rateLimitPolicy
+		}
+		limits := make(map[gamecenter.ActionType]gamecenter.ActionLimit, len(cfg.RateLimit.Actions))
+		for action, limit := range cfg.RateLimit.Actions {
+			limits[gamecenter.ActionType(action)] = gamecenter.ActionLimit{PerSecond: limit.PerSec, Burst: limit.Burst}
+		}
+		if err := gameCenterService.EnableActionRateLimit(limits, gamecenter.RateLimitPolicy(actionPolicy)); err != nil {
+			logger.Error("failed to enable action rate limit", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("action rate limit enabled", "actions", len(limits), "policy", actionPolicy)
+	}
+	// 應用層 heartbeat，量測往返時間並中斷已停止處理訊息的半開連線
+	if cfg.Heartbeat.IntervalSec > 0 {
+		if err := gameCenterService.EnableHeartbeat(connCtx, time.Duration(cfg.Heartbeat.IntervalSec)*time.Second, cfg.Heartbeat.MaxMissed); err != nil {
+			logger.Error("failed to enable heartbeat", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("application heartbeat enabled", "intervalSec", cfg.Heartbeat.IntervalSec, "maxMissed", cfg.Heartbeat.MaxMissed)
+	}
+
+	// 7. 註冊所有遊戲實例到 Game Center
+	// 各遊戲的下注限制來自 games 設定，只能收窄平台與營運商的限制
+	betLimits := make(map[int]game.BetLimits, 2)
+	for _, id := range []int{1000, 1001} {
+		limits, err := cfg.GameBetLimits(id)
+		if err != nil {
+			logger.Error("invalid game config", "error", err)
+			os.Exit(1)
+		}
+		betLimits[id] = limits
 	}
-	wsServer := wss.NewServer(ctx, wssConfig, logger.With("component", "wss"))
+	gameCenterService.RegisterGame(game1000.NewGame(logger, walletService, betLimits[1000]))
+	gameCenterService.RegisterGame(game1001.NewGame(logger, walletService, broadcaster, betLimits[1001]))
 
 	// 8. 建立框架轉接器，並將其註冊到 WebSocket 伺服器
 	wsAdapter := ws.NewGameCenterAdapter(gameCenterService)
@@ -157,6 +321,12 @@ func main() {
 	// WebSocket 端點
 	engine.GET("/ws", gin.WrapH(wsServer))
 
+	// 健康檢查與 Prometheus 指標
+	healthHandler := internalHTTP.NewHealthHandler(walletService)
+	engine.GET("/healthz/wallet", healthHandler.HandleWalletHealth)
+	engine.GET("/readyz", internalHTTP.NewReadinessHandler(wsServer).HandleReady)
+	engine.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
 	// 10. 建立並啟動 HTTP 伺服器
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", port),
@@ -171,11 +341,24 @@ func main() {
 		}
 	}()
 
-	// 11. 等待中斷信號，執行優雅關機
+	// 11. 等待中斷信號，排空連線後執行優雅關機
 	<-ctx.Done()
 	stop()
 	logger.Info("shutting down gracefully, press Ctrl+C again to force")
 
+	// readiness 轉為未就緒並拒絕新的連線，通知客戶端重新連線到其他實體，等待進行中的局結算後才關閉所有連線
+	drainTimeout := time.Duration(cfg.Drain.TimeoutSec) * time.Second
+	if drainTimeout <= 0 {
+		drainTimeout = 30 * time.Second
+	}
+	wsServer.Drain()
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), drainTimeout)
+	if err := gameCenterService.Drain(drainCtx, time.Duration(cfg.Drain.ReconnectDelayMs)*time.Millisecond); err != nil {
+		logger.Error("drain did not complete before deadline, closing connections", "error", err)
+	}
+	cancelDrain()
+	closeConnections()
+
 	// 設定一個超時 context
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -183,6 +366,21 @@ func main() {
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		logger.Error("server shutdown failed", "error", err)
 	}
+	if err := wsServer.Wait(shutdownCtx); err != nil {
+		logger.Warn("websocket connections did not close in time", "error", err)
+	}
 
 	logger.Info("server exiting")
 }
+
+// newAuthClient 依驗證模式建立單一營運商的驗證客戶端。
+func newAuthClient(cfg config.AuthConfig) (login.AuthClient, error) {
+	switch cfg.Mode {
+	case config.ModeReal:
+		return authReal.NewAuthClient(cfg.Verify), nil
+	case config.ModeJWT:
+		return authJWT.NewAuthClient(cfg.JWT)
+	default:
+		return authMock.NewAuthClient(), nil
+	}
+}
@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/joe_shih/slot-factory/internal/application/audit"
+	"gorm.io/gorm"
+)
+
+// EntryModel 對應資料庫的 admin_audit_logs 表。
+type EntryModel struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement"`
+	Actor     string    `gorm:"column:actor"`
+	Role      string    `gorm:"column:role"`
+	Method    string    `gorm:"column:method"`
+	Action    string    `gorm:"column:action"`
+	Params    string    `gorm:"column:params"`
+	RemoteIP  string    `gorm:"column:remote_ip"`
+	Status    int       `gorm:"column:status"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (EntryModel) TableName() string {
+	return "admin_audit_logs"
+}
+
+// GormStore 是 audit.Store 的資料庫實作。
+type GormStore struct {
+	db *gorm.DB
+}
+
+var _ audit.Store = (*GormStore)(nil)
+
+// NewGormStore 建立一個以 gorm 為底層的稽核紀錄儲存。
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+// Record 寫入一筆稽核紀錄。
+func (s *GormStore) Record(ctx context.Context, e audit.Entry) error {
+	m := EntryModel{
+		Actor:     e.Actor,
+		Role:      e.Role,
+		Method:    e.Method,
+		Action:    e.Action,
+		Params:    e.Params,
+		RemoteIP:  e.RemoteIP,
+		Status:    e.Status,
+		CreatedAt: e.CreatedAt,
+	}
+	return s.db.WithContext(ctx).Create(&m).Error
+}
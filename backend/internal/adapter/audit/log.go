@@ -0,0 +1,35 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/joe_shih/slot-factory/internal/application/audit"
+)
+
+// LogStore 是 audit.Store 的日誌實作，將稽核紀錄寫入結構化日誌，用於沒有資料庫的本地開發。
+type LogStore struct {
+	logger *slog.Logger
+}
+
+var _ audit.Store = (*LogStore)(nil)
+
+// NewLogStore 建立一個寫入日誌的稽核紀錄儲存。
+func NewLogStore(logger *slog.Logger) *LogStore {
+	return &LogStore{logger: logger}
+}
+
+// Record 以 Info 等級輸出一筆稽核紀錄。
+func (s *LogStore) Record(ctx context.Context, e audit.Entry) error {
+	s.logger.InfoContext(ctx, "admin audit",
+		"actor", e.Actor,
+		"role", e.Role,
+		"method", e.Method,
+		"action", e.Action,
+		"params", e.Params,
+		"remoteIP", e.RemoteIP,
+		"status", e.Status,
+		"createdAt", e.CreatedAt,
+	)
+	return nil
+}
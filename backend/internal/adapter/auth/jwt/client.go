@@ -0,0 +1,179 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/joe_shih/slot-factory/internal/application/login"
+	"github.com/joe_shih/slot-factory/internal/config"
+)
+
+// 預設的 JWKS 重新載入週期。
+const (
+	defaultRefreshInterval    = 5 * time.Minute
+	defaultMinRefreshInterval = 10 * time.Second
+)
+
+// defaultAlgorithms 是未設定時允許的簽章演算法。
+// HS256 使用共享密鑰，持有密鑰的一方都能簽發 token，因此必須在設定中明確列出才會啟用。
+var defaultAlgorithms = []string{"RS256", "ES256"}
+
+// playerClaims 是營運商簽發的玩家 session token 內容，sub 為玩家 ID。
+type playerClaims struct {
+	Name       string `json:"name"`
+	Currency   string `json:"currency"`
+	Language   string `json:"language"`
+	OperatorID string `json:"operatorID"`
+	Country    string `json:"country"`
+	gojwt.RegisteredClaims
+}
+
+// Verifier 在本地驗證 JWT 的簽章、exp、nbf、iss 與 aud，並將內容解析到呼叫端指定的 claims。
+// 玩家登入 (AuthClient) 與 REST API 的管理員驗證共用同一套金鑰管理與檢查邏輯。
+type Verifier struct {
+	parser     *gojwt.Parser
+	keys       *keySet
+	hmacSecret []byte
+}
+
+// NewVerifier 建立一個本地 JWT 驗證器。
+//
+// 參數說明：
+//   - cfg: config.AuthJWTConfig, 金鑰來源、允許的演算法與 iss/aud 檢查設定。
+//
+// 回傳值：
+//   - *Verifier: 初始化完成的驗證器。
+//   - error: 如果沒有設定任何金鑰來源，或設定了 hmacSecret 卻未允許 HS256，則返回錯誤。
+func NewVerifier(cfg config.AuthJWTConfig) (*Verifier, error) {
+	algorithms := cfg.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = defaultAlgorithms
+	}
+	refresh := time.Duration(cfg.RefreshIntervalSec) * time.Second
+	if refresh <= 0 {
+		refresh = defaultRefreshInterval
+	}
+	minRefresh := time.Duration(cfg.MinRefreshIntervalSec) * time.Second
+	if minRefresh <= 0 {
+		minRefresh = defaultMinRefreshInterval
+	}
+
+	keys := newKeySet(cfg.JWKSURL, cfg.JWKSFile, refresh, minRefresh)
+	if keys == nil && cfg.HMACSecret == "" {
+		return nil, errors.New("jwt auth requires jwksUrl, jwksFile or hmacSecret")
+	}
+	if cfg.HMACSecret != "" && !slices.Contains(algorithms, "HS256") {
+		return nil, errors.New("jwt hmacSecret requires HS256 in algorithms")
+	}
+
+	opts := []gojwt.ParserOption{
+		gojwt.WithValidMethods(algorithms),
+		gojwt.WithExpirationRequired(),
+		gojwt.WithLeeway(time.Duration(cfg.LeewaySec) * time.Second),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, gojwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, gojwt.WithAudience(cfg.Audience))
+	}
+
+	v := &Verifier{
+		parser: gojwt.NewParser(opts...),
+		keys:   keys,
+	}
+	if cfg.HMACSecret != "" {
+		v.hmacSecret = []byte(cfg.HMACSecret)
+	}
+	return v, nil
+}
+
+// Verify 驗證 token 並將內容解析到 claims。
+//
+// 回傳的錯誤會包裝 login.ErrTokenExpired、login.ErrTokenInvalid 或 login.ErrAuthUnavailable (無法載入 JWKS)。
+func (v *Verifier) Verify(ctx context.Context, token string, claims gojwt.Claims) error {
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *gojwt.Token) (any, error) {
+		return v.keyFor(ctx, t)
+	})
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gojwt.ErrTokenExpired):
+		return fmt.Errorf("%w: %v", login.ErrTokenExpired, err)
+	case errors.Is(err, errKeysUnavailable):
+		return fmt.Errorf("%w: %v", login.ErrAuthUnavailable, err)
+	default:
+		return fmt.Errorf("%w: %v", login.ErrTokenInvalid, err)
+	}
+}
+
+// keyFor 根據 token 的演算法與 kid 選擇驗證用的金鑰。
+// HS 系列只使用本地設定的共享密鑰，其餘從 JWKS 取得；金鑰型別與演算法不符時由 golang-jwt 拒絕。
+func (v *Verifier) keyFor(ctx context.Context, t *gojwt.Token) (any, error) {
+	if strings.HasPrefix(t.Method.Alg(), "HS") {
+		if v.hmacSecret == nil {
+			return nil, fmt.Errorf("%s requires a configured hmacSecret", t.Method.Alg())
+		}
+		return v.hmacSecret, nil
+	}
+	if v.keys == nil {
+		return nil, fmt.Errorf("no key source for %s", t.Method.Alg())
+	}
+	kid, _ := t.Header["kid"].(string)
+	return v.keys.lookup(ctx, kid)
+}
+
+// AuthClient 是一個在本地驗證 JWT 的 login.AuthClient 實作，
+// 登入時不需要呼叫營運商，只需要事先取得營運商的公鑰 (JWKS) 或共享密鑰。
+type AuthClient struct {
+	verifier *Verifier
+}
+
+var _ login.AuthClient = (*AuthClient)(nil)
+
+// NewAuthClient 建立一個本地 JWT 驗證客戶端。
+//
+// 參數說明：
+//   - cfg: config.AuthJWTConfig, 金鑰來源、允許的演算法與 iss/aud 檢查設定。
+//
+// 回傳值：
+//   - *AuthClient: 初始化完成的驗證客戶端。
+//   - error: 如果沒有設定任何金鑰來源，則返回錯誤。
+func NewAuthClient(cfg config.AuthJWTConfig) (*AuthClient, error) {
+	v, err := NewVerifier(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &AuthClient{verifier: v}, nil
+}
+
+// VerifyToken 驗證 JWT 的簽章、exp、nbf、iss 與 aud，並將 claims 轉換為 login.UserData。
+//
+// 回傳的錯誤會包裝 login.ErrTokenExpired、login.ErrTokenInvalid 或 login.ErrAuthUnavailable (無法載入 JWKS)。
+func (c *AuthClient) VerifyToken(ctx context.Context, token string) (login.UserData, error) {
+	if err := ctx.Err(); err != nil {
+		return login.UserData{}, err
+	}
+
+	var claims playerClaims
+	if err := c.verifier.Verify(ctx, token, &claims); err != nil {
+		return login.UserData{}, err
+	}
+
+	if claims.Subject == "" {
+		return login.UserData{}, fmt.Errorf("%w: missing sub claim", login.ErrTokenInvalid)
+	}
+	return login.UserData{
+		ID:         claims.Subject,
+		Name:       claims.Name,
+		Currency:   claims.Currency,
+		Language:   claims.Language,
+		OperatorID: claims.OperatorID,
+		Country:    claims.Country,
+	}, nil
+}
@@ -0,0 +1,263 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// errKeysUnavailable 表示無法載入 JWKS，屬於上游錯誤而非 token 本身的問題。
+var errKeysUnavailable = errors.New("jwks unavailable")
+
+// maxJWKSSize 是讀取 JWKS 的大小上限。
+const maxJWKSSize = 1 << 20
+
+// jwk 是 JWKS 中單一金鑰的 JSON 格式 (RFC 7517)。
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// keySet 快取 JWKS 的金鑰，並依設定的週期或遇到未知 kid 時重新載入。
+//
+// 重新載入失敗時會保留舊的金鑰，避免 JWKS 來源短暫故障導致所有登入失敗。
+// 載入在鎖外進行，同一時間只會有一次載入，其他需要重新載入的請求等待同一次載入的結果 (singleflight)，
+// 查詢快取中的金鑰不會被進行中的載入阻塞。
+type keySet struct {
+	load            func(ctx context.Context) ([]byte, error)
+	refreshInterval time.Duration
+	minRefresh      time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]any // key: kid
+	fetchedAt time.Time
+	lastErr   error
+	inflight  chan struct{} // 非 nil 時表示載入進行中，載入完成後關閉
+}
+
+// newKeySet 建立一個 JWKS 快取，url 與 file 擇一，皆為空時回傳 nil。
+func newKeySet(url, file string, refreshInterval, minRefresh time.Duration) *keySet {
+	var load func(ctx context.Context) ([]byte, error)
+	switch {
+	case url != "":
+		client := &http.Client{Timeout: 5 * time.Second}
+		load = func(ctx context.Context) ([]byte, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			defer func() { _ = resp.Body.Close() }()
+			if resp.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("jwks url returned status %d", resp.StatusCode)
+			}
+			return io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+		}
+	case file != "":
+		load = func(context.Context) ([]byte, error) {
+			return os.ReadFile(file)
+		}
+	default:
+		return nil
+	}
+	return &keySet{
+		load:            load,
+		refreshInterval: refreshInterval,
+		minRefresh:      minRefresh,
+	}
+}
+
+// lookup 根據 kid 取得金鑰。kid 為空時，若只有一把金鑰則使用該金鑰。
+// 快取過期或找不到 kid 時會重新載入 (未知 kid 的重新載入受 minRefresh 限制)。
+func (s *keySet) lookup(ctx context.Context, kid string) (any, error) {
+	s.mu.Lock()
+	stale := s.keys == nil || time.Since(s.fetchedAt) > s.refreshInterval
+	s.mu.Unlock()
+	if stale {
+		s.refresh(ctx)
+	}
+	if key, ok := s.find(kid); ok {
+		return key, nil
+	}
+
+	s.mu.Lock()
+	retry := time.Since(s.fetchedAt) > s.minRefresh
+	s.mu.Unlock()
+	if retry {
+		// 可能是金鑰剛輪替，重新載入一次
+		s.refresh(ctx)
+		if key, ok := s.find(kid); ok {
+			return key, nil
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.keys == nil {
+		return nil, fmt.Errorf("%w: %v", errKeysUnavailable, s.lastErr)
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// find 在目前快取中尋找金鑰。
+func (s *keySet) find(kid string) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// refresh 重新載入 JWKS，失敗時保留舊金鑰。
+//
+// 已有載入進行中時只等待該次載入完成 (或 ctx 到期)，不會重複發出請求。
+// 載入不受單一請求取消的影響，避免一個被取消的登入讓等待中的其他登入一起失敗。
+func (s *keySet) refresh(ctx context.Context) {
+	s.mu.Lock()
+	if done := s.inflight; done != nil {
+		s.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+		return
+	}
+	done := make(chan struct{})
+	s.inflight = done
+	s.mu.Unlock()
+
+	data, err := s.load(context.WithoutCancel(ctx))
+	var keys map[string]any
+	if err == nil {
+		keys, err = parseJWKS(data)
+	}
+
+	s.mu.Lock()
+	if err == nil {
+		s.keys = keys
+	}
+	s.lastErr = err
+	// 失敗時也更新時間，避免來源故障時每個請求都重試
+	s.fetchedAt = time.Now()
+	s.inflight = nil
+	s.mu.Unlock()
+	close(done)
+}
+
+// parseJWKS 解析 JWKS，略過用途為加密 (use=enc) 或無法辨識的金鑰。
+func parseJWKS(data []byte) (map[string]any, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use == "enc" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwk %q: %w", k.Kid, err)
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwks contains no usable keys")
+	}
+	return keys, nil
+}
+
+// publicKey 將 JWK 轉換為 golang-jwt 可用的金鑰型別，不支援的 kty 回傳 nil。
+//
+// 對稱金鑰 (kty=oct) 不應出現在公開的 JWKS 中，接受它會讓攻擊者可以用 HS 演算法偽造 token，因此直接拒絕；
+// HMAC 只使用本地設定的 hmacSecret。
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeSegment(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeSegment(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() > 1<<31-1 {
+			return nil, errors.New("rsa exponent too large")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeSegment(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeSegment(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) > size || len(y) > size {
+			return nil, errors.New("ec coordinate too long")
+		}
+		point := make([]byte, 1+2*size)
+		point[0] = 4 // 未壓縮格式
+		copy(point[1+size-len(x):1+size], x)
+		copy(point[1+2*size-len(y):], y)
+		return ecdsa.ParseUncompressedPublicKey(curve, point)
+	case "oct":
+		return nil, errors.New("symmetric (oct) keys are not allowed in jwks")
+	default:
+		return nil, nil
+	}
+}
+
+// decodeSegment 解碼 base64url (無 padding) 字串。
+func decodeSegment(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("missing key parameter")
+	}
+	return base64.RawURLEncoding.DecodeString(s)
+}
@@ -0,0 +1,184 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/joe_shih/slot-factory/internal/application/login"
+	"github.com/joe_shih/slot-factory/internal/config"
+)
+
+// testKey 是測試用的 ES256 簽章金鑰，testJWKS 是其公鑰 (kid=k1)。
+var (
+	testKey  = mustECKey()
+	testJWKS = ecJWKS("k1", &testKey.PublicKey)
+)
+
+func mustECKey() *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func ecJWKS(kid string, pub *ecdsa.PublicKey) string {
+	enc := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	pub.X.FillBytes(x)
+	pub.Y.FillBytes(y)
+	return fmt.Sprintf(`{"keys":[{"kty":"EC","kid":%q,"crv":"P-256","x":%q,"y":%q}]}`, kid, enc(x), enc(y))
+}
+
+func TestKeySetRefreshIsSingleFlight(t *testing.T) {
+	var loads atomic.Int32
+	release := make(chan struct{})
+	s := &keySet{
+		load: func(context.Context) ([]byte, error) {
+			loads.Add(1)
+			<-release
+			return []byte(testJWKS), nil
+		},
+		refreshInterval: time.Hour,
+		minRefresh:      time.Hour,
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.lookup(context.Background(), "k1")
+			errs <- err
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("lookup: %v", err)
+		}
+	}
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("jwks loaded %d times, want 1", n)
+	}
+}
+
+func TestKeySetLookupDoesNotWaitForUnrelatedFetch(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	s := &keySet{
+		load: func(context.Context) ([]byte, error) {
+			<-release
+			return []byte(testJWKS), nil
+		},
+		refreshInterval: time.Hour,
+		minRefresh:      0,
+		keys:            map[string]any{"k1": &testKey.PublicKey},
+		fetchedAt:       time.Now(),
+	}
+
+	// 未知 kid 觸發重新載入並卡住
+	go func() { _, _ = s.lookup(context.Background(), "unknown") }()
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.lookup(context.Background(), "k1")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("lookup cached key: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("lookup of a cached key blocked on an in-flight jwks fetch")
+	}
+}
+
+func TestParseJWKSRejectsSymmetricKeys(t *testing.T) {
+	if _, err := parseJWKS([]byte(`{"keys":[{"kty":"oct","kid":"k1","k":"c2VjcmV0"}]}`)); err == nil {
+		t.Fatal("parseJWKS accepted an oct key")
+	}
+}
+
+func TestVerifierJWKS(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, []byte(testJWKS), 0o600); err != nil {
+		t.Fatalf("write jwks: %v", err)
+	}
+	v, err := NewVerifier(config.AuthJWTConfig{JWKSFile: path, Algorithms: []string{"ES256", "HS256"}, HMACSecret: "local-secret"})
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	claims := gojwt.RegisteredClaims{Subject: "p1", ExpiresAt: gojwt.NewNumericDate(time.Now().Add(time.Minute))}
+	sign := func(method gojwt.SigningMethod, key any) string {
+		tok := gojwt.NewWithClaims(method, claims)
+		tok.Header["kid"] = "k1"
+		s, err := tok.SignedString(key)
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "es256 signed by jwks key", token: sign(gojwt.SigningMethodES256, testKey)},
+		{name: "hs256 signed by local secret", token: sign(gojwt.SigningMethodHS256, []byte("local-secret"))},
+		{name: "hs256 signed with jwks public key bytes", token: sign(gojwt.SigningMethodHS256, []byte(testJWKS)), wantErr: login.ErrTokenInvalid},
+		{name: "es256 signed by unknown key", token: sign(gojwt.SigningMethodES256, mustECKey()), wantErr: login.ErrTokenInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got gojwt.RegisteredClaims
+			err := v.Verify(context.Background(), tt.token, &got)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify: got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifierRejectsHMACWithoutLocalSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, []byte(testJWKS), 0o600); err != nil {
+		t.Fatalf("write jwks: %v", err)
+	}
+	v, err := NewVerifier(config.AuthJWTConfig{JWKSFile: path, Algorithms: []string{"ES256", "HS256"}})
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	tok := gojwt.NewWithClaims(gojwt.SigningMethodHS256, gojwt.RegisteredClaims{ExpiresAt: gojwt.NewNumericDate(time.Now().Add(time.Minute))})
+	tok.Header["kid"] = "k1"
+	signed, err := tok.SignedString([]byte(testJWKS))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if err := v.Verify(context.Background(), signed, &gojwt.RegisteredClaims{}); !errors.Is(err, login.ErrTokenInvalid) {
+		t.Fatalf("Verify: got %v, want ErrTokenInvalid", err)
+	}
+}
@@ -1,30 +1,40 @@
 package mock
 
 import (
+	"context"
 	"strconv"
+	"sync/atomic"
 
 	"github.com/joe_shih/slot-factory/internal/application/login"
+	"github.com/joe_shih/slot-factory/internal/domain/operator"
 )
 
 // AuthClient 是一個 login.AuthClient 的模擬實作，用於測試和本地開發。
 type AuthClient struct {
-	// 流水號
-	counterID int
+	// 流水號 (多個連線可能同時登入，需使用原子操作)
+	counterID atomic.Int64
 }
 
 // NewAuthClient 創建一個新的 AuthClient 實例。
 func NewAuthClient() *AuthClient {
-	return &AuthClient{counterID: 1000000}
+	c := &AuthClient{}
+	c.counterID.Store(1000000)
+	return c
 }
 
 // VerifyToken 模擬驗證 token 的過程，並始終回傳一個固定的假使用者資料。
-func (c *AuthClient) VerifyToken(token string) (login.UserData, error) {
+// 模擬版本接受任何營運商，回傳的 OperatorID 即為 context 中負責驗證的營運商。
+func (c *AuthClient) VerifyToken(ctx context.Context, token string) (login.UserData, error) {
+	if err := ctx.Err(); err != nil {
+		return login.UserData{}, err
+	}
 	// 在模擬版本中，我們忽略 token，直接回傳成功
-	c.counterID++
-	strID := strconv.Itoa(c.counterID)
+	strID := strconv.FormatInt(c.counterID.Add(1), 10)
+	operatorID, _ := operator.IDFromContext(ctx)
 	userData := login.UserData{
-		ID:   strID,
-		Name: "MockPlayer" + strID,
+		ID:         strID,
+		Name:       "MockPlayer" + strID,
+		OperatorID: operatorID,
 	}
 	return userData, nil
 }
@@ -1,35 +1,141 @@
 package real
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
 	"github.com/go-resty/resty/v2"
 	"github.com/joe_shih/slot-factory/internal/application/login"
+	"github.com/joe_shih/slot-factory/internal/config"
+)
+
+// 驗證端點回傳的錯誤代碼。
+const (
+	codeTokenExpired = "token_expired"
+	codeTokenInvalid = "token_invalid"
 )
 
-// AuthClient 是一個使用 resty 的 login.AuthClient 實作。
+// AuthClient 是一個使用 resty 的 login.AuthClient 實作，
+// 會呼叫營運商的 token 驗證端點並將結果轉換為 login.UserData。
+//
+// 驗證端點的約定：
+//
+//	POST {url}  {"token": "..."}
+//	200 {"userID", "name", "currency", "language", "operatorID", "country"}
+//	4xx {"code": "token_expired" | "token_invalid", "message"}
+//
+// 網路錯誤、429 與 5xx 會以指數退避重試，其他回應不重試。
 type AuthClient struct {
 	client *resty.Client
+	url    string
+}
+
+var _ login.AuthClient = (*AuthClient)(nil)
+
+// verifyRequest 是驗證端點的請求內容。
+type verifyRequest struct {
+	Token string `json:"token"`
+}
+
+// verifyResponse 是驗證端點成功時的回應內容。
+type verifyResponse struct {
+	UserID     string `json:"userID"`
+	Name       string `json:"name"`
+	Currency   string `json:"currency"`
+	Language   string `json:"language"`
+	OperatorID string `json:"operatorID"`
+	Country    string `json:"country"`
+}
+
+// verifyError 是驗證端點失敗時的回應內容。
+type verifyError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 // NewAuthClient 創建一個新的 AuthClient 實例。
-func NewAuthClient() *AuthClient {
-	return &AuthClient{
-		client: resty.New(),
+//
+// 參數說明：
+//   - cfg: config.AuthVerifyConfig, 驗證端點的網址、金鑰、逾時與重試設定。
+//
+// 回傳值：
+//   - *AuthClient: 初始化完成的驗證客戶端。
+func NewAuthClient(cfg config.AuthVerifyConfig) *AuthClient {
+	timeout := time.Duration(cfg.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	retryWait := time.Duration(cfg.RetryWaitMs) * time.Millisecond
+	if retryWait <= 0 {
+		retryWait = 100 * time.Millisecond
+	}
+
+	client := resty.New().
+		SetTimeout(timeout).
+		SetRetryCount(cfg.MaxRetries).
+		SetRetryWaitTime(retryWait).
+		SetRetryMaxWaitTime(10 * retryWait).
+		AddRetryCondition(func(resp *resty.Response, err error) bool {
+			if err != nil {
+				return true
+			}
+			return resp.StatusCode() == http.StatusTooManyRequests || resp.StatusCode() >= 500
+		})
+	if cfg.APIKey != "" {
+		client.SetAuthToken(cfg.APIKey)
 	}
+	return &AuthClient{client: client, url: cfg.URL}
 }
 
-// VerifyToken 使用 resty client 驗證 token。
-// TODO: 目前這個函式只回傳假資料，未來需要實現真正的 HTTP 請求邏輯。
-func (c *AuthClient) VerifyToken(token string) (login.UserData, error) {
-	// 在未來的實作中，這裡會發送一個 HTTP 請求到外部驗證服務
-	// resp, err := c.client.R().
-	// 	 SetAuthToken(token).
-	// 	 SetResult(&login.UserData{}).
-	// 	 Post("https://your-auth-service.com/verify")
-
-	// 目前回傳一個固定的假資料
-	userData := login.UserData{
-		ID:   "real_user_456",
-		Name: "Resty Client",
+// VerifyToken 呼叫營運商驗證端點驗證 token。
+//
+// 回傳的錯誤會包裝 login.ErrTokenExpired、login.ErrTokenInvalid 或 login.ErrAuthUnavailable，
+// ctx 被取消時則直接回傳 ctx 的錯誤。
+func (c *AuthClient) VerifyToken(ctx context.Context, token string) (login.UserData, error) {
+	if err := ctx.Err(); err != nil {
+		return login.UserData{}, err
+	}
+
+	var (
+		result  verifyResponse
+		failure verifyError
+	)
+	resp, err := c.client.R().
+		SetContext(ctx).
+		SetBody(verifyRequest{Token: token}).
+		SetResult(&result).
+		SetError(&failure).
+		Post(c.url)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return login.UserData{}, ctxErr
+		}
+		return login.UserData{}, fmt.Errorf("%w: %v", login.ErrAuthUnavailable, err)
+	}
+
+	status := resp.StatusCode()
+	switch {
+	case status >= 200 && status < 300:
+		if result.UserID == "" {
+			return login.UserData{}, fmt.Errorf("%w: verify response missing userID", login.ErrAuthUnavailable)
+		}
+		return login.UserData{
+			ID:         result.UserID,
+			Name:       result.Name,
+			Currency:   result.Currency,
+			Language:   result.Language,
+			OperatorID: result.OperatorID,
+			Country:    result.Country,
+		}, nil
+	case failure.Code == codeTokenExpired:
+		return login.UserData{}, fmt.Errorf("%w: %s", login.ErrTokenExpired, failure.Message)
+	case failure.Code == codeTokenInvalid,
+		status == http.StatusBadRequest, status == http.StatusUnauthorized,
+		status == http.StatusForbidden, status == http.StatusNotFound:
+		return login.UserData{}, fmt.Errorf("%w: status %d %s", login.ErrTokenInvalid, status, failure.Message)
+	default:
+		return login.UserData{}, fmt.Errorf("%w: status %d", login.ErrAuthUnavailable, status)
 	}
-	return userData, nil
 }
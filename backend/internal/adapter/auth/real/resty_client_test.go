@@ -0,0 +1,164 @@
+package real
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/joe_shih/slot-factory/internal/application/login"
+	"github.com/joe_shih/slot-factory/internal/config"
+)
+
+// reply 是驗證端點在某次呼叫時回傳的狀態碼與內容。
+type reply struct {
+	status int
+	body   string
+	delay  time.Duration
+}
+
+// newVerifyServer 啟動依序回傳 replies 的驗證端點，超過長度後重複最後一個回應，並回傳呼叫次數。
+func newVerifyServer(t *testing.T, replies ...reply) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1)) - 1
+		if n >= len(replies) {
+			n = len(replies) - 1
+		}
+		rep := replies[n]
+		if rep.delay > 0 {
+			select {
+			case <-time.After(rep.delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer api-key" {
+			t.Errorf("Authorization = %q, want bearer api key", got)
+		}
+		var req verifyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token != "tok" {
+			t.Errorf("unexpected request body: %+v, %v", req, err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(rep.status)
+		_, _ = w.Write([]byte(rep.body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestAuthClientVerifyToken(t *testing.T) {
+	const okBody = `{"userID":"u1","name":"Alice","currency":"USD","language":"en","operatorID":"op1","country":"US"}`
+	tests := []struct {
+		name      string
+		replies   []reply
+		want      login.UserData
+		wantErr   error
+		wantCalls int32
+	}{
+		{
+			name:      "ok",
+			replies:   []reply{{status: 200, body: okBody}},
+			want:      login.UserData{ID: "u1", Name: "Alice", Currency: "USD", Language: "en", OperatorID: "op1", Country: "US"},
+			wantCalls: 1,
+		},
+		{
+			name:      "ok without userID",
+			replies:   []reply{{status: 200, body: `{"name":"Alice"}`}},
+			wantErr:   login.ErrAuthUnavailable,
+			wantCalls: 1,
+		},
+		{
+			name:      "expired code",
+			replies:   []reply{{status: 401, body: `{"code":"token_expired","message":"expired"}`}},
+			wantErr:   login.ErrTokenExpired,
+			wantCalls: 1,
+		},
+		{
+			name:      "invalid code",
+			replies:   []reply{{status: 422, body: `{"code":"token_invalid"}`}},
+			wantErr:   login.ErrTokenInvalid,
+			wantCalls: 1,
+		},
+		{name: "400 without code", replies: []reply{{status: 400}}, wantErr: login.ErrTokenInvalid, wantCalls: 1},
+		{name: "401 without code", replies: []reply{{status: 401}}, wantErr: login.ErrTokenInvalid, wantCalls: 1},
+		{name: "403 without code", replies: []reply{{status: 403}}, wantErr: login.ErrTokenInvalid, wantCalls: 1},
+		{name: "404 without code", replies: []reply{{status: 404}}, wantErr: login.ErrTokenInvalid, wantCalls: 1},
+		{name: "other 4xx", replies: []reply{{status: 418}}, wantErr: login.ErrAuthUnavailable, wantCalls: 1},
+		{
+			name:      "5xx retried until exhausted",
+			replies:   []reply{{status: 500, body: `{"message":"boom"}`}},
+			wantErr:   login.ErrAuthUnavailable,
+			wantCalls: 3,
+		},
+		{name: "429 retried until exhausted", replies: []reply{{status: 429}}, wantErr: login.ErrAuthUnavailable, wantCalls: 3},
+		{
+			name:      "5xx then ok",
+			replies:   []reply{{status: 503}, {status: 200, body: okBody}},
+			want:      login.UserData{ID: "u1", Name: "Alice", Currency: "USD", Language: "en", OperatorID: "op1", Country: "US"},
+			wantCalls: 2,
+		},
+		{
+			name:      "timeout retried until exhausted",
+			replies:   []reply{{status: 200, body: okBody, delay: 300 * time.Millisecond}},
+			wantErr:   login.ErrAuthUnavailable,
+			wantCalls: 3,
+		},
+		{
+			name:      "timeout then ok",
+			replies:   []reply{{status: 200, body: okBody, delay: 300 * time.Millisecond}, {status: 200, body: okBody}},
+			want:      login.UserData{ID: "u1", Name: "Alice", Currency: "USD", Language: "en", OperatorID: "op1", Country: "US"},
+			wantCalls: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := newVerifyServer(t, tt.replies...)
+			client := NewAuthClient(config.AuthVerifyConfig{
+				URL:         srv.URL,
+				APIKey:      "api-key",
+				TimeoutMs:   100,
+				MaxRetries:  2,
+				RetryWaitMs: 1,
+			})
+
+			got, err := client.VerifyToken(context.Background(), "tok")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("VerifyToken: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("user = %+v, want %+v", got, tt.want)
+			}
+			if n := calls.Load(); n != tt.wantCalls {
+				t.Fatalf("endpoint called %d times, want %d", n, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestAuthClientVerifyTokenContextCanceled(t *testing.T) {
+	srv, _ := newVerifyServer(t, reply{status: 200, delay: 300 * time.Millisecond})
+	client := NewAuthClient(config.AuthVerifyConfig{URL: srv.URL, APIKey: "api-key", TimeoutMs: 5000, MaxRetries: 2})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.VerifyToken(ctx, "tok"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+
+	canceled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	if _, err := client.VerifyToken(canceled, "tok"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
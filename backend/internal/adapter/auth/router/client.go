@@ -0,0 +1,101 @@
+package router
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/joe_shih/slot-factory/internal/application/login"
+	"github.com/joe_shih/slot-factory/internal/domain/operator"
+)
+
+// AuthClient 是一個依營運商路由的 login.AuthClient 實作。
+//
+// 每個營運商可以有各自的驗證方式 (mock、real 或 jwt)，登入時依下列順序決定由哪個營運商驗證：
+//  1. context 中的營運商 ID (客戶端登入時帶上的 operatorId)。
+//  2. token 為 JWT 時，未驗證的 payload 中的 operatorID claim (僅用於選擇驗證者，最終仍由該營運商驗證)。
+//  3. 預設營運商。
+//
+// 前兩項都由客戶端控制，只用來挑選驗證者，不代表玩家屬於該營運商。驗證通過後，
+// 使用者資料的 OperatorID (例如 JWT 已驗證的 operatorID claim 或驗證端點回傳的 operatorID)
+// 必須與負責驗證的營運商一致；只有預設營運商允許驗證結果不帶 OperatorID，此時會自動填入。
+// 否則客戶端可以把 token 交給不回報營運商的驗證者，冒充其他營運商的同名玩家。
+type AuthClient struct {
+	clients   map[string]login.AuthClient
+	defaultID string
+}
+
+var _ login.AuthClient = (*AuthClient)(nil)
+
+// NewAuthClient 建立一個依營運商路由的驗證客戶端。
+//
+// 參數說明：
+//   - defaultID: string, 無法從登入資訊判斷營運商時使用的營運商 ID。
+//   - clients: map[string]login.AuthClient, key 為營運商 ID，value 為該營運商的驗證客戶端。
+//
+// 回傳值：
+//   - *AuthClient: 初始化完成的驗證客戶端。
+//   - error: 如果預設營運商沒有驗證客戶端，則返回錯誤。
+func NewAuthClient(defaultID string, clients map[string]login.AuthClient) (*AuthClient, error) {
+	if _, ok := clients[defaultID]; !ok {
+		return nil, fmt.Errorf("default operator %s has no auth client", defaultID)
+	}
+	return &AuthClient{clients: clients, defaultID: defaultID}, nil
+}
+
+// VerifyToken 交由玩家所屬營運商的驗證客戶端驗證 token。
+func (c *AuthClient) VerifyToken(ctx context.Context, token string) (login.UserData, error) {
+	operatorID := c.resolve(ctx, token)
+	client, ok := c.clients[operatorID]
+	if !ok {
+		return login.UserData{}, fmt.Errorf("%w: unknown operator %s", login.ErrTokenInvalid, operatorID)
+	}
+
+	data, err := client.VerifyToken(operator.NewContext(ctx, operatorID), token)
+	if err != nil {
+		return login.UserData{}, err
+	}
+	if data.OperatorID == "" {
+		if operatorID != c.defaultID {
+			return login.UserData{}, fmt.Errorf("%w: verified token does not identify operator %s", login.ErrTokenInvalid, operatorID)
+		}
+		data.OperatorID = operatorID
+	}
+	if data.OperatorID != operatorID {
+		return login.UserData{}, fmt.Errorf("%w: token issued for operator %s, verified by %s", login.ErrTokenInvalid, data.OperatorID, operatorID)
+	}
+	return data, nil
+}
+
+// resolve 決定負責驗證 token 的營運商。
+func (c *AuthClient) resolve(ctx context.Context, token string) string {
+	if id, ok := operator.IDFromContext(ctx); ok {
+		return id
+	}
+	if id := jwtOperatorHint(token); id != "" {
+		return id
+	}
+	return c.defaultID
+}
+
+// jwtOperatorHint 從 JWT 的 payload 讀取 operatorID claim，不是 JWT 或沒有此 claim 時回傳空字串。
+// 此處不驗證簽章，結果只用來挑選驗證者。
+func jwtOperatorHint(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+	var claims struct {
+		OperatorID string `json:"operatorID"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+	return claims.OperatorID
+}
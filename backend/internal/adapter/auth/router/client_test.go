@@ -0,0 +1,64 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/joe_shih/slot-factory/internal/application/login"
+	"github.com/joe_shih/slot-factory/internal/domain/operator"
+)
+
+// stubClient 回傳固定的驗證結果。
+type stubClient struct {
+	data login.UserData
+}
+
+func (c stubClient) VerifyToken(context.Context, string) (login.UserData, error) {
+	return c.data, nil
+}
+
+func TestAuthClientChecksVerifiedOperator(t *testing.T) {
+	client, err := NewAuthClient("default", map[string]login.AuthClient{
+		"default": stubClient{data: login.UserData{ID: "p1"}},
+		"op-a":    stubClient{data: login.UserData{ID: "p1", OperatorID: "op-a"}},
+		"op-b":    stubClient{data: login.UserData{ID: "p1"}},
+		"op-c":    stubClient{data: login.UserData{ID: "p1", OperatorID: "op-a"}},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthClient: %v", err)
+	}
+	tests := []struct {
+		name       string
+		operatorID string
+		want       string
+		wantErr    error
+	}{
+		{name: "default fills operator", want: "default"},
+		{name: "verified operator matches", operatorID: "op-a", want: "op-a"},
+		{name: "verifier without operator", operatorID: "op-b", wantErr: login.ErrTokenInvalid},
+		{name: "verified operator differs", operatorID: "op-c", wantErr: login.ErrTokenInvalid},
+		{name: "unknown operator", operatorID: "op-x", wantErr: login.ErrTokenInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.operatorID != "" {
+				ctx = operator.NewContext(ctx, tt.operatorID)
+			}
+			data, err := client.VerifyToken(ctx, "tok")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyToken: %v", err)
+			}
+			if data.OperatorID != tt.want {
+				t.Fatalf("operator = %q, want %q", data.OperatorID, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	authJWT "github.com/joe_shih/slot-factory/internal/adapter/auth/jwt"
+	"github.com/joe_shih/slot-factory/internal/application/audit"
+	"github.com/joe_shih/slot-factory/internal/application/login"
+	"github.com/joe_shih/slot-factory/internal/config"
+)
+
+// Role 是 REST API 呼叫端的角色，權限由低到高為 viewer < support < admin。
+type Role string
+
+const (
+	// RoleViewer 只能查詢遊戲列表等公開資訊。
+	RoleViewer Role = "viewer"
+	// RoleSupport 可以查詢玩家的交易紀錄。
+	RoleSupport Role = "support"
+	// RoleAdmin 可以執行踢線、發放紅利等管理操作。
+	RoleAdmin Role = "admin"
+)
+
+// roleRank 定義角色的權限高低，數字越大權限越高。
+var roleRank = map[Role]int{
+	RoleViewer:  1,
+	RoleSupport: 2,
+	RoleAdmin:   3,
+}
+
+// 驗證方式
+const (
+	MethodAPIKey    = "api_key"
+	MethodJWT       = "jwt"
+	MethodAnonymous = "anonymous"
+)
+
+// principalKey 是 Principal 存放在 gin.Context 中的 key。
+const principalKey = "principal"
+
+// maxAuditBodySize 是稽核紀錄保存的請求 body 上限，超過的部分會被截斷。
+const maxAuditBodySize = 64 << 10
+
+// maxAdminBodySize 是管理操作請求 body 的上限，超過時讀取 body 會失敗。
+const maxAdminBodySize = 1 << 20
+
+// Principal 是通過驗證的呼叫端。
+type Principal struct {
+	Name   string
+	Role   Role
+	Method string
+}
+
+// allows 判斷 p 的角色是否達到 role 的權限。
+func (p Principal) allows(role Role) bool {
+	return roleRank[p.Role] >= roleRank[role]
+}
+
+// adminClaims 是後台操作人員 JWT 的內容，sub 為操作者。
+type adminClaims struct {
+	Role string `json:"role"`
+	gojwt.RegisteredClaims
+}
+
+// Authenticator 驗證 REST API 請求的 API Key 或 JWT。
+type Authenticator struct {
+	enabled  bool
+	keys     map[[sha256.Size]byte]Principal // key: API Key 的雜湊，避免以明文做字串比對
+	verifier *authJWT.Verifier
+}
+
+// NewAuthenticator 建立一個 REST API 驗證器。
+//
+// 參數說明：
+//   - cfg: config.APIAuthConfig, API Key 列表與操作人員 JWT 的驗證設定。
+//
+// 回傳值：
+//   - *Authenticator: 初始化完成的驗證器。
+//   - error: 如果 API Key 的角色不合法，或 JWT 設定有誤，則返回錯誤。
+func NewAuthenticator(cfg config.APIAuthConfig) (*Authenticator, error) {
+	a := &Authenticator{
+		enabled: cfg.Enabled,
+		keys:    make(map[[sha256.Size]byte]Principal, len(cfg.Keys)),
+	}
+	for _, k := range cfg.Keys {
+		role := Role(k.Role)
+		if _, ok := roleRank[role]; !ok {
+			return nil, fmt.Errorf("api key %q has unknown role %q", k.Name, k.Role)
+		}
+		if k.Key == "" {
+			return nil, fmt.Errorf("api key %q is empty", k.Name)
+		}
+		a.keys[sha256.Sum256([]byte(k.Key))] = Principal{Name: k.Name, Role: role, Method: MethodAPIKey}
+	}
+	if cfg.JWT.JWKSURL != "" || cfg.JWT.JWKSFile != "" || cfg.JWT.HMACSecret != "" {
+		v, err := authJWT.NewVerifier(cfg.JWT)
+		if err != nil {
+			return nil, err
+		}
+		a.verifier = v
+	}
+	return a, nil
+}
+
+// Authenticate 回傳驗證身分的 middleware。
+//
+// 驗證通過後將 Principal 存入 gin.Context；缺少或無效的憑證回傳 401，
+// JWKS 暫時無法取得時回傳 503。未啟用驗證時所有請求視為匿名 admin。
+func (a *Authenticator) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !a.enabled {
+			c.Set(principalKey, Principal{Name: "anonymous", Role: RoleAdmin, Method: MethodAnonymous})
+			c.Next()
+			return
+		}
+
+		p, status, err := a.authenticate(c)
+		if err != nil {
+			c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.Set(principalKey, p)
+		c.Next()
+	}
+}
+
+// authenticate 依序檢查 X-API-Key 與 Authorization 標頭。
+func (a *Authenticator) authenticate(c *gin.Context) (Principal, int, error) {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		p, ok := a.keys[sha256.Sum256([]byte(key))]
+		if !ok {
+			return Principal{}, http.StatusUnauthorized, errors.New("invalid api key")
+		}
+		return p, http.StatusOK, nil
+	}
+
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return Principal{}, http.StatusUnauthorized, errors.New("missing credentials")
+	}
+	if a.verifier == nil {
+		return Principal{}, http.StatusUnauthorized, errors.New("jwt authentication is not configured")
+	}
+
+	var claims adminClaims
+	if err := a.verifier.Verify(c.Request.Context(), token, &claims); err != nil {
+		switch {
+		case errors.Is(err, login.ErrAuthUnavailable):
+			return Principal{}, http.StatusServiceUnavailable, errors.New("authentication temporarily unavailable")
+		case errors.Is(err, login.ErrTokenExpired):
+			return Principal{}, http.StatusUnauthorized, errors.New("token expired")
+		default:
+			return Principal{}, http.StatusUnauthorized, errors.New("invalid token")
+		}
+	}
+	role := Role(claims.Role)
+	if _, ok := roleRank[role]; !ok || claims.Subject == "" {
+		return Principal{}, http.StatusUnauthorized, errors.New("token missing sub or valid role claim")
+	}
+	return Principal{Name: claims.Subject, Role: role, Method: MethodJWT}, http.StatusOK, nil
+}
+
+// RequireRole 回傳檢查角色的 middleware，權限不足時回傳 403。
+// 必須放在 Authenticate 之後。
+func RequireRole(role Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := PrincipalFrom(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+			return
+		}
+		if !p.allows(role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("requires %s role", role)})
+			return
+		}
+		c.Next()
+	}
+}
+
+// PrincipalFrom 取得 Authenticate 存入的呼叫端。
+func PrincipalFrom(c *gin.Context) (Principal, bool) {
+	v, ok := c.Get(principalKey)
+	if !ok {
+		return Principal{}, false
+	}
+	p, ok := v.(Principal)
+	return p, ok
+}
+
+// Audit 回傳記錄管理操作的 middleware。
+//
+// 每個請求 (包含被拒絕的) 都會在處理完成後寫入一筆紀錄，內容為操作者、時間、參數與回應狀態碼。
+// body 不會預先讀取，只保存 handler 實際讀到的前 maxAuditBodySize 位元組，
+// 因此在 RequireRole 被拒絕的請求只記錄狀態碼；body 超過 maxAdminBodySize 時讀取會失敗。
+// 寫入失敗只記錄錯誤，不影響已完成的操作。
+//
+// 參數說明：
+//   - store: audit.Store, 稽核紀錄的儲存實作。
+//   - logger: *slog.Logger, 寫入失敗時使用的日誌。
+//
+// 回傳值：
+//   - gin.HandlerFunc: 稽核 middleware，必須放在 Authenticate 之後。
+func Audit(store audit.Store, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body *auditBody
+		if c.Request.Body != nil {
+			body = &auditBody{ReadCloser: http.MaxBytesReader(c.Writer, c.Request.Body, maxAdminBodySize)}
+			c.Request.Body = body
+		}
+		startedAt := time.Now()
+
+		c.Next()
+
+		p, _ := PrincipalFrom(c)
+		entry := audit.Entry{
+			Actor:     p.Name,
+			Role:      string(p.Role),
+			Method:    p.Method,
+			Action:    c.Request.Method + " " + c.FullPath(),
+			Params:    auditParams(c, body),
+			RemoteIP:  c.ClientIP(),
+			Status:    c.Writer.Status(),
+			CreatedAt: startedAt,
+		}
+		// 請求結束後 ctx 可能已被取消，稽核紀錄仍必須寫入
+		if err := store.Record(context.WithoutCancel(c.Request.Context()), entry); err != nil {
+			logger.Error("failed to record audit entry", "error", err, "actor", entry.Actor, "action", entry.Action)
+		}
+	}
+}
+
+// auditBody 在 handler 讀取請求 body 時保存前 maxAuditBodySize 位元組。
+type auditBody struct {
+	io.ReadCloser
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	keep := min(n, maxAuditBodySize-b.buf.Len())
+	b.buf.Write(p[:keep])
+	if keep < n {
+		b.truncated = true
+	}
+	return n, err
+}
+
+// auditParams 將 query 與 body 組合成 JSON 字串。body 不是合法 JSON 或被截斷時以字串保存。
+func auditParams(c *gin.Context, body *auditBody) string {
+	params := make(map[string]any, 3)
+	if q := c.Request.URL.Query(); len(q) > 0 {
+		params["query"] = q
+	}
+	if body != nil && body.buf.Len() > 0 {
+		data := body.buf.Bytes()
+		if body.truncated {
+			params["body"] = string(data)
+			params["truncated"] = true
+		} else if json.Valid(data) {
+			params["body"] = json.RawMessage(data)
+		} else {
+			params["body"] = string(data)
+		}
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joe_shih/slot-factory/internal/application/audit"
+	"github.com/joe_shih/slot-factory/internal/config"
+)
+
+// recordStore 是保存在記憶體中的 audit.Store。
+type recordStore struct {
+	mu      sync.Mutex
+	entries []audit.Entry
+}
+
+func (s *recordStore) Record(_ context.Context, e audit.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+// newAuditRouter 建立與 cmd/api 相同順序的 admin 路由，handler 讀取整個 body 並回傳讀到的長度。
+func newAuditRouter(t *testing.T, store audit.Store) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	auth, err := NewAuthenticator(config.APIAuthConfig{
+		Enabled: true,
+		Keys: []config.APIKeyConfig{
+			{Name: "ops", Key: "admin-key", Role: string(RoleAdmin)},
+			{Name: "dashboard", Key: "viewer-key", Role: string(RoleViewer)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+	r := gin.New()
+	admin := r.Group("/admin", auth.Authenticate(), Audit(store, slog.New(slog.NewTextHandler(io.Discard, nil))), RequireRole(RoleAdmin))
+	admin.POST("/op", func(c *gin.Context) {
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"read": len(data)})
+	})
+	return r
+}
+
+func TestAuditBody(t *testing.T) {
+	tests := []struct {
+		name          string
+		key           string
+		body          string
+		wantStatus    int
+		wantBody      bool
+		wantTruncated bool
+	}{
+		{name: "denied request records status only", key: "viewer-key", body: `{"all":true}`, wantStatus: http.StatusForbidden},
+		{name: "small json body", key: "admin-key", body: `{"all":true}`, wantStatus: http.StatusOK, wantBody: true},
+		{name: "large body truncated", key: "admin-key", body: strings.Repeat("a", maxAuditBodySize+10), wantStatus: http.StatusOK, wantBody: true, wantTruncated: true},
+		{name: "body over admin cap", key: "admin-key", body: strings.Repeat("a", maxAdminBodySize+1), wantStatus: http.StatusRequestEntityTooLarge, wantBody: true, wantTruncated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &recordStore{}
+			r := newAuditRouter(t, store)
+			req := httptest.NewRequest(http.MethodPost, "/admin/op", strings.NewReader(tt.body))
+			req.Header.Set("X-API-Key", tt.key)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if len(store.entries) != 1 {
+				t.Fatalf("recorded %d entries, want 1", len(store.entries))
+			}
+			entry := store.entries[0]
+			if entry.Status != tt.wantStatus {
+				t.Fatalf("audit status = %d, want %d", entry.Status, tt.wantStatus)
+			}
+			var params struct {
+				Body      json.RawMessage `json:"body"`
+				Truncated bool            `json:"truncated"`
+			}
+			if err := json.Unmarshal([]byte(entry.Params), &params); err != nil {
+				t.Fatalf("audit params %q: %v", entry.Params, err)
+			}
+			if got := len(params.Body) > 0; got != tt.wantBody {
+				t.Fatalf("audit has body = %v, want %v", got, tt.wantBody)
+			}
+			if params.Truncated != tt.wantTruncated {
+				t.Fatalf("audit truncated = %v, want %v", params.Truncated, tt.wantTruncated)
+			}
+			if len(params.Body) > maxAuditBodySize+2 {
+				t.Fatalf("audit body length %d exceeds cap", len(params.Body))
+			}
+		})
+	}
+}
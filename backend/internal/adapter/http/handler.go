@@ -1,12 +1,17 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joe_shih/slot-factory/internal/application/gamecenter"
 	"github.com/joe_shih/slot-factory/internal/application/wallet"
+	"github.com/joe_shih/slot-factory/internal/domain/operator"
+	"github.com/shopspring/decimal"
 )
 
 // Handler 處理所有 REST API 請求。
@@ -14,6 +19,8 @@ type Handler struct {
 	gameProvider  gamecenter.GameProvider
 	adminProvider gamecenter.AdminProvider
 	history       wallet.HistoryProvider
+	bonus         wallet.BonusProvider
+	operators     *operator.Registry
 }
 
 // NewHandler 建立一個新的 HTTP Handler 實例。
@@ -24,25 +31,80 @@ type Handler struct {
 //   - gp: gamecenter.GameProvider, 提供遊戲查詢功能。
 //   - ap: gamecenter.AdminProvider, 提供管理員指令功能。
 //   - hp: wallet.HistoryProvider, 提供錢包歷史查詢功能。
+//   - bp: wallet.BonusProvider, 提供紅利發放功能。
+//   - operators: *operator.Registry, 營運商註冊表，用於檢查請求指定的營運商是否存在。
 //
 // 回傳值：
 //   - *Handler: 初始化完成的 HTTP Handler 指標。
-func NewHandler(gp gamecenter.GameProvider, ap gamecenter.AdminProvider, hp wallet.HistoryProvider) *Handler {
+func NewHandler(gp gamecenter.GameProvider, ap gamecenter.AdminProvider, hp wallet.HistoryProvider, bp wallet.BonusProvider, operators *operator.Registry) *Handler {
 	return &Handler{
 		gameProvider:  gp,
 		adminProvider: ap,
 		history:       hp,
+		bonus:         bp,
+		operators:     operators,
 	}
 }
 
-// HandleGetGames 回回傳所有註冊的遊戲列表。
+// HandleGetGames 回傳遊戲目錄，包含名稱、類型、RTP、各幣種的下注限制、上下架狀態與線上人數。
+//
+// 方法：GET /api/v1/games?operatorID=...
+// 帶上 operatorID 時只回傳該營運商開放的遊戲，下注限制也會套用營運商的設定。
 func (h *Handler) HandleGetGames(c *gin.Context) {
-	games := h.gameProvider.GetGames(c.Request.Context())
+	games, err := h.gameProvider.GetGames(c.Request.Context(), c.Query("operatorID"))
+	if err != nil {
+		if errors.Is(err, operator.ErrUnknownOperator) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"games": games,
 	})
 }
 
+// setGameStatusRequest 是上下架遊戲的請求內容。
+type setGameStatusRequest struct {
+	Status gamecenter.GameStatus `json:"status" binding:"required"`
+}
+
+// HandleSetGameStatus 處理上架或下架遊戲的請求。
+//
+// 下架後所有服務實體會拒絕新的加入與下注，進行中的局仍會正常結算。
+// 方法：POST /api/v1/admin/games/:id/status
+//
+// 參數說明：
+//   - c: *gin.Context, Gin 框架的 Context，body 為 {"status": "enabled" | "disabled"}。
+//
+// 回傳值：
+//   - JSON Response: 成功時回傳 200 OK，參數錯誤時回傳 400，遊戲不存在時回傳 404。
+func (h *Handler) HandleSetGameStatus(c *gin.Context) {
+	gameID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game id"})
+		return
+	}
+	var req setGameStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.adminProvider.SetGameStatus(c.Request.Context(), gameID, req.Status)
+	switch {
+	case errors.Is(err, gamecenter.ErrInvalidGameStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, gamecenter.ErrGameNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"gameId": gameID, "status": req.Status})
+	}
+}
+
 // HandleKickAll 處理全域踢除玩家的請求。
 //
 // 此 API 會廣播踢線指令到所有 WebSocket 伺服器實體。
@@ -61,7 +123,96 @@ func (h *Handler) HandleKickAll(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "kick all signal sent"})
 }
 
+// HandleListMaintenance 回傳尚未結束的維護時段。
+//
+// 方法：GET /api/v1/maintenance
+func (h *Handler) HandleListMaintenance(c *gin.Context) {
+	windows, err := h.adminProvider.ListMaintenance(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"windows": windows})
+}
+
+// scheduleMaintenanceRequest 是排定維護的請求內容。
+//
+// StartAt 省略時立即開始；結束時間以 EndAt 或 DurationSec 擇一指定。
+type scheduleMaintenanceRequest struct {
+	GameID      int        `json:"gameId"`
+	StartAt     *time.Time `json:"startAt"`
+	EndAt       *time.Time `json:"endAt"`
+	DurationSec int        `json:"durationSec"`
+	Message     string     `json:"message"`
+}
+
+// HandleScheduleMaintenance 處理排定維護的請求。
+//
+// 開始前會向受影響的玩家廣播倒數通知，維護期間拒絕新的登入與下注，時間到後自動解除。
+// 方法：POST /api/v1/admin/maintenance
+//
+// 參數說明：
+//   - c: *gin.Context, Gin 框架的 Context，body 為 {"gameId": 0, "startAt": "...", "endAt": "...", "message": "..."}，gameId 為 0 表示全平台維護。
+//
+// 回傳值：
+//   - JSON Response: 成功時回傳 201 Created 與排定的時段，參數錯誤時回傳 400，遊戲不存在時回傳 404。
+func (h *Handler) HandleScheduleMaintenance(c *gin.Context) {
+	var req scheduleMaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	window := gamecenter.MaintenanceWindow{GameID: req.GameID, Message: req.Message}
+	if req.StartAt != nil {
+		window.StartAt = *req.StartAt
+	}
+	switch {
+	case req.EndAt != nil:
+		window.EndAt = *req.EndAt
+	case req.DurationSec > 0:
+		start := window.StartAt
+		if start.IsZero() {
+			start = time.Now()
+		}
+		window.EndAt = start.Add(time.Duration(req.DurationSec) * time.Second)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "endAt or durationSec is required"})
+		return
+	}
+
+	scheduled, err := h.adminProvider.ScheduleMaintenance(c.Request.Context(), window)
+	switch {
+	case errors.Is(err, gamecenter.ErrInvalidMaintenanceWindow):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, gamecenter.ErrGameNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusCreated, gin.H{"window": scheduled})
+	}
+}
+
+// HandleCancelMaintenance 處理取消維護的請求，已開始的維護會立即解除。
+//
+// 方法：DELETE /api/v1/admin/maintenance/:id
+func (h *Handler) HandleCancelMaintenance(c *gin.Context) {
+	err := h.adminProvider.CancelMaintenance(c.Request.Context(), c.Param("id"))
+	switch {
+	case errors.Is(err, gamecenter.ErrMaintenanceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "maintenance cancelled"})
+	}
+}
+
 // HandleGetHistory 回傳玩家的交易歷史紀錄。
+//
+// 方法：GET /api/v1/history?playerID=...&operatorID=...&limit=20
+// 帶上 operatorID 時只回傳該營運商的紀錄，否則合併所有營運商的紀錄。
 func (h *Handler) HandleGetHistory(c *gin.Context) {
 	playerID := c.Query("playerID")
 	if playerID == "" {
@@ -75,14 +226,65 @@ func (h *Handler) HandleGetHistory(c *gin.Context) {
 		limit = 20
 	}
 
-	history, pErr := h.history.GetHistory(playerID, limit)
+	ctx := c.Request.Context()
+	operatorID := c.Query("operatorID")
+	if operatorID != "" {
+		ctx = operator.NewContext(ctx, operatorID)
+	}
+
+	history, pErr := h.history.GetHistory(ctx, playerID, limit)
 	if pErr != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": pErr.Message})
+		c.JSON(pErr.Code, gin.H{"error": pErr.Message})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"playerID": playerID,
-		"history":  history,
+		"playerID":   playerID,
+		"operatorID": operatorID,
+		"history":    history,
 	})
 }
+
+// grantBonusRequest 是發放紅利的請求內容。
+// 玩家 ID 只在營運商內唯一，因此必須指定營運商。
+type grantBonusRequest struct {
+	OperatorID         string          `json:"operatorID" binding:"required"`
+	PlayerID           string          `json:"playerID" binding:"required"`
+	Currency           string          `json:"currency" binding:"required"`
+	Amount             decimal.Decimal `json:"amount"`
+	WageringMultiplier decimal.Decimal `json:"wageringMultiplier"`
+}
+
+// HandleGrantBonus 處理發放紅利的請求。
+//
+// 方法：POST /api/v1/admin/bonus
+//
+// 參數說明：
+//   - c: *gin.Context, Gin 框架的 Context。
+//
+// 回傳值：
+//   - JSON Response: 成功時回傳 200 OK 與建立的紅利，參數錯誤或營運商不存在時回傳 400 Bad Request。
+func (h *Handler) HandleGrantBonus(c *gin.Context) {
+	var req grantBonusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Resolve 會把空字串視為預設營運商，必須先排除，避免紅利發給預設營運商底下的同名玩家
+	if strings.TrimSpace(req.OperatorID) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "operatorID is required"})
+		return
+	}
+	if _, err := h.operators.Resolve(req.OperatorID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := operator.NewContext(c.Request.Context(), req.OperatorID)
+	bonus, pErr := h.bonus.GrantBonus(ctx, req.PlayerID, req.Currency, req.Amount, req.WageringMultiplier)
+	if pErr != nil {
+		c.JSON(pErr.Code, gin.H{"error": pErr.Message})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"bonus": bonus})
+}
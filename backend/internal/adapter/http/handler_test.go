@@ -0,0 +1,72 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joe_shih/slot-factory/internal/application/wallet"
+	"github.com/joe_shih/slot-factory/internal/domain/operator"
+	"github.com/shopspring/decimal"
+)
+
+// recordBonus 是記錄發放對象營運商的 wallet.BonusProvider。
+type recordBonus struct {
+	operators []string
+}
+
+func (b *recordBonus) GrantBonus(ctx context.Context, playerID, currency string, amount, wageringMultiplier decimal.Decimal) (*wallet.Bonus, *wallet.PaymentError) {
+	id, _ := operator.IDFromContext(ctx)
+	b.operators = append(b.operators, id)
+	return &wallet.Bonus{PlayerID: playerID, Currency: currency}, nil
+}
+
+func TestHandleGrantBonusValidatesOperator(t *testing.T) {
+	operators, err := operator.NewRegistry("default", operator.Operator{ID: "default"}, operator.Operator{ID: "op-a"})
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	tests := []struct {
+		name       string
+		operatorID string
+		wantStatus int
+	}{
+		{name: "known operator", operatorID: `"op-a"`, wantStatus: http.StatusOK},
+		{name: "unknown operator", operatorID: `"op-b"`, wantStatus: http.StatusBadRequest},
+		{name: "empty operator", operatorID: `""`, wantStatus: http.StatusBadRequest},
+		{name: "blank operator", operatorID: `"  "`, wantStatus: http.StatusBadRequest},
+		{name: "missing operator", wantStatus: http.StatusBadRequest},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bonus := &recordBonus{}
+			h := NewHandler(nil, nil, nil, bonus, operators)
+			r := gin.New()
+			r.POST("/bonus", h.HandleGrantBonus)
+
+			body := `{"playerID":"p1","currency":"TWD","amount":"10","wageringMultiplier":"1"`
+			if tt.operatorID != "" {
+				body += `,"operatorID":` + tt.operatorID
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/bonus", strings.NewReader(body+"}")))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if len(bonus.operators) != 0 {
+					t.Fatalf("bonus granted for rejected request: %v", bonus.operators)
+				}
+				return
+			}
+			if len(bonus.operators) != 1 || bonus.operators[0] != "op-a" {
+				t.Fatalf("bonus granted under operators %v, want [op-a]", bonus.operators)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joe_shih/slot-factory/internal/application/wallet"
+)
+
+// HealthHandler 處理健康檢查相關的 HTTP 請求。
+type HealthHandler struct {
+	wallet wallet.HealthProvider
+}
+
+// NewHealthHandler 建立一個新的健康檢查 Handler。
+//
+// 參數說明：
+//   - wp: wallet.HealthProvider, 提供錢包依賴的健康狀態。
+//
+// 回傳值：
+//   - *HealthHandler: 初始化完成的健康檢查 Handler 指標。
+func NewHealthHandler(wp wallet.HealthProvider) *HealthHandler {
+	return &HealthHandler{wallet: wp}
+}
+
+// HandleWalletHealth 回傳外接錢包（斷路器）的健康狀態。
+//
+// 方法：GET /healthz/wallet
+//
+// 回傳值：
+//   - JSON Response: 健康時回傳 200 OK，斷路器開啟時回傳 503 Service Unavailable。
+func (h *HealthHandler) HandleWalletHealth(c *gin.Context) {
+	status := h.wallet.Health()
+	code := http.StatusOK
+	if !status.Healthy {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, status)
+}
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DrainStatus 提供服務實體是否正在排空連線。
+type DrainStatus interface {
+	Draining() bool
+}
+
+// ReadinessHandler 處理 k8s readiness probe，服務實體開始排空連線後回報未就緒，
+// 讓 Service 停止將新的連線導向此實體。
+type ReadinessHandler struct {
+	drain DrainStatus
+}
+
+// NewReadinessHandler 建立一個新的 readiness Handler。
+//
+// 參數說明：
+//   - drain: DrainStatus, 提供服務實體是否正在排空連線。
+//
+// 回傳值：
+//   - *ReadinessHandler: 初始化完成的 readiness Handler 指標。
+func NewReadinessHandler(drain DrainStatus) *ReadinessHandler {
+	return &ReadinessHandler{drain: drain}
+}
+
+// HandleReady 回傳服務實體是否可以接收新的連線。
+//
+// 方法：GET /readyz
+//
+// 回傳值：
+//   - JSON Response: 可以接收連線時回傳 200 OK，排空連線時回傳 503 Service Unavailable。
+func (h *ReadinessHandler) HandleReady(c *gin.Context) {
+	if h.drain.Draining() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"ready": false, "reason": "draining"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ready": true})
+}
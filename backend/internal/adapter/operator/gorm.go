@@ -0,0 +1,81 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/joe_shih/slot-factory/internal/config"
+	"gorm.io/gorm"
+)
+
+// OperatorModel 對應資料庫的 operators 表。
+//
+// 串接設定 (驗證方式、錢包端點、憑證) 與營運規則以 JSON 存放在 settings 欄位，
+// 欄位名稱與設定檔的 operators.list 相同，例如 {"wallet": {"baseUrl": "..."}, "currencies": ["TWD"]}。
+type OperatorModel struct {
+	ID        string    `gorm:"primaryKey;column:id"`
+	Name      string    `gorm:"column:name"`
+	Enabled   bool      `gorm:"column:enabled"`
+	Settings  string    `gorm:"column:settings"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+func (OperatorModel) TableName() string {
+	return "operators"
+}
+
+// GormStore 從資料庫讀取營運商設定。
+type GormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore 建立一個以 gorm 為底層的營運商設定來源。
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+// List 回傳所有啟用中的營運商設定，依 ID 排序。
+func (s *GormStore) List(ctx context.Context) ([]config.OperatorConfig, error) {
+	var models []OperatorModel
+	if err := s.db.WithContext(ctx).Where("enabled = ?", true).Order("id ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	list := make([]config.OperatorConfig, 0, len(models))
+	for _, m := range models {
+		oc, err := decodeSettings(m)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, oc)
+	}
+	return list, nil
+}
+
+// decodeSettings 以與設定檔相同的欄位名稱解析 settings 欄位。
+func decodeSettings(m OperatorModel) (config.OperatorConfig, error) {
+	var raw map[string]any
+	if m.Settings != "" {
+		if err := json.Unmarshal([]byte(m.Settings), &raw); err != nil {
+			return config.OperatorConfig{}, fmt.Errorf("invalid settings for operator %s: %w", m.ID, err)
+		}
+	}
+
+	var oc config.OperatorConfig
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName:          "mapstructure",
+		WeaklyTypedInput: true,
+		Result:           &oc,
+	})
+	if err != nil {
+		return config.OperatorConfig{}, err
+	}
+	if err := decoder.Decode(raw); err != nil {
+		return config.OperatorConfig{}, fmt.Errorf("invalid settings for operator %s: %w", m.ID, err)
+	}
+	oc.ID = m.ID
+	oc.Name = m.Name
+	return oc, nil
+}
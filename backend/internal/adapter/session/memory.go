@@ -0,0 +1,86 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/joe_shih/slot-factory/internal/application/session"
+)
+
+// MemoryStore 是 session.Store 的記憶體實作，用於沒有 Redis 的本地開發。
+// session 只在同一個服務實體內有效。
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	session   session.Session
+	expiresAt time.Time
+}
+
+var _ session.Store = (*MemoryStore)(nil)
+
+// NewMemoryStore 建立一個記憶體 session 儲存。
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]memoryEntry)}
+}
+
+func (s *MemoryStore) Save(_ context.Context, sess session.Session, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purgeLocked()
+	s.sessions[sess.Token] = memoryEntry{session: sess, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, token string) (session.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.sessions[token]
+	if !ok || time.Now().After(e.expiresAt) {
+		delete(s.sessions, token)
+		return session.Session{}, session.ErrSessionNotFound
+	}
+	return e.session, nil
+}
+
+func (s *MemoryStore) Take(_ context.Context, token string) (session.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.sessions[token]
+	delete(s.sessions, token)
+	if !ok || time.Now().After(e.expiresAt) {
+		return session.Session{}, session.ErrSessionNotFound
+	}
+	return e.session, nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, token)
+	return nil
+}
+
+func (s *MemoryStore) DeletePlayer(_ context.Context, operatorID string, playerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for token, e := range s.sessions {
+		if e.session.OperatorID == operatorID && e.session.PlayerID == playerID {
+			delete(s.sessions, token)
+		}
+	}
+	return nil
+}
+
+// purgeLocked 清除已過期的 session。呼叫前必須持有鎖。
+func (s *MemoryStore) purgeLocked() {
+	now := time.Now()
+	for token, e := range s.sessions {
+		if now.After(e.expiresAt) {
+			delete(s.sessions, token)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/joe_shih/slot-factory/internal/application/session"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis 相關常數
+const (
+	RedisKeySessionPrefix        = "session:%s"
+	RedisKeyPlayerSessionsPrefix = "player_sessions:%s:%s"
+)
+
+// RedisStore 是 session.Store 的 Redis 實作，讓玩家可以重連到任何一個服務實體。
+//
+// 每個 session 存成一個帶 TTL 的 key，另外以玩家為單位維護一個 token 集合，用於踢線時撤銷所有 session。
+type RedisStore struct {
+	rdb *redis.Client
+}
+
+var _ session.Store = (*RedisStore)(nil)
+
+// NewRedisStore 建立一個 Redis session 儲存。
+func NewRedisStore(rdb *redis.Client) *RedisStore {
+	return &RedisStore{rdb: rdb}
+}
+
+func (s *RedisStore) Save(ctx context.Context, sess session.Session, ttl time.Duration) error {
+	data, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	playerKey := fmt.Sprintf(RedisKeyPlayerSessionsPrefix, sess.OperatorID, sess.PlayerID)
+	pipe := s.rdb.TxPipeline()
+	pipe.Set(ctx, fmt.Sprintf(RedisKeySessionPrefix, sess.Token), data, ttl)
+	pipe.SAdd(ctx, playerKey, sess.Token)
+	pipe.Expire(ctx, playerKey, ttl)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) Get(ctx context.Context, token string) (session.Session, error) {
+	data, err := s.rdb.Get(ctx, fmt.Sprintf(RedisKeySessionPrefix, token)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return session.Session{}, session.ErrSessionNotFound
+	}
+	if err != nil {
+		return session.Session{}, err
+	}
+	var sess session.Session
+	if err := json.Unmarshal(data, &sess); err != nil {
+		return session.Session{}, err
+	}
+	return sess, nil
+}
+
+// Take 以 GETDEL 原子地取出並刪除 session，只有一個併發的呼叫會拿到資料。
+func (s *RedisStore) Take(ctx context.Context, token string) (session.Session, error) {
+	data, err := s.rdb.GetDel(ctx, fmt.Sprintf(RedisKeySessionPrefix, token)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return session.Session{}, session.ErrSessionNotFound
+	}
+	if err != nil {
+		return session.Session{}, err
+	}
+	var sess session.Session
+	if err := json.Unmarshal(data, &sess); err != nil {
+		return session.Session{}, err
+	}
+	// 玩家的 token 集合只用於撤銷，token 已經失效，移除失敗只會留下隨集合過期的殘留項目
+	_ = s.rdb.SRem(ctx, fmt.Sprintf(RedisKeyPlayerSessionsPrefix, sess.OperatorID, sess.PlayerID), token).Err()
+	return sess, nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, token string) error {
+	_, err := s.Take(ctx, token)
+	if errors.Is(err, session.ErrSessionNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (s *RedisStore) DeletePlayer(ctx context.Context, operatorID string, playerID string) error {
+	playerKey := fmt.Sprintf(RedisKeyPlayerSessionsPrefix, operatorID, playerID)
+	tokens, err := s.rdb.SMembers(ctx, playerKey).Result()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(tokens)+1)
+	for _, token := range tokens {
+		keys = append(keys, fmt.Sprintf(RedisKeySessionPrefix, token))
+	}
+	keys = append(keys, playerKey)
+	return s.rdb.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,188 @@
+package bonus
+
+import (
+	"context"
+	"time"
+
+	"github.com/joe_shih/slot-factory/internal/application/wallet"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BonusModel 對應資料庫的 wallet_bonuses 表。
+type BonusModel struct {
+	ID               int64           `gorm:"primaryKey;autoIncrement"`
+	OperatorID       string          `gorm:"column:operator_id"`
+	PlayerID         string          `gorm:"column:player_id"`
+	Currency         string          `gorm:"column:currency"`
+	Granted          decimal.Decimal `gorm:"column:granted;type:decimal(30,8)"`
+	Balance          decimal.Decimal `gorm:"column:balance;type:decimal(30,8)"`
+	WageringRequired decimal.Decimal `gorm:"column:wagering_required;type:decimal(30,8)"`
+	Wagered          decimal.Decimal `gorm:"column:wagered;type:decimal(30,8)"`
+	Status           string          `gorm:"column:status"`
+	CreatedAt        time.Time       `gorm:"column:created_at"`
+	UpdatedAt        time.Time       `gorm:"column:updated_at"`
+}
+
+func (BonusModel) TableName() string {
+	return "wallet_bonuses"
+}
+
+// StakeModel 對應資料庫的 wallet_bonus_stakes 表，保存尚未結算的下注。
+// 每個營運商的每位玩家每個幣種一列，同時作為 WithLock 鎖定紅利帳時的鎖。
+type StakeModel struct {
+	OperatorID string          `gorm:"column:operator_id;primaryKey"`
+	PlayerID   string          `gorm:"column:player_id;primaryKey"`
+	Currency   string          `gorm:"column:currency;primaryKey"`
+	Total      decimal.Decimal `gorm:"column:total;type:decimal(30,8)"`
+	Bonus      decimal.Decimal `gorm:"column:bonus;type:decimal(30,8)"`
+	UpdatedAt  time.Time       `gorm:"column:updated_at"`
+}
+
+func (StakeModel) TableName() string {
+	return "wallet_bonus_stakes"
+}
+
+// GormStore 是 wallet.BonusStore 的資料庫實作，讓多個服務實體共用紅利狀態。
+type GormStore struct {
+	db *gorm.DB
+}
+
+var _ wallet.BonusStore = (*GormStore)(nil)
+
+// NewGormStore 建立一個以 gorm 為底層的紅利儲存。
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+// ListActive 回傳玩家指定幣種所有進行中的紅利，依發放時間由舊到新排序。
+func (s *GormStore) ListActive(ctx context.Context, operatorID string, playerID string, currency string) ([]*wallet.Bonus, error) {
+	var models []BonusModel
+	err := s.db.WithContext(ctx).Where("operator_id = ? AND player_id = ? AND currency = ? AND status = ?", operatorID, playerID, currency, string(wallet.BonusActive)).
+		Order("id ASC").
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*wallet.Bonus, len(models))
+	for i, m := range models {
+		list[i] = toBonus(m)
+	}
+	return list, nil
+}
+
+// Create 新增一筆紅利並填入 ID。
+func (s *GormStore) Create(ctx context.Context, bonus *wallet.Bonus) error {
+	m := toModel(bonus)
+	if err := s.db.WithContext(ctx).Create(&m).Error; err != nil {
+		return err
+	}
+	bonus.ID = m.ID
+	return nil
+}
+
+// WithLock 在資料庫交易中以 SELECT ... FOR UPDATE 鎖定玩家的未結算下注列後執行 fn，
+// 其他實體對同一玩家同一幣種的操作會等待交易結束。fn 回傳錯誤時交易會被回滾。
+func (s *GormStore) WithLock(ctx context.Context, operatorID string, playerID string, currency string, fn func(tx wallet.BonusTx) error) error {
+	return s.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		// 先確保鎖定用的列存在，沒有紅利或未結算下注的玩家也能被序列化
+		seed := StakeModel{OperatorID: operatorID, PlayerID: playerID, Currency: currency, Total: decimal.Zero, Bonus: decimal.Zero, UpdatedAt: time.Now()}
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&seed).Error; err != nil {
+			return err
+		}
+		var stake StakeModel
+		err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("operator_id = ? AND player_id = ? AND currency = ?", operatorID, playerID, currency).
+			Take(&stake).Error
+		if err != nil {
+			return err
+		}
+		return fn(&gormTx{db: db, operatorID: operatorID, playerID: playerID, currency: currency})
+	})
+}
+
+// gormTx 是 GormStore 鎖定期間的操作，所有查詢都在同一個交易中執行。
+type gormTx struct {
+	db         *gorm.DB
+	operatorID string
+	playerID   string
+	currency   string
+}
+
+func (t *gormTx) ListActive(ctx context.Context) ([]*wallet.Bonus, error) {
+	var models []BonusModel
+	err := t.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("operator_id = ? AND player_id = ? AND currency = ? AND status = ?", t.operatorID, t.playerID, t.currency, string(wallet.BonusActive)).
+		Order("id ASC").
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*wallet.Bonus, len(models))
+	for i, m := range models {
+		list[i] = toBonus(m)
+	}
+	return list, nil
+}
+
+// Update 更新紅利的餘額、流水進度與狀態。
+func (t *gormTx) Update(ctx context.Context, bonus *wallet.Bonus) error {
+	return t.db.WithContext(ctx).Model(&BonusModel{ID: bonus.ID}).Updates(map[string]interface{}{
+		"balance":    bonus.Balance,
+		"wagered":    bonus.Wagered,
+		"status":     string(bonus.Status),
+		"updated_at": bonus.UpdatedAt,
+	}).Error
+}
+
+func (t *gormTx) Stake(ctx context.Context) (wallet.Stake, error) {
+	var m StakeModel
+	err := t.db.WithContext(ctx).Where("operator_id = ? AND player_id = ? AND currency = ?", t.operatorID, t.playerID, t.currency).Take(&m).Error
+	if err != nil {
+		return wallet.Stake{}, err
+	}
+	return wallet.Stake{Total: m.Total, Bonus: m.Bonus}, nil
+}
+
+func (t *gormTx) SetStake(ctx context.Context, stake wallet.Stake) error {
+	return t.db.WithContext(ctx).Model(&StakeModel{}).
+		Where("operator_id = ? AND player_id = ? AND currency = ?", t.operatorID, t.playerID, t.currency).
+		Updates(map[string]interface{}{
+			"total":      stake.Total,
+			"bonus":      stake.Bonus,
+			"updated_at": time.Now(),
+		}).Error
+}
+
+func toBonus(m BonusModel) *wallet.Bonus {
+	return &wallet.Bonus{
+		ID:               m.ID,
+		OperatorID:       m.OperatorID,
+		PlayerID:         m.PlayerID,
+		Currency:         m.Currency,
+		Granted:          m.Granted,
+		Balance:          m.Balance,
+		WageringRequired: m.WageringRequired,
+		Wagered:          m.Wagered,
+		Status:           wallet.BonusStatus(m.Status),
+		CreatedAt:        m.CreatedAt,
+		UpdatedAt:        m.UpdatedAt,
+	}
+}
+
+func toModel(b *wallet.Bonus) BonusModel {
+	return BonusModel{
+		ID:               b.ID,
+		OperatorID:       b.OperatorID,
+		PlayerID:         b.PlayerID,
+		Currency:         b.Currency,
+		Granted:          b.Granted,
+		Balance:          b.Balance,
+		WageringRequired: b.WageringRequired,
+		Wagered:          b.Wagered,
+		Status:           string(b.Status),
+		CreatedAt:        b.CreatedAt,
+		UpdatedAt:        b.UpdatedAt,
+	}
+}
@@ -0,0 +1,105 @@
+package bonus
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/joe_shih/slot-factory/internal/application/wallet"
+)
+
+// MemoryStore 是 wallet.BonusStore 的記憶體實作，用於本地開發與 Mock 模式。
+// 鎖只在單一實體內有效，多個實體必須改用 GormStore。
+type MemoryStore struct {
+	mu      sync.RWMutex
+	nextID  int64
+	bonuses map[int64]*wallet.Bonus
+	stakes  map[string]wallet.Stake
+
+	locks sync.Map // key: operator:player:currency, value: *sync.Mutex
+}
+
+var _ wallet.BonusStore = (*MemoryStore)(nil)
+
+// NewMemoryStore 建立一個新的記憶體紅利儲存。
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		bonuses: make(map[int64]*wallet.Bonus),
+		stakes:  make(map[string]wallet.Stake),
+	}
+}
+
+// ListActive 回傳玩家指定幣種所有進行中的紅利副本，依發放時間由舊到新排序。
+func (s *MemoryStore) ListActive(_ context.Context, operatorID string, playerID string, currency string) ([]*wallet.Bonus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var list []*wallet.Bonus
+	for _, b := range s.bonuses {
+		if b.OperatorID == operatorID && b.PlayerID == playerID && b.Currency == currency && b.Status == wallet.BonusActive {
+			copied := *b
+			list = append(list, &copied)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// Create 新增一筆紅利並填入 ID。
+func (s *MemoryStore) Create(_ context.Context, bonus *wallet.Bonus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	bonus.ID = s.nextID
+	copied := *bonus
+	s.bonuses[bonus.ID] = &copied
+	return nil
+}
+
+// WithLock 以營運商、玩家與幣種的互斥鎖序列化 fn。
+func (s *MemoryStore) WithLock(_ context.Context, operatorID string, playerID string, currency string, fn func(tx wallet.BonusTx) error) error {
+	key := operatorID + ":" + playerID + ":" + currency
+	v, _ := s.locks.LoadOrStore(key, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	defer mu.Unlock()
+	return fn(&memoryTx{store: s, operatorID: operatorID, playerID: playerID, currency: currency, key: key})
+}
+
+// memoryTx 是 MemoryStore 鎖定期間的操作。
+type memoryTx struct {
+	store      *MemoryStore
+	operatorID string
+	playerID   string
+	currency   string
+	key        string
+}
+
+func (t *memoryTx) ListActive(ctx context.Context) ([]*wallet.Bonus, error) {
+	return t.store.ListActive(ctx, t.operatorID, t.playerID, t.currency)
+}
+
+// Update 以傳入的紅利覆蓋既有資料。
+func (t *memoryTx) Update(_ context.Context, bonus *wallet.Bonus) error {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+	copied := *bonus
+	t.store.bonuses[bonus.ID] = &copied
+	return nil
+}
+
+func (t *memoryTx) Stake(_ context.Context) (wallet.Stake, error) {
+	t.store.mu.RLock()
+	defer t.store.mu.RUnlock()
+	return t.store.stakes[t.key], nil
+}
+
+func (t *memoryTx) SetStake(_ context.Context, stake wallet.Stake) error {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+	if stake.Total.IsZero() && stake.Bonus.IsZero() {
+		delete(t.store.stakes, t.key)
+		return nil
+	}
+	t.store.stakes[t.key] = stake
+	return nil
+}
@@ -1,63 +1,231 @@
 package mock
 
 import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/joe_shih/slot-factory/internal/application/wallet"
+	"github.com/joe_shih/slot-factory/internal/config"
+	"github.com/joe_shih/slot-factory/internal/domain/requestid"
 	"github.com/shopspring/decimal"
 )
 
+// defaultStartingBalance 是未針對幣種設定起始餘額時使用的預設值。
+var defaultStartingBalance = decimal.NewFromInt(100000)
+
+// defaultMaxHistory 是每位玩家保留的交易紀錄筆數上限預設值。
+const defaultMaxHistory = 1000
+
+// account 是單一玩家單一幣種的錢包，擁有自己的鎖，
+// 讓不同玩家的操作可以並行，同一玩家的操作則被序列化。
+type account struct {
+	mu      sync.Mutex
+	balance decimal.Decimal
+}
+
+// MockPayment 是 wallet.Payment 的記憶體實作，可在本地與壓力測試環境中取代外部錢包。
+//
+// 它是併發安全的：每個玩家 (與幣種) 有獨立的鎖，並保存完整的交易紀錄。
+// 透過設定可以注入延遲與失敗率，模擬真實外部平台的行為。
 type MockPayment struct {
-	fakeUserBalanceList map[string]decimal.Decimal
+	accountsMu sync.RWMutex
+	accounts   map[string]*account // key: 玩家ID:幣種
+
+	historyMu  sync.RWMutex
+	history    map[string][]wallet.TransactionRecord // key: 玩家ID，新的在後
+	nextTxID   int64
+	maxHistory int
+
+	startingBalances map[string]decimal.Decimal // key: 幣種 (大寫)
+	latency          time.Duration
+	latencyJitter    time.Duration
+	failureRate      float64
 }
 
 var _ wallet.Payment = (*MockPayment)(nil)
 
-func NewPayment() *MockPayment {
-	return &MockPayment{
-		fakeUserBalanceList: make(map[string]decimal.Decimal),
+// NewPayment 建立一個記憶體錢包。
+//
+// 參數說明：
+//   - cfg: config.MockWalletConfig, 起始餘額、延遲與失敗率等設定，零值即為無延遲、不失敗。
+//
+// 回傳值：
+//   - *MockPayment: 初始化完成的記憶體錢包。
+//   - error: 如果起始餘額格式錯誤或失敗率不在 0~1 之間，則返回錯誤。
+func NewPayment(cfg config.MockWalletConfig) (*MockPayment, error) {
+	if cfg.FailureRate < 0 || cfg.FailureRate > 1 {
+		return nil, fmt.Errorf("mock wallet failureRate must be between 0 and 1, got %v", cfg.FailureRate)
+	}
+	starting := make(map[string]decimal.Decimal, len(cfg.StartingBalances))
+	for cur, amount := range cfg.StartingBalances {
+		d, err := decimal.NewFromString(amount)
+		if err != nil {
+			return nil, fmt.Errorf("invalid starting balance for %s: %w", cur, err)
+		}
+		starting[strings.ToUpper(cur)] = d
 	}
+	maxHistory := cfg.MaxHistory
+	if maxHistory <= 0 {
+		maxHistory = defaultMaxHistory
+	}
+	return &MockPayment{
+		accounts:         make(map[string]*account),
+		history:          make(map[string][]wallet.TransactionRecord),
+		maxHistory:       maxHistory,
+		startingBalances: starting,
+		latency:          time.Duration(cfg.LatencyMs) * time.Millisecond,
+		latencyJitter:    time.Duration(cfg.LatencyJitterMs) * time.Millisecond,
+		failureRate:      cfg.FailureRate,
+	}, nil
 }
 
-func (p *MockPayment) GetBalance(playerID string) (decimal.Decimal, *wallet.PaymentError) {
-	balance, ok := p.fakeUserBalanceList[playerID]
-	if !ok {
-		balance = decimal.NewFromInt(100000)
-		p.fakeUserBalanceList[playerID] = balance
+func (p *MockPayment) GetBalance(ctx context.Context, playerID string, currency string) (decimal.Decimal, *wallet.PaymentError) {
+	if err := p.simulate(ctx); err != nil {
+		return decimal.Zero, err
 	}
-	return balance, nil
+	acc := p.account(playerID, currency)
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+	return acc.balance, nil
 }
 
-func (p *MockPayment) Debit(playerID string, amount decimal.Decimal) (decimal.Decimal, *wallet.PaymentError) {
-	balance, _ := p.GetBalance(playerID)
-	if balance.LessThan(amount) {
-		return balance, &wallet.PaymentError{
+func (p *MockPayment) Debit(ctx context.Context, playerID string, currency string, amount decimal.Decimal) (decimal.Decimal, *wallet.PaymentError) {
+	if err := p.simulate(ctx); err != nil {
+		return decimal.Zero, err
+	}
+	acc := p.account(playerID, currency)
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+	if acc.balance.LessThan(amount) {
+		return acc.balance, &wallet.PaymentError{
 			Code:    400,
 			Message: "balance is not enough",
 		}
 	}
-	balance = balance.Sub(amount)
-	return balance, nil
+	acc.balance = acc.balance.Sub(amount)
+	p.record(ctx, playerID, currency, amount.Neg(), "BET", acc.balance)
+	return acc.balance, nil
 }
 
-func (p *MockPayment) Credit(playerID string, amount decimal.Decimal) (decimal.Decimal, *wallet.PaymentError) {
-	balance, _ := p.GetBalance(playerID)
-	balance = balance.Add(amount)
-	p.fakeUserBalanceList[playerID] = balance
-	return balance, nil
+func (p *MockPayment) Credit(ctx context.Context, playerID string, currency string, amount decimal.Decimal) (decimal.Decimal, *wallet.PaymentError) {
+	if err := p.simulate(ctx); err != nil {
+		return decimal.Zero, err
+	}
+	acc := p.account(playerID, currency)
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+	acc.balance = acc.balance.Add(amount)
+	p.record(ctx, playerID, currency, amount, "PAY", acc.balance)
+	return acc.balance, nil
 }
 
-func (p *MockPayment) DebitAndCredit(playerID string, debitAmount decimal.Decimal, creditAmount decimal.Decimal) (decimal.Decimal, *wallet.PaymentError) {
-	balance, _ := p.GetBalance(playerID)
-	if balance.LessThan(debitAmount) {
-		return balance, &wallet.PaymentError{
+func (p *MockPayment) DebitAndCredit(ctx context.Context, playerID string, currency string, debitAmount decimal.Decimal, creditAmount decimal.Decimal) (decimal.Decimal, *wallet.PaymentError) {
+	if err := p.simulate(ctx); err != nil {
+		return decimal.Zero, err
+	}
+	acc := p.account(playerID, currency)
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+	if acc.balance.LessThan(debitAmount) {
+		return acc.balance, &wallet.PaymentError{
 			Code:    400,
 			Message: "balance is not enough",
 		}
 	}
-	balance = balance.Sub(debitAmount).Add(creditAmount)
-	p.fakeUserBalanceList[playerID] = balance
-	return balance, nil
+	acc.balance = acc.balance.Sub(debitAmount).Add(creditAmount)
+	p.record(ctx, playerID, currency, creditAmount.Sub(debitAmount), "BETANDPAY", acc.balance)
+	return acc.balance, nil
 }
 
-func (p *MockPayment) GetHistory(playerID string, limit int) ([]wallet.TransactionRecord, *wallet.PaymentError) {
-	return []wallet.TransactionRecord{}, nil
+// GetHistory 回傳玩家最新的 limit 筆交易紀錄，由新到舊排序。
+func (p *MockPayment) GetHistory(ctx context.Context, playerID string, limit int) ([]wallet.TransactionRecord, *wallet.PaymentError) {
+	if err := wallet.FromContextError(ctx.Err()); err != nil {
+		return nil, err
+	}
+	p.historyMu.RLock()
+	defer p.historyMu.RUnlock()
+	list := p.history[playerID]
+	if limit <= 0 || limit > len(list) {
+		limit = len(list)
+	}
+	records := make([]wallet.TransactionRecord, 0, limit)
+	for i := len(list) - 1; i >= len(list)-limit; i-- {
+		records = append(records, list[i])
+	}
+	return records, nil
+}
+
+// --- 輔助方法 ---
+
+// account 取得 (或建立) 玩家指定幣種的錢包。
+func (p *MockPayment) account(playerID string, currency string) *account {
+	key := playerID + ":" + currency
+	p.accountsMu.RLock()
+	acc, ok := p.accounts[key]
+	p.accountsMu.RUnlock()
+	if ok {
+		return acc
+	}
+
+	p.accountsMu.Lock()
+	defer p.accountsMu.Unlock()
+	if acc, ok = p.accounts[key]; ok {
+		return acc
+	}
+	balance, ok := p.startingBalances[strings.ToUpper(currency)]
+	if !ok {
+		balance = defaultStartingBalance
+	}
+	acc = &account{balance: balance}
+	p.accounts[key] = acc
+	return acc
+}
+
+// record 新增一筆交易紀錄，超過上限時丟棄最舊的紀錄。呼叫前必須持有該玩家錢包的鎖。
+func (p *MockPayment) record(ctx context.Context, playerID string, currency string, amount decimal.Decimal, txType string, balanceAfter decimal.Decimal) {
+	p.historyMu.Lock()
+	defer p.historyMu.Unlock()
+	p.nextTxID++
+	list := append(p.history[playerID], wallet.TransactionRecord{
+		ID:              p.nextTxID,
+		PlayerID:        playerID,
+		Currency:        currency,
+		Amount:          amount,
+		TransactionType: txType,
+		BalanceAfter:    balanceAfter,
+		RequestID:       requestid.FromContext(ctx),
+		CreatedAt:       time.Now(),
+	})
+	if len(list) > p.maxHistory {
+		list = list[len(list)-p.maxHistory:]
+	}
+	p.history[playerID] = list
+}
+
+// simulate 依設定注入延遲與隨機失敗，模擬外部平台。延遲期間 ctx 被取消則立即返回。
+func (p *MockPayment) simulate(ctx context.Context) *wallet.PaymentError {
+	if err := wallet.FromContextError(ctx.Err()); err != nil {
+		return err
+	}
+	delay := p.latency
+	if p.latencyJitter > 0 {
+		delay += rand.N(p.latencyJitter)
+	}
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return wallet.FromContextError(ctx.Err())
+		}
+	}
+	if p.failureRate > 0 && rand.Float64() < p.failureRate {
+		return &wallet.PaymentError{Code: 502, Message: "Injected mock wallet failure"}
+	}
+	return nil
 }
@@ -0,0 +1,222 @@
+package mock
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joe_shih/slot-factory/internal/application/wallet"
+	"github.com/joe_shih/slot-factory/internal/config"
+	"github.com/joe_shih/slot-factory/internal/domain/requestid"
+	"github.com/shopspring/decimal"
+)
+
+func newTestPayment(t *testing.T, cfg config.MockWalletConfig) *MockPayment {
+	t.Helper()
+	if cfg.StartingBalances == nil {
+		cfg.StartingBalances = map[string]string{"TWD": "1000"}
+	}
+	p, err := NewPayment(cfg)
+	if err != nil {
+		t.Fatalf("NewPayment: %v", err)
+	}
+	return p
+}
+
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func TestConcurrentDebitAndCreditConserveBalance(t *testing.T) {
+	p := newTestPayment(t, config.MockWalletConfig{})
+	ctx := context.Background()
+
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			if _, err := p.Debit(ctx, "p1", "TWD", dec("10")); err != nil {
+				t.Errorf("Debit: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := p.Credit(ctx, "p1", "TWD", dec("7")); err != nil {
+				t.Errorf("Credit: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := p.DebitAndCredit(ctx, "p1", "TWD", dec("2"), dec("1")); err != nil {
+				t.Errorf("DebitAndCredit: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 1000 - 50*10 + 50*7 - 50*1 = 800
+	balance, err := p.GetBalance(ctx, "p1", "TWD")
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	if !balance.Equal(dec("800")) {
+		t.Fatalf("balance = %s, want 800", balance)
+	}
+
+	records, err := p.GetHistory(ctx, "p1", 0)
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+	if len(records) != 3*n {
+		t.Fatalf("history has %d records, want %d", len(records), 3*n)
+	}
+	// 依交易順序重放紀錄，每筆的餘額都必須與前一筆的餘額加上異動金額一致
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	running := dec("1000")
+	for _, r := range records {
+		running = running.Add(r.Amount)
+		if !r.BalanceAfter.Equal(running) {
+			t.Fatalf("record %d: balanceAfter = %s, want %s", r.ID, r.BalanceAfter, running)
+		}
+	}
+}
+
+func TestInsufficientFundsLeavesAccountUntouched(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(p *MockPayment) (decimal.Decimal, *wallet.PaymentError)
+	}{
+		{name: "debit", call: func(p *MockPayment) (decimal.Decimal, *wallet.PaymentError) {
+			return p.Debit(context.Background(), "p1", "TWD", dec("1000.01"))
+		}},
+		{name: "debit and credit", call: func(p *MockPayment) (decimal.Decimal, *wallet.PaymentError) {
+			return p.DebitAndCredit(context.Background(), "p1", "TWD", dec("1001"), dec("5000"))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPayment(t, config.MockWalletConfig{})
+			balance, err := tt.call(p)
+			if err == nil || err.Code != 400 {
+				t.Fatalf("err = %v, want code 400", err)
+			}
+			if !balance.Equal(dec("1000")) {
+				t.Fatalf("returned balance = %s, want the unchanged 1000", balance)
+			}
+			if records, _ := p.GetHistory(context.Background(), "p1", 0); len(records) != 0 {
+				t.Fatalf("rejected transaction was recorded: %+v", records)
+			}
+		})
+	}
+}
+
+func TestLatencyHonorsContext(t *testing.T) {
+	p := newTestPayment(t, config.MockWalletConfig{LatencyMs: 50})
+
+	start := time.Now()
+	if _, err := p.Credit(context.Background(), "p1", "TWD", dec("1")); err != nil {
+		t.Fatalf("Credit: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("call took %s, want at least the configured 50ms latency", elapsed)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelExpired()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want int
+	}{
+		{name: "canceled before the call", ctx: canceled, want: wallet.CodeCanceled},
+		{name: "deadline during latency", ctx: expired, want: wallet.CodeDeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			_, err := p.Debit(tt.ctx, "p1", "TWD", dec("1"))
+			if err == nil || err.Code != tt.want {
+				t.Fatalf("err = %v, want code %d", err, tt.want)
+			}
+			if elapsed := time.Since(start); elapsed >= 50*time.Millisecond {
+				t.Fatalf("call took %s, want it to return when the context is done", elapsed)
+			}
+		})
+	}
+	// 被取消的扣款不會異動餘額
+	if balance, _ := p.GetBalance(context.Background(), "p1", "TWD"); !balance.Equal(dec("1001")) {
+		t.Fatalf("balance = %s, want 1001", balance)
+	}
+}
+
+func TestNewPaymentValidatesConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.MockWalletConfig
+		wantErr bool
+	}{
+		{name: "zero failure rate", cfg: config.MockWalletConfig{FailureRate: 0}},
+		{name: "always fail", cfg: config.MockWalletConfig{FailureRate: 1}},
+		{name: "negative failure rate", cfg: config.MockWalletConfig{FailureRate: -0.1}, wantErr: true},
+		{name: "failure rate above 1", cfg: config.MockWalletConfig{FailureRate: 1.1}, wantErr: true},
+		{name: "invalid starting balance", cfg: config.MockWalletConfig{StartingBalances: map[string]string{"TWD": "abc"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPayment(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewPayment error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFailureRateOneFailsEveryCall(t *testing.T) {
+	p := newTestPayment(t, config.MockWalletConfig{FailureRate: 1})
+	for i := 0; i < 10; i++ {
+		if _, err := p.Credit(context.Background(), "p1", "TWD", dec("1")); err == nil || err.Code != 502 {
+			t.Fatalf("call %d: err = %v, want an injected 502", i, err)
+		}
+	}
+	if records, _ := p.GetHistory(context.Background(), "p1", 0); len(records) != 0 {
+		t.Fatalf("failed calls were recorded: %+v", records)
+	}
+}
+
+func TestHistoryIsCappedAtMaxHistory(t *testing.T) {
+	p := newTestPayment(t, config.MockWalletConfig{MaxHistory: 3})
+	for i := 1; i <= 5; i++ {
+		ctx := requestid.NewContext(context.Background(), string(rune('a'+i-1)))
+		if _, err := p.Credit(ctx, "p1", "TWD", decimal.NewFromInt(int64(i))); err != nil {
+			t.Fatalf("Credit: %v", err)
+		}
+	}
+
+	records, err := p.GetHistory(context.Background(), "p1", 0)
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+	var ids []string
+	for _, r := range records {
+		ids = append(ids, r.RequestID)
+	}
+	// 只保留最新的 3 筆，由新到舊排序
+	if got := ids; len(got) != 3 || got[0] != "e" || got[1] != "d" || got[2] != "c" {
+		t.Fatalf("history = %v, want [e d c]", got)
+	}
+	if records, _ := p.GetHistory(context.Background(), "p1", 2); len(records) != 2 || records[0].RequestID != "e" {
+		t.Fatalf("GetHistory(limit 2) = %+v, want the 2 newest", records)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.GetHistory(canceled, "p1", 0); err == nil || err.Code != wallet.CodeCanceled {
+		t.Fatalf("GetHistory with canceled context = %v, want code %d", err, wallet.CodeCanceled)
+	}
+}
@@ -0,0 +1,210 @@
+package proxy
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/joe_shih/slot-factory/internal/config"
+)
+
+// 斷路器預設值，當設定檔未填寫時套用。
+const (
+	defaultFailureThreshold = 5
+	defaultSlowCall         = 2 * time.Second
+	defaultOpenDuration     = 10 * time.Second
+	defaultHalfOpenProbes   = 1
+	defaultMaxConcurrent    = 64
+)
+
+var (
+	// errCircuitOpen 表示斷路器開啟中，請求未送出即被拒絕。
+	errCircuitOpen = errors.New("wallet circuit breaker is open")
+	// errBulkheadFull 表示同時進行中的請求已達上限，請求未送出即被拒絕。
+	errBulkheadFull = errors.New("wallet bulkhead is full")
+)
+
+// breakerState 代表斷路器目前的狀態。
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateHalfOpen
+	stateOpen
+)
+
+// String 回傳狀態名稱，用於日誌與健康檢查。
+func (s breakerState) String() string {
+	switch s {
+	case stateClosed:
+		return "closed"
+	case stateHalfOpen:
+		return "half_open"
+	case stateOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// circuitBreaker 結合了斷路器與艙壁隔離 (Bulkhead)。
+//
+//   - Closed: 正常放行，連續失敗（含慢呼叫）達門檻後轉為 Open。
+//   - Open: 所有請求快速失敗，經過 openDuration 後轉為 HalfOpen。
+//   - HalfOpen: 只放行有限數量的試探請求，成功則回到 Closed，失敗則重新 Open。
+//
+// 不論狀態為何，同時進行中的請求都受 bulkhead 上限保護。
+type circuitBreaker struct {
+	failureThreshold int
+	slowCall         time.Duration
+	openDuration     time.Duration
+	halfOpenProbes   int
+
+	bulkhead chan struct{}
+
+	// operator 是監控指標的營運商標籤。
+	operator string
+
+	mu                  sync.Mutex
+	state               breakerState
+	consecutiveFailures int
+	openedAt            time.Time
+	probesInFlight      int
+
+	now func() time.Time
+}
+
+// newCircuitBreaker 根據設定建立斷路器，未設定的欄位使用預設值。operator 為監控指標的營運商標籤。
+func newCircuitBreaker(cfg config.WalletBreakerConfig, operator string) *circuitBreaker {
+	b := &circuitBreaker{
+		failureThreshold: cfg.FailureThreshold,
+		slowCall:         time.Duration(cfg.SlowCallMs) * time.Millisecond,
+		openDuration:     time.Duration(cfg.OpenSec) * time.Second,
+		halfOpenProbes:   cfg.HalfOpenProbes,
+		state:            stateClosed,
+		operator:         operator,
+		now:              time.Now,
+	}
+	if b.failureThreshold <= 0 {
+		b.failureThreshold = defaultFailureThreshold
+	}
+	if b.slowCall <= 0 {
+		b.slowCall = defaultSlowCall
+	}
+	if b.openDuration <= 0 {
+		b.openDuration = defaultOpenDuration
+	}
+	if b.halfOpenProbes <= 0 {
+		b.halfOpenProbes = defaultHalfOpenProbes
+	}
+	maxConcurrent := cfg.MaxConcurrent
+	if maxConcurrent <= 0 {
+		maxConcurrent = defaultMaxConcurrent
+	}
+	b.bulkhead = make(chan struct{}, maxConcurrent)
+	breakerStateGauge.WithLabelValues(operator).Set(float64(stateClosed))
+	return b
+}
+
+// execute 在斷路器與艙壁的保護下執行 fn。
+//
+// 如果請求被拒絕，回傳 errCircuitOpen 或 errBulkheadFull，且 fn 不會被呼叫。
+// 否則回傳 fn 的錯誤；fn 的結果（失敗或延遲過高）會回饋給斷路器。
+func (b *circuitBreaker) execute(fn func() error) error {
+	select {
+	case b.bulkhead <- struct{}{}:
+	default:
+		breakerRejectedTotal.WithLabelValues(b.operator, "bulkhead").Inc()
+		return errBulkheadFull
+	}
+	defer func() { <-b.bulkhead }()
+
+	probe, err := b.acquire()
+	if err != nil {
+		breakerRejectedTotal.WithLabelValues(b.operator, "open").Inc()
+		return err
+	}
+
+	inFlightGauge.WithLabelValues(b.operator).Inc()
+	start := b.now()
+	callErr := fn()
+	elapsed := b.now().Sub(start)
+	inFlightGauge.WithLabelValues(b.operator).Dec()
+	requestDuration.WithLabelValues(b.operator).Observe(elapsed.Seconds())
+
+	b.record(probe, callErr == nil && elapsed < b.slowCall)
+	return callErr
+}
+
+// acquire 判斷目前是否允許送出請求，並回傳此請求是否為半開狀態下的試探請求。
+func (b *circuitBreaker) acquire() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == stateOpen {
+		if b.now().Sub(b.openedAt) < b.openDuration {
+			return false, errCircuitOpen
+		}
+		b.setState(stateHalfOpen)
+	}
+	if b.state == stateHalfOpen {
+		if b.probesInFlight >= b.halfOpenProbes {
+			return false, errCircuitOpen
+		}
+		b.probesInFlight++
+		return true, nil
+	}
+	return false, nil
+}
+
+// record 將一次呼叫的結果回饋給斷路器，必要時切換狀態。
+func (b *circuitBreaker) record(probe bool, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probesInFlight--
+	}
+	if success {
+		requestsTotal.WithLabelValues(b.operator, "success").Inc()
+		b.consecutiveFailures = 0
+		if b.state == stateHalfOpen {
+			b.setState(stateClosed)
+		}
+		return
+	}
+
+	requestsTotal.WithLabelValues(b.operator, "failure").Inc()
+	b.consecutiveFailures++
+	if b.state == stateHalfOpen || b.consecutiveFailures >= b.failureThreshold {
+		b.setState(stateOpen)
+	}
+}
+
+// setState 切換斷路器狀態，呼叫前必須持有鎖。
+func (b *circuitBreaker) setState(next breakerState) {
+	if b.state == next {
+		return
+	}
+	b.state = next
+	if next == stateOpen {
+		b.openedAt = b.now()
+	}
+	if next == stateClosed {
+		b.consecutiveFailures = 0
+	}
+	breakerStateGauge.WithLabelValues(b.operator).Set(float64(next))
+	breakerTransitionsTotal.WithLabelValues(b.operator, next.String()).Inc()
+}
+
+// snapshot 回傳斷路器目前的狀態資訊，用於健康檢查。
+func (b *circuitBreaker) snapshot() (state breakerState, consecutiveFailures int, inFlight int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state = b.state
+	if state == stateOpen && b.now().Sub(b.openedAt) >= b.openDuration {
+		// 開路時間已到，下一個請求即會進入半開試探
+		state = stateHalfOpen
+	}
+	return state, b.consecutiveFailures, len(b.bulkhead)
+}
@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/joe_shih/slot-factory/internal/config"
+)
+
+var errWallet = errors.New("wallet unavailable")
+
+// newTestBreaker 建立使用可控時鐘的斷路器：連續 2 次失敗開路、開路 10 秒、慢呼叫門檻 1 秒。
+func newTestBreaker(maxConcurrent int) (*circuitBreaker, *time.Time) {
+	clock := time.Unix(0, 0)
+	b := newCircuitBreaker(config.WalletBreakerConfig{
+		FailureThreshold: 2,
+		SlowCallMs:       1000,
+		OpenSec:          10,
+		HalfOpenProbes:   1,
+		MaxConcurrent:    maxConcurrent,
+	}, "test-op")
+	b.now = func() time.Time { return clock }
+	return b, &clock
+}
+
+func fail() error    { return errWallet }
+func succeed() error { return nil }
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	b, _ := newTestBreaker(0)
+
+	_ = b.execute(fail)
+	if state, failures, _ := b.snapshot(); state != stateClosed || failures != 1 {
+		t.Fatalf("after 1 failure: state = %s, failures = %d, want closed, 1", state, failures)
+	}
+	// 成功會重設連續失敗次數
+	_ = b.execute(succeed)
+	_ = b.execute(fail)
+	if state, _, _ := b.snapshot(); state != stateClosed {
+		t.Fatalf("non-consecutive failures opened the breaker: %s", state)
+	}
+	_ = b.execute(fail)
+	if state, _, _ := b.snapshot(); state != stateOpen {
+		t.Fatalf("state = %s, want open", state)
+	}
+
+	called := false
+	err := b.execute(func() error { called = true; return nil })
+	if !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("execute while open = %v, want errCircuitOpen", err)
+	}
+	if called {
+		t.Fatal("request sent while the breaker is open")
+	}
+}
+
+func TestBreakerCountsSlowCallsAsFailures(t *testing.T) {
+	b, clock := newTestBreaker(0)
+	slow := func() error {
+		*clock = clock.Add(time.Second)
+		return nil
+	}
+	for i := 0; i < 2; i++ {
+		if err := b.execute(slow); err != nil {
+			t.Fatalf("slow call returned %v, want the call's own result", err)
+		}
+	}
+	if state, _, _ := b.snapshot(); state != stateOpen {
+		t.Fatalf("state = %s, want open after slow calls", state)
+	}
+}
+
+func TestBreakerHalfOpenProbe(t *testing.T) {
+	tests := []struct {
+		name  string
+		probe func() error
+		want  breakerState
+	}{
+		{name: "probe success closes", probe: succeed, want: stateClosed},
+		{name: "probe failure reopens", probe: fail, want: stateOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, clock := newTestBreaker(0)
+			_ = b.execute(fail)
+			_ = b.execute(fail)
+
+			*clock = clock.Add(10 * time.Second)
+			if state, _, _ := b.snapshot(); state != stateHalfOpen {
+				t.Fatalf("state = %s, want half_open after the open duration", state)
+			}
+
+			err := b.execute(func() error {
+				// 試探請求進行中時，其他請求仍被拒絕
+				if err := b.execute(succeed); !errors.Is(err, errCircuitOpen) {
+					t.Errorf("second request during probe = %v, want errCircuitOpen", err)
+				}
+				return tt.probe()
+			})
+			if !errors.Is(err, tt.probe()) {
+				t.Fatalf("probe returned %v", err)
+			}
+			if state, _, _ := b.snapshot(); state != tt.want {
+				t.Fatalf("state = %s, want %s", state, tt.want)
+			}
+			if tt.want == stateOpen {
+				if err := b.execute(succeed); !errors.Is(err, errCircuitOpen) {
+					t.Fatalf("execute after failed probe = %v, want errCircuitOpen", err)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerBulkheadRejectsWhenFull(t *testing.T) {
+	b, _ := newTestBreaker(1)
+
+	err := b.execute(func() error {
+		if _, _, inFlight := b.snapshot(); inFlight != 1 {
+			t.Errorf("in flight = %d, want 1", inFlight)
+		}
+		if err := b.execute(succeed); !errors.Is(err, errBulkheadFull) {
+			t.Errorf("execute with full bulkhead = %v, want errBulkheadFull", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if err := b.execute(succeed); err != nil {
+		t.Fatalf("execute after the slot was released: %v", err)
+	}
+	// 被艙壁拒絕的請求不影響斷路器狀態
+	if state, failures, _ := b.snapshot(); state != stateClosed || failures != 0 {
+		t.Fatalf("state = %s, failures = %d, want closed, 0", state, failures)
+	}
+}
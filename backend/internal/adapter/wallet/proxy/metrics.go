@@ -0,0 +1,40 @@
+package proxy
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus 指標，描述外接錢包呼叫與斷路器的狀態，皆以營運商 (operator) 區分。
+var (
+	breakerStateGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "slot_wallet_breaker_state",
+		Help: "Current wallet circuit breaker state (0=closed, 1=half_open, 2=open).",
+	}, []string{"operator"})
+
+	breakerTransitionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "slot_wallet_breaker_transitions_total",
+		Help: "Number of wallet circuit breaker state transitions, by target state.",
+	}, []string{"operator", "state"})
+
+	breakerRejectedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "slot_wallet_breaker_rejected_total",
+		Help: "Number of wallet calls rejected without reaching the platform, by reason.",
+	}, []string{"operator", "reason"})
+
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "slot_wallet_requests_total",
+		Help: "Number of wallet platform calls, by result (slow calls count as failure).",
+	}, []string{"operator", "result"})
+
+	inFlightGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "slot_wallet_inflight_requests",
+		Help: "Number of wallet platform calls currently in flight.",
+	}, []string{"operator"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "slot_wallet_request_duration_seconds",
+		Help:    "Latency of wallet platform calls.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operator"})
+)
@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKeyNoncePrefix 是 Redis 中已使用 Nonce 的 key 前綴。
+const redisKeyNoncePrefix = "wallet_nonce:"
+
+// NonceStore 記錄已使用過的請求 Nonce，讓伺服器端可以拒絕在重放時間窗內被重送的請求。
+//
+// 多個實體共同接收請求時必須使用共享的實作 (例如 RedisNonceStore)，否則請求可以被重放到其他實體。
+type NonceStore interface {
+	// Reserve 登記 nonce，ttl 後自動失效。nonce 已被登記過時回傳 false。
+	Reserve(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+// MemoryNonceStore 是單一實體使用的 NonceStore。
+//
+// Nonce 依到期時間放在最小堆積中，登記新 Nonce 時只從堆積頂端清除已過期的 Nonce，
+// 每次登記的清除成本為 O(log n) 攤銷，不需要掃描所有 Nonce。
+type MemoryNonceStore struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+	queue   nonceHeap
+	now     func() time.Time
+}
+
+var _ NonceStore = (*MemoryNonceStore)(nil)
+
+// NewMemoryNonceStore 建立一個記憶體 NonceStore。
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{expires: make(map[string]time.Time), now: time.Now}
+}
+
+func (s *MemoryNonceStore) Reserve(_ context.Context, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.purgeLocked(now)
+	if _, ok := s.expires[nonce]; ok {
+		return false, nil
+	}
+	exp := now.Add(ttl)
+	s.expires[nonce] = exp
+	heap.Push(&s.queue, nonceEntry{nonce: nonce, expiresAt: exp})
+	return true, nil
+}
+
+// purgeLocked 從堆積頂端清除已過期的 Nonce。呼叫前必須持有鎖。
+func (s *MemoryNonceStore) purgeLocked(now time.Time) {
+	for len(s.queue) > 0 && !now.Before(s.queue[0].expiresAt) {
+		e := heap.Pop(&s.queue).(nonceEntry)
+		delete(s.expires, e.nonce)
+	}
+}
+
+// nonceEntry 是堆積中的一個 Nonce 與其到期時間。
+type nonceEntry struct {
+	nonce     string
+	expiresAt time.Time
+}
+
+// nonceHeap 是依到期時間排序的最小堆積，實作 heap.Interface。
+type nonceHeap []nonceEntry
+
+func (h nonceHeap) Len() int           { return len(h) }
+func (h nonceHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h nonceHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *nonceHeap) Push(x any)        { *h = append(*h, x.(nonceEntry)) }
+func (h *nonceHeap) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	*h = old[:n-1]
+	return e
+}
+
+// RedisNonceStore 是以 Redis SET NX 實作的 NonceStore，可在多個實體間共用。
+type RedisNonceStore struct {
+	rdb *redis.Client
+}
+
+var _ NonceStore = (*RedisNonceStore)(nil)
+
+// NewRedisNonceStore 建立一個 Redis NonceStore。
+func NewRedisNonceStore(rdb *redis.Client) *RedisNonceStore {
+	return &RedisNonceStore{rdb: rdb}
+}
+
+func (s *RedisNonceStore) Reserve(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	return s.rdb.SetNX(ctx, redisKeyNoncePrefix+nonce, 1, ttl).Result()
+}
@@ -2,12 +2,20 @@ package proxy
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/joe_shih/slot-factory/internal/application/wallet"
+	"github.com/joe_shih/slot-factory/internal/config"
+	"github.com/joe_shih/slot-factory/internal/domain/requestid"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
@@ -15,10 +23,13 @@ import (
 // TransactionModel 對應資料庫的 wallet_transactions 表，用於紀錄流水。
 type TransactionModel struct {
 	ID              int64           `gorm:"primaryKey;autoIncrement"`
+	OperatorID      string          `gorm:"column:operator_id"`
 	PlayerID        string          `gorm:"column:player_id"`
-	Amount          decimal.Decimal `gorm:"column:amount;type:decimal(18,4)"`
+	Currency        string          `gorm:"column:currency"`
+	Amount          decimal.Decimal `gorm:"column:amount;type:decimal(30,8)"`
 	TransactionType string          `gorm:"column:transaction_type"`
-	BalanceAfter    decimal.Decimal `gorm:"column:balance_after;type:decimal(18,4)"`
+	BalanceAfter    decimal.Decimal `gorm:"column:balance_after;type:decimal(30,8)"`
+	RequestID       string          `gorm:"column:request_id"`
 	CreatedAt       time.Time       `gorm:"column:created_at"`
 }
 
@@ -28,92 +39,154 @@ func (TransactionModel) TableName() string {
 
 // ProxyPayment 實現了 wallet.Payment 介面，
 // 它會呼叫外部 API 並將成功的異動紀錄寫入本地 DB (Audit Log)。
+//
+// 每筆異動都帶上交易 ID (transactionID)，作為平台端的冪等鍵與沖正時的識別，
+// 呼叫端以 wallet.WithTransactionID 指定時沿用，否則每次呼叫都產生新的 ID；
+// 客戶端的請求 ID (requestID) 只作為對帳用的附註，不能用來識別交易。
+//
+// 所有外部呼叫都經過斷路器與艙壁隔離保護，外部平台變慢或故障時會快速失敗，
+// 避免遊戲流程被逾時的 HTTP 請求拖住。每個營運商各自建立一個實例，斷路器互不影響。
 type ProxyPayment struct {
-	db      *gorm.DB // 用於紀錄流水，如果 db 為 nil 則跳過紀錄
-	baseURL string
-	apiKey  string
-	client  *http.Client
+	db         *gorm.DB // 用於紀錄流水，如果 db 為 nil 則跳過紀錄
+	operatorID string
+	baseURL    string
+	client     *http.Client
+	signer     Signer
+	breaker    *circuitBreaker
+	logger     *slog.Logger
+
+	newTransactionID func() string
 }
 
-var _ wallet.Payment = (*ProxyPayment)(nil)
+var (
+	_ wallet.Payment        = (*ProxyPayment)(nil)
+	_ wallet.HealthProvider = (*ProxyPayment)(nil)
+)
 
 // NewPayment 建立一個新的 Proxy 錢包實作。
-func NewPayment(db *gorm.DB, baseURL, apiKey string) *ProxyPayment {
-	return &ProxyPayment{
-		db:      db,
-		baseURL: baseURL,
-		apiKey:  apiKey,
-		client:  &http.Client{Timeout: 5 * time.Second},
+//
+// 參數說明：
+//   - db: *gorm.DB, 用於寫入本地流水紀錄，傳入 nil 則不紀錄。
+//   - operatorID: string, 此錢包所屬的營運商，用於標記流水紀錄與監控指標。
+//   - cfg: config.ExternalWalletConfig, 外接錢包 API 的連線與斷路器設定。
+//   - logger: *slog.Logger, 記錄沖正失敗等需要人工對帳的告警。
+//
+// 回傳值：
+//   - *ProxyPayment: 初始化完成的 Proxy 錢包實作。
+//   - error: 如果簽章設定無效（例如未知的簽章方案），則返回錯誤。
+func NewPayment(db *gorm.DB, operatorID string, cfg config.ExternalWalletConfig, logger *slog.Logger) (*ProxyPayment, error) {
+	signer, err := newSigner(cfg)
+	if err != nil {
+		return nil, err
 	}
+	timeout := time.Duration(cfg.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &ProxyPayment{
+		db:         db,
+		operatorID: operatorID,
+		baseURL:    cfg.BaseURL,
+		client:     &http.Client{Timeout: timeout},
+		signer:     signer,
+		breaker:    newCircuitBreaker(cfg.Breaker, operatorID),
+		logger:     logger.With("operatorID", operatorID),
+
+		newTransactionID: uuid.NewString,
+	}, nil
 }
 
 // --- 介面實作 ---
 
-func (p *ProxyPayment) GetBalance(playerID string) (decimal.Decimal, *wallet.PaymentError) {
-	// 這裡示範呼叫外部 API
-	resp, err := p.callAPI("GET", fmt.Sprintf("/balance/%s", playerID), nil)
+func (p *ProxyPayment) GetBalance(ctx context.Context, playerID string, currency string) (decimal.Decimal, *wallet.PaymentError) {
+	path := fmt.Sprintf("/balance/%s?currency=%s", url.PathEscape(playerID), url.QueryEscape(currency))
+	resp, err := p.callAPI(ctx, "GET", path, nil)
 	if err != nil {
-		return decimal.Zero, &wallet.PaymentError{Code: 502, Message: "External API error"}
+		return decimal.Zero, toPaymentError(err)
 	}
 	return resp.Balance, nil
 }
 
-func (p *ProxyPayment) Debit(playerID string, amount decimal.Decimal) (decimal.Decimal, *wallet.PaymentError) {
+func (p *ProxyPayment) Debit(ctx context.Context, playerID string, currency string, amount decimal.Decimal) (decimal.Decimal, *wallet.PaymentError) {
+	transactionID := p.transactionID(ctx)
 	reqBody := map[string]interface{}{
-		"playerID": playerID,
-		"amount":   amount,
+		"playerID":      playerID,
+		"currency":      currency,
+		"amount":        amount,
+		"transactionID": transactionID,
+		"requestID":     requestid.FromContext(ctx),
 	}
-	resp, err := p.callAPI("POST", "/debit", reqBody)
+	resp, err := p.callAPI(ctx, "POST", "/debit", reqBody)
 	if err != nil {
-		return decimal.Zero, &wallet.PaymentError{Code: 502, Message: "External API error"}
+		p.rollbackIfUncertain(ctx, err, playerID, currency, txTypeDebit, transactionID, amount)
+		return decimal.Zero, toPaymentError(err)
 	}
 
 	// 寫入本地流水紀錄
-	p.logTransaction(playerID, amount.Neg(), "BET", resp.Balance)
+	p.logTransaction(ctx, playerID, currency, amount.Neg(), "BET", resp.Balance)
 
 	return resp.Balance, nil
 }
 
-func (p *ProxyPayment) Credit(playerID string, amount decimal.Decimal) (decimal.Decimal, *wallet.PaymentError) {
+func (p *ProxyPayment) Credit(ctx context.Context, playerID string, currency string, amount decimal.Decimal) (decimal.Decimal, *wallet.PaymentError) {
 	reqBody := map[string]interface{}{
-		"playerID": playerID,
-		"amount":   amount,
+		"playerID":      playerID,
+		"currency":      currency,
+		"amount":        amount,
+		"transactionID": p.transactionID(ctx),
+		"requestID":     requestid.FromContext(ctx),
 	}
-	resp, err := p.callAPI("POST", "/credit", reqBody)
+	resp, err := p.callAPI(ctx, "POST", "/credit", reqBody)
 	if err != nil {
-		return decimal.Zero, &wallet.PaymentError{Code: 502, Message: "External API error"}
+		return decimal.Zero, toPaymentError(err)
 	}
 
 	// 寫入本地流水紀錄
-	p.logTransaction(playerID, amount, "PAY", resp.Balance)
+	p.logTransaction(ctx, playerID, currency, amount, "PAY", resp.Balance)
 
 	return resp.Balance, nil
 }
 
-func (p *ProxyPayment) DebitAndCredit(playerID string, debitAmount, creditAmount decimal.Decimal) (decimal.Decimal, *wallet.PaymentError) {
+func (p *ProxyPayment) DebitAndCredit(ctx context.Context, playerID string, currency string, debitAmount, creditAmount decimal.Decimal) (decimal.Decimal, *wallet.PaymentError) {
+	transactionID := p.transactionID(ctx)
 	reqBody := map[string]interface{}{
-		"playerID":     playerID,
-		"debitAmount":  debitAmount,
-		"creditAmount": creditAmount,
+		"playerID":      playerID,
+		"currency":      currency,
+		"debitAmount":   debitAmount,
+		"creditAmount":  creditAmount,
+		"transactionID": transactionID,
+		"requestID":     requestid.FromContext(ctx),
 	}
-	resp, err := p.callAPI("POST", "/spin", reqBody)
+	resp, err := p.callAPI(ctx, "POST", "/spin", reqBody)
 	if err != nil {
-		return decimal.Zero, &wallet.PaymentError{Code: 502, Message: "External API error"}
+		p.rollbackIfUncertain(ctx, err, playerID, currency, txTypeSpin, transactionID, debitAmount.Sub(creditAmount))
+		return decimal.Zero, toPaymentError(err)
 	}
 
 	// 寫入本地流水紀錄 (紀錄淨額)
-	p.logTransaction(playerID, creditAmount.Sub(debitAmount), "BETANDPAY", resp.Balance)
+	p.logTransaction(ctx, playerID, currency, creditAmount.Sub(debitAmount), "BETANDPAY", resp.Balance)
 
 	return resp.Balance, nil
 }
 
-func (p *ProxyPayment) GetHistory(playerID string, limit int) ([]wallet.TransactionRecord, *wallet.PaymentError) {
+// Health 回報斷路器與艙壁目前的狀態。斷路器開啟時視為不健康。
+func (p *ProxyPayment) Health() wallet.HealthStatus {
+	state, failures, inFlight := p.breaker.snapshot()
+	return wallet.HealthStatus{
+		Healthy:             state != stateOpen,
+		State:               state.String(),
+		InFlight:            inFlight,
+		ConsecutiveFailures: failures,
+	}
+}
+
+func (p *ProxyPayment) GetHistory(ctx context.Context, playerID string, limit int) ([]wallet.TransactionRecord, *wallet.PaymentError) {
 	if p.db == nil {
 		return nil, &wallet.PaymentError{Code: 500, Message: "Local database not enabled"}
 	}
 
 	var models []TransactionModel
-	err := p.db.Where("player_id = ?", playerID).Order("created_at DESC").Limit(limit).Find(&models).Error
+	err := p.db.WithContext(ctx).Where("operator_id = ? AND player_id = ?", p.operatorID, playerID).Order("created_at DESC").Limit(limit).Find(&models).Error
 	if err != nil {
 		return nil, &wallet.PaymentError{Code: 500, Message: "Database error"}
 	}
@@ -122,10 +195,13 @@ func (p *ProxyPayment) GetHistory(playerID string, limit int) ([]wallet.Transact
 	for i, m := range models {
 		records[i] = wallet.TransactionRecord{
 			ID:              m.ID,
+			OperatorID:      m.OperatorID,
 			PlayerID:        m.PlayerID,
+			Currency:        m.Currency,
 			Amount:          m.Amount,
 			TransactionType: m.TransactionType,
 			BalanceAfter:    m.BalanceAfter,
+			RequestID:       m.RequestID,
 			CreatedAt:       m.CreatedAt,
 		}
 	}
@@ -134,58 +210,202 @@ func (p *ProxyPayment) GetHistory(playerID string, limit int) ([]wallet.Transact
 
 // --- 輔助方法 ---
 
+// transactionID 回傳呼叫端指定的交易 ID，沒有指定時產生新的 ID。
+func (p *ProxyPayment) transactionID(ctx context.Context) string {
+	if id := wallet.TransactionIDFromContext(ctx); id != "" {
+		return id
+	}
+	return p.newTransactionID()
+}
+
+// 平台端的交易類型，與 /rollback 請求的 type 欄位一致。
+const (
+	txTypeDebit = "debit"
+	txTypeSpin  = "spin"
+)
+
+// rollbackTimeout 是沖正請求的逾時時間，不受原請求的 context 取消影響。
+const rollbackTimeout = 5 * time.Second
+
+// rollbackIfUncertain 在扣款結果不確定時 (逾時、連線中斷、5xx) 要求平台沖正該筆交易，避免玩家被扣款卻沒有下注成功。
+//
+// 平台以玩家、交易類型與交易 ID 識別交易。
+// 平台明確拒絕 (4xx) 或請求未送出 (斷路器、艙壁) 時不需要沖正；平台沒有這筆交易時回傳 404，同樣視為已處理。
+// netDebit 為原交易的淨扣款金額，用於寫入本地流水紀錄。
+func (p *ProxyPayment) rollbackIfUncertain(ctx context.Context, err error, playerID, currency, txType, transactionID string, netDebit decimal.Decimal) {
+	var se *statusError
+	switch {
+	case errors.As(err, &se) && se.status < 500,
+		errors.Is(err, errCircuitOpen),
+		errors.Is(err, errBulkheadFull):
+		return
+	}
+
+	rbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+	resp, rbErr := p.doRequest(rbCtx, "POST", "/rollback", map[string]interface{}{
+		"playerID":      playerID,
+		"type":          txType,
+		"transactionID": transactionID,
+	})
+	if errors.As(rbErr, &se) && se.status == http.StatusNotFound {
+		return
+	}
+	if rbErr != nil {
+		// 沖正失敗時帳務可能不一致，需人工對帳
+		p.logger.Error("[ALARM] wallet rollback failed", "playerID", playerID, "transactionID", transactionID, "requestID", requestid.FromContext(ctx), "txType", txType, "error", rbErr)
+		return
+	}
+	p.logTransaction(ctx, playerID, currency, netDebit, "ROLLBACK", resp.Balance)
+}
+
+// maxResponseSize 是讀取外部錢包回應的大小上限。
+const maxResponseSize = 1 << 20
+
 type apiResponse struct {
 	Balance decimal.Decimal `json:"balance"`
 	Status  string          `json:"status"`
+	Message string          `json:"message,omitempty"`
 }
 
-func (p *ProxyPayment) callAPI(method, path string, body interface{}) (*apiResponse, error) {
-	var bodyReader *bytes.Buffer
-	if body != nil {
-		jsonData, _ := json.Marshal(body)
-		bodyReader = bytes.NewBuffer(jsonData)
-	}
+// statusError 表示外部平台回傳了非 2xx 的狀態碼。
+type statusError struct {
+	status  int
+	message string
+}
 
-	url := p.baseURL + path
+func (e *statusError) Error() string {
+	return fmt.Sprintf("wallet api returned status %d: %s", e.status, e.message)
+}
 
-	var finalReader *bytes.Buffer
+// callAPI 在斷路器保護下呼叫外部錢包 API。
+func (p *ProxyPayment) callAPI(ctx context.Context, method, path string, body interface{}) (*apiResponse, error) {
+	var (
+		result    *apiResponse
+		rejectErr error
+	)
+	err := p.breaker.execute(func() error {
+		resp, err := p.doRequest(ctx, method, path, body)
+		var se *statusError
+		if errors.As(err, &se) && se.status < 500 {
+			// 業務錯誤 (例如餘額不足) 代表平台仍正常運作，不計入斷路器失敗
+			rejectErr = err
+			return nil
+		}
+		if errors.Is(err, context.Canceled) {
+			// 呼叫端主動取消，與平台健康無關
+			rejectErr = err
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		result = resp
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rejectErr != nil {
+		return nil, rejectErr
+	}
+	return result, nil
+}
+
+// doRequest 發送實際的 HTTP 請求並解析回應。
+func (p *ProxyPayment) doRequest(ctx context.Context, method, path string, body interface{}) (*apiResponse, error) {
+	var reqBody []byte
+	var bodyReader io.Reader
 	if body != nil {
-		finalReader = bodyReader
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("marshal request body: %w", err)
+		}
+		reqBody = jsonData
+		bodyReader = bytes.NewReader(jsonData)
 	}
 
-	req, _ := http.NewRequest(method, url, nil)
-	if finalReader != nil {
-		req, _ = http.NewRequest(method, url, finalReader)
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, bodyReader)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
 	}
-	req.Header.Set("Authorization", "Bearer "+p.apiKey)
 	req.Header.Set("Content-Type", "application/json")
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set("X-Request-ID", id)
+	}
+	if err := p.signer.SignRequest(req, reqBody); err != nil {
+		return nil, fmt.Errorf("sign request: %w", err)
+	}
 
-	// 這裡目前只是個示意，因為還沒有真的外部 API
-	// 您之後可以根據對手的 API 文檔來精修此處格式
-	_ = req // 展示用
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("call wallet api: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
 
-	// Mock 回傳 (之後請換成真實 http 呼叫)
-	return &apiResponse{
-		Balance: decimal.NewFromInt(100000),
-		Status:  "ok",
-	}, nil
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("read wallet api response: %w", err)
+	}
+	if err := p.signer.VerifyResponse(req, resp, respBody); err != nil {
+		return nil, err
+	}
+
+	var result apiResponse
+	decodeErr := json.Unmarshal(respBody, &result)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &statusError{status: resp.StatusCode, message: result.Message}
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("decode wallet api response: %w", decodeErr)
+	}
+	return &result, nil
+}
+
+// toPaymentError 將外部呼叫的錯誤轉換為 wallet.PaymentError。
+func toPaymentError(err error) *wallet.PaymentError {
+	if pErr := wallet.FromContextError(err); pErr != nil {
+		return pErr
+	}
+	var se *statusError
+	switch {
+	case errors.As(err, &se) && se.status < 500:
+		message := se.message
+		if message == "" {
+			message = "Wallet request rejected"
+		}
+		return &wallet.PaymentError{Code: se.status, Message: message}
+	case errors.Is(err, errCircuitOpen):
+		return &wallet.PaymentError{Code: wallet.CodeCircuitOpen, Message: "Wallet service temporarily unavailable"}
+	case errors.Is(err, errBulkheadFull):
+		return &wallet.PaymentError{Code: wallet.CodeTooManyRequests, Message: "Wallet service busy"}
+	case errors.Is(err, errUnsignedResponse), errors.Is(err, errInvalidSignature):
+		return &wallet.PaymentError{Code: 502, Message: "External API signature rejected"}
+	default:
+		return &wallet.PaymentError{Code: 502, Message: "External API error"}
+	}
 }
 
-func (p *ProxyPayment) logTransaction(playerID string, amount decimal.Decimal, txType string, balanceAfter decimal.Decimal) {
+// logTransaction 非同步寫入本地流水紀錄，並記下觸發交易的客戶端請求 ID 以便對帳。
+func (p *ProxyPayment) logTransaction(ctx context.Context, playerID string, currency string, amount decimal.Decimal, txType string, balanceAfter decimal.Decimal) {
 	if p.db == nil {
 		return
 	}
+	requestID := requestid.FromContext(ctx)
 	// 非同步寫入流水，不要擋住遊戲主邏輯
 	go func() {
 		err := p.db.Create(&TransactionModel{
+			OperatorID:      p.operatorID,
 			PlayerID:        playerID,
+			Currency:        currency,
 			Amount:          amount,
 			TransactionType: txType,
 			BalanceAfter:    balanceAfter,
+			RequestID:       requestID,
 		}).Error
 		if err != nil {
 			// 如果流水沒記成，建議至少要噴個 Error Log 便於對帳
-			fmt.Printf("[ALARM] Local transaction log failed: %v\n", err)
+			p.logger.Error("[ALARM] local transaction log failed", "playerID", playerID, "requestID", requestID, "txType", txType, "error", err)
 		}
 	}()
 }
@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/joe_shih/slot-factory/internal/application/wallet"
+	"github.com/joe_shih/slot-factory/internal/config"
+	"github.com/joe_shih/slot-factory/internal/domain/requestid"
+	"github.com/shopspring/decimal"
+)
+
+// newTestPayment 建立指向 handler 的 ProxyPayment，斷路器在連續 2 次失敗後開啟。
+func newTestPayment(t *testing.T, handler http.Handler, cfg config.ExternalWalletConfig) *ProxyPayment {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	cfg.BaseURL = srv.URL
+	if cfg.Breaker.FailureThreshold == 0 {
+		cfg.Breaker.FailureThreshold = 2
+	}
+	p, err := NewPayment(nil, "test-op", cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewPayment: %v", err)
+	}
+	return p
+}
+
+func TestCallAPIClientErrorsDoNotTripBreaker(t *testing.T) {
+	var calls atomic.Int32
+	p := newTestPayment(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusPaymentRequired)
+		_, _ = w.Write([]byte(`{"status":"error","message":"insufficient balance"}`))
+	}), config.ExternalWalletConfig{})
+
+	for i := 0; i < 5; i++ {
+		_, pErr := p.Debit(context.Background(), "p1", "USD", decimal.NewFromInt(1))
+		if pErr == nil || pErr.Code != http.StatusPaymentRequired {
+			t.Fatalf("call %d: got %v, want code 402", i, pErr)
+		}
+	}
+	if got := calls.Load(); got != 5 {
+		t.Fatalf("server received %d calls, want 5", got)
+	}
+	if h := p.Health(); !h.Healthy || h.ConsecutiveFailures != 0 {
+		t.Fatalf("breaker affected by 4xx: %+v", h)
+	}
+}
+
+func TestRollbackFailureLogsAlarm(t *testing.T) {
+	p := newTestPayment(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}), config.ExternalWalletConfig{})
+	var buf bytes.Buffer
+	p.logger = slog.New(slog.NewJSONHandler(&buf, nil)).With("operatorID", "test-op")
+	p.newTransactionID = func() string { return "tx-1" }
+
+	ctx := requestid.NewContext(context.Background(), "req-1")
+	if _, pErr := p.Debit(ctx, "p1", "USD", decimal.NewFromInt(1)); pErr == nil {
+		t.Fatal("Debit succeeded against a failing wallet")
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("alarm log %q: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"level":         "ERROR",
+		"msg":           "[ALARM] wallet rollback failed",
+		"operatorID":    "test-op",
+		"playerID":      "p1",
+		"requestID":     "req-1",
+		"transactionID": "tx-1",
+		"txType":        txTypeDebit,
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("log field %s = %v, want %v", k, entry[k], v)
+		}
+	}
+}
+
+func TestCreditUsesTransactionIDFromContext(t *testing.T) {
+	ids := make(chan string, 2)
+	p := newTestPayment(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			TransactionID string `json:"transactionID"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		ids <- body.TransactionID
+		_, _ = w.Write([]byte(`{"status":"ok","balance":"1"}`))
+	}), config.ExternalWalletConfig{})
+	p.newTransactionID = func() string { return "generated" }
+
+	ctx := requestid.NewContext(context.Background(), "r1")
+	for _, ctx := range []context.Context{ctx, wallet.WithTransactionID(ctx, "bonus-release-7")} {
+		if _, pErr := p.Credit(ctx, "p1", "USD", decimal.NewFromInt(1)); pErr != nil {
+			t.Fatalf("Credit: %v", pErr)
+		}
+	}
+	if got := <-ids; got != "generated" {
+		t.Errorf("transactionID without override = %q, want generated", got)
+	}
+	if got := <-ids; got != "bonus-release-7" {
+		t.Errorf("transactionID with override = %q, want bonus-release-7", got)
+	}
+}
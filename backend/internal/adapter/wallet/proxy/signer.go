@@ -0,0 +1,285 @@
+package proxy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joe_shih/slot-factory/internal/config"
+)
+
+// 簽章方案名稱。
+const (
+	SchemeBearer     = "bearer"
+	SchemeHMACSHA256 = "hmac-sha256"
+)
+
+// 簽章相關的 HTTP 標頭。
+const (
+	HeaderKeyID     = "X-Key-Id"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderNonce     = "X-Nonce"
+	HeaderSignature = "X-Signature"
+)
+
+// defaultReplayWindow 是未設定時允許的時間戳記誤差。
+const defaultReplayWindow = 30 * time.Second
+
+var (
+	// errUnsignedResponse 表示外部平台的回應缺少簽章。
+	errUnsignedResponse = errors.New("wallet response is not signed")
+	// errInvalidSignature 表示簽章驗證失敗或已超出重放時間窗。
+	errInvalidSignature = errors.New("wallet signature is invalid")
+	// errReplayedNonce 表示請求的 Nonce 已在重放時間窗內使用過。
+	errReplayedNonce = errors.New("wallet request nonce has already been used")
+)
+
+// Signer 定義了外部錢包請求的簽章與回應驗章方式。
+//
+// 不同的錢包整合商要求的簽章方式不同，透過此介面可以在不修改 ProxyPayment 的情況下擴充新的方案。
+type Signer interface {
+	// SignRequest 在請求送出前附加驗證資訊。body 為請求的原始內容（可能為 nil）。
+	SignRequest(req *http.Request, body []byte) error
+
+	// VerifyResponse 驗證外部平台回應的真實性。req 為對應的原始請求。
+	VerifyResponse(req *http.Request, resp *http.Response, body []byte) error
+}
+
+// SignerFactory 根據錢包設定建立 Signer。
+type SignerFactory func(cfg config.ExternalWalletConfig) (Signer, error)
+
+var (
+	signerFactoriesMu sync.RWMutex
+	signerFactories   = map[string]SignerFactory{
+		SchemeBearer: func(cfg config.ExternalWalletConfig) (Signer, error) {
+			return &bearerSigner{apiKey: cfg.APIKey}, nil
+		},
+		SchemeHMACSHA256: func(cfg config.ExternalWalletConfig) (Signer, error) {
+			return newHMACSignerFromConfig(cfg)
+		},
+	}
+)
+
+// RegisterSigner 註冊一個自訂的簽章方案，同名方案會被覆蓋。
+func RegisterSigner(scheme string, factory SignerFactory) {
+	signerFactoriesMu.Lock()
+	defer signerFactoriesMu.Unlock()
+	signerFactories[scheme] = factory
+}
+
+// newSigner 根據設定中的簽章方案建立 Signer，未設定時使用 bearer。
+func newSigner(cfg config.ExternalWalletConfig) (Signer, error) {
+	scheme := cfg.Signing.Scheme
+	if scheme == "" {
+		scheme = SchemeBearer
+	}
+	signerFactoriesMu.RLock()
+	factory, ok := signerFactories[scheme]
+	signerFactoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown wallet signing scheme: %s", scheme)
+	}
+	return factory(cfg)
+}
+
+// --- Bearer ---
+
+// bearerSigner 只附加靜態的 Bearer API Key，不驗證回應。
+type bearerSigner struct {
+	apiKey string
+}
+
+func (s *bearerSigner) SignRequest(req *http.Request, _ []byte) error {
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	return nil
+}
+
+func (s *bearerSigner) VerifyResponse(_ *http.Request, _ *http.Response, _ []byte) error {
+	return nil
+}
+
+// --- HMAC-SHA256 ---
+
+// HMACSigner 以 HMAC-SHA256 對請求與回應簽章。
+//
+// 請求簽章內容為：
+//
+//	METHOD\nPATH?QUERY\nTIMESTAMP\nNONCE\nHEX(SHA256(BODY))
+//
+// 回應簽章內容為：
+//
+//	STATUS\nPATH?QUERY\nTIMESTAMP\nNONCE\nHEX(SHA256(BODY))
+//
+// 回應必須回傳請求的 NONCE，藉此將回應綁定到這次請求，避免舊回應被重放。
+// 雙方的時間戳記都必須落在重放時間窗內；伺服器端另外記錄已使用的 NONCE，
+// 拒絕在時間窗內被原樣重送的請求。
+//
+// HMACSigner 同時提供伺服器端使用的 VerifyRequest 與 SignResponse，
+// 讓本地的模擬平台可以使用同一套演算法。
+type HMACSigner struct {
+	keyID           string
+	secret          []byte
+	replayWindow    time.Duration
+	allowUnsigned   bool
+	nonces          NonceStore
+	now             func() time.Time
+	generateNonceFn func() string
+}
+
+var _ Signer = (*HMACSigner)(nil)
+
+// NewHMACSigner 建立一個 HMAC-SHA256 簽章器。
+//
+// 參數說明：
+//   - keyID: string, 告知對方使用哪一把金鑰，可為空字串。
+//   - secret: string, 雙方共享的密鑰。
+//   - replayWindow: time.Duration, 允許的時間戳記誤差，0 表示使用預設值 30 秒。
+//
+// 回傳值：
+//   - *HMACSigner: 初始化完成的簽章器，VerifyRequest 預設以記憶體記錄已使用的 Nonce。
+func NewHMACSigner(keyID, secret string, replayWindow time.Duration) *HMACSigner {
+	if replayWindow <= 0 {
+		replayWindow = defaultReplayWindow
+	}
+	return &HMACSigner{
+		keyID:           keyID,
+		secret:          []byte(secret),
+		replayWindow:    replayWindow,
+		nonces:          NewMemoryNonceStore(),
+		now:             time.Now,
+		generateNonceFn: uuid.NewString,
+	}
+}
+
+// SetNonceStore 替換 VerifyRequest 記錄已使用 Nonce 的儲存，多個實體共同驗章時應使用共享的儲存。
+func (s *HMACSigner) SetNonceStore(store NonceStore) {
+	s.nonces = store
+}
+
+// newHMACSignerFromConfig 根據錢包設定建立 HMACSigner。
+func newHMACSignerFromConfig(cfg config.ExternalWalletConfig) (*HMACSigner, error) {
+	if cfg.Signing.Secret == "" {
+		return nil, errors.New("hmac-sha256 signing requires a secret")
+	}
+	s := NewHMACSigner(cfg.Signing.KeyID, cfg.Signing.Secret, time.Duration(cfg.Signing.ReplayWindowSec)*time.Second)
+	s.allowUnsigned = cfg.Signing.AllowUnsignedResponse
+	return s, nil
+}
+
+// SignRequest 為請求附加時間戳記、Nonce 與簽章標頭。
+func (s *HMACSigner) SignRequest(req *http.Request, body []byte) error {
+	ts := strconv.FormatInt(s.now().Unix(), 10)
+	nonce := s.generateNonceFn()
+	if s.keyID != "" {
+		req.Header.Set(HeaderKeyID, s.keyID)
+	}
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, s.sign(req.Method, requestTarget(req), ts, nonce, body))
+	return nil
+}
+
+// VerifyResponse 驗證回應的簽章、Nonce 與時間戳記。
+func (s *HMACSigner) VerifyResponse(req *http.Request, resp *http.Response, body []byte) error {
+	sig := resp.Header.Get(HeaderSignature)
+	if sig == "" {
+		if s.allowUnsigned {
+			return nil
+		}
+		return errUnsignedResponse
+	}
+	nonce := resp.Header.Get(HeaderNonce)
+	if nonce != req.Header.Get(HeaderNonce) {
+		return fmt.Errorf("%w: nonce mismatch", errInvalidSignature)
+	}
+	ts := resp.Header.Get(HeaderTimestamp)
+	if err := s.checkTimestamp(ts); err != nil {
+		return err
+	}
+	expected := s.sign(strconv.Itoa(resp.StatusCode), requestTarget(req), ts, nonce, body)
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return errInvalidSignature
+	}
+	return nil
+}
+
+// VerifyRequest 供伺服器端驗證收到的請求簽章、時間戳記與 Nonce。
+//
+// 簽章通過後才登記 Nonce，避免未經簽章的請求佔用別人的 Nonce。
+// Nonce 保留兩倍的重放時間窗，涵蓋時間戳記往前與往後的誤差。
+func (s *HMACSigner) VerifyRequest(req *http.Request, body []byte) error {
+	sig := req.Header.Get(HeaderSignature)
+	if sig == "" {
+		return fmt.Errorf("%w: missing signature", errInvalidSignature)
+	}
+	nonce := req.Header.Get(HeaderNonce)
+	if nonce == "" {
+		return fmt.Errorf("%w: missing nonce", errInvalidSignature)
+	}
+	ts := req.Header.Get(HeaderTimestamp)
+	if err := s.checkTimestamp(ts); err != nil {
+		return err
+	}
+	expected := s.sign(req.Method, requestTarget(req), ts, nonce, body)
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return errInvalidSignature
+	}
+	fresh, err := s.nonces.Reserve(req.Context(), s.keyID+":"+nonce, 2*s.replayWindow)
+	if err != nil {
+		return fmt.Errorf("reserve nonce: %w", err)
+	}
+	if !fresh {
+		return errReplayedNonce
+	}
+	return nil
+}
+
+// SignResponse 供伺服器端為回應附加簽章標頭，必須在寫入 status code 之前呼叫。
+func (s *HMACSigner) SignResponse(w http.ResponseWriter, req *http.Request, status int, body []byte) {
+	ts := strconv.FormatInt(s.now().Unix(), 10)
+	nonce := req.Header.Get(HeaderNonce)
+	if s.keyID != "" {
+		w.Header().Set(HeaderKeyID, s.keyID)
+	}
+	w.Header().Set(HeaderTimestamp, ts)
+	w.Header().Set(HeaderNonce, nonce)
+	w.Header().Set(HeaderSignature, s.sign(strconv.Itoa(status), requestTarget(req), ts, nonce, body))
+}
+
+// checkTimestamp 確認時間戳記落在重放時間窗內。
+func (s *HMACSigner) checkTimestamp(ts string) error {
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp", errInvalidSignature)
+	}
+	diff := s.now().Sub(time.Unix(unix, 0))
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > s.replayWindow {
+		return fmt.Errorf("%w: timestamp outside replay window", errInvalidSignature)
+	}
+	return nil
+}
+
+// sign 計算標準化字串的 HMAC-SHA256 簽章（hex 編碼）。
+func (s *HMACSigner) sign(first, target, ts, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	canonical := strings.Join([]string{first, target, ts, nonce, hex.EncodeToString(bodyHash[:])}, "\n")
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// requestTarget 回傳請求的路徑與查詢字串。
+func requestTarget(req *http.Request) string {
+	return req.URL.RequestURI()
+}
@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHMACSignerVerifyRequestRejectsReplayedNonce(t *testing.T) {
+	client := NewHMACSigner("k1", "secret", time.Minute)
+	server := NewHMACSigner("k1", "secret", time.Minute)
+	body := []byte(`{"playerID":"p1"}`)
+
+	req := httptest.NewRequest("POST", "/debit", strings.NewReader(string(body)))
+	if err := client.SignRequest(req, body); err != nil {
+		t.Fatalf("SignRequest: %v", err)
+	}
+	if err := server.VerifyRequest(req, body); err != nil {
+		t.Fatalf("first VerifyRequest: %v", err)
+	}
+	if err := server.VerifyRequest(req, body); !errors.Is(err, errReplayedNonce) {
+		t.Fatalf("replayed VerifyRequest: got %v, want errReplayedNonce", err)
+	}
+}
+
+func TestHMACSignerVerifyRequestRequiresNonce(t *testing.T) {
+	client := NewHMACSigner("k1", "secret", time.Minute)
+	client.generateNonceFn = func() string { return "" }
+	server := NewHMACSigner("k1", "secret", time.Minute)
+
+	req := httptest.NewRequest("GET", "/balance/p1", nil)
+	if err := client.SignRequest(req, nil); err != nil {
+		t.Fatalf("SignRequest: %v", err)
+	}
+	if err := server.VerifyRequest(req, nil); !errors.Is(err, errInvalidSignature) {
+		t.Fatalf("VerifyRequest without nonce: got %v, want errInvalidSignature", err)
+	}
+}
+
+func TestMemoryNonceStoreExpires(t *testing.T) {
+	store := NewMemoryNonceStore()
+	now := time.Unix(1000, 0)
+	store.now = func() time.Time { return now }
+
+	if ok, _ := store.Reserve(t.Context(), "n1", time.Second); !ok {
+		t.Fatal("first reserve rejected")
+	}
+	if ok, _ := store.Reserve(t.Context(), "n1", time.Second); ok {
+		t.Fatal("duplicate reserve accepted")
+	}
+	now = now.Add(2 * time.Second)
+	if ok, _ := store.Reserve(t.Context(), "n1", time.Second); !ok {
+		t.Fatal("reserve after expiry rejected")
+	}
+}
+
+func TestMemoryNonceStorePurgesExpiredOnReserve(t *testing.T) {
+	store := NewMemoryNonceStore()
+	now := time.Unix(1000, 0)
+	store.now = func() time.Time { return now }
+
+	for i := 0; i < 100; i++ {
+		ttl := time.Second
+		if i%10 == 0 {
+			ttl = time.Hour
+		}
+		if ok, _ := store.Reserve(t.Context(), fmt.Sprintf("n%d", i), ttl); !ok {
+			t.Fatalf("reserve n%d rejected", i)
+		}
+	}
+	now = now.Add(2 * time.Second)
+	if ok, _ := store.Reserve(t.Context(), "fresh", time.Second); !ok {
+		t.Fatal("reserve fresh rejected")
+	}
+	// 10 個長效 Nonce 與剛登記的 Nonce 仍有效，其他已過期的 Nonce 都被清除
+	if len(store.expires) != 11 || len(store.queue) != 11 {
+		t.Fatalf("store holds %d nonces (%d queued), want 11", len(store.expires), len(store.queue))
+	}
+	if ok, _ := store.Reserve(t.Context(), "n0", time.Second); ok {
+		t.Fatal("unexpired nonce n0 accepted again")
+	}
+}
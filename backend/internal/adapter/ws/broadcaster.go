@@ -0,0 +1,40 @@
+package ws
+
+import (
+	"github.com/joe_shih/slot-factory/internal/domain/game"
+	"github.com/joe_shih/slot-factory/pkg/codec"
+	"github.com/joe_shih/slot-factory/pkg/wss"
+)
+
+// Broadcaster 將 wss.Server 的群組廣播轉接成 game.Broadcaster。
+// 訊息依各連線協商的子協定編碼，每種子協定只編碼一次。
+type Broadcaster struct {
+	server *wss.Server
+}
+
+// 確保 Broadcaster 在編譯時期就實現了 game.Broadcaster 介面。
+var _ game.Broadcaster = (*Broadcaster)(nil)
+
+// NewBroadcaster 創建一個新的廣播轉接器實例。
+func NewBroadcaster(server *wss.Server) *Broadcaster {
+	return &Broadcaster{server: server}
+}
+
+// Broadcast 將訊息發送給群組內的所有連線。
+func (b *Broadcaster) Broadcast(group string, message game.Envelope) int {
+	return b.server.Broadcast(group, encoder(message))
+}
+
+// BroadcastAll 將訊息發送給所有連線。
+func (b *Broadcaster) BroadcastAll(message game.Envelope) int {
+	return b.server.BroadcastAll(encoder(message))
+}
+
+// encoder 回傳依子協定選擇 codec 編碼訊息的 wss.Encoder。
+func encoder(message game.Envelope) wss.Encoder {
+	return func(subprotocol string) ([]byte, bool, error) {
+		c := codec.ForSubprotocol(subprotocol)
+		data, err := c.Encode(message)
+		return data, c.Binary(), err
+	}
+}
@@ -1,10 +1,12 @@
 package ws
 
 import (
+	"context"
 	"net"
 	"strings"
 
 	"github.com/joe_shih/slot-factory/internal/domain/game"
+	"github.com/joe_shih/slot-factory/pkg/codec"
 	"github.com/joe_shih/slot-factory/pkg/wss"
 )
 
@@ -12,21 +14,29 @@ import (
 // 它扮演了轉接頭的角色，填補了兩個介面之間的差異。
 type GameClientAdapter struct {
 	client wss.Client
+	codec  codec.Codec
 }
 
 // 確保 GameClientAdapter 在編譯時期就實現了 game.GameClient 介面。
 var _ game.GameClient = (*GameClientAdapter)(nil)
 
-// NewGameClientAdapter 創建一個新的轉接器實例。
+// NewGameClientAdapter 創建一個新的轉接器實例，並依連線協商的子協定選擇訊息編碼。
 func NewGameClientAdapter(client wss.Client) game.GameClient {
-	return &GameClientAdapter{client: client}
+	return &GameClientAdapter{client: client, codec: codec.ForSubprotocol(client.Subprotocol())}
 }
 
 // --- 實現 game.GameClient 介面 ---
 
-// SendMessage 直接呼叫底層 client 的同名方法。
-func (a *GameClientAdapter) SendMessage(message string) error {
-	return a.client.SendMessage(message)
+// SendMessage 以連線協商的 codec 編碼訊息，二進位編碼以 binary frame 發送，JSON 以 text frame 發送。
+func (a *GameClientAdapter) SendMessage(message game.Envelope) error {
+	data, err := a.codec.Encode(message)
+	if err != nil {
+		return err
+	}
+	if a.codec.Binary() {
+		return a.client.SendBinary(data)
+	}
+	return a.client.SendMessage(string(data))
 }
 
 // Kick 直接呼叫底層 client 的同名方法。
@@ -34,6 +44,16 @@ func (a *GameClientAdapter) Kick(reason string) error {
 	return a.client.Kick(reason)
 }
 
+// Join 直接呼叫底層 client 的同名方法。
+func (a *GameClientAdapter) Join(group string) {
+	a.client.Join(group)
+}
+
+// Leave 直接呼叫底層 client 的同名方法。
+func (a *GameClientAdapter) Leave(group string) {
+	a.client.Leave(group)
+}
+
 // SetTag 直接呼叫底層 client 的同名方法。
 func (a *GameClientAdapter) SetTag(key string, value any) {
 	a.client.SetTag(key, value)
@@ -45,8 +65,12 @@ func (a *GameClientAdapter) GetTag(key string) (any, bool) {
 }
 
 // GetIP 是此 Adapter 的核心轉接邏輯。
-// 它呼叫底層 client 的 RemoteAddr() 方法，並從中解析出 IP 位址。
+// 它優先使用 wss 在升級時解析出的客戶端 IP (位於反向代理之後時取自受信任的代理標頭)，
+// 否則呼叫底層 client 的 RemoteAddr() 方法，並從中解析出 IP 位址。
 func (a *GameClientAdapter) GetIP() string {
+	if ip := a.client.ClientIP(); ip != "" {
+		return ip
+	}
 	addr := a.client.RemoteAddr()
 	// net.SplitHostPort 對 IPv6 的位址 (例如 "[::1]:1234") 也能正常處理
 	host, _, err := net.SplitHostPort(addr)
@@ -59,6 +83,11 @@ func (a *GameClientAdapter) GetIP() string {
 	return host
 }
 
+// Context 直接呼叫底層 client 的同名方法。
+func (a *GameClientAdapter) Context() context.Context {
+	return a.client.Context()
+}
+
 // GetID implements game.GameClient.
 func (a *GameClientAdapter) GetID() string {
 	return a.client.ID()
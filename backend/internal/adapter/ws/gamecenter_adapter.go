@@ -1,7 +1,11 @@
 package ws
 
 import (
+	"time"
+
 	"github.com/joe_shih/slot-factory/internal/application/gamecenter"
+	"github.com/joe_shih/slot-factory/internal/domain/game"
+	"github.com/joe_shih/slot-factory/pkg/codec"
 	"github.com/joe_shih/slot-factory/pkg/wss"
 )
 
@@ -14,6 +18,9 @@ type GameCenterAdapter struct {
 // 確保 GameCenterAdapter 在編譯時期就實現了 wss.Subscriber 介面。
 var _ wss.Subscriber = (*GameCenterAdapter)(nil)
 
+// 確保 GameCenterAdapter 會收到連線層的速率限制通知。
+var _ wss.RateLimitSubscriber = (*GameCenterAdapter)(nil)
+
 // NewGameCenterAdapter 創建一個新的 GameCenterAdapter 實例。
 func NewGameCenterAdapter(handler gamecenter.EventHandler) *GameCenterAdapter {
 	return &GameCenterAdapter{handler: handler}
@@ -33,7 +40,19 @@ func (a *GameCenterAdapter) OnDisconnect(client wss.Client) {
 }
 
 // OnMessage 在收到 wss 的訊息事件時被呼叫。
+// 訊息會先依連線協商的 codec 轉為 JSON，核心應用層只需處理 JSON。
+// 無法以協商的 codec 解碼時，直接以標準錯誤訊息回應，不會把原始位元組交給核心應用層。
 func (a *GameCenterAdapter) OnMessage(client wss.Client, message []byte) {
 	gameClient := NewGameClientAdapter(client)
-	a.handler.HandleMessage(gameClient, message)
+	data, err := codec.ForSubprotocol(client.Subprotocol()).ToJSON(message)
+	if err != nil {
+		_ = gameClient.SendMessage(game.NewErrorEnvelope("", game.ErrCodeInvalidMessage, "invalid message encoding"))
+		return
+	}
+	a.handler.HandleMessage(gameClient, data)
+}
+
+// OnRateLimited 在客戶端超過連線層的速率限制時被呼叫，由核心應用層以客戶端的訊息格式送出警告。
+func (a *GameCenterAdapter) OnRateLimited(client wss.Client, limit string, retryAfter time.Duration) {
+	a.handler.HandleRateLimited(NewGameClientAdapter(client), limit, retryAfter)
 }
@@ -0,0 +1,31 @@
+package audit
+
+import (
+	"context"
+	"time"
+)
+
+// Entry 是一筆管理操作的稽核紀錄。
+type Entry struct {
+	// Actor 是操作者，API Key 為其名稱，JWT 為 sub。
+	Actor string `json:"actor"`
+	// Role 是操作者當時的角色。
+	Role string `json:"role"`
+	// Method 是驗證方式："api_key"、"jwt" 或 "anonymous"。
+	Method string `json:"method"`
+	// Action 是操作名稱，例如 "POST /api/v1/admin/kick_all"。
+	Action string `json:"action"`
+	// Params 是請求參數 (query 與 body)，以 JSON 字串保存。
+	Params string `json:"params"`
+	// RemoteIP 是呼叫端 IP。
+	RemoteIP string `json:"remoteIP"`
+	// Status 是回應的 HTTP 狀態碼，用於區分成功與失敗的操作。
+	Status    int       `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Store 定義了稽核紀錄的儲存介面。
+type Store interface {
+	// Record 寫入一筆稽核紀錄。
+	Record(ctx context.Context, e Entry) error
+}
@@ -0,0 +1,147 @@
+package gamecenter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Admin 提供遊戲目錄、維護時段的查詢與跨實體的管理指令。
+//
+// 管理指令只寫入 Redis 並透過 game_control 頻道通知各 wsserver 實體，Admin 本身不監聽控制頻道、
+// 也不執行維護檢查，因此 cmd/api 可以直接使用而不必建立完整的遊戲中心。
+type Admin struct {
+	rdb         *redis.Client
+	catalog     *Catalog
+	maintenance *maintenanceSchedule
+	logger      *slog.Logger
+}
+
+// NewAdmin 建立一個管理服務，供不承載玩家連線的服務 (例如 cmd/api) 使用。
+//
+// 參數說明：
+//   - rdb: *redis.Client, 讀取目錄與維護時段並發布控制指令。如果為 nil，則只能查詢，管理指令會返回錯誤。
+//   - catalog: *Catalog, 遊戲目錄。如果為 nil，則使用不依營運商篩選的目錄。
+//   - logger: *slog.Logger, 記錄管理操作的 Logger 實例。
+//
+// 回傳值：
+//   - *Admin: 初始化完成的管理服務。
+func NewAdmin(rdb *redis.Client, catalog *Catalog, logger *slog.Logger) *Admin {
+	if catalog == nil {
+		catalog = NewCatalog(rdb, nil, nil)
+	}
+	return newAdmin(rdb, catalog, newMaintenanceSchedule(rdb), logger)
+}
+
+// newAdmin 建立與遊戲中心共用目錄與維護時段的管理服務。
+func newAdmin(rdb *redis.Client, catalog *Catalog, maintenance *maintenanceSchedule, logger *slog.Logger) *Admin {
+	return &Admin{rdb: rdb, catalog: catalog, maintenance: maintenance, logger: logger}
+}
+
+// GetGames 取得營運商可以看到的遊戲目錄，包含上下架狀態、各幣種的下注限制與跨實體的線上人數。
+//
+// 參數說明：
+//   - ctx: context.Context, 用於控制 Redis 請求的 Context。
+//   - operatorID: string, 營運商 ID，空字串表示列出所有遊戲。
+//
+// 回傳值：
+//   - []GameInfo: 遊戲目錄，依遊戲 ID 排序。
+//   - error: 如果營運商不存在或 Redis 讀取失敗，則返回錯誤。
+func (a *Admin) GetGames(ctx context.Context, operatorID string) ([]GameInfo, error) {
+	return a.catalog.List(ctx, operatorID)
+}
+
+// SetGameStatus 上架或下架遊戲。
+//
+// 狀態寫入 Redis 後透過 game_control 頻道通知所有服務實體；下架的遊戲拒絕新的加入與下注，
+// 已在遊戲中的玩家會收到 game_status 通知，進行中的局仍會正常結算。
+//
+// 參數說明：
+//   - ctx: context.Context, 用於控制 Redis 請求的 Context。
+//   - gameID: int, 遊戲 ID。
+//   - status: GameStatus, GameEnabled 或 GameDisabled。
+//
+// 回傳值：
+//   - error: 如果狀態無效、遊戲不存在或 Redis 寫入失敗，則返回錯誤。
+func (a *Admin) SetGameStatus(ctx context.Context, gameID int, status GameStatus) error {
+	if status != GameEnabled && status != GameDisabled {
+		return fmt.Errorf("%w: %s", ErrInvalidGameStatus, status)
+	}
+	if err := a.catalog.setStatus(ctx, gameID, status); err != nil {
+		return err
+	}
+	data, _ := json.Marshal(statusCommand{GameID: gameID, Status: status})
+	payload, _ := json.Marshal(ControlCommand{Action: "game_status", Data: string(data)})
+	return a.rdb.Publish(ctx, RedisChannelControl, payload).Err()
+}
+
+// ScheduleMaintenance 排定維護時段。
+//
+// 時段寫入 Redis 後透過 game_control 頻道通知所有服務實體。開始前會向受影響的玩家發送倒數通知，
+// 開始後拒絕新的登入與下注 (進行中的局仍會正常結算)，時間到後自動解除。
+//
+// 參數說明：
+//   - ctx: context.Context, 用於控制 Redis 請求的 Context。
+//   - window: MaintenanceWindow, 維護時段。GameID 為 0 表示全平台維護，StartAt 為零值表示立即開始，ID 由系統產生。
+//
+// 回傳值：
+//   - MaintenanceWindow: 已排定的維護時段。
+//   - error: 如果時間設定不合法、遊戲不存在或 Redis 寫入失敗，則返回錯誤。
+func (a *Admin) ScheduleMaintenance(ctx context.Context, window MaintenanceWindow) (MaintenanceWindow, error) {
+	if window.GameID > 0 {
+		if exists, err := a.catalog.exists(ctx, window.GameID); err != nil {
+			return MaintenanceWindow{}, err
+		} else if !exists {
+			return MaintenanceWindow{}, fmt.Errorf("%w: %d", ErrGameNotFound, window.GameID)
+		}
+	}
+	w, err := a.maintenance.schedule(ctx, window, time.Now())
+	if err != nil {
+		return MaintenanceWindow{}, err
+	}
+	a.logger.Warn("maintenance scheduled", "id", w.ID, "gameID", w.GameID, "startAt", w.StartAt, "endAt", w.EndAt)
+	return w, a.publishMaintenance(ctx)
+}
+
+// CancelMaintenance 取消維護時段，並通知所有服務實體。已開始的維護會立即解除。
+//
+// 參數說明：
+//   - ctx: context.Context, 用於控制 Redis 請求的 Context。
+//   - id: string, 維護時段 ID。
+//
+// 回傳值：
+//   - error: 如果時段不存在或 Redis 寫入失敗，則返回錯誤。
+func (a *Admin) CancelMaintenance(ctx context.Context, id string) error {
+	if err := a.maintenance.cancel(ctx, id); err != nil {
+		return err
+	}
+	a.logger.Warn("maintenance cancelled", "id", id)
+	return a.publishMaintenance(ctx)
+}
+
+// ListMaintenance 回傳尚未結束的維護時段，依開始時間排序。
+func (a *Admin) ListMaintenance(ctx context.Context) ([]MaintenanceWindow, error) {
+	return a.maintenance.list(ctx, time.Now())
+}
+
+// publishMaintenance 通知所有服務實體重新載入維護時段。
+func (a *Admin) publishMaintenance(ctx context.Context) error {
+	payload, _ := json.Marshal(ControlCommand{Action: "maintenance"})
+	return a.rdb.Publish(ctx, RedisChannelControl, payload).Err()
+}
+
+func (a *Admin) KickAll(ctx context.Context) error {
+	if a.rdb == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+
+	cmd := ControlCommand{
+		Action: "kick_all",
+	}
+	payload, _ := json.Marshal(cmd)
+	return a.rdb.Publish(ctx, RedisChannelControl, payload).Err()
+}
@@ -0,0 +1,325 @@
+package gamecenter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/joe_shih/slot-factory/internal/domain/currency"
+	"github.com/joe_shih/slot-factory/internal/domain/game"
+	"github.com/joe_shih/slot-factory/internal/domain/operator"
+	"github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
+)
+
+// Redis 相關常數
+const (
+	// RedisKeyGameCatalog 是遊戲目錄資訊的 hash，field 為遊戲 ID，value 為 game.Info 的 JSON。
+	RedisKeyGameCatalog = "games:catalog"
+	// RedisKeyGameStatus 是遊戲上下架狀態的 hash，field 為遊戲 ID，沒有 field 表示上架中。
+	RedisKeyGameStatus = "games:status"
+)
+
+// GameStatus 是遊戲的上下架狀態。
+type GameStatus string
+
+const (
+	// GameEnabled 表示遊戲開放中。
+	GameEnabled GameStatus = "enabled"
+	// GameDisabled 表示遊戲已下架：拒絕新的加入與下注，進行中的局會正常結算。
+	GameDisabled GameStatus = "disabled"
+)
+
+var (
+	// ErrGameNotFound 表示遊戲不在目錄中。
+	ErrGameNotFound = errors.New("game not found")
+	// ErrInvalidGameStatus 表示上下架狀態不是 GameEnabled 或 GameDisabled。
+	ErrInvalidGameStatus = errors.New("invalid game status")
+)
+
+// BetLimit 是遊戲在單一幣種下的下注限制，為 0 表示不限制。
+type BetLimit struct {
+	MinBet decimal.Decimal `json:"minBet"`
+	MaxBet decimal.Decimal `json:"maxBet"`
+}
+
+// GameInfo 是遊戲目錄中的一筆遊戲資訊。
+type GameInfo struct {
+	ID          int             `json:"id"`
+	Name        string          `json:"name"`
+	Type        string          `json:"type"`
+	RTP         decimal.Decimal `json:"rtp"`
+	Status      GameStatus      `json:"status"`
+	PlayerCount int             `json:"playerCount"`
+	// BetLimits 是各幣種的下注限制，key 為幣種代碼，已套用遊戲與營運商的限制。
+	BetLimits map[string]BetLimit `json:"betLimits"`
+}
+
+// statusCommand 是 game_status 控制指令的內容。
+type statusCommand struct {
+	GameID int        `json:"gameID"`
+	Status GameStatus `json:"status"`
+}
+
+// Catalog 管理遊戲目錄：每個營運商可以看到與加入的遊戲、各幣種的下注限制，以及執行期的上下架狀態。
+//
+// 遊戲資訊在 wsserver 註冊遊戲時寫入 Redis，讓 API 服務不需要載入遊戲也能列出目錄。
+// 上下架狀態存放在 Redis，變更時透過 game_control 頻道通知所有 wsserver 實體更新本地快取。
+type Catalog struct {
+	rdb        *redis.Client
+	currencies *currency.Registry
+	operators  *operator.Registry
+
+	mu     sync.RWMutex
+	games  map[int]game.Info  // 本實體註冊的遊戲
+	status map[int]GameStatus // 上下架狀態的本地快取
+}
+
+// NewCatalog 建立一個遊戲目錄。
+//
+// 參數說明：
+//   - rdb: *redis.Client, 跨實體共用目錄與上下架狀態。如果為 nil，則只使用本實體註冊的遊戲。
+//   - currencies: *currency.Registry, 平台支援的幣種與下注限制。
+//   - operators: *operator.Registry, 營運商註冊表，決定各營運商開放的遊戲與下注限制。傳入 nil 則不依營運商篩選。
+//
+// 回傳值：
+//   - *Catalog: 初始化完成的遊戲目錄。
+func NewCatalog(rdb *redis.Client, currencies *currency.Registry, operators *operator.Registry) *Catalog {
+	return &Catalog{
+		rdb:        rdb,
+		currencies: currencies,
+		operators:  operators,
+		games:      make(map[int]game.Info),
+		status:     make(map[int]GameStatus),
+	}
+}
+
+// register 將遊戲加入目錄，並寫入 Redis 供其他服務讀取。
+func (c *Catalog) register(ctx context.Context, gameID int, info game.Info) error {
+	c.mu.Lock()
+	c.games[gameID] = info
+	c.mu.Unlock()
+
+	if c.rdb == nil {
+		return nil
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return c.rdb.HSet(ctx, RedisKeyGameCatalog, strconv.Itoa(gameID), data).Err()
+}
+
+// Status 回傳遊戲目前的上下架狀態 (本地快取)。
+func (c *Catalog) Status(gameID int) GameStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if st, ok := c.status[gameID]; ok {
+		return st
+	}
+	return GameEnabled
+}
+
+// Visible 判斷營運商是否可以看到與加入遊戲。op 為 nil 表示不依營運商篩選。
+func (c *Catalog) Visible(op *operator.Operator, gameID int) bool {
+	return op == nil || op.AllowsGame(gameID)
+}
+
+// setStatus 將上下架狀態寫入 Redis 並更新本地快取。
+func (c *Catalog) setStatus(ctx context.Context, gameID int, status GameStatus) error {
+	if c.rdb == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	field := strconv.Itoa(gameID)
+	exists, err := c.exists(ctx, gameID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: %d", ErrGameNotFound, gameID)
+	}
+	if status == GameEnabled {
+		err = c.rdb.HDel(ctx, RedisKeyGameStatus, field).Err()
+	} else {
+		err = c.rdb.HSet(ctx, RedisKeyGameStatus, field, string(status)).Err()
+	}
+	if err != nil {
+		return err
+	}
+	c.applyStatus(gameID, status)
+	return nil
+}
+
+// exists 判斷遊戲是否在目錄中。有 Redis 時以 Redis 為準，否則只檢查本實體註冊的遊戲。
+func (c *Catalog) exists(ctx context.Context, gameID int) (bool, error) {
+	if c.rdb == nil {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		_, ok := c.games[gameID]
+		return ok, nil
+	}
+	return c.rdb.HExists(ctx, RedisKeyGameCatalog, strconv.Itoa(gameID)).Result()
+}
+
+// applyStatus 更新本地快取，由控制指令觸發。
+func (c *Catalog) applyStatus(gameID int, status GameStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if status == GameEnabled {
+		delete(c.status, gameID)
+		return
+	}
+	c.status[gameID] = status
+}
+
+// refresh 從 Redis 重新載入所有上下架狀態，用於啟動時與重新訂閱控制頻道後同步。
+func (c *Catalog) refresh(ctx context.Context) error {
+	if c.rdb == nil {
+		return nil
+	}
+	all, err := c.rdb.HGetAll(ctx, RedisKeyGameStatus).Result()
+	if err != nil {
+		return err
+	}
+	status := make(map[int]GameStatus, len(all))
+	for field, value := range all {
+		id, err := strconv.Atoi(field)
+		if err != nil {
+			continue
+		}
+		status[id] = GameStatus(value)
+	}
+	c.mu.Lock()
+	c.status = status
+	c.mu.Unlock()
+	return nil
+}
+
+// List 回傳營運商可以看到的遊戲目錄，依遊戲 ID 排序。
+//
+// 有 Redis 時以 Redis 中的目錄、上下架狀態與跨實體的線上人數為準；否則使用本實體的資料。
+//
+// 參數說明：
+//   - ctx: context.Context, 用於控制 Redis 請求的 Context。
+//   - operatorID: string, 營運商 ID，空字串表示不依營運商篩選並列出所有幣種的下注限制。
+//
+// 回傳值：
+//   - []GameInfo: 遊戲目錄。
+//   - error: 如果營運商不存在或 Redis 讀取失敗，則返回錯誤。
+func (c *Catalog) List(ctx context.Context, operatorID string) ([]GameInfo, error) {
+	var op *operator.Operator
+	if operatorID != "" && c.operators != nil {
+		var err error
+		if op, err = c.operators.Resolve(operatorID); err != nil {
+			return nil, err
+		}
+	}
+
+	games, status, counts, err := c.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]GameInfo, 0, len(games))
+	for id, info := range games {
+		if !c.Visible(op, id) {
+			continue
+		}
+		st := GameEnabled
+		if s, ok := status[id]; ok {
+			st = s
+		}
+		list = append(list, GameInfo{
+			ID:          id,
+			Name:        info.Name,
+			Type:        info.Type,
+			RTP:         info.RTP,
+			Status:      st,
+			PlayerCount: counts[id],
+			BetLimits:   c.betLimits(info.BetLimits, op),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// snapshot 讀取目錄、上下架狀態與線上人數。
+func (c *Catalog) snapshot(ctx context.Context) (map[int]game.Info, map[int]GameStatus, map[int]int, error) {
+	games := make(map[int]game.Info)
+	status := make(map[int]GameStatus)
+	counts := make(map[int]int)
+
+	if c.rdb == nil {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		for id, info := range c.games {
+			games[id] = info
+		}
+		for id, st := range c.status {
+			status[id] = st
+		}
+		return games, status, counts, nil
+	}
+
+	pipe := c.rdb.Pipeline()
+	catalogCmd := pipe.HGetAll(ctx, RedisKeyGameCatalog)
+	statusCmd := pipe.HGetAll(ctx, RedisKeyGameStatus)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, nil, nil, err
+	}
+	for field, value := range catalogCmd.Val() {
+		id, err := strconv.Atoi(field)
+		if err != nil {
+			continue
+		}
+		var info game.Info
+		if err := json.Unmarshal([]byte(value), &info); err != nil {
+			continue
+		}
+		games[id] = info
+	}
+	for field, value := range statusCmd.Val() {
+		if id, err := strconv.Atoi(field); err == nil {
+			status[id] = GameStatus(value)
+		}
+	}
+
+	// 線上人數 (跨服務實體加總)
+	pipe = c.rdb.Pipeline()
+	countCmds := make(map[int]*redis.StringCmd, len(games))
+	for id := range games {
+		countCmds[id] = pipe.Get(ctx, fmt.Sprintf(RedisKeyPlayerCountPrefix, id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, nil, nil, err
+	}
+	for id, cmd := range countCmds {
+		counts[id], _ = cmd.Int()
+	}
+	return games, status, counts, nil
+}
+
+// betLimits 回傳遊戲在營運商可用幣種的下注限制，op 為 nil 時回傳平台所有幣種的限制。
+//
+// 平台幣種的限制先以遊戲自身的限制收窄，再以營運商的限制收窄，與玩家下注時檢查的限制一致。
+func (c *Catalog) betLimits(gameLimits game.BetLimits, op *operator.Operator) map[string]BetLimit {
+	limits := make(map[string]BetLimit)
+	if c.currencies == nil {
+		return limits
+	}
+	for _, cur := range c.currencies.List() {
+		if op != nil && !op.AllowsCurrency(cur.Code) {
+			continue
+		}
+		cur = gameLimits.Apply(cur)
+		if op != nil {
+			cur = op.ApplyLimits(cur)
+		}
+		limits[cur.Code] = BetLimit{MinBet: cur.MinBet, MaxBet: cur.MaxBet}
+	}
+	return limits
+}
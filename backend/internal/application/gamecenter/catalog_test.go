@@ -0,0 +1,73 @@
+package gamecenter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/joe_shih/slot-factory/internal/domain/currency"
+	"github.com/joe_shih/slot-factory/internal/domain/game"
+	"github.com/joe_shih/slot-factory/internal/domain/operator"
+	"github.com/shopspring/decimal"
+)
+
+func TestCatalogListBetLimits(t *testing.T) {
+	d := decimal.RequireFromString
+	currencies, err := currency.NewRegistry("TWD",
+		currency.Currency{Code: "TWD", Precision: 2, MinBet: d("1"), MaxBet: d("100000")},
+		currency.Currency{Code: "USD", Precision: 2, MinBet: d("0.1"), MaxBet: d("5000")},
+	)
+	if err != nil {
+		t.Fatalf("currency.NewRegistry: %v", err)
+	}
+	operators, err := operator.NewRegistry("default",
+		operator.Operator{ID: "default"},
+		operator.Operator{ID: "vip", BetLimits: map[string]operator.BetLimit{"TWD": {MinBet: d("5"), MaxBet: d("10000")}}},
+	)
+	if err != nil {
+		t.Fatalf("operator.NewRegistry: %v", err)
+	}
+	catalog := NewCatalog(nil, currencies, operators)
+	ctx := context.Background()
+	if err := catalog.register(ctx, 1000, game.Info{Name: "plain"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := catalog.register(ctx, 1001, game.Info{Name: "limited", BetLimits: game.BetLimits{"TWD": {MinBet: d("10"), MaxBet: d("50000")}}}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		operator string
+		gameID   int
+		code     string
+		wantMin  string
+		wantMax  string
+	}{
+		{name: "platform limits without game or operator limits", gameID: 1000, code: "TWD", wantMin: "1", wantMax: "100000"},
+		{name: "game limits narrow platform limits", gameID: 1001, code: "TWD", wantMin: "10", wantMax: "50000"},
+		{name: "game without limits for currency", gameID: 1001, code: "USD", wantMin: "0.1", wantMax: "5000"},
+		{name: "operator narrows game max", operator: "vip", gameID: 1001, code: "TWD", wantMin: "10", wantMax: "10000"},
+		{name: "operator limits apply to game without limits", operator: "vip", gameID: 1000, code: "TWD", wantMin: "5", wantMax: "10000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := catalog.List(ctx, tt.operator)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			var limit BetLimit
+			found := false
+			for _, g := range list {
+				if g.ID == tt.gameID {
+					limit, found = g.BetLimits[tt.code]
+				}
+			}
+			if !found {
+				t.Fatalf("no %s limit for game %d", tt.code, tt.gameID)
+			}
+			if !limit.MinBet.Equal(d(tt.wantMin)) || !limit.MaxBet.Equal(d(tt.wantMax)) {
+				t.Fatalf("limit = %s..%s, want %s..%s", limit.MinBet, limit.MaxBet, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
@@ -4,6 +4,12 @@ type ActionType string
 
 const (
 	// --- Client to Server Actions ---
-	Login ActionType = "login"
-	Play  ActionType = "play"
+	Login  ActionType = "login"
+	Play   ActionType = "play"
+	Resume ActionType = "resume"
+	Logout ActionType = "logout"
+	// Ping 由伺服器定期發送，客戶端也可以主動送出以量測往返時間。
+	Ping ActionType = "ping"
+	// Pong 是對 ping 的回應。
+	Pong ActionType = "pong"
 )
@@ -0,0 +1,121 @@
+package gamecenter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/joe_shih/slot-factory/internal/domain/game"
+)
+
+// drainState 記錄服務實體是否正在排空連線，以及尚未完成的下注。
+//
+// 排空開始後不再接受新的登入與下注；mu 確保 plays.Add 只會在排空開始前發生，
+// 讓 Drain 可以安全地等待 plays。
+type drainState struct {
+	mu       sync.Mutex
+	draining bool
+	payload  serverDrainingPayload
+	plays    sync.WaitGroup
+}
+
+// begin 標記開始排空，回傳 false 表示已在排空中。
+func (d *drainState) begin(payload serverDrainingPayload) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.draining = true
+	d.payload = payload
+	return true
+}
+
+// startPlay 在未排空時登記一筆進行中的下注，呼叫端必須在下注完成後呼叫 plays.Done。
+// 排空中時回傳 false 與通知客戶端的內容。
+func (d *drainState) startPlay() (serverDrainingPayload, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return d.payload, false
+	}
+	d.plays.Add(1)
+	return serverDrainingPayload{}, true
+}
+
+// status 回傳是否正在排空，以及通知客戶端的內容。
+func (d *drainState) status() (serverDrainingPayload, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.payload, d.draining
+}
+
+// Drain 開始排空本實體的連線，用於滾動更新時讓玩家轉移到其他實體。
+//
+// 它會向所有連線廣播 server_draining，建議客戶端在 reconnectDelay 內 (加上隨機延遲) 重新連線到其他實體，
+// 之後拒絕新的登入、恢復連線與下注，並等待進行中的下注與各遊戲已下注的局結算完成。
+// 呼叫端應在 Drain 返回後關閉所有連線。
+//
+// 參數說明：
+//   - ctx: context.Context, 排空的期限，到期時不再等待尚未結算的局。
+//   - reconnectDelay: time.Duration, 建議客戶端重新連線前等待的時間。
+//
+// 回傳值：
+//   - error: 如果在所有局結算前 ctx 到期，則返回 ctx 的錯誤。
+func (s *gameCenter) Drain(ctx context.Context, reconnectDelay time.Duration) error {
+	payload := serverDrainingPayload{ReconnectDelayMs: reconnectDelay.Milliseconds()}
+	if deadline, ok := ctx.Deadline(); ok {
+		payload.Deadline = &deadline
+	}
+	if !s.drain.begin(payload) {
+		return nil
+	}
+	s.logger.Warn("draining connections", "reconnectDelay", reconnectDelay, "deadline", payload.Deadline)
+	if s.broadcaster != nil {
+		s.broadcaster.BroadcastAll(serverDrainingEnvelope(payload))
+	}
+
+	// 等待進行中的下注完成，再等待多人遊戲已下注的局開獎並派彩
+	plays := make(chan struct{})
+	go func() {
+		s.drain.plays.Wait()
+		close(plays)
+	}()
+	select {
+	case <-plays:
+	case <-ctx.Done():
+		s.logger.Error("drain deadline exceeded with plays in progress")
+		return ctx.Err()
+	}
+	for id, g := range s.games {
+		drainer, ok := g.(game.Drainer)
+		if !ok {
+			continue
+		}
+		if err := drainer.Drain(ctx); err != nil {
+			s.logger.Error("drain deadline exceeded with unsettled rounds", "gameID", id, "error", err)
+			return err
+		}
+	}
+	s.logger.Info("all rounds settled, ready to close connections")
+	return nil
+}
+
+// rejectWhileDraining 在排空期間通知客戶端重新連線到其他實體並中斷連線，回傳 true 表示已拒絕登入。
+// session 不會被撤銷，客戶端可以在其他實體以 resume 恢復。
+func (s *gameCenter) rejectWhileDraining(gameClient game.GameClient, requestID string) bool {
+	payload, draining := s.drain.status()
+	if !draining {
+		return false
+	}
+	s.reply(gameClient, requestID, serverDrainingEnvelope(payload))
+	if err := gameClient.Kick("server draining"); err != nil {
+		s.logger.Error("kick client failed", "error", err, "ip", gameClient.GetIP())
+	}
+	return true
+}
+
+// serverDrainingEnvelope 建立通知客戶端服務實體即將關閉的訊息。
+func serverDrainingEnvelope(payload serverDrainingPayload) game.Envelope {
+	return game.Envelope{Action: "server_draining", Payload: payload}
+}
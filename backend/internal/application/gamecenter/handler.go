@@ -1,6 +1,10 @@
 package gamecenter
 
-import "github.com/joe_shih/slot-factory/internal/domain/game"
+import (
+	"time"
+
+	"github.com/joe_shih/slot-factory/internal/domain/game"
+)
 
 // EventHandler 定義了 gamecenter 處理外部連線事件所需實現的介面。
 // 這是 application 層的入口點 (port)，由外部的 adapter 來驅動。
@@ -8,4 +12,6 @@ type EventHandler interface {
 	HandleConnect(client game.GameClient)
 	HandleDisconnect(client game.GameClient)
 	HandleMessage(client game.GameClient, message []byte)
+	// HandleRateLimited 在客戶端超過連線層的訊息速率限制時被呼叫，limit 為超過的限制 ("messages" 或 "bytes")。
+	HandleRateLimited(client game.GameClient, limit string, retryAfter time.Duration)
 }
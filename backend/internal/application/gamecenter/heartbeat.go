@@ -0,0 +1,175 @@
+package gamecenter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/joe_shih/slot-factory/internal/domain/game"
+)
+
+// tagHeartbeat 是連線上存放 clientHeartbeat 的 tag，客戶端第一次回應 pong 後才會建立。
+const tagHeartbeat = "heartbeat"
+
+// TagRTT 是連線上存放最近一次應用層往返時間 (time.Duration) 的 tag。
+const TagRTT = "rtt"
+
+// heartbeatHistory 是保留發送時間的 ping 數量，超過此數量的 pong 仍會更新存活時間，但不計算往返時間。
+const heartbeatHistory = 8
+
+// heartbeat 定期向所有已登入的玩家廣播應用層 ping，並以客戶端回應的 pong 計算往返時間。
+//
+// WebSocket 協定層的 ping/pong 由瀏覽器自動回應，只能證明 TCP 連線仍在；
+// 應用層的 pong 由客戶端程式回應，可以偵測連線仍在、但客戶端已停止處理訊息的半開連線 (例如分頁被凍結)。
+// 只有回應過 pong 的連線才會被檢查，不支援 heartbeat 的舊客戶端不受影響。
+type heartbeat struct {
+	interval  time.Duration
+	maxMissed int
+
+	mu   sync.Mutex
+	seq  uint64
+	sent [heartbeatHistory]pingRecord
+}
+
+// pingRecord 是一次 ping 的序號與發送時間。
+type pingRecord struct {
+	seq uint64
+	at  time.Time
+}
+
+// clientHeartbeat 記錄單一連線最近一次回應 pong 的時間 (UnixNano)。
+type clientHeartbeat struct {
+	lastPong atomic.Int64
+}
+
+// EnableHeartbeat 啟用應用層 heartbeat：每隔 interval 向所有已登入的玩家廣播 ping，
+// 計算每條連線的往返時間，並中斷連續 maxMissed 次未回應的半開連線。
+// 必須在開始接受連線前呼叫。
+//
+// 參數說明：
+//   - ctx: context.Context, 控制 heartbeat 的生命週期，結束時停止發送 ping。
+//   - interval: time.Duration, 發送 ping 的間隔。
+//   - maxMissed: int, 連續未回應幾次後中斷連線，0 表示使用預設值 3。
+//
+// 回傳值：
+//   - error: 如果 interval 不合法或沒有 broadcaster，則返回錯誤。
+func (s *gameCenter) EnableHeartbeat(ctx context.Context, interval time.Duration, maxMissed int) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid heartbeat interval: %s", interval)
+	}
+	if s.broadcaster == nil {
+		return fmt.Errorf("heartbeat requires a broadcaster")
+	}
+	if maxMissed <= 0 {
+		maxMissed = 3
+	}
+	s.heartbeat = &heartbeat{interval: interval, maxMissed: maxMissed}
+	go s.runHeartbeat(ctx)
+	return nil
+}
+
+// runHeartbeat 定期廣播 ping，並中斷超過期限未回應 pong 的連線，ctx 結束時返回。
+func (s *gameCenter) runHeartbeat(ctx context.Context) {
+	ticker := time.NewTicker(s.heartbeat.interval)
+	defer ticker.Stop()
+	for {
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+		seq := s.heartbeat.record(now)
+		// 所有連線收到相同的 ping，只需要序列化一次
+		s.broadcaster.Broadcast(game.GroupPlayers, game.Envelope{
+			Action:  string(Ping),
+			Payload: pingPayload{Seq: seq, ServerTime: now.UnixMilli()},
+		})
+		s.kickUnresponsive(now)
+	}
+}
+
+// record 記錄一次 ping 的發送時間，回傳其序號。
+func (h *heartbeat) record(now time.Time) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seq++
+	h.sent[h.seq%heartbeatHistory] = pingRecord{seq: h.seq, at: now}
+	return h.seq
+}
+
+// sentAt 回傳 ping 的發送時間，序號太舊或不存在時回傳 false。
+func (h *heartbeat) sentAt(seq uint64) (time.Time, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r := h.sent[seq%heartbeatHistory]
+	if seq == 0 || r.seq != seq {
+		return time.Time{}, false
+	}
+	return r.at, true
+}
+
+// kickUnresponsive 中斷連續 maxMissed 個間隔都沒有回應 pong 的連線。
+func (s *gameCenter) kickUnresponsive(now time.Time) {
+	timeout := s.heartbeat.interval * time.Duration(s.heartbeat.maxMissed)
+	for _, client := range s.clients() {
+		v, ok := client.GetTag(tagHeartbeat)
+		if !ok {
+			continue
+		}
+		last := time.Unix(0, v.(*clientHeartbeat).lastPong.Load())
+		if now.Sub(last) <= timeout {
+			continue
+		}
+		heartbeatTimeoutsTotal.Inc()
+		s.logger.Warn("kicking unresponsive client", "lastPong", last, "ip", client.GetIP())
+		if err := client.Kick("heartbeat timeout"); err != nil {
+			s.logger.Error("kick client failed", "error", err, "ip", client.GetIP())
+		}
+	}
+}
+
+// handlePong 處理客戶端對 ping 的回應：更新存活時間，並記錄往返時間。
+func (s *gameCenter) handlePong(gameClient game.GameClient, payload pongPayload) {
+	now := time.Now()
+	s.touchHeartbeat(gameClient, now)
+	if s.heartbeat == nil {
+		return
+	}
+	sentAt, ok := s.heartbeat.sentAt(payload.Seq)
+	if !ok {
+		return
+	}
+	rtt := now.Sub(sentAt)
+	gameClient.SetTag(TagRTT, rtt)
+	clientRTT.Observe(rtt.Seconds())
+}
+
+// handlePing 回應客戶端主動送出的 ping，原樣帶回客戶端的時間戳記，讓客戶端自行計算往返時間。
+func (s *gameCenter) handlePing(gameClient game.GameClient, requestID string, payload clientPingPayload) {
+	s.touchHeartbeat(gameClient, time.Now())
+	s.reply(gameClient, requestID, game.Envelope{
+		Action:  string(Pong),
+		Payload: clientPongPayload{ClientTime: payload.ClientTime, ServerTime: time.Now().UnixMilli()},
+	})
+}
+
+// touchHeartbeat 記錄連線最近一次證明仍在處理訊息的時間，第一次呼叫時讓連線開始受半開連線檢查。
+// 只有已登入的連線會收到 ping，因此未登入的連線不受檢查。
+// 同一條連線的訊息依序處理，因此建立 clientHeartbeat 不會與自己競爭。
+func (s *gameCenter) touchHeartbeat(gameClient game.GameClient, now time.Time) {
+	if s.heartbeat == nil {
+		return
+	}
+	if _, ok := gameClient.GetTag("player"); !ok {
+		return
+	}
+	v, ok := gameClient.GetTag(tagHeartbeat)
+	if !ok {
+		v = &clientHeartbeat{}
+		gameClient.SetTag(tagHeartbeat, v)
+	}
+	v.(*clientHeartbeat).lastPong.Store(now.UnixNano())
+}
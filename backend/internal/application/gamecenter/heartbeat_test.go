@@ -0,0 +1,191 @@
+package gamecenter
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joe_shih/slot-factory/internal/application/login"
+	"github.com/joe_shih/slot-factory/internal/domain/currency"
+	"github.com/joe_shih/slot-factory/internal/domain/game"
+)
+
+// pingRecorder 是記錄廣播訊息的 game.Broadcaster。
+type pingRecorder struct {
+	mu   sync.Mutex
+	sent []game.Envelope
+}
+
+func (b *pingRecorder) Broadcast(_ string, message game.Envelope) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sent = append(b.sent, message)
+	return 1
+}
+
+func (b *pingRecorder) BroadcastAll(message game.Envelope) int {
+	return b.Broadcast("", message)
+}
+
+func (b *pingRecorder) count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.sent)
+}
+
+// newHeartbeatCenter 建立 heartbeat 狀態已設定、但沒有啟動背景 goroutine 的遊戲中心。
+func newHeartbeatCenter(interval time.Duration, maxMissed int) *gameCenter {
+	loginService := login.NewService(nopAuthClient{}, currency.DefaultRegistry(), nil)
+	s := NewService(*loginService, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, nil, nil, &pingRecorder{})
+	s.heartbeat = &heartbeat{interval: interval, maxMissed: maxMissed}
+	return s
+}
+
+// newLoggedInClient 建立已登入並加入遊戲中心連線清單的 fakeClient。
+func newLoggedInClient(s *gameCenter, id string) *fakeClient {
+	c := newFakeClient(id)
+	c.tags["player"] = struct{}{}
+	s.clientList[id] = c
+	return c
+}
+
+func TestKickUnresponsive(t *testing.T) {
+	s := newHeartbeatCenter(10*time.Second, 3)
+	now := time.Unix(1_700_000_000, 0)
+
+	tests := []struct {
+		id       string
+		lastPong time.Duration // 距離 now 多久以前回應過 pong，負數表示從未回應
+		wantKick bool
+	}{
+		{id: "fresh", lastPong: 5 * time.Second},
+		{id: "at the limit", lastPong: 30 * time.Second},
+		{id: "missed too many", lastPong: 31 * time.Second, wantKick: true},
+		{id: "legacy client", lastPong: -1},
+	}
+	clients := make(map[string]*fakeClient, len(tests))
+	for _, tt := range tests {
+		c := newLoggedInClient(s, tt.id)
+		if tt.lastPong >= 0 {
+			s.touchHeartbeat(c, now.Add(-tt.lastPong))
+		}
+		clients[tt.id] = c
+	}
+
+	s.kickUnresponsive(now)
+	for _, tt := range tests {
+		if got := clients[tt.id].kicked; (got != "") != tt.wantKick {
+			t.Errorf("%s: kicked = %q, want kick %v", tt.id, got, tt.wantKick)
+		}
+	}
+	if got := clients["missed too many"].kicked; got != "heartbeat timeout" {
+		t.Errorf("kick reason = %q, want heartbeat timeout", got)
+	}
+}
+
+func TestTouchHeartbeatIgnoresAnonymousClients(t *testing.T) {
+	s := newHeartbeatCenter(time.Second, 1)
+	c := newFakeClient("anonymous")
+	s.clientList[c.id] = c
+
+	s.touchHeartbeat(c, time.Unix(0, 0))
+	s.kickUnresponsive(time.Unix(1_700_000_000, 0))
+	if c.kicked != "" {
+		t.Fatalf("client that never logged in was kicked: %q", c.kicked)
+	}
+}
+
+func TestHeartbeatSentAtWraparound(t *testing.T) {
+	h := &heartbeat{}
+	t0 := time.Unix(1_700_000_000, 0)
+	const pings = heartbeatHistory + 3
+	for i := 1; i <= pings; i++ {
+		if seq := h.record(t0.Add(time.Duration(i) * time.Second)); seq != uint64(i) {
+			t.Fatalf("record returned seq %d, want %d", seq, i)
+		}
+	}
+
+	tests := []struct {
+		seq    uint64
+		wantOK bool
+	}{
+		{seq: 0},
+		{seq: 1},
+		{seq: pings - heartbeatHistory},
+		{seq: pings - heartbeatHistory + 1, wantOK: true},
+		{seq: pings, wantOK: true},
+		{seq: pings + 1},
+		// 與保留中的序號落在同一格，但不是同一個 ping
+		{seq: pings + heartbeatHistory},
+	}
+	for _, tt := range tests {
+		at, ok := h.sentAt(tt.seq)
+		if ok != tt.wantOK {
+			t.Fatalf("sentAt(%d) ok = %v, want %v", tt.seq, ok, tt.wantOK)
+		}
+		if want := t0.Add(time.Duration(tt.seq) * time.Second); ok && !at.Equal(want) {
+			t.Fatalf("sentAt(%d) = %s, want %s", tt.seq, at, want)
+		}
+	}
+}
+
+func TestHandlePongRecordsRTT(t *testing.T) {
+	s := newHeartbeatCenter(time.Second, 3)
+	c := newLoggedInClient(s, "c1")
+	seq := s.heartbeat.record(time.Now().Add(-50 * time.Millisecond))
+
+	s.handlePong(c, pongPayload{Seq: seq})
+	v, ok := c.GetTag(TagRTT)
+	if !ok {
+		t.Fatal("RTT not recorded")
+	}
+	if rtt := v.(time.Duration); rtt < 50*time.Millisecond || rtt > time.Second {
+		t.Fatalf("RTT = %s, want about 50ms", rtt)
+	}
+	if _, ok := c.GetTag(tagHeartbeat); !ok {
+		t.Fatal("pong did not start heartbeat tracking")
+	}
+
+	// 未知的序號仍證明客戶端存活，但不更新往返時間
+	c.SetTag(TagRTT, time.Duration(0))
+	s.handlePong(c, pongPayload{Seq: seq + 100})
+	if v, _ := c.GetTag(TagRTT); v.(time.Duration) != 0 {
+		t.Fatalf("RTT updated from unknown seq: %s", v)
+	}
+}
+
+func TestRunHeartbeatStopsWithContext(t *testing.T) {
+	s := newHeartbeatCenter(5*time.Millisecond, 3)
+	broadcaster := s.broadcaster.(*pingRecorder)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.runHeartbeat(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for broadcaster.count() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("no ping broadcast")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("runHeartbeat did not return after the context was canceled")
+	}
+	sent := broadcaster.count()
+	time.Sleep(20 * time.Millisecond)
+	if got := broadcaster.count(); got != sent {
+		t.Fatalf("pings broadcast after stop: %d, want %d", got, sent)
+	}
+	if msg := broadcaster.sent[0]; msg.Action != string(Ping) || msg.Payload.(pingPayload).Seq != 1 {
+		t.Fatalf("first broadcast = %+v, want ping seq 1", msg)
+	}
+}
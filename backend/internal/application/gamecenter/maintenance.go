@@ -0,0 +1,277 @@
+package gamecenter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisKeyMaintenance 是維護時段的 hash，field 為時段 ID，value 為 MaintenanceWindow 的 JSON。
+const RedisKeyMaintenance = "maintenance:windows"
+
+// maintenanceTickInterval 是檢查維護時段與發送倒數通知的間隔。
+const maintenanceTickInterval = time.Second
+
+// maintenanceNoticeOffsets 是維護開始前發送倒數通知的時間點，由大到小排列。
+var maintenanceNoticeOffsets = []time.Duration{
+	30 * time.Minute,
+	10 * time.Minute,
+	5 * time.Minute,
+	time.Minute,
+	30 * time.Second,
+	10 * time.Second,
+}
+
+var (
+	// ErrInvalidMaintenanceWindow 表示維護時段的時間設定不合法。
+	ErrInvalidMaintenanceWindow = errors.New("invalid maintenance window")
+	// ErrMaintenanceNotFound 表示維護時段不存在或已結束。
+	ErrMaintenanceNotFound = errors.New("maintenance window not found")
+)
+
+// MaintenancePhase 是維護通知的階段。
+type MaintenancePhase string
+
+const (
+	// MaintenanceCountdown 表示維護即將開始。
+	MaintenanceCountdown MaintenancePhase = "countdown"
+	// MaintenanceStarted 表示維護已開始：拒絕新的登入與下注，進行中的局會正常結算。
+	MaintenanceStarted MaintenancePhase = "started"
+	// MaintenanceEnded 表示維護已結束或被取消。
+	MaintenanceEnded MaintenancePhase = "ended"
+)
+
+// MaintenanceWindow 是一個排定的維護時段。
+type MaintenanceWindow struct {
+	ID string `json:"id"`
+	// GameID 是維護的遊戲 ID，0 表示全平台維護。
+	GameID  int       `json:"gameId"`
+	StartAt time.Time `json:"startAt"`
+	EndAt   time.Time `json:"endAt"`
+	// Message 是顯示給玩家的維護說明。
+	Message string `json:"message,omitempty"`
+}
+
+// Covers 判斷維護時段是否影響指定遊戲。
+func (w MaintenanceWindow) Covers(gameID int) bool {
+	return w.GameID == 0 || w.GameID == gameID
+}
+
+// ActiveAt 判斷維護時段在指定時間是否生效中。
+func (w MaintenanceWindow) ActiveAt(t time.Time) bool {
+	return !t.Before(w.StartAt) && t.Before(w.EndAt)
+}
+
+// maintenanceNotice 是一則要發送給受影響玩家的維護通知。
+type maintenanceNotice struct {
+	window      MaintenanceWindow
+	phase       MaintenancePhase
+	secondsLeft int
+}
+
+// noticeState 記錄本實體對一個維護時段已發送的通知，避免每次檢查都重複發送。
+type noticeState struct {
+	countdowns int  // 已通知過的倒數時間點數量
+	started    bool // 是否已通知維護開始
+}
+
+// maintenanceSchedule 管理維護時段。
+//
+// 時段存放在 Redis，排定或取消時透過 game_control 頻道通知所有服務實體重新載入；
+// 每個實體依本地快取判斷是否阻擋登入與下注，並各自通知自己的連線。
+// 維護結束不需要任何指令，時間一到即自動解除。
+type maintenanceSchedule struct {
+	rdb *redis.Client
+
+	mu      sync.RWMutex
+	windows map[string]MaintenanceWindow
+	notices map[string]*noticeState
+	// lifted 是重新載入時被取消、且已通知過開始的時段，下一次檢查時通知結束。
+	lifted []MaintenanceWindow
+}
+
+// newMaintenanceSchedule 建立維護時段管理器。rdb 為 nil 時無法排定維護。
+func newMaintenanceSchedule(rdb *redis.Client) *maintenanceSchedule {
+	return &maintenanceSchedule{
+		rdb:     rdb,
+		windows: make(map[string]MaintenanceWindow),
+		notices: make(map[string]*noticeState),
+	}
+}
+
+// schedule 驗證並寫入一個維護時段，StartAt 為零值時立即開始。
+func (m *maintenanceSchedule) schedule(ctx context.Context, w MaintenanceWindow, now time.Time) (MaintenanceWindow, error) {
+	if m.rdb == nil {
+		return MaintenanceWindow{}, fmt.Errorf("redis client is nil")
+	}
+	if w.StartAt.IsZero() {
+		w.StartAt = now
+	}
+	if !w.EndAt.After(w.StartAt) || !w.EndAt.After(now) {
+		return MaintenanceWindow{}, fmt.Errorf("%w: endAt must be after startAt and in the future", ErrInvalidMaintenanceWindow)
+	}
+	if w.GameID < 0 {
+		return MaintenanceWindow{}, fmt.Errorf("%w: invalid game id %d", ErrInvalidMaintenanceWindow, w.GameID)
+	}
+	w.ID = uuid.NewString()
+	w.StartAt = w.StartAt.UTC()
+	w.EndAt = w.EndAt.UTC()
+
+	data, err := json.Marshal(w)
+	if err != nil {
+		return MaintenanceWindow{}, err
+	}
+	if err := m.rdb.HSet(ctx, RedisKeyMaintenance, w.ID, data).Err(); err != nil {
+		return MaintenanceWindow{}, err
+	}
+	m.mu.Lock()
+	m.windows[w.ID] = w
+	m.mu.Unlock()
+	return w, nil
+}
+
+// cancel 從 Redis 刪除維護時段。
+func (m *maintenanceSchedule) cancel(ctx context.Context, id string) error {
+	if m.rdb == nil {
+		return fmt.Errorf("redis client is nil")
+	}
+	n, err := m.rdb.HDel(ctx, RedisKeyMaintenance, id).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %s", ErrMaintenanceNotFound, id)
+	}
+	return nil
+}
+
+// list 從 Redis 讀取所有尚未結束的維護時段，依開始時間排序。
+func (m *maintenanceSchedule) list(ctx context.Context, now time.Time) ([]MaintenanceWindow, error) {
+	windows, err := m.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]MaintenanceWindow, 0, len(windows))
+	for _, w := range windows {
+		if now.Before(w.EndAt) {
+			list = append(list, w)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartAt.Before(list[j].StartAt) })
+	return list, nil
+}
+
+// load 讀取 Redis 中所有的維護時段，無法解析的資料會被略過。
+func (m *maintenanceSchedule) load(ctx context.Context) (map[string]MaintenanceWindow, error) {
+	windows := make(map[string]MaintenanceWindow)
+	if m.rdb == nil {
+		return windows, nil
+	}
+	all, err := m.rdb.HGetAll(ctx, RedisKeyMaintenance).Result()
+	if err != nil {
+		return nil, err
+	}
+	for id, value := range all {
+		var w MaintenanceWindow
+		if err := json.Unmarshal([]byte(value), &w); err != nil {
+			continue
+		}
+		windows[id] = w
+	}
+	return windows, nil
+}
+
+// refresh 從 Redis 重新載入維護時段，用於啟動時與收到 maintenance 控制指令時同步。
+func (m *maintenanceSchedule) refresh(ctx context.Context) error {
+	windows, err := m.load(ctx)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, w := range m.windows {
+		if _, ok := windows[id]; ok {
+			continue
+		}
+		if st := m.notices[id]; st != nil && st.started {
+			m.lifted = append(m.lifted, w)
+		}
+		delete(m.notices, id)
+	}
+	m.windows = windows
+	return nil
+}
+
+// active 回傳在指定時間影響遊戲的維護時段，gameID 為 0 時只檢查全平台維護。
+func (m *maintenanceSchedule) active(gameID int, now time.Time) (MaintenanceWindow, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, w := range m.windows {
+		if w.ActiveAt(now) && w.Covers(gameID) {
+			return w, true
+		}
+	}
+	return MaintenanceWindow{}, false
+}
+
+// due 推進所有時段的狀態並回傳此時應發送的通知，已結束的時段會從本地快取移除。
+// expired 是已結束、應從 Redis 刪除的時段 ID。
+func (m *maintenanceSchedule) due(now time.Time) (notices []maintenanceNotice, expired []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, w := range m.lifted {
+		notices = append(notices, maintenanceNotice{window: w, phase: MaintenanceEnded})
+	}
+	m.lifted = nil
+
+	for id, w := range m.windows {
+		st := m.notices[id]
+		if st == nil {
+			st = &noticeState{}
+			m.notices[id] = st
+		}
+		switch {
+		case !now.Before(w.EndAt):
+			if st.started {
+				notices = append(notices, maintenanceNotice{window: w, phase: MaintenanceEnded})
+			}
+			delete(m.windows, id)
+			delete(m.notices, id)
+			expired = append(expired, id)
+		case !now.Before(w.StartAt):
+			if !st.started {
+				st.started = true
+				notices = append(notices, maintenanceNotice{window: w, phase: MaintenanceStarted, secondsLeft: int(w.EndAt.Sub(now).Seconds())})
+			}
+		default:
+			left := w.StartAt.Sub(now)
+			crossed := 0
+			for _, offset := range maintenanceNoticeOffsets {
+				if left <= offset {
+					crossed++
+				}
+			}
+			if crossed > st.countdowns {
+				st.countdowns = crossed
+				notices = append(notices, maintenanceNotice{window: w, phase: MaintenanceCountdown, secondsLeft: int(left.Round(time.Second).Seconds())})
+			}
+		}
+	}
+	return notices, expired
+}
+
+// purge 從 Redis 刪除已結束的時段。多個實體可能同時刪除，HDEL 本身是冪等的。
+func (m *maintenanceSchedule) purge(ctx context.Context, ids []string) error {
+	if m.rdb == nil || len(ids) == 0 {
+		return nil
+	}
+	return m.rdb.HDel(ctx, RedisKeyMaintenance, ids...).Err()
+}
@@ -0,0 +1,25 @@
+package gamecenter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus 指標，描述玩家操作的頻率限制與應用層 heartbeat。
+var (
+	actionRateLimitedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "slot_action_rate_limited_total",
+		Help: "Number of client actions rejected by per-action rate limits, by action and policy.",
+	}, []string{"action", "policy"})
+
+	clientRTT = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "slot_client_rtt_seconds",
+		Help:    "Application-level round-trip time between a heartbeat ping and the client's pong.",
+		Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.2, 0.4, 0.8, 1.6, 3.2, 6.4},
+	})
+
+	heartbeatTimeoutsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "slot_heartbeat_timeouts_total",
+		Help: "Number of connections kicked for not answering application-level heartbeat pings (half-open connections).",
+	})
+)
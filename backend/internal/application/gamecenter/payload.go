@@ -1,14 +1,166 @@
 package gamecenter
 
-import "github.com/shopspring/decimal"
+import (
+	"time"
+
+	"github.com/joe_shih/slot-factory/pkg/codec"
+	"github.com/shopspring/decimal"
+)
+
+// 確保伺服器送出的訊息內容都有 slot.proto 的編碼。
+var (
+	_ codec.ProtoMessage = authSuccessPayload{}
+	_ codec.ProtoMessage = gameStatusPayload{}
+	_ codec.ProtoMessage = rateLimitedPayload{}
+	_ codec.ProtoMessage = maintenancePayload{}
+	_ codec.ProtoMessage = serverDrainingPayload{}
+	_ codec.ProtoMessage = pingPayload{}
+	_ codec.ProtoMessage = clientPongPayload{}
+)
 
 // loginPayload Login專用資料結構
 type loginPayload struct {
 	Sid    string `json:"sid"`
 	GameID int    `json:"gameId"`
+	// OperatorID 是選填的營運商 ID，用於選擇負責驗證 token 的營運商。
+	OperatorID string `json:"operatorId,omitempty"`
 }
 
 // playPayload Play專用結構
 type playPayload struct {
 	BetAmount decimal.Decimal `json:"betAmount"`
 }
+
+// resumePayload Resume專用結構
+type resumePayload struct {
+	SessionToken string `json:"sessionToken"`
+}
+
+// authSuccessPayload 是登入或恢復連線成功時回傳給客戶端的內容。
+// 斷線後客戶端可以用 SessionToken 送出 resume，在到期前恢復同一個玩家與遊戲。
+type authSuccessPayload struct {
+	Message          string     `json:"message"`
+	PlayerID         string     `json:"playerID"`
+	Currency         string     `json:"currency"`
+	GameID           int        `json:"gameId"`
+	SessionToken     string     `json:"sessionToken,omitempty"`
+	SessionExpiresAt *time.Time `json:"sessionExpiresAt,omitempty"`
+	Resumed          bool       `json:"resumed"`
+}
+
+// AppendProto 依 slot.proto 的 AuthSuccess 編碼。
+func (p authSuccessPayload) AppendProto(b []byte) []byte {
+	b = codec.AppendString(b, 1, p.Message)
+	b = codec.AppendString(b, 2, p.PlayerID)
+	b = codec.AppendString(b, 3, p.Currency)
+	b = codec.AppendInt64(b, 4, int64(p.GameID))
+	b = codec.AppendString(b, 5, p.SessionToken)
+	if p.SessionExpiresAt != nil {
+		b = codec.AppendInt64(b, 6, p.SessionExpiresAt.UnixMilli())
+	}
+	return codec.AppendBool(b, 7, p.Resumed)
+}
+
+// gameStatusPayload 是遊戲上下架狀態變更時通知客戶端的內容。
+type gameStatusPayload struct {
+	GameID int        `json:"gameId"`
+	Status GameStatus `json:"status"`
+}
+
+// AppendProto 依 slot.proto 的 GameStatus 編碼。
+func (p gameStatusPayload) AppendProto(b []byte) []byte {
+	b = codec.AppendInt64(b, 1, int64(p.GameID))
+	return codec.AppendString(b, 2, string(p.Status))
+}
+
+// rateLimitedPayload 是操作超過頻率限制被忽略時通知客戶端的內容。
+type rateLimitedPayload struct {
+	// Scope 是限制的範圍："connection" (連線的訊息數或位元組數) 或 "action" (單一操作)。
+	Scope string `json:"scope"`
+	// Limit 是超過的限制："messages"、"bytes" 或 action 名稱。
+	Limit string `json:"limit"`
+	// RetryAfterMs 是建議等待的毫秒數。
+	RetryAfterMs int64 `json:"retryAfterMs"`
+}
+
+// AppendProto 依 slot.proto 的 RateLimited 編碼。
+func (p rateLimitedPayload) AppendProto(b []byte) []byte {
+	b = codec.AppendString(b, 1, p.Scope)
+	b = codec.AppendString(b, 2, p.Limit)
+	return codec.AppendInt64(b, 3, p.RetryAfterMs)
+}
+
+// maintenancePayload 是維護倒數、開始與結束時通知客戶端的內容。
+type maintenancePayload struct {
+	ID      string           `json:"id"`
+	GameID  int              `json:"gameId"`
+	Phase   MaintenancePhase `json:"phase"`
+	StartAt time.Time        `json:"startAt"`
+	EndAt   time.Time        `json:"endAt"`
+	// SecondsLeft 在倒數階段為距離開始的秒數，在開始階段為距離結束的秒數。
+	SecondsLeft int    `json:"secondsLeft"`
+	Message     string `json:"message,omitempty"`
+}
+
+// AppendProto 依 slot.proto 的 Maintenance 編碼。
+func (p maintenancePayload) AppendProto(b []byte) []byte {
+	b = codec.AppendString(b, 1, p.ID)
+	b = codec.AppendInt64(b, 2, int64(p.GameID))
+	b = codec.AppendString(b, 3, string(p.Phase))
+	b = codec.AppendInt64(b, 4, p.StartAt.UnixMilli())
+	b = codec.AppendInt64(b, 5, p.EndAt.UnixMilli())
+	b = codec.AppendInt64(b, 6, int64(p.SecondsLeft))
+	return codec.AppendString(b, 7, p.Message)
+}
+
+// serverDrainingPayload 是服務實體開始排空連線時通知客戶端的內容。
+// 客戶端應在 ReconnectDelayMs 內隨機選擇時間重新連線，由負載平衡導向其他實體，並以 session token 恢復。
+type serverDrainingPayload struct {
+	// ReconnectDelayMs 是建議重新連線前等待的毫秒數。
+	ReconnectDelayMs int64 `json:"reconnectDelayMs"`
+	// Deadline 是本實體關閉所有連線的時間，進行中的局會在此之前結算。
+	Deadline *time.Time `json:"deadline,omitempty"`
+}
+
+// AppendProto 依 slot.proto 的 ServerDraining 編碼。
+func (p serverDrainingPayload) AppendProto(b []byte) []byte {
+	b = codec.AppendInt64(b, 1, p.ReconnectDelayMs)
+	if p.Deadline != nil {
+		b = codec.AppendInt64(b, 2, p.Deadline.UnixMilli())
+	}
+	return b
+}
+
+// pingPayload 是伺服器定期發送的應用層 ping，客戶端應以相同的 Seq 回應 pong。
+type pingPayload struct {
+	Seq        uint64 `json:"seq"`
+	ServerTime int64  `json:"serverTime"`
+}
+
+// AppendProto 依 slot.proto 的 Ping 編碼。
+func (p pingPayload) AppendProto(b []byte) []byte {
+	b = codec.AppendUint64(b, 1, p.Seq)
+	return codec.AppendInt64(b, 2, p.ServerTime)
+}
+
+// pongPayload 是客戶端對伺服器 ping 的回應。
+type pongPayload struct {
+	Seq uint64 `json:"seq"`
+}
+
+// clientPingPayload 是客戶端主動送出的 ping，ClientTime 是客戶端的時間戳記 (毫秒)。
+type clientPingPayload struct {
+	ClientTime int64 `json:"clientTime"`
+}
+
+// clientPongPayload 是伺服器對客戶端 ping 的回應，原樣帶回 ClientTime 讓客戶端計算往返時間。
+type clientPongPayload struct {
+	ClientTime int64 `json:"clientTime"`
+	ServerTime int64 `json:"serverTime"`
+}
+
+// AppendProto 依 slot.proto 的 Pong 編碼。
+func (p clientPongPayload) AppendProto(b []byte) []byte {
+	b = codec.AppendInt64(b, 1, p.ClientTime)
+	return codec.AppendInt64(b, 2, p.ServerTime)
+}
@@ -0,0 +1,144 @@
+package gamecenter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/joe_shih/slot-factory/internal/domain/game"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitPolicy 決定玩家的操作超過頻率限制時的處理方式。
+type RateLimitPolicy string
+
+const (
+	// RateLimitDrop 忽略超過限制的操作。
+	RateLimitDrop RateLimitPolicy = "drop"
+	// RateLimitWarn 忽略超過限制的操作，並回傳 rate_limited 通知客戶端稍後再試。
+	RateLimitWarn RateLimitPolicy = "warn"
+	// RateLimitKick 中斷超過限制的連線。
+	RateLimitKick RateLimitPolicy = "kick"
+)
+
+// rate_limited 通知的範圍。
+const (
+	// rateLimitScopeConnection 表示超過連線層的訊息數或位元組數限制。
+	rateLimitScopeConnection = "connection"
+	// rateLimitScopeAction 表示超過單一 action 的頻率限制。
+	rateLimitScopeAction = "action"
+)
+
+// rateLimitWarnInterval 是同一條連線兩次 rate_limited 通知之間的最短間隔。
+const rateLimitWarnInterval = time.Second
+
+// tagActionLimiter 是連線上存放 actionLimiter 的 tag。
+const tagActionLimiter = "action_limiter"
+
+// ActionLimit 是單一 action 的頻率限制 (token bucket)。
+type ActionLimit struct {
+	// PerSecond 是每秒補充的次數。
+	PerSecond float64
+	// Burst 是可以連續執行的次數，0 表示使用 PerSecond (至少 1)。
+	Burst int
+}
+
+// actionLimiter 是單一連線各 action 的 token bucket。
+// 同一條連線的訊息依序處理，因此只會被該連線的事件 goroutine 使用。
+type actionLimiter struct {
+	limiters map[ActionType]*rate.Limiter
+	lastWarn time.Time
+}
+
+// EnableActionRateLimit 啟用每條連線各 action 的頻率限制，避免單一客戶端以大量下注壓垮錢包平台。
+// 必須在開始接受連線前呼叫。
+//
+// 參數說明：
+//   - limits: map[ActionType]ActionLimit, 各 action 的頻率限制，未列出的 action 不限制。
+//   - policy: RateLimitPolicy, 超過限制時忽略、警告或中斷連線。
+//
+// 回傳值：
+//   - error: 如果 policy 無效或限制設定不合法，則返回錯誤。
+func (s *gameCenter) EnableActionRateLimit(limits map[ActionType]ActionLimit, policy RateLimitPolicy) error {
+	switch policy {
+	case RateLimitDrop, RateLimitWarn, RateLimitKick:
+	default:
+		return fmt.Errorf("unknown rate limit policy: %s", policy)
+	}
+	for action, limit := range limits {
+		if limit.PerSecond <= 0 || limit.Burst < 0 {
+			return fmt.Errorf("invalid rate limit for action %s", action)
+		}
+	}
+	s.actionLimits = limits
+	s.rateLimitPolicy = policy
+	return nil
+}
+
+// allowAction 判斷連線是否可以執行 action，超過限制時依 policy 處理並回傳 false。
+func (s *gameCenter) allowAction(gameClient game.GameClient, action ActionType, requestID string) bool {
+	return s.allowActionAt(time.Now(), gameClient, action, requestID)
+}
+
+// allowActionAt 以 now 作為目前時間執行 allowAction 的判斷。
+func (s *gameCenter) allowActionAt(now time.Time, gameClient game.GameClient, action ActionType, requestID string) bool {
+	limit, ok := s.actionLimits[action]
+	if !ok {
+		return true
+	}
+	var limiter *actionLimiter
+	if v, ok := gameClient.GetTag(tagActionLimiter); ok {
+		limiter = v.(*actionLimiter)
+	} else {
+		limiter = &actionLimiter{limiters: make(map[ActionType]*rate.Limiter)}
+		gameClient.SetTag(tagActionLimiter, limiter)
+	}
+	bucket := limiter.limiters[action]
+	if bucket == nil {
+		burst := limit.Burst
+		if burst <= 0 {
+			burst = max(int(limit.PerSecond), 1)
+		}
+		bucket = rate.NewLimiter(rate.Limit(limit.PerSecond), burst)
+		limiter.limiters[action] = bucket
+	}
+
+	r := bucket.ReserveN(now, 1)
+	retryAfter := r.DelayFrom(now)
+	if retryAfter <= 0 {
+		return true
+	}
+	r.CancelAt(now)
+
+	actionRateLimitedTotal.WithLabelValues(string(action), string(s.rateLimitPolicy)).Inc()
+	switch s.rateLimitPolicy {
+	case RateLimitKick:
+		s.logger.Warn("kicking client for exceeding action rate limit", "action", action, "ip", gameClient.GetIP())
+		if err := gameClient.Kick("rate limit exceeded"); err != nil {
+			s.logger.Error("kick client failed", "error", err, "ip", gameClient.GetIP())
+		}
+	case RateLimitWarn:
+		if now.Sub(limiter.lastWarn) >= rateLimitWarnInterval {
+			limiter.lastWarn = now
+			s.logger.Warn("client exceeded action rate limit", "action", action, "requestID", requestID, "ip", gameClient.GetIP())
+			s.reply(gameClient, requestID, rateLimitedEnvelope(rateLimitScopeAction, string(action), retryAfter))
+		}
+	}
+	return false
+}
+
+// HandleRateLimited 在連線層的訊息數或位元組數超過限制時通知客戶端。
+// 連線層已負責節流與中斷連線，這裡只負責以客戶端的訊息格式送出警告。
+func (s *gameCenter) HandleRateLimited(client game.GameClient, limit string, retryAfter time.Duration) {
+	if err := client.SendMessage(rateLimitedEnvelope(rateLimitScopeConnection, limit, retryAfter)); err != nil {
+		s.logger.Error("send message failed", "error", err, "ip", client.GetIP())
+	}
+}
+
+// rateLimitedEnvelope 建立超過頻率限制時通知客戶端的訊息。
+func rateLimitedEnvelope(scope, limit string, retryAfter time.Duration) game.Envelope {
+	return game.Envelope{Action: "rate_limited", Payload: rateLimitedPayload{
+		Scope:        scope,
+		Limit:        limit,
+		RetryAfterMs: retryAfter.Milliseconds(),
+	}}
+}
@@ -0,0 +1,128 @@
+package gamecenter
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/joe_shih/slot-factory/internal/application/login"
+	"github.com/joe_shih/slot-factory/internal/domain/currency"
+)
+
+// newRateLimitedCenter 建立啟用 play 與 ping 頻率限制的遊戲中心。
+func newRateLimitedCenter(t *testing.T, policy RateLimitPolicy) *gameCenter {
+	t.Helper()
+	loginService := login.NewService(nopAuthClient{}, currency.DefaultRegistry(), nil)
+	s := NewService(*loginService, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, nil, nil, nil)
+	err := s.EnableActionRateLimit(map[ActionType]ActionLimit{
+		Play: {PerSecond: 2, Burst: 2},
+		Ping: {PerSecond: 1}, // Burst 0 表示使用 PerSecond
+	}, policy)
+	if err != nil {
+		t.Fatalf("EnableActionRateLimit: %v", err)
+	}
+	return s
+}
+
+func TestAllowActionTokenBuckets(t *testing.T) {
+	t0 := time.Unix(1_700_000_000, 0)
+	steps := []struct {
+		at        time.Duration
+		action    ActionType
+		wantOK    bool
+		wantRetry int64 // 大於 0 表示預期送出 rate_limited 通知
+	}{
+		{at: 0, action: Play, wantOK: true},
+		{at: 0, action: Play, wantOK: true},
+		{at: 0, action: Play, wantRetry: 500},
+		{at: 0, action: Login, wantOK: true}, // 未設定限制的 action 不受影響
+		// 距離上次通知未滿 1 秒，只丟棄不通知
+		{at: 100 * time.Millisecond, action: Play},
+		// 各 action 各自計算 token，但通知的節流由整條連線共用
+		{at: 100 * time.Millisecond, action: Ping, wantOK: true},
+		{at: 100 * time.Millisecond, action: Ping},
+		// 被拒絕的操作不消耗 token，500ms 補回一個
+		{at: 500 * time.Millisecond, action: Play, wantOK: true},
+		{at: 500 * time.Millisecond, action: Play},
+		{at: time.Second, action: Play, wantOK: true},
+		{at: time.Second, action: Play, wantRetry: 500},
+	}
+
+	s := newRateLimitedCenter(t, RateLimitWarn)
+	client := newFakeClient("c1")
+	for i, step := range steps {
+		sent := len(client.sent)
+		if got := s.allowActionAt(t0.Add(step.at), client, step.action, "r1"); got != step.wantOK {
+			t.Fatalf("step %d (%s at %s): allowed = %v, want %v", i, step.action, step.at, got, step.wantOK)
+		}
+		if step.wantRetry == 0 {
+			if len(client.sent) != sent {
+				t.Fatalf("step %d: sent %+v, want no notice", i, client.sent[sent:])
+			}
+			continue
+		}
+		if len(client.sent) != sent+1 {
+			t.Fatalf("step %d: sent %d messages, want one rate_limited notice", i, len(client.sent)-sent)
+		}
+		msg := client.sent[sent]
+		want := rateLimitedPayload{Scope: rateLimitScopeAction, Limit: string(step.action), RetryAfterMs: step.wantRetry}
+		if msg.Action != "rate_limited" || msg.RequestID != "r1" || msg.Payload != want {
+			t.Fatalf("step %d: notice = %+v, want %+v", i, msg, want)
+		}
+	}
+	if client.kicked != "" {
+		t.Fatalf("warn policy kicked the client: %q", client.kicked)
+	}
+}
+
+func TestAllowActionPolicy(t *testing.T) {
+	tests := []struct {
+		policy     RateLimitPolicy
+		wantNotice bool
+		wantKick   bool
+	}{
+		{policy: RateLimitDrop},
+		{policy: RateLimitWarn, wantNotice: true},
+		{policy: RateLimitKick, wantKick: true},
+	}
+	now := time.Unix(1_700_000_000, 0)
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			s := newRateLimitedCenter(t, tt.policy)
+			client := newFakeClient("c1")
+			if !s.allowActionAt(now, client, Ping, "r1") {
+				t.Fatal("first ping rejected")
+			}
+			if s.allowActionAt(now, client, Ping, "r2") {
+				t.Fatal("ping over the limit allowed")
+			}
+			if got := len(client.sent) == 1; got != tt.wantNotice {
+				t.Fatalf("sent %v, want notice %v", client.actions(), tt.wantNotice)
+			}
+			if got := client.kicked != ""; got != tt.wantKick {
+				t.Fatalf("kicked = %q, want kick %v", client.kicked, tt.wantKick)
+			}
+		})
+	}
+}
+
+func TestEnableActionRateLimitValidates(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits map[ActionType]ActionLimit
+		policy RateLimitPolicy
+	}{
+		{name: "unknown policy", limits: map[ActionType]ActionLimit{Play: {PerSecond: 1}}, policy: "ignore"},
+		{name: "zero rate", limits: map[ActionType]ActionLimit{Play: {PerSecond: 0}}, policy: RateLimitDrop},
+		{name: "negative burst", limits: map[ActionType]ActionLimit{Play: {PerSecond: 1, Burst: -1}}, policy: RateLimitDrop},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newRateLimitedCenter(t, RateLimitDrop)
+			if err := s.EnableActionRateLimit(tt.limits, tt.policy); err == nil {
+				t.Fatal("EnableActionRateLimit accepted an invalid configuration")
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package gamecenter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisKeyPlayerOnlinePrefix 是玩家目前連線擁有者的 key (營運商ID、玩家ID)，值為 "實體ID:連線ID"。
+// 玩家 ID 只在營運商內唯一，不同營運商的同名玩家各自登記。
+const RedisKeyPlayerOnlinePrefix = "player_online:%s:%s"
+
+// SessionPolicy 決定同一位玩家重複登入時的處理方式。
+type SessionPolicy string
+
+const (
+	// SessionKickOld 踢除舊連線 (不論在哪個實體)，由新連線接手。
+	SessionKickOld SessionPolicy = "kick_old"
+	// SessionRejectNew 保留舊連線，拒絕新的登入。
+	SessionRejectNew SessionPolicy = "reject_new"
+)
+
+// defaultRegistryTTL 是線上登記的預設存活時間。
+const defaultRegistryTTL = 30 * time.Second
+
+// claimScript 在 key 不存在、已屬於自己，或允許接手時寫入新的擁有者，並回傳原本的擁有者 (沒有則回傳空字串)。
+var claimScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if (not cur) or cur == ARGV[1] or ARGV[3] == '1' then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+end
+if (not cur) or cur == ARGV[1] then
+	return ''
+end
+return cur
+`)
+
+// releaseScript 只在 key 仍屬於自己時刪除，避免刪掉已被其他連線接手的登記。
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// refreshScript 只在 key 仍屬於自己時延長存活時間。
+var refreshScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// onlineRegistry 以 Redis 記錄每位玩家目前唯一的連線擁有者，跨多個服務實體共用。
+//
+// 每筆登記都有 TTL，由擁有者的實體定期續期；實體當機後登記會在 TTL 到期時自動清除，
+// 不會讓玩家永遠無法再次登入。
+type onlineRegistry struct {
+	rdb    *redis.Client
+	ttl    time.Duration
+	logger *slog.Logger
+
+	mu    sync.Mutex
+	local map[string]string // key: 線上登記的 Redis key, value: 擁有者，本實體持有的登記
+}
+
+// newOnlineRegistry 建立線上登記並啟動續期的背景 goroutine，ctx 結束時停止。
+func newOnlineRegistry(ctx context.Context, rdb *redis.Client, ttl time.Duration, logger *slog.Logger) *onlineRegistry {
+	if ttl <= 0 {
+		ttl = defaultRegistryTTL
+	}
+	r := &onlineRegistry{
+		rdb:    rdb,
+		ttl:    ttl,
+		logger: logger,
+		local:  make(map[string]string),
+	}
+	go r.keepAlive(ctx)
+	return r
+}
+
+// claim 登記 owner 為玩家的連線擁有者。
+// takeover 為 true 時即使已有其他擁有者也會覆蓋。回傳原本的其他擁有者，沒有則為空字串。
+func (r *onlineRegistry) claim(ctx context.Context, operatorID, playerID, owner string, takeover bool) (string, error) {
+	flag := "0"
+	if takeover {
+		flag = "1"
+	}
+	key := onlineKey(operatorID, playerID)
+	prev, err := claimScript.Run(ctx, r.rdb, []string{key}, owner, r.ttl.Milliseconds(), flag).Text()
+	if err != nil {
+		return "", err
+	}
+	if prev == "" || takeover {
+		r.mu.Lock()
+		r.local[key] = owner
+		r.mu.Unlock()
+	}
+	return prev, nil
+}
+
+// release 在登記仍屬於 owner 時移除。
+func (r *onlineRegistry) release(ctx context.Context, operatorID, playerID, owner string) error {
+	key := onlineKey(operatorID, playerID)
+	r.mu.Lock()
+	if r.local[key] == owner {
+		delete(r.local, key)
+	}
+	r.mu.Unlock()
+	return releaseScript.Run(ctx, r.rdb, []string{key}, owner).Err()
+}
+
+// keepAlive 定期為本實體持有的登記續期。
+func (r *onlineRegistry) keepAlive(ctx context.Context) {
+	ticker := time.NewTicker(r.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		r.refresh(ctx)
+	}
+}
+
+// refresh 為本實體持有的登記續期，並移除已被其他連線接手或已過期的登記，不再為它們續期。
+func (r *onlineRegistry) refresh(ctx context.Context) {
+	r.mu.Lock()
+	owned := make(map[string]string, len(r.local))
+	for key, owner := range r.local {
+		owned[key] = owner
+	}
+	r.mu.Unlock()
+	if len(owned) == 0 {
+		return
+	}
+
+	pipe := r.rdb.Pipeline()
+	cmds := make(map[string]*redis.Cmd, len(owned))
+	for key, owner := range owned {
+		cmds[key] = refreshScript.Eval(ctx, pipe, []string{key}, owner, r.ttl.Milliseconds())
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		r.logger.Error("refresh online registry failed", "error", err, "players", len(owned))
+	}
+
+	lost := 0
+	r.mu.Lock()
+	for key, cmd := range cmds {
+		// 只有明確回傳 0 (登記已不屬於自己) 才移除，Redis 暫時無法連線時保留登記等待下次續期
+		if n, err := cmd.Int(); err != nil || n != 0 {
+			continue
+		}
+		// 續期期間同一個 key 可能已由本實體的新連線重新登記，只移除仍是舊擁有者的項目
+		if r.local[key] == owned[key] {
+			delete(r.local, key)
+			lost++
+		}
+	}
+	r.mu.Unlock()
+	if lost > 0 {
+		r.logger.Info("online registry ownership lost", "players", lost)
+	}
+}
+
+// onlineKey 組合玩家線上登記的 key。
+func onlineKey(operatorID, playerID string) string {
+	return fmt.Sprintf(RedisKeyPlayerOnlinePrefix, operatorID, playerID)
+}
@@ -0,0 +1,245 @@
+package gamecenter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/joe_shih/slot-factory/internal/application/login"
+	"github.com/joe_shih/slot-factory/internal/domain/currency"
+	"github.com/joe_shih/slot-factory/internal/domain/game"
+	"github.com/redis/go-redis/v9"
+)
+
+const testRegistryTTL = 30 * time.Second
+
+// newTestRedis 啟動 miniredis 並回傳連線到它的客戶端，客戶端不重試失敗的指令。
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { _ = rdb.Close() })
+	return mr, rdb
+}
+
+// newTestRegistry 建立不啟動背景續期的線上登記，續期由測試直接呼叫 refresh。
+func newTestRegistry(rdb *redis.Client) *onlineRegistry {
+	return &onlineRegistry{
+		rdb:    rdb,
+		ttl:    testRegistryTTL,
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		local:  make(map[string]string),
+	}
+}
+
+func TestRegistryClaimConflict(t *testing.T) {
+	tests := []struct {
+		name      string
+		takeover  bool
+		wantOwner string
+	}{
+		{name: "reject_new keeps the old owner", takeover: false, wantOwner: "a:c1"},
+		{name: "kick_old takes over", takeover: true, wantOwner: "b:c2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr, rdb := newTestRedis(t)
+			a, b := newTestRegistry(rdb), newTestRegistry(rdb)
+			ctx := context.Background()
+			key := onlineKey("op", "p1")
+
+			if prev, err := a.claim(ctx, "op", "p1", "a:c1", tt.takeover); err != nil || prev != "" {
+				t.Fatalf("first claim = %q, %v; want no previous owner", prev, err)
+			}
+			// 同一個擁有者重複登記不算衝突
+			if prev, err := a.claim(ctx, "op", "p1", "a:c1", false); err != nil || prev != "" {
+				t.Fatalf("repeated claim = %q, %v; want no previous owner", prev, err)
+			}
+
+			prev, err := b.claim(ctx, "op", "p1", "b:c2", tt.takeover)
+			if err != nil {
+				t.Fatalf("conflicting claim: %v", err)
+			}
+			if prev != "a:c1" {
+				t.Fatalf("conflicting claim returned %q, want the old owner a:c1", prev)
+			}
+			if got, _ := mr.Get(key); got != tt.wantOwner {
+				t.Fatalf("owner = %q, want %q", got, tt.wantOwner)
+			}
+			if _, owned := b.local[key]; owned != tt.takeover {
+				t.Fatalf("new instance holds the registration = %v, want %v", owned, tt.takeover)
+			}
+			if ttl := mr.TTL(key); ttl <= 0 || ttl > testRegistryTTL {
+				t.Fatalf("ttl = %s, want within %s", ttl, testRegistryTTL)
+			}
+		})
+	}
+}
+
+func TestRegistryStaleReleaseAfterTakeover(t *testing.T) {
+	mr, rdb := newTestRedis(t)
+	a, b := newTestRegistry(rdb), newTestRegistry(rdb)
+	ctx := context.Background()
+	key := onlineKey("op", "p1")
+
+	if _, err := a.claim(ctx, "op", "p1", "a:c1", true); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if _, err := b.claim(ctx, "op", "p1", "b:c2", true); err != nil {
+		t.Fatalf("takeover: %v", err)
+	}
+	// 被接手的舊連線稍後才斷線，不能刪掉新連線的登記
+	if err := a.release(ctx, "op", "p1", "a:c1"); err != nil {
+		t.Fatalf("stale release: %v", err)
+	}
+	if got, _ := mr.Get(key); got != "b:c2" {
+		t.Fatalf("owner after stale release = %q, want b:c2", got)
+	}
+	if _, ok := a.local[key]; ok {
+		t.Fatal("old instance still refreshes the registration after release")
+	}
+
+	if err := b.release(ctx, "op", "p1", "b:c2"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if mr.Exists(key) {
+		t.Fatal("registration still exists after the owner released it")
+	}
+}
+
+func TestRegistryRefreshAfterOwnershipLost(t *testing.T) {
+	mr, rdb := newTestRedis(t)
+	a := newTestRegistry(rdb)
+	ctx := context.Background()
+	kept, lost, expired := onlineKey("op", "p1"), onlineKey("op", "p2"), onlineKey("op", "p3")
+	for _, p := range []string{"p1", "p2", "p3"} {
+		if _, err := a.claim(ctx, "op", p, "a:"+p, false); err != nil {
+			t.Fatalf("claim %s: %v", p, err)
+		}
+	}
+	// p2 已由其他實體接手，p3 的登記已過期
+	if err := mr.Set(lost, "b:c9"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	mr.Del(expired)
+	mr.FastForward(testRegistryTTL / 2)
+
+	a.refresh(ctx)
+
+	if ttl := mr.TTL(kept); ttl != testRegistryTTL {
+		t.Fatalf("ttl of owned registration = %s, want refreshed to %s", ttl, testRegistryTTL)
+	}
+	if got, _ := mr.Get(lost); got != "b:c9" || mr.TTL(lost) != 0 {
+		t.Fatalf("registration taken over by another owner was modified: %q, ttl %s", got, mr.TTL(lost))
+	}
+	if mr.Exists(expired) {
+		t.Fatal("refresh recreated an expired registration")
+	}
+	if _, ok := a.local[kept]; !ok {
+		t.Fatal("owned registration dropped from local")
+	}
+	for _, key := range []string{lost, expired} {
+		if _, ok := a.local[key]; ok {
+			t.Fatalf("%s still refreshed after ownership was lost", key)
+		}
+	}
+}
+
+func TestRegistryRefreshKeepsOwnershipWhenRedisIsDown(t *testing.T) {
+	mr, rdb := newTestRedis(t)
+	a := newTestRegistry(rdb)
+	ctx := context.Background()
+	if _, err := a.claim(ctx, "op", "p1", "a:c1", false); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	mr.Close()
+	a.refresh(ctx)
+	if _, ok := a.local[onlineKey("op", "p1")]; !ok {
+		t.Fatal("registration dropped because redis was unreachable")
+	}
+}
+
+// newTestCenter 建立使用 rdb 並啟用單一連線限制的遊戲中心。
+func newTestCenter(t *testing.T, rdb *redis.Client, policy SessionPolicy) *gameCenter {
+	t.Helper()
+	loginService := login.NewService(nopAuthClient{}, currency.DefaultRegistry(), nil)
+	s := NewService(*loginService, slog.New(slog.NewTextHandler(io.Discard, nil)), rdb, nil, nil, nil)
+	s.registry = newTestRegistry(rdb)
+	s.policy = policy
+	return s
+}
+
+func TestClaimOnlineConflictPolicy(t *testing.T) {
+	twd, err := currency.DefaultRegistry().Resolve("TWD")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	tests := []struct {
+		policy    SessionPolicy
+		wantLogin bool
+	}{
+		{policy: SessionKickOld, wantLogin: true},
+		{policy: SessionRejectNew, wantLogin: false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			mr, rdb := newTestRedis(t)
+			a, b := newTestCenter(t, rdb, tt.policy), newTestCenter(t, rdb, tt.policy)
+			key := onlineKey("op", "p1")
+
+			// 訂閱控制頻道，確認 kick_old 會要求舊連線的實體踢線
+			sub := rdb.Subscribe(context.Background(), RedisChannelControl)
+			t.Cleanup(func() { _ = sub.Close() })
+			if _, err := sub.Receive(context.Background()); err != nil {
+				t.Fatalf("subscribe: %v", err)
+			}
+
+			oldClient, newClient := newFakeClient("c1"), newFakeClient("c2")
+			player := func(c *fakeClient) *game.Player {
+				p := game.NewPlayer("p1", "player", twd, c)
+				p.OperatorID = "op"
+				return p
+			}
+			if !a.claimOnline(oldClient, player(oldClient), nil) {
+				t.Fatal("first login rejected")
+			}
+			if got := b.claimOnline(newClient, player(newClient), nil); got != tt.wantLogin {
+				t.Fatalf("second login allowed = %v, want %v", got, tt.wantLogin)
+			}
+
+			owner, _ := mr.Get(key)
+			if tt.wantLogin {
+				if owner != b.ownerOf(newClient) {
+					t.Fatalf("owner = %q, want the new connection", owner)
+				}
+				msg, err := sub.ReceiveMessage(context.Background())
+				if err != nil {
+					t.Fatalf("ReceiveMessage: %v", err)
+				}
+				var cmd ControlCommand
+				var kick kickPlayerCommand
+				if err := json.Unmarshal([]byte(msg.Payload), &cmd); err != nil || cmd.Action != "kick_player" {
+					t.Fatalf("control command = %s, want kick_player", msg.Payload)
+				}
+				if err := json.Unmarshal([]byte(cmd.Data), &kick); err != nil || kick.Owner != a.ownerOf(oldClient) {
+					t.Fatalf("kick_player = %s, want the old owner", cmd.Data)
+				}
+				return
+			}
+			if owner != a.ownerOf(oldClient) {
+				t.Fatalf("owner = %q, want the old connection", owner)
+			}
+			if newClient.kicked == "" {
+				t.Fatal("rejected connection was not kicked")
+			}
+			if oldClient.kicked != "" {
+				t.Fatalf("old connection kicked: %q", oldClient.kicked)
+			}
+		})
+	}
+}
@@ -3,13 +3,19 @@ package gamecenter
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/joe_shih/slot-factory/internal/application/login"
+	"github.com/joe_shih/slot-factory/internal/application/session"
 	"github.com/joe_shih/slot-factory/internal/domain/game"
+	"github.com/joe_shih/slot-factory/internal/domain/operator"
+	"github.com/joe_shih/slot-factory/internal/domain/requestid"
 	"github.com/redis/go-redis/v9"
 	"github.com/shopspring/decimal"
 )
@@ -26,23 +32,32 @@ type ControlCommand struct {
 	Data   string `json:"data"`
 }
 
-// GameInfo 包含遊戲的基本資訊。
-type GameInfo struct {
-	ID          string `json:"id"`
-	PlayerCount int    `json:"playerCount"`
-}
-
 // 確保 service 類型在編譯時期就實現了 EventHandler 接口。
 var _ EventHandler = (*gameCenter)(nil)
 
+// 確保 Admin 在編譯時期就實現了 cmd/api 需要的介面。
+var (
+	_ GameProvider  = (*Admin)(nil)
+	_ AdminProvider = (*Admin)(nil)
+)
+
 // GameProvider 提供了讀取遊戲列表的介面。
 type GameProvider interface {
-	GetGames(ctx context.Context) []GameInfo
+	// GetGames 回傳營運商可以看到的遊戲目錄，operatorID 為空字串時列出所有遊戲。
+	GetGames(ctx context.Context, operatorID string) ([]GameInfo, error)
 }
 
 // AdminProvider 提供了系統管理相關的介面。
 type AdminProvider interface {
 	KickAll(ctx context.Context) error
+	// SetGameStatus 上架或下架遊戲，並通知所有服務實體。
+	SetGameStatus(ctx context.Context, gameID int, status GameStatus) error
+	// ScheduleMaintenance 排定維護時段，並通知所有服務實體。
+	ScheduleMaintenance(ctx context.Context, window MaintenanceWindow) (MaintenanceWindow, error)
+	// CancelMaintenance 取消維護時段，已開始的維護會立即解除。
+	CancelMaintenance(ctx context.Context, id string) error
+	// ListMaintenance 回傳尚未結束的維護時段。
+	ListMaintenance(ctx context.Context) ([]MaintenanceWindow, error)
 }
 
 // Service 定義了遊戲核心業務邏輯的介面（整合型，供 wsserver 使用）。
@@ -54,12 +69,39 @@ type Service interface {
 }
 
 // gameCenter 是 Service 介面的具體實現。
+//
+// 目錄查詢與管理指令由內嵌的 *Admin 提供，與 cmd/api 共用同一份實作。
 type gameCenter struct {
+	*Admin
 	loginService login.Service
+	sessions     *session.Service
 	logger       *slog.Logger
 	redisClient  *redis.Client
 	games        map[int]game.IGame
 	clientList   map[string]game.GameClient
+	clientsMu    sync.RWMutex // 保護 clientList，連線事件由各連線的 goroutine 並行呼叫
+	catalog      *Catalog
+	maintenance  *maintenanceSchedule
+	broadcaster  game.Broadcaster
+	drain        drainState
+	// heartbeat 是應用層 heartbeat 的狀態，nil 表示未啟用。
+	heartbeat *heartbeat
+
+	// instanceID 識別此服務實體，用於跨實體定位玩家的連線。
+	instanceID string
+	// registry 與 policy 實現跨實體的單一連線限制，registry 為 nil 時不限制。
+	registry *onlineRegistry
+	policy   SessionPolicy
+	// actionLimits 是各 action 的頻率限制，nil 表示不限制。
+	actionLimits    map[ActionType]ActionLimit
+	rateLimitPolicy RateLimitPolicy
+}
+
+// kickPlayerCommand 是 kick_player 控制指令的內容，要求持有 Owner 連線的實體踢除該連線。
+type kickPlayerCommand struct {
+	OperatorID string `json:"operatorID"`
+	PlayerID   string `json:"playerID"`
+	Owner      string `json:"owner"`
 }
 
 // NewService 建立並初始化一個新的遊戲中心服務實例。
@@ -70,39 +112,85 @@ type gameCenter struct {
 //   - loginService: login.Service, 負責玩家登入驗證的服務。
 //   - logger: *slog.Logger, 用於記錄日誌的 Logger 實例。
 //   - rdb: *redis.Client, Redis 客戶端，用於全域計數與廣播。如果為 nil，則相關功能將被略過。
+//   - sessions: *session.Service, 簽發可恢復連線的 session token。如果為 nil，則不支援 resume。
+//   - catalog: *Catalog, 遊戲目錄，決定各營運商可見的遊戲與上下架狀態。如果為 nil，則使用不依營運商篩選的目錄。
+//   - broadcaster: game.Broadcaster, 向群組廣播上下架與維護通知。如果為 nil，則不發送廣播通知。
 //
 // 回傳值：
 //   - *gameCenter: 初始化完成的遊戲中心服務結構指標。
-func NewService(loginService login.Service, logger *slog.Logger, rdb *redis.Client) *gameCenter {
+func NewService(loginService login.Service, logger *slog.Logger, rdb *redis.Client, sessions *session.Service, catalog *Catalog, broadcaster game.Broadcaster) *gameCenter {
+	if catalog == nil {
+		catalog = NewCatalog(rdb, nil, nil)
+	}
+	maintenance := newMaintenanceSchedule(rdb)
 	s := &gameCenter{
+		Admin:        newAdmin(rdb, catalog, maintenance, logger),
 		loginService: loginService,
+		sessions:     sessions,
 		logger:       logger,
 		redisClient:  rdb,
 		games:        make(map[int]game.IGame),
 		clientList:   make(map[string]game.GameClient),
+		catalog:      catalog,
+		maintenance:  maintenance,
+		broadcaster:  broadcaster,
+		instanceID:   uuid.NewString(),
 	}
 
-	// 如果有 Redis，啟動監聽器處理廣播指令
+	// 如果有 Redis，啟動監聽器處理廣播指令，並定期檢查維護時段
 	if rdb != nil {
 		go s.listenControlCommands()
+		go s.runMaintenance()
 	}
 
 	return s
 }
 
+// EnableSingleSession 啟用跨實體的單一連線限制，同一位玩家同時只能有一條連線。
+//
+// 參數說明：
+//   - policy: SessionPolicy, 重複登入時踢除舊連線或拒絕新登入。
+//   - ttl: time.Duration, 線上登記的存活時間，實體當機後登記會在此時間後自動清除，0 表示使用預設值 30 秒。
+//
+// 回傳值：
+//   - error: 如果沒有 Redis 或 policy 無效，則返回錯誤。
+func (s *gameCenter) EnableSingleSession(policy SessionPolicy, ttl time.Duration) error {
+	if s.redisClient == nil {
+		return fmt.Errorf("single session requires redis")
+	}
+	switch policy {
+	case SessionKickOld, SessionRejectNew:
+	default:
+		return fmt.Errorf("unknown single session policy: %s", policy)
+	}
+	s.policy = policy
+	s.registry = newOnlineRegistry(context.Background(), s.redisClient, ttl, s.logger)
+	return nil
+}
+
 func (s *gameCenter) HandleConnect(client game.GameClient) {
 	s.logger.Info("game service: client connected", "ip", client.GetIP())
 	clientID := client.GetID()
+	s.clientsMu.Lock()
 	s.clientList[clientID] = client
+	s.clientsMu.Unlock()
 }
 
 func (s *gameCenter) HandleDisconnect(client game.GameClient) {
 	s.logger.Info("game service: client disconnected", "ip", client.GetIP())
 	clientID := client.GetID()
+	s.clientsMu.Lock()
 	delete(s.clientList, clientID)
+	s.clientsMu.Unlock()
 	// 在這裡可以加入玩家離線的處理邏輯，例如從遊戲中移除
 	player, _ := client.GetTag("player")
 	if player != nil {
+		if s.registry != nil {
+			p := player.(*game.Player)
+			if err := s.registry.release(context.Background(), p.OperatorID, p.ID, s.ownerOf(client)); err != nil {
+				s.logger.Error("release online registry failed", "operatorID", p.OperatorID, "playerID", p.ID, "error", err)
+			}
+		}
 		err := s.leaveGame(*player.(*game.Player))
 		if err != nil {
 			s.logger.Error("leave game failed", "playerID", player.(*game.Player).ID, "error", err)
@@ -113,49 +201,102 @@ func (s *gameCenter) HandleDisconnect(client game.GameClient) {
 func (s *gameCenter) HandleMessage(client game.GameClient, message []byte) {
 	// 先解析 action 層
 	var base struct {
-		Action ActionType      `json:"action"`
-		Data   json.RawMessage `json:"data"`
+		Action ActionType `json:"action"`
+		// RequestID 是客戶端選填的請求 ID，會帶入直接回應此請求的訊息、日誌與錢包交易紀錄。
+		RequestID string          `json:"requestId"`
+		Data      json.RawMessage `json:"data"`
 	}
 	if err := json.Unmarshal(message, &base); err != nil {
 		s.logger.Warn("failed to unmarshal message", "error", err, "ip", client.GetIP())
+		s.replyError(client, "", game.ErrCodeInvalidMessage, "invalid message format")
 		err := client.Kick("invalid message format")
 		if err != nil {
 			s.logger.Error("kick client failed", "error", err, "ip", client.GetIP())
 		}
 		return
 	}
+	requestID := base.RequestID
+	if len(requestID) > requestid.MaxLength {
+		s.logger.Warn("request id too long", "action", base.Action, "length", len(requestID), "ip", client.GetIP())
+		s.replyError(client, "", game.ErrCodeInvalidRequestID, fmt.Sprintf("requestId must be at most %d characters", requestid.MaxLength))
+		return
+	}
+	if !s.allowAction(client, base.Action, requestID) {
+		return
+	}
 
-	s.logger.Info("message received", "action", base.Action, "ip", client.GetIP())
+	// heartbeat 訊息頻繁且沒有業務意義，不記錄 Info 日誌
+	switch base.Action {
+	case Pong:
+		var payload pongPayload
+		if err := json.Unmarshal(base.Data, &payload); err != nil {
+			s.replyError(client, requestID, game.ErrCodeInvalidPayload, "invalid pong data")
+			return
+		}
+		s.handlePong(client, payload)
+		return
+	case Ping:
+		var payload clientPingPayload
+		if len(base.Data) > 0 {
+			if err := json.Unmarshal(base.Data, &payload); err != nil {
+				s.replyError(client, requestID, game.ErrCodeInvalidPayload, "invalid ping data")
+				return
+			}
+		}
+		s.handlePing(client, requestID, payload)
+		return
+	}
+
+	s.logger.Info("message received", "action", base.Action, "requestID", requestID, "ip", client.GetIP())
 
 	switch base.Action {
 	case Login:
 		var payload loginPayload
 		if err := json.Unmarshal(base.Data, &payload); err != nil {
-			s.logger.Warn("invalid auth payload", "error", err, "ip", client.GetIP())
+			s.logger.Warn("invalid auth payload", "error", err, "requestID", requestID, "ip", client.GetIP())
+			s.replyError(client, requestID, game.ErrCodeInvalidPayload, "invalid login data")
 			return
 		}
-		s.handleLogin(client, payload.Sid)
-		player, _ := client.GetTag("player")
-		if player != nil {
-			domainPlayer := *player.(*game.Player)
-			err := s.joinGame(payload.GameID, domainPlayer)
-			if err != nil {
-				s.logger.Error("join game failed", "playerID", domainPlayer.ID, "error", err)
-			}
+		s.handleLogin(client, requestID, payload)
+	case Resume:
+		var payload resumePayload
+		if err := json.Unmarshal(base.Data, &payload); err != nil {
+			s.logger.Warn("invalid resume payload", "error", err, "requestID", requestID, "ip", client.GetIP())
+			s.replyError(client, requestID, game.ErrCodeInvalidPayload, "invalid resume data")
+			return
 		}
+		s.handleResume(client, requestID, payload.SessionToken)
+	case Logout:
+		s.handleLogout(client)
 	case Play:
 		var payload playPayload
 		if err := json.Unmarshal(base.Data, &payload); err != nil {
-			s.logger.Warn("invalid auth payload", "error", err, "ip", client.GetIP())
+			s.logger.Warn("invalid play payload", "error", err, "requestID", requestID, "ip", client.GetIP())
+			s.replyError(client, requestID, game.ErrCodeInvalidPayload, "invalid play data")
 			return
 		}
-		s.handlePlay(client, payload.BetAmount)
+		s.handlePlay(client, requestID, payload.BetAmount)
 	default:
-		s.logger.Warn("unknown action", "action", base.Action, "ip", client.GetIP())
+		s.logger.Warn("unknown action", "action", base.Action, "requestID", requestID, "ip", client.GetIP())
+		s.replyError(client, requestID, game.ErrCodeUnknownAction, fmt.Sprintf("unknown action: %s", base.Action))
+	}
+}
+
+// reply 將請求 ID 帶入訊息後發送給客戶端，用於直接回應客戶端請求的訊息。
+func (s *gameCenter) reply(gameClient game.GameClient, requestID string, message game.Envelope) {
+	message.RequestID = requestID
+	if err := gameClient.SendMessage(message); err != nil {
+		s.logger.Error("send message failed", "error", err, "requestID", requestID, "ip", gameClient.GetIP())
 	}
 }
 
-func (s *gameCenter) handleLogin(gameClient game.GameClient, token string) {
+// replyError 以標準錯誤訊息回應客戶端請求。
+func (s *gameCenter) replyError(gameClient game.GameClient, requestID, code, message string) {
+	s.reply(gameClient, requestID, game.NewErrorEnvelope(requestID, code, message))
+}
+
+func (s *gameCenter) handleLogin(gameClient game.GameClient, requestID string, payload loginPayload) {
+	token := payload.Sid
 	if token == "" {
 		err := gameClient.Kick("auth failed: token is missing")
 		if err != nil {
@@ -164,33 +305,258 @@ func (s *gameCenter) handleLogin(gameClient game.GameClient, token string) {
 		return
 	}
 
-	player, err := s.loginService.Authenticate(token, gameClient)
+	if s.rejectWhileDraining(gameClient, requestID) {
+		return
+	}
+	if s.rejectDuringMaintenance(gameClient, requestID, payload.GameID) {
+		return
+	}
+
+	// 客戶端帶上營運商時，交由該營運商驗證 token
+	ctx := requestid.NewContext(gameClient.Context(), requestID)
+	if payload.OperatorID != "" {
+		ctx = operator.NewContext(ctx, payload.OperatorID)
+	}
+	player, err := s.loginService.Authenticate(ctx, token, gameClient)
 	if err != nil {
-		s.logger.Error("authentication failed", "error", err, "ip", gameClient.GetIP())
-		err := gameClient.Kick("authentication failed")
+		s.logger.Error("authentication failed", "error", err, "requestID", requestID, "ip", gameClient.GetIP())
+		err := gameClient.Kick(authFailureReason(err))
+		if err != nil {
+			s.logger.Error("kick client failed", "error", err, "ip", gameClient.GetIP())
+		}
+		return
+	}
+
+	var sess *session.Session
+	if s.sessions != nil {
+		issued, err := s.sessions.Issue(gameClient.Context(), session.Session{
+			PlayerID:   player.ID,
+			Name:       player.Name,
+			Currency:   player.Currency.Code,
+			Language:   player.Language,
+			OperatorID: player.OperatorID,
+			Country:    player.Country,
+			GameID:     payload.GameID,
+		})
+		if err != nil {
+			// 無法簽發 session 不影響本次登入，只是斷線後需要重新向營運商驗證
+			s.logger.Error("issue session failed", "error", err, "playerID", player.ID)
+		} else {
+			sess = &issued
+		}
+	}
+	s.completeLogin(gameClient, requestID, player, payload.GameID, sess, false)
+}
+
+// handleResume 以 session token 恢復斷線前的玩家與遊戲，不需要再向營運商驗證。
+func (s *gameCenter) handleResume(gameClient game.GameClient, requestID string, token string) {
+	if s.sessions == nil || token == "" {
+		err := gameClient.Kick("resume failed: session is not available")
 		if err != nil {
 			s.logger.Error("kick client failed", "error", err, "ip", gameClient.GetIP())
 		}
 		return
 	}
+	if s.rejectWhileDraining(gameClient, requestID) {
+		return
+	}
+
+	// 先以 Peek 取得 session 檢查是否允許恢復，被拒絕時不輪替 token，客戶端之後仍可恢復
+	sess, err := s.sessions.Peek(gameClient.Context(), token)
+	if err != nil {
+		s.rejectResume(gameClient, err)
+		return
+	}
+	if s.rejectDuringMaintenance(gameClient, requestID, sess.GameID) {
+		return
+	}
+
+	// 玩家建立失敗時 token 尚未輪替，客戶端仍可用同一個 token 重試
+	player, err := s.loginService.NewPlayer(login.UserData{
+		ID:         sess.PlayerID,
+		Name:       sess.Name,
+		Currency:   sess.Currency,
+		Language:   sess.Language,
+		OperatorID: sess.OperatorID,
+		Country:    sess.Country,
+	}, gameClient)
+	if err != nil {
+		s.logger.Error("restore player failed", "error", err, "playerID", sess.PlayerID)
+		if err := gameClient.Kick("resume failed: please login again"); err != nil {
+			s.logger.Error("kick client failed", "error", err, "ip", gameClient.GetIP())
+		}
+		return
+	}
+
+	sess, err = s.sessions.Resume(gameClient.Context(), token)
+	if err != nil {
+		s.rejectResume(gameClient, err)
+		return
+	}
+	s.completeLogin(gameClient, requestID, player, sess.GameID, &sess, true)
+}
+
+// rejectResume 依 session 錯誤踢除恢復失敗的客戶端。
+func (s *gameCenter) rejectResume(gameClient game.GameClient, err error) {
+	reason := "resume failed: please login again"
+	if errors.Is(err, session.ErrSessionNotFound) {
+		reason = "resume failed: session expired or revoked"
+	} else {
+		s.logger.Error("resume session failed", "error", err, "ip", gameClient.GetIP())
+	}
+	if err := gameClient.Kick(reason); err != nil {
+		s.logger.Error("kick client failed", "error", err, "ip", gameClient.GetIP())
+	}
+}
+
+// completeLogin 將玩家附加到連線上、回傳 auth_success 並加入遊戲。
+func (s *gameCenter) completeLogin(gameClient game.GameClient, requestID string, player *game.Player, gameID int, sess *session.Session, resumed bool) {
+	if !s.claimOnline(gameClient, player, sess) {
+		return
+	}
 
 	// 將驗證成功的 Player 物件附加到連線上
 	gameClient.SetTag("player", player)
-	s.logger.Info("client authenticated successfully", "playerID", player.ID, "playerName", player.Name, "ip", gameClient.GetIP())
+	gameClient.Join(game.GroupPlayers)
+	if sess != nil {
+		gameClient.SetTag("session", sess.Token)
+	}
+	s.logger.Info("client authenticated successfully", "playerID", player.ID, "playerName", player.Name, "operatorID", player.OperatorID, "currency", player.Currency.Code, "resumed", resumed, "requestID", requestID, "ip", gameClient.GetIP())
+
+	payload := authSuccessPayload{
+		Message:  "authenticated successfully",
+		PlayerID: player.ID,
+		Currency: player.Currency.Code,
+		GameID:   gameID,
+		Resumed:  resumed,
+	}
+	if sess != nil {
+		payload.SessionToken = sess.Token
+		payload.SessionExpiresAt = &sess.ExpiresAt
+	}
+	s.reply(gameClient, requestID, game.Envelope{Action: "auth_success", Payload: payload})
+
+	if err := s.joinGame(requestID, gameID, *player); err != nil {
+		s.logger.Error("join game failed", "playerID", player.ID, "error", err)
+	}
+}
 
-	response := game.Envelope{
-		Action:  "auth_success",
-		Payload: fmt.Sprintf("{\"message\": \"authenticated successfully\", \"playerID\": \"%s\"}", player.ID),
+// claimOnline 在啟用單一連線限制時，將此連線登記為玩家唯一的連線。
+//
+// 玩家已在其他連線 (可能在其他實體) 上線時，依 policy 透過 game_control 頻道踢除舊連線，
+// 或拒絕這次登入並撤銷剛簽發的 session。回傳 false 表示登入被拒絕。
+// Redis 發生錯誤時不阻擋登入 (fail-open)，避免 Redis 故障讓所有玩家無法登入。
+func (s *gameCenter) claimOnline(gameClient game.GameClient, player *game.Player, sess *session.Session) bool {
+	if s.registry == nil {
+		return true
 	}
-	err = player.SendMessage(response)
+	ctx := gameClient.Context()
+	prev, err := s.registry.claim(ctx, player.OperatorID, player.ID, s.ownerOf(gameClient), s.policy == SessionKickOld)
 	if err != nil {
-		s.logger.Error("send message failed", "error", err, "ip", gameClient.GetIP())
+		s.logger.Error("claim online registry failed", "playerID", player.ID, "error", err)
+		return true
+	}
+	if prev == "" {
+		return true
+	}
+
+	if s.policy == SessionRejectNew {
+		s.logger.Warn("login rejected: player already online", "playerID", player.ID, "owner", prev, "ip", gameClient.GetIP())
+		if sess != nil && s.sessions != nil {
+			if err := s.sessions.Revoke(context.Background(), sess.Token); err != nil {
+				s.logger.Error("revoke session failed", "error", err, "playerID", player.ID)
+			}
+		}
+		if err := gameClient.Kick("login rejected: already logged in on another connection"); err != nil {
+			s.logger.Error("kick client failed", "error", err, "ip", gameClient.GetIP())
+		}
+		return false
+	}
+
+	s.logger.Info("player took over existing connection", "playerID", player.ID, "previousOwner", prev, "ip", gameClient.GetIP())
+	s.leaveLocalGame(prev)
+	data, _ := json.Marshal(kickPlayerCommand{OperatorID: player.OperatorID, PlayerID: player.ID, Owner: prev})
+	payload, _ := json.Marshal(ControlCommand{Action: "kick_player", Data: string(data)})
+	if err := s.redisClient.Publish(ctx, RedisChannelControl, payload).Err(); err != nil {
+		s.logger.Error("publish kick_player failed", "error", err, "playerID", player.ID)
+	}
+	return true
+}
+
+// leaveLocalGame 在舊連線位於本實體時，讓它立即離開遊戲並清除遊戲標籤。
+// 新連線會在 claimOnline 返回後加入遊戲，不能等 kick_player 指令非同步送達，
+// 否則舊連線仍在房內；清除標籤後舊連線斷線時也不會再離開一次。
+func (s *gameCenter) leaveLocalGame(owner string) {
+	instanceID, clientID, ok := strings.Cut(owner, ":")
+	if !ok || instanceID != s.instanceID {
+		return
+	}
+	s.clientsMu.RLock()
+	client, exists := s.clientList[clientID]
+	s.clientsMu.RUnlock()
+	if !exists {
+		return
+	}
+	player, ok := client.GetTag("player")
+	if !ok || player == nil {
+		return
+	}
+	if err := s.leaveGame(*player.(*game.Player)); err != nil {
+		s.logger.Error("leave game failed", "playerID", player.(*game.Player).ID, "error", err)
+	}
+	client.SetTag("game", nil)
+}
+
+// ownerOf 回傳連線在線上登記中的擁有者識別："實體ID:連線ID"。
+func (s *gameCenter) ownerOf(gameClient game.GameClient) string {
+	return s.instanceID + ":" + gameClient.GetID()
+}
+
+// handleLogout 撤銷目前的 session token 並中斷連線，斷線處理會讓玩家離開遊戲。
+func (s *gameCenter) handleLogout(gameClient game.GameClient) {
+	if token, ok := gameClient.GetTag("session"); ok && s.sessions != nil {
+		if err := s.sessions.Revoke(context.Background(), token.(string)); err != nil {
+			s.logger.Error("revoke session failed", "error", err, "ip", gameClient.GetIP())
+		}
+	}
+	if err := gameClient.Kick("logged out"); err != nil {
+		s.logger.Error("kick client failed", "error", err, "ip", gameClient.GetIP())
 	}
 }
 
-func (s *gameCenter) handlePlay(gameClient game.GameClient, betAmount decimal.Decimal) {
+// kickAndRevoke 踢除客戶端並撤銷其玩家所有的 session，讓被踢的玩家無法以 session token 重連。
+func (s *gameCenter) kickAndRevoke(gameClient game.GameClient, reason string) error {
+	if player, ok := gameClient.GetTag("player"); ok && s.sessions != nil {
+		p := player.(*game.Player)
+		if err := s.sessions.RevokePlayer(context.Background(), p.OperatorID, p.ID); err != nil {
+			s.logger.Error("revoke player sessions failed", "error", err, "operatorID", p.OperatorID, "playerID", p.ID)
+		}
+	}
+	return gameClient.Kick(reason)
+}
+
+// authFailureReason 將驗證錯誤轉換為回傳給客戶端的踢線原因，讓客戶端可以決定是否重新取得 token 或稍後重試。
+func authFailureReason(err error) string {
+	switch {
+	case errors.Is(err, login.ErrTokenExpired):
+		return "auth failed: token expired"
+	case errors.Is(err, login.ErrTokenInvalid):
+		return "auth failed: token invalid"
+	case errors.Is(err, login.ErrAuthUnavailable):
+		return "auth failed: auth service unavailable, please retry later"
+	case errors.Is(err, operator.ErrUnknownOperator):
+		return "auth failed: unknown operator"
+	case errors.Is(err, login.ErrCurrencyNotAllowed):
+		return "auth failed: currency not available for operator"
+	default:
+		return "authentication failed"
+	}
+}
+
+func (s *gameCenter) handlePlay(gameClient game.GameClient, requestID string, betAmount decimal.Decimal) {
 	player, _ := gameClient.GetTag("player")
 	if player == nil {
+		s.replyError(gameClient, requestID, game.ErrCodeNotLoggedIn, "login required")
 		err := gameClient.Kick("Not Login")
 		if err != nil {
 			s.logger.Error("kick client failed", "error", err, "ip", gameClient.GetIP())
@@ -198,42 +564,83 @@ func (s *gameCenter) handlePlay(gameClient game.GameClient, betAmount decimal.De
 		return
 	}
 	domainPlayer := player.(*game.Player)
-	gameID, exists := domainPlayer.GetTag("game")
+	gameID, _ := domainPlayer.GetTag("game")
+	realGameID, exists := gameID.(int)
 	if !exists {
+		s.replyError(gameClient, requestID, game.ErrCodeGameNotFound, "not in any game")
 		err := gameClient.Kick("Not in any game")
 		if err != nil {
 			s.logger.Error("kick client failed", "error", err, "ip", gameClient.GetIP())
 		}
 		return
 	}
-	realGameID := gameID.(int)
-	game := s.games[realGameID]
-	if game == nil {
+	g := s.games[realGameID]
+	if g == nil {
+		s.replyError(gameClient, requestID, game.ErrCodeGameNotFound, "game not found")
 		err := domainPlayer.Kick("game not found")
 		if err != nil {
 			s.logger.Error("kick client failed", "error", err, "ip", gameClient.GetIP())
 		}
 		return
 	}
-	game.Play(domainPlayer, betAmount)
+	// 已下架的遊戲不接受新的下注，進行中的局仍會正常結算
+	if st := s.catalog.Status(realGameID); st != GameEnabled {
+		s.reply(gameClient, requestID, gameStatusEnvelope(realGameID, st))
+		return
+	}
+	// 維護期間不接受新的下注，進行中的局仍會正常結算
+	now := time.Now()
+	if w, ok := s.maintenance.active(realGameID, now); ok {
+		s.reply(gameClient, requestID, maintenanceEnvelope(w, MaintenanceStarted, int(w.EndAt.Sub(now).Seconds())))
+		return
+	}
+	// 排空期間不接受新的下注，讓進行中的局在關閉前結算
+	payload, ok := s.drain.startPlay()
+	if !ok {
+		s.reply(gameClient, requestID, serverDrainingEnvelope(payload))
+		return
+	}
+	defer s.drain.plays.Done()
+	g.Play(requestid.NewContext(domainPlayer.Context(), requestID), domainPlayer, betAmount)
 }
 
+// RegisterGame 註冊一個遊戲並加入遊戲目錄。
 func (s *gameCenter) RegisterGame(game game.IGame) {
 	id := game.ID()
 	s.games[id] = game
+	if err := s.catalog.register(context.Background(), id, game.Info()); err != nil {
+		s.logger.Error("register game to catalog failed", "gameID", id, "error", err)
+	}
 }
 
-func (s *gameCenter) joinGame(gameID int, player game.Player) error {
-	game := s.games[gameID]
-	if game == nil {
+// joinGame 將玩家加入遊戲，requestID 是觸發加入的登入請求，會帶入遊戲回應的初始訊息。
+func (s *gameCenter) joinGame(requestID string, gameID int, player game.Player) error {
+	g := s.games[gameID]
+	if g == nil {
 		err := player.Kick("joinGame game not found")
 		if err != nil {
 			s.logger.Error("kick client failed", "error", err, "ip", player.IP())
 		}
 		return fmt.Errorf("game not found")
 	}
-	game.AddPlayer(&player)
+	if !s.catalog.Visible(player.Operator, gameID) {
+		err := player.Kick("game not available for operator")
+		if err != nil {
+			s.logger.Error("kick client failed", "error", err, "ip", player.IP())
+		}
+		return fmt.Errorf("game %d not enabled for operator %s", gameID, player.OperatorID)
+	}
+	if s.catalog.Status(gameID) != GameEnabled {
+		err := player.Kick("game is disabled")
+		if err != nil {
+			s.logger.Error("kick client failed", "error", err, "ip", player.IP())
+		}
+		return fmt.Errorf("game %d is disabled", gameID)
+	}
+	g.AddPlayer(requestid.NewContext(player.Context(), requestID), &player)
 	player.SetTag("game", gameID)
+	// 加入遊戲後才加入房間群組，遊戲在 AddPlayer 中廣播的加入通知不會發給玩家自己
+	player.Join(game.GameGroup(gameID))
 
 	// Redis 全域計數
 	if s.redisClient != nil {
@@ -246,14 +653,16 @@ func (s *gameCenter) joinGame(gameID int, player game.Player) error {
 }
 
 func (s *gameCenter) leaveGame(player game.Player) error {
-	gameID, exists := player.GetTag("game")
+	gameID, _ := player.GetTag("game")
+	// 被新連線接手時遊戲標籤已清除，不需要再離開一次
+	realGameID, exists := gameID.(int)
 	if !exists {
 		return nil
 	}
-	realGameID := gameID.(int)
-	game := s.games[realGameID]
-	if game != nil {
-		game.RemovePlayer(&player)
+	// 先離開房間群組，遊戲在 RemovePlayer 中廣播的離開通知不會發給已離開的玩家
+	player.Leave(game.GameGroup(realGameID))
+	if g := s.games[realGameID]; g != nil {
+		g.RemovePlayer(&player)
 	}
 
 	// Redis 全域計數
@@ -266,75 +675,6 @@ func (s *gameCenter) leaveGame(player game.Player) error {
 	return nil
 }
 
-// GetGames 取得所有遊戲的狀態列表。
-//
-// 此方法會嘗試從 Redis 取得所有遊戲的即時線上人數（跨服務實體加總）。
-// 它使用了 Redis 的 SCAN 指令來避免阻塞，並透過 Pipeline 批次讀取計數。
-//
-// 參數說明：
-//   - ctx: context.Context, 用於控制 Redis 請求的 Context。
-//
-// 回傳值：
-//   - []GameInfo: 包含遊戲 ID 與當前線上人數的結構列表。
-func (s *gameCenter) GetGames(ctx context.Context) []GameInfo {
-	var (
-		cursor uint64
-		keys   []string
-		games  []GameInfo
-	)
-
-	// 使用 SCAN 迭代搜尋所有符合 games:*:count 模式的 key
-	for {
-		var k []string
-		var err error
-		k, cursor, err = s.redisClient.Scan(ctx, cursor, "games:*:count", 100).Result()
-		if err != nil {
-			panic(err)
-		}
-
-		keys = append(keys, k...)
-
-		if cursor == 0 {
-			break
-		}
-	}
-
-	pipe := s.redisClient.Pipeline()
-	cmds := make([]*redis.StringCmd, 0, len(keys))
-	for _, key := range keys {
-		cmds = append(cmds, pipe.Get(ctx, key))
-	}
-
-	_, err := pipe.Exec(ctx)
-	if err != nil {
-		panic(err)
-	}
-
-	for i, cmd := range cmds {
-		fmt.Println(keys[i], cmd.Val())
-		parts := strings.Split(keys[i], ":")
-		strGameID := parts[1]
-		playerCount, _ := strconv.Atoi(cmd.Val())
-		games = append(games, GameInfo{
-			ID:          strGameID,
-			PlayerCount: playerCount,
-		})
-	}
-	return games
-}
-
-func (s *gameCenter) KickAll(ctx context.Context) error {
-	if s.redisClient == nil {
-		return fmt.Errorf("redis client is nil")
-	}
-
-	cmd := ControlCommand{
-		Action: "kick_all",
-	}
-	payload, _ := json.Marshal(cmd)
-	return s.redisClient.Publish(ctx, RedisChannelControl, payload).Err()
-}
-
 func (s *gameCenter) listenControlCommands() {
 	ctx := context.Background()
 	pubsub := s.redisClient.Subscribe(ctx, RedisChannelControl)
@@ -345,6 +685,17 @@ func (s *gameCenter) listenControlCommands() {
 		}
 	}()
 
+	// 訂閱生效後再同步上下架狀態，避免遺漏訂閱前的變更
+	if _, err := pubsub.Receive(ctx); err != nil {
+		s.logger.Error("subscribe control channel failed", "error", err)
+	}
+	if err := s.catalog.refresh(ctx); err != nil {
+		s.logger.Error("load game status failed", "error", err)
+	}
+	if err := s.maintenance.refresh(ctx); err != nil {
+		s.logger.Error("load maintenance windows failed", "error", err)
+	}
+
 	ch := pubsub.Channel()
 	s.logger.Info("listening to redis control commands")
 
@@ -360,13 +711,133 @@ func (s *gameCenter) listenControlCommands() {
 		switch cmd.Action {
 		case "kick_all":
 			s.handleGlobalKickAll()
+		case "kick_player":
+			var kick kickPlayerCommand
+			if err := json.Unmarshal([]byte(cmd.Data), &kick); err != nil {
+				s.logger.Warn("received invalid kick_player command", "data", cmd.Data)
+				continue
+			}
+			s.handleKickPlayer(kick)
+		case "game_status":
+			var status statusCommand
+			if err := json.Unmarshal([]byte(cmd.Data), &status); err != nil {
+				s.logger.Warn("received invalid game_status command", "data", cmd.Data)
+				continue
+			}
+			s.handleGameStatus(status)
+		case "maintenance":
+			if err := s.maintenance.refresh(ctx); err != nil {
+				s.logger.Error("load maintenance windows failed", "error", err)
+			}
 		}
 	}
 }
 
+// clients 回傳目前所有連線的快照，讓呼叫端可以在不持有鎖的情況下逐一發送訊息或踢線。
+func (s *gameCenter) clients() []game.GameClient {
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+	list := make([]game.GameClient, 0, len(s.clientList))
+	for _, client := range s.clientList {
+		list = append(list, client)
+	}
+	return list
+}
+
 func (s *gameCenter) handleGlobalKickAll() {
 	s.logger.Warn("EXECUTING GLOBAL KICK ALL")
-	for _, client := range s.clientList {
-		_ = client.Kick("api kick !")
+	for _, client := range s.clients() {
+		_ = s.kickAndRevoke(client, "api kick !")
+	}
+}
+
+// handleKickPlayer 踢除被其他連線接手的舊連線。只有持有該連線的實體會處理。
+// 舊連線的 session token 會被撤銷，避免以舊 token 搶回連線。
+func (s *gameCenter) handleKickPlayer(cmd kickPlayerCommand) {
+	instanceID, clientID, ok := strings.Cut(cmd.Owner, ":")
+	if !ok || instanceID != s.instanceID {
+		return
 	}
+	s.clientsMu.RLock()
+	client, exists := s.clientList[clientID]
+	s.clientsMu.RUnlock()
+	if !exists {
+		return
+	}
+	if token, ok := client.GetTag("session"); ok && s.sessions != nil {
+		if err := s.sessions.Revoke(context.Background(), token.(string)); err != nil {
+			s.logger.Error("revoke session failed", "error", err, "operatorID", cmd.OperatorID, "playerID", cmd.PlayerID)
+		}
+	}
+	s.logger.Info("kicking connection taken over by new login", "operatorID", cmd.OperatorID, "playerID", cmd.PlayerID, "clientID", clientID)
+	_ = client.Kick("logged in from another connection")
+}
+
+// handleGameStatus 更新本地的上下架狀態，並通知目前在該遊戲中的玩家。
+func (s *gameCenter) handleGameStatus(cmd statusCommand) {
+	s.catalog.applyStatus(cmd.GameID, cmd.Status)
+	s.logger.Warn("game status changed", "gameID", cmd.GameID, "status", cmd.Status)
+	if s.broadcaster != nil {
+		s.broadcaster.Broadcast(game.GameGroup(cmd.GameID), gameStatusEnvelope(cmd.GameID, cmd.Status))
+	}
+}
+
+// gameStatusEnvelope 建立通知客戶端遊戲上下架狀態的訊息。
+func gameStatusEnvelope(gameID int, status GameStatus) game.Envelope {
+	return game.Envelope{Action: "game_status", Payload: gameStatusPayload{GameID: gameID, Status: status}}
+}
+
+// runMaintenance 定期檢查維護時段，向受影響的玩家發送倒數、開始與結束通知，並清除已結束的時段。
+func (s *gameCenter) runMaintenance() {
+	ticker := time.NewTicker(maintenanceTickInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		notices, expired := s.maintenance.due(now)
+		for _, n := range notices {
+			s.logger.Warn("maintenance notice", "id", n.window.ID, "gameID", n.window.GameID, "phase", n.phase, "secondsLeft", n.secondsLeft)
+			s.notifyMaintenance(n)
+		}
+		if err := s.maintenance.purge(context.Background(), expired); err != nil {
+			s.logger.Error("purge maintenance windows failed", "error", err)
+		}
+	}
+}
+
+// notifyMaintenance 將維護通知發送給本實體上受影響的玩家：全平台維護通知所有已登入的玩家，否則只通知該遊戲房間內的玩家。
+func (s *gameCenter) notifyMaintenance(n maintenanceNotice) {
+	if s.broadcaster == nil {
+		return
+	}
+	group := game.GroupPlayers
+	if n.window.GameID != 0 {
+		group = game.GameGroup(n.window.GameID)
+	}
+	s.broadcaster.Broadcast(group, maintenanceEnvelope(n.window, n.phase, n.secondsLeft))
+}
+
+// rejectDuringMaintenance 在遊戲維護中時通知客戶端並中斷連線，回傳 true 表示已拒絕登入。
+func (s *gameCenter) rejectDuringMaintenance(gameClient game.GameClient, requestID string, gameID int) bool {
+	now := time.Now()
+	w, ok := s.maintenance.active(gameID, now)
+	if !ok {
+		return false
+	}
+	s.reply(gameClient, requestID, maintenanceEnvelope(w, MaintenanceStarted, int(w.EndAt.Sub(now).Seconds())))
+	if err := gameClient.Kick("server under maintenance"); err != nil {
+		s.logger.Error("kick client failed", "error", err, "ip", gameClient.GetIP())
+	}
+	return true
+}
+
+// maintenanceEnvelope 建立通知客戶端維護狀態的訊息。
+func maintenanceEnvelope(w MaintenanceWindow, phase MaintenancePhase, secondsLeft int) game.Envelope {
+	return game.Envelope{Action: "maintenance", Payload: maintenancePayload{
+		ID:          w.ID,
+		GameID:      w.GameID,
+		Phase:       phase,
+		StartAt:     w.StartAt,
+		EndAt:       w.EndAt,
+		SecondsLeft: secondsLeft,
+		Message:     w.Message,
+	}}
 }
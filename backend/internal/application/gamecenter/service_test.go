@@ -0,0 +1,228 @@
+package gamecenter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sessionStore "github.com/joe_shih/slot-factory/internal/adapter/session"
+	"github.com/joe_shih/slot-factory/internal/application/login"
+	"github.com/joe_shih/slot-factory/internal/application/session"
+	"github.com/joe_shih/slot-factory/internal/domain/currency"
+	"github.com/joe_shih/slot-factory/internal/domain/game"
+	"github.com/joe_shih/slot-factory/internal/domain/requestid"
+	"github.com/shopspring/decimal"
+)
+
+// fakeClient 是記錄送出訊息與踢線原因的 game.GameClient。
+type fakeClient struct {
+	id string
+
+	mu     sync.Mutex
+	sent   []game.Envelope
+	kicked string
+	tags   map[string]any
+}
+
+func newFakeClient(id string) *fakeClient {
+	return &fakeClient{id: id, tags: make(map[string]any)}
+}
+
+func (c *fakeClient) GetID() string            { return c.id }
+func (c *fakeClient) Context() context.Context { return context.Background() }
+func (c *fakeClient) GetIP() string            { return "127.0.0.1" }
+func (c *fakeClient) Join(string)              {}
+func (c *fakeClient) Leave(string)             {}
+
+func (c *fakeClient) SendMessage(message game.Envelope) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, message)
+	return nil
+}
+
+func (c *fakeClient) Kick(reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.kicked = reason
+	return nil
+}
+
+func (c *fakeClient) GetTag(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.tags[key]
+	return v, ok
+}
+
+func (c *fakeClient) SetTag(key string, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tags[key] = value
+}
+
+// actions 回傳已送出訊息的 action。
+func (c *fakeClient) actions() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	actions := make([]string, 0, len(c.sent))
+	for _, m := range c.sent {
+		actions = append(actions, m.Action)
+	}
+	return actions
+}
+
+// stubGame 是不做任何事的 game.IGame。
+type stubGame struct{ id int }
+
+func (g stubGame) ID() int                                             { return g.id }
+func (g stubGame) Info() game.Info                                     { return game.Info{Name: "stub"} }
+func (g stubGame) AddPlayer(context.Context, *game.Player)             {}
+func (g stubGame) RemovePlayer(*game.Player)                           {}
+func (g stubGame) Play(context.Context, *game.Player, decimal.Decimal) {}
+
+type nopAuthClient struct{}
+
+func (nopAuthClient) VerifyToken(context.Context, string) (login.UserData, error) {
+	return login.UserData{}, errors.New("not used")
+}
+
+func TestResumeDuringMaintenanceKeepsToken(t *testing.T) {
+	sessions := session.NewService(sessionStore.NewMemoryStore(), time.Minute)
+	loginService := login.NewService(nopAuthClient{}, currency.DefaultRegistry(), nil)
+	s := NewService(*loginService, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, sessions, nil, nil)
+	s.RegisterGame(stubGame{id: 1000})
+
+	ctx := context.Background()
+	issued, err := sessions.Issue(ctx, session.Session{PlayerID: "p1", Name: "player", GameID: 1000})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	now := time.Now()
+	s.maintenance.windows["w1"] = MaintenanceWindow{ID: "w1", GameID: 1000, StartAt: now.Add(-time.Minute), EndAt: now.Add(time.Hour)}
+	client := newFakeClient("c1")
+	s.handleResume(client, "r1", issued.Token)
+	if client.kicked != "server under maintenance" {
+		t.Fatalf("kick reason = %q, want server under maintenance", client.kicked)
+	}
+	if _, err := sessions.Peek(ctx, issued.Token); err != nil {
+		t.Fatalf("token consumed by rejected resume: %v", err)
+	}
+
+	// 維護結束後，同一個 token 仍可恢復並輪替
+	delete(s.maintenance.windows, "w1")
+	client = newFakeClient("c2")
+	s.handleResume(client, "r2", issued.Token)
+	if client.kicked != "" {
+		t.Fatalf("resume after maintenance kicked: %q", client.kicked)
+	}
+	if got := client.actions(); len(got) == 0 || got[0] != "auth_success" {
+		t.Fatalf("sent actions = %v, want auth_success first", got)
+	}
+	if _, err := sessions.Peek(ctx, issued.Token); !errors.Is(err, session.ErrSessionNotFound) {
+		t.Fatalf("old token still valid after resume: %v", err)
+	}
+}
+
+func TestResumeRestorePlayerFailureKeepsToken(t *testing.T) {
+	sessions := session.NewService(sessionStore.NewMemoryStore(), time.Minute)
+	loginService := login.NewService(nopAuthClient{}, currency.DefaultRegistry(), nil)
+	s := NewService(*loginService, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, sessions, nil, nil)
+
+	ctx := context.Background()
+	issued, err := sessions.Issue(ctx, session.Session{PlayerID: "p1", Currency: "XXX", GameID: 1000})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	client := newFakeClient("c1")
+	s.handleResume(client, "r1", issued.Token)
+	if client.kicked != "resume failed: please login again" {
+		t.Fatalf("kick reason = %q, want resume failed", client.kicked)
+	}
+	if _, err := sessions.Peek(ctx, issued.Token); err != nil {
+		t.Fatalf("token consumed by failed resume: %v", err)
+	}
+}
+
+func TestHandleMessageRequestID(t *testing.T) {
+	maxID := strings.Repeat("r", requestid.MaxLength)
+	tests := []struct {
+		name     string
+		message  string
+		want     game.Envelope
+		wantKick bool
+	}{
+		{
+			name:    "echoes request id on reply",
+			message: `{"action":"ping","requestId":"req-1","data":{"clientTime":42}}`,
+			want:    game.Envelope{Action: string(Pong), RequestID: "req-1"},
+		},
+		{
+			name:    "reply without request id",
+			message: `{"action":"ping","data":{"clientTime":42}}`,
+			want:    game.Envelope{Action: string(Pong)},
+		},
+		{
+			name:    "request id at the length limit",
+			message: `{"action":"ping","requestId":"` + maxID + `"}`,
+			want:    game.Envelope{Action: string(Pong), RequestID: maxID},
+		},
+		{
+			// 過長的 requestId 不帶回，避免把不受信任的長字串寫進回應
+			name:    "request id too long",
+			message: `{"action":"ping","requestId":"` + maxID + `x"}`,
+			want:    game.NewErrorEnvelope("", game.ErrCodeInvalidRequestID, "requestId must be at most 64 characters"),
+		},
+		{
+			name:    "unknown action",
+			message: `{"action":"dance","requestId":"req-2"}`,
+			want:    game.NewErrorEnvelope("req-2", game.ErrCodeUnknownAction, "unknown action: dance"),
+		},
+		{
+			name:    "invalid payload",
+			message: `{"action":"play","requestId":"req-3","data":"ten"}`,
+			want:    game.NewErrorEnvelope("req-3", game.ErrCodeInvalidPayload, "invalid play data"),
+		},
+		{
+			name:     "not logged in",
+			message:  `{"action":"play","requestId":"req-4","data":{"betAmount":"10"}}`,
+			want:     game.NewErrorEnvelope("req-4", game.ErrCodeNotLoggedIn, "login required"),
+			wantKick: true,
+		},
+		{
+			name:     "invalid message",
+			message:  `{"action":`,
+			want:     game.NewErrorEnvelope("", game.ErrCodeInvalidMessage, "invalid message format"),
+			wantKick: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loginService := login.NewService(nopAuthClient{}, currency.DefaultRegistry(), nil)
+			s := NewService(*loginService, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, nil, nil, nil)
+			client := newFakeClient("c1")
+
+			s.HandleMessage(client, []byte(tt.message))
+			if len(client.sent) != 1 {
+				t.Fatalf("sent %d messages, want 1: %+v", len(client.sent), client.sent)
+			}
+			got := client.sent[0]
+			if got.Action != tt.want.Action || got.RequestID != tt.want.RequestID {
+				t.Fatalf("reply = %s (requestId %q), want %s (requestId %q)", got.Action, got.RequestID, tt.want.Action, tt.want.RequestID)
+			}
+			if tt.want.Action == game.ActionError && got.Payload != tt.want.Payload {
+				t.Fatalf("error payload = %+v, want %+v", got.Payload, tt.want.Payload)
+			}
+			if (client.kicked != "") != tt.wantKick {
+				t.Fatalf("kicked = %q, want kick %v", client.kicked, tt.wantKick)
+			}
+		})
+	}
+}
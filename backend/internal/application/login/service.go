@@ -1,7 +1,25 @@
 package login
 
 import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/joe_shih/slot-factory/internal/domain/currency"
 	"github.com/joe_shih/slot-factory/internal/domain/game"
+	"github.com/joe_shih/slot-factory/internal/domain/operator"
+)
+
+// 身份驗證失敗的原因，AuthClient 實作應以 %w 包裝這些錯誤，讓呼叫端可以用 errors.Is 區分。
+var (
+	// ErrTokenExpired 表示 token 已過期，玩家需要重新取得 token。
+	ErrTokenExpired = errors.New("token expired")
+	// ErrTokenInvalid 表示 token 無效（格式錯誤、被撤銷或不存在）。
+	ErrTokenInvalid = errors.New("token invalid")
+	// ErrAuthUnavailable 表示驗證服務暫時無法使用（逾時、5xx 或回應格式錯誤），可稍後重試。
+	ErrAuthUnavailable = errors.New("auth service unavailable")
+	// ErrCurrencyNotAllowed 表示使用者的幣種未在其營運商開放。
+	ErrCurrencyNotAllowed = errors.New("currency not allowed for operator")
 )
 
 // AuthClient 定義了外部身份驗證服務需要實現的介面。
@@ -10,12 +28,13 @@ type AuthClient interface {
 	// VerifyToken 驗證一個 token 並回傳使用者資料。
 	//
 	// Params:
+	//   - ctx: context.Context, 控制請求的取消與期限，玩家斷線時會被取消。
 	//   - token: string, 從客戶端傳來的驗證權杖。
 	//
 	// Returns:
 	//   - UserData: 包含使用者 ID 和名稱的資料結構。
 	//   - error: 如果驗證失敗，則回傳錯誤。
-	VerifyToken(token string) (UserData, error)
+	VerifyToken(ctx context.Context, token string) (UserData, error)
 }
 
 // UserData 是從身份驗證服務成功回傳的使用者資訊。
@@ -24,30 +43,80 @@ type UserData struct {
 	ID string
 	// Name 是使用者的名稱。
 	Name string
+	// Currency 是使用者錢包的幣種代碼，空字串表示使用預設幣種。
+	Currency string
+	// Language 是使用者的語系，例如 "zh-TW"。
+	Language string
+	// OperatorID 是使用者所屬營運商的識別碼。
+	OperatorID string
+	// Country 是使用者所在國家的 ISO 3166-1 alpha-2 代碼。
+	Country string
 }
 
 // Service 提供了身份驗證相關的 use case。
 type Service struct {
 	authClient AuthClient
+	currencies *currency.Registry
+	operators  *operator.Registry
 }
 
 // NewService 創建一個新的 Service 實例。
 //
 // Params:
 //   - client: AuthClient, 一個實現了 AuthClient 介面的外部服務客戶端。
+//   - currencies: *currency.Registry, 支援的幣種註冊表，用於解析使用者的幣種。
+//   - operators: *operator.Registry, 營運商註冊表，用於解析玩家所屬營運商的幣種與下注限制。傳入 nil 則不套用營運商規則。
 //
 // Returns:
 //   - *Service: 新的 Service 實例。
-func NewService(client AuthClient) *Service {
-	return &Service{authClient: client}
+func NewService(client AuthClient, currencies *currency.Registry, operators *operator.Registry) *Service {
+	return &Service{authClient: client, currencies: currencies, operators: operators}
 }
 
 // Authenticate 根據 token 驗證使用者身份，並回傳一個 domain 層的 Player 物件。
-func (s *Service) Authenticate(token string, conn game.GameClient) (*game.Player, error) {
-	data, err := s.authClient.VerifyToken(token)
+func (s *Service) Authenticate(ctx context.Context, token string, conn game.GameClient) (*game.Player, error) {
+	data, err := s.authClient.VerifyToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return s.NewPlayer(data, conn)
+}
+
+// NewPlayer 根據已驗證的使用者資料建立 Player，用於登入與以 session token 恢復連線。
+//
+// 啟用營運商模型時，會解析玩家所屬的營運商 (未帶營運商時使用預設營運商)、
+// 檢查幣種是否開放，並將營運商的下注限制套用到玩家的幣種上。
+func (s *Service) NewPlayer(data UserData, conn game.GameClient) (*game.Player, error) {
+	var op *operator.Operator
+	code := data.Currency
+	if s.operators != nil {
+		var err error
+		if op, err = s.operators.Resolve(data.OperatorID); err != nil {
+			return nil, err
+		}
+		if code == "" {
+			code = op.DefaultCurrency
+		}
+	}
+
+	cur, err := s.currencies.Resolve(code)
 	if err != nil {
 		return nil, err
 	}
-	player := game.NewPlayer(data.ID, data.Name, conn)
+	if op != nil {
+		if !op.AllowsCurrency(cur.Code) {
+			return nil, fmt.Errorf("%w: %s (%s)", ErrCurrencyNotAllowed, cur.Code, op.ID)
+		}
+		cur = op.ApplyLimits(cur)
+	}
+
+	player := game.NewPlayer(data.ID, data.Name, cur, conn)
+	player.Language = data.Language
+	player.OperatorID = data.OperatorID
+	player.Country = data.Country
+	if op != nil {
+		player.OperatorID = op.ID
+		player.Operator = op
+	}
 	return player, nil
 }
@@ -0,0 +1,116 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// defaultTTL 是未設定時 session token 的有效期限。
+const defaultTTL = 15 * time.Minute
+
+// ErrSessionNotFound 表示 session token 不存在、已過期或已被撤銷。
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session 是伺服器在登入成功後簽發的可恢復連線狀態。
+//
+// 斷線重連時，客戶端出示 Token 即可恢復同一個玩家與遊戲，不需要再向營運商驗證。
+type Session struct {
+	Token      string    `json:"token"`
+	PlayerID   string    `json:"playerID"`
+	Name       string    `json:"name"`
+	Currency   string    `json:"currency"`
+	Language   string    `json:"language,omitempty"`
+	OperatorID string    `json:"operatorID,omitempty"`
+	Country    string    `json:"country,omitempty"`
+	GameID     int       `json:"gameID"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// Store 定義了 session 的儲存介面。實作必須讓過期的 session 自動失效。
+type Store interface {
+	// Save 儲存 session，ttl 後自動失效。
+	Save(ctx context.Context, s Session, ttl time.Duration) error
+	// Get 取得 session，不存在或已過期時回傳 ErrSessionNotFound。
+	Get(ctx context.Context, token string) (Session, error)
+	// Take 原子地取得並刪除 session，同一個 token 併發呼叫時只有一個呼叫會成功，
+	// 其他呼叫 (以及不存在或已過期時) 回傳 ErrSessionNotFound。
+	Take(ctx context.Context, token string) (Session, error)
+	// Delete 刪除單一 session。
+	Delete(ctx context.Context, token string) error
+	// DeletePlayer 刪除營運商下玩家所有的 session，玩家 ID 只在營運商內唯一。
+	DeletePlayer(ctx context.Context, operatorID string, playerID string) error
+}
+
+// Service 負責簽發、恢復與撤銷 session token。
+type Service struct {
+	store Store
+	ttl   time.Duration
+}
+
+// NewService 建立一個 session 服務。
+//
+// 參數說明：
+//   - store: Store, session 的儲存實作，多個實體共用時應使用 Redis。
+//   - ttl: time.Duration, session token 的有效期限，0 表示使用預設值 15 分鐘。
+//
+// 回傳值：
+//   - *Service: 初始化完成的 session 服務。
+func NewService(store Store, ttl time.Duration) *Service {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	return &Service{store: store, ttl: ttl}
+}
+
+// Issue 為 s 產生新的 token 並儲存，回傳填入 Token 與 ExpiresAt 的 session。
+func (svc *Service) Issue(ctx context.Context, s Session) (Session, error) {
+	token, err := newToken()
+	if err != nil {
+		return Session{}, err
+	}
+	s.Token = token
+	s.ExpiresAt = time.Now().Add(svc.ttl)
+	if err := svc.store.Save(ctx, s, svc.ttl); err != nil {
+		return Session{}, fmt.Errorf("save session: %w", err)
+	}
+	return s, nil
+}
+
+// Peek 取得 token 對應的 session 但不使其失效，用於在恢復前檢查是否允許恢復。
+func (svc *Service) Peek(ctx context.Context, token string) (Session, error) {
+	return svc.store.Get(ctx, token)
+}
+
+// Resume 以舊 token 恢復 session，並輪替為新的 token。
+//
+// 舊 token 以 Store.Take 原子地取出並失效，同一個 token 併發恢復時只有一個會成功。
+func (svc *Service) Resume(ctx context.Context, token string) (Session, error) {
+	s, err := svc.store.Take(ctx, token)
+	if err != nil {
+		return Session{}, err
+	}
+	return svc.Issue(ctx, s)
+}
+
+// Revoke 撤銷單一 token (登出)。
+func (svc *Service) Revoke(ctx context.Context, token string) error {
+	return svc.store.Delete(ctx, token)
+}
+
+// RevokePlayer 撤銷營運商下玩家所有的 token (被踢線)。
+func (svc *Service) RevokePlayer(ctx context.Context, operatorID string, playerID string) error {
+	return svc.store.DeletePlayer(ctx, operatorID, playerID)
+}
+
+// newToken 產生 256 位元的隨機 token。
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate session token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
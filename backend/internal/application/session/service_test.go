@@ -0,0 +1,50 @@
+package session_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sessionStore "github.com/joe_shih/slot-factory/internal/adapter/session"
+	"github.com/joe_shih/slot-factory/internal/application/session"
+)
+
+func TestResumeConsumesTokenOnce(t *testing.T) {
+	svc := session.NewService(sessionStore.NewMemoryStore(), time.Minute)
+	ctx := context.Background()
+	issued, err := svc.Issue(ctx, session.Session{PlayerID: "p1", GameID: 1000})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		resumed  atomic.Int32
+		notFound atomic.Int32
+	)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := svc.Resume(ctx, issued.Token)
+			switch {
+			case err == nil:
+				resumed.Add(1)
+				if s.Token == issued.Token || s.PlayerID != "p1" {
+					t.Errorf("unexpected resumed session: %+v", s)
+				}
+			case errors.Is(err, session.ErrSessionNotFound):
+				notFound.Add(1)
+			default:
+				t.Errorf("Resume: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if resumed.Load() != 1 || notFound.Load() != 15 {
+		t.Fatalf("resumed %d, not found %d; want 1 and 15", resumed.Load(), notFound.Load())
+	}
+}
@@ -0,0 +1,217 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/joe_shih/slot-factory/internal/domain/currency"
+	"github.com/joe_shih/slot-factory/internal/domain/operator"
+	"github.com/shopspring/decimal"
+)
+
+// SpendOrder 決定扣款時現金與紅利的使用順序。
+type SpendOrder string
+
+const (
+	// SpendBonusFirst 先扣紅利，不足的部分再扣現金。
+	SpendBonusFirst SpendOrder = "bonus_first"
+	// SpendCashFirst 先扣現金，不足的部分再扣紅利。
+	SpendCashFirst SpendOrder = "cash_first"
+)
+
+// BonusStatus 代表紅利的狀態。
+type BonusStatus string
+
+const (
+	// BonusActive 紅利仍在流水 (Wagering) 進行中，不可提領。
+	BonusActive BonusStatus = "active"
+	// BonusCompleted 流水已達標，剩餘紅利已轉為現金。
+	BonusCompleted BonusStatus = "completed"
+	// BonusDepleted 流水未達標前紅利已用盡。
+	BonusDepleted BonusStatus = "depleted"
+)
+
+// Bonus 代表一筆發放給玩家的紅利與其流水要求。
+type Bonus struct {
+	ID               int64           `json:"id"`
+	OperatorID       string          `json:"operatorID"`
+	PlayerID         string          `json:"playerID"`
+	Currency         string          `json:"currency"`
+	Granted          decimal.Decimal `json:"granted"`
+	Balance          decimal.Decimal `json:"balance"`
+	WageringRequired decimal.Decimal `json:"wageringRequired"`
+	Wagered          decimal.Decimal `json:"wagered"`
+	Status           BonusStatus     `json:"status"`
+	CreatedAt        time.Time       `json:"createdAt"`
+	UpdatedAt        time.Time       `json:"updatedAt"`
+}
+
+// BonusStore 定義了紅利的持久化介面。
+// 玩家 ID 只在營運商內唯一，紅利帳一律以營運商 ID、玩家 ID 與幣種識別。
+type BonusStore interface {
+	// ListActive 回傳玩家指定幣種所有進行中的紅利，依發放時間由舊到新排序。只用於查詢，不可據此修改紅利。
+	ListActive(ctx context.Context, operatorID string, playerID string, currency string) ([]*Bonus, error)
+	// Create 新增一筆紅利，成功後會填入 ID。
+	Create(ctx context.Context, bonus *Bonus) error
+	// WithLock 鎖定玩家指定幣種的紅利帳後執行 fn，fn 透過 BonusTx 讀寫紅利與尚未結算的下注。
+	// 多個服務實體共用同一個儲存時，鎖必須跨實體生效 (例如資料庫的列鎖)，避免紅利被重複使用。
+	// fn 回傳錯誤時，fn 內的寫入不保證被保留。
+	WithLock(ctx context.Context, operatorID string, playerID string, currency string, fn func(tx BonusTx) error) error
+}
+
+// BonusTx 是 BonusStore.WithLock 鎖定期間對單一玩家、單一幣種紅利帳的操作。
+type BonusTx interface {
+	// ListActive 回傳所有進行中的紅利，依發放時間由舊到新排序。
+	ListActive(ctx context.Context) ([]*Bonus, error)
+	// Update 更新紅利的餘額、流水進度與狀態。
+	Update(ctx context.Context, bonus *Bonus) error
+	// Stake 回傳尚未結算的下注，沒有時回傳零值。
+	Stake(ctx context.Context) (Stake, error)
+	// SetStake 覆寫尚未結算的下注。
+	SetStake(ctx context.Context, stake Stake) error
+}
+
+// BonusProvider 提供了紅利發放的介面，用於 API 服務層。
+type BonusProvider interface {
+	GrantBonus(ctx context.Context, playerID string, currency string, amount decimal.Decimal, wageringMultiplier decimal.Decimal) (*Bonus, *PaymentError)
+}
+
+// Balances 是玩家現金與紅利的餘額明細。
+type Balances struct {
+	Cash  decimal.Decimal `json:"cash"`
+	Bonus decimal.Decimal `json:"bonus"`
+	Total decimal.Decimal `json:"total"`
+}
+
+// Stake 記錄尚未結算的下注中，由紅利支付的部分，用於將派彩按比例分回紅利與現金。
+//
+// 多人遊戲的下注與派彩相隔一局，期間玩家可能轉到其他服務實體，因此由 BonusStore 保存。
+type Stake struct {
+	Total decimal.Decimal `json:"total"`
+	Bonus decimal.Decimal `json:"bonus"`
+}
+
+// BonusLedger 負責紅利子餘額的計算：扣款分配、流水進度與派彩分配。
+//
+// 現金仍由外部錢包 (Payment) 管理，紅利與尚未結算的下注則由 BonusStore 保存。
+// 同一位玩家同一幣種的操作透過 BonusStore.WithLock 序列化，避免併發扣款時紅利被重複使用。
+type BonusLedger struct {
+	store           BonusStore
+	order           SpendOrder
+	currencies      *currency.Registry
+	defaultOperator string
+}
+
+// NewBonusLedger 建立一個紅利帳本。
+//
+// 參數說明：
+//   - store: BonusStore, 紅利的持久化實作。
+//   - order: SpendOrder, 扣款時現金與紅利的使用順序，空字串時預設為先扣紅利。
+//   - currencies: *currency.Registry, 用於取得幣種精度以分配派彩。
+//   - defaultOperator: string, context 沒有營運商時使用的營運商 ID，需與錢包路由 (OperatorRouter) 的預設營運商一致。
+//
+// 回傳值：
+//   - *BonusLedger: 初始化完成的紅利帳本。
+func NewBonusLedger(store BonusStore, order SpendOrder, currencies *currency.Registry, defaultOperator string) *BonusLedger {
+	if order == "" {
+		order = SpendBonusFirst
+	}
+	return &BonusLedger{
+		store:           store,
+		order:           order,
+		currencies:      currencies,
+		defaultOperator: defaultOperator,
+	}
+}
+
+// operatorOf 取得 context 中的營運商 ID，沒有時使用預設營運商，並回傳帶有營運商 ID 的 context，
+// 讓紅利帳與現金錢包路由到同一個營運商。
+func (l *BonusLedger) operatorOf(ctx context.Context) (context.Context, string) {
+	if id, ok := operator.IDFromContext(ctx); ok {
+		return ctx, id
+	}
+	return operator.NewContext(ctx, l.defaultOperator), l.defaultOperator
+}
+
+// balanceOf 計算進行中紅利的總餘額。
+func balanceOf(bonuses []*Bonus) decimal.Decimal {
+	total := decimal.Zero
+	for _, b := range bonuses {
+		total = total.Add(b.Balance)
+	}
+	return total
+}
+
+// split 根據扣款順序，將下注金額拆成現金與紅利兩部分。
+// cashBalance 只在先扣現金時使用。
+func (l *BonusLedger) split(amount, bonusBalance, cashBalance decimal.Decimal) (fromCash, fromBonus decimal.Decimal, err *PaymentError) {
+	if l.order == SpendCashFirst {
+		fromCash = decimal.Min(cashBalance, amount)
+		fromBonus = amount.Sub(fromCash)
+		if fromBonus.GreaterThan(bonusBalance) {
+			return decimal.Zero, decimal.Zero, &PaymentError{Code: 400, Message: "balance is not enough"}
+		}
+		return fromCash, fromBonus, nil
+	}
+	fromBonus = decimal.Min(bonusBalance, amount)
+	return amount.Sub(fromBonus), fromBonus, nil
+}
+
+// consume 從最舊的紅利開始扣除 amount，並將下注 wager 計入流水進度：
+// 由紅利支付的部分計入實際支付的各筆紅利，由現金支付的部分 (wager - amount) 計入最舊的紅利。
+func (l *BonusLedger) consume(bonuses []*Bonus, amount decimal.Decimal, wager decimal.Decimal) {
+	if len(bonuses) == 0 {
+		return
+	}
+	if fromCash := wager.Sub(amount); fromCash.IsPositive() {
+		bonuses[0].Wagered = bonuses[0].Wagered.Add(fromCash)
+	}
+	for _, b := range bonuses {
+		if !amount.IsPositive() {
+			return
+		}
+		used := decimal.Min(b.Balance, amount)
+		b.Balance = b.Balance.Sub(used)
+		b.Wagered = b.Wagered.Add(used)
+		amount = amount.Sub(used)
+	}
+}
+
+// winShare 依下注中紅利支付的比例，計算派彩中應回到紅利的部分。
+func (l *BonusLedger) winShare(cur string, win decimal.Decimal, s Stake) decimal.Decimal {
+	if !win.IsPositive() || !s.Bonus.IsPositive() || !s.Total.IsPositive() {
+		return decimal.Zero
+	}
+	share := win.Mul(s.Bonus).Div(s.Total)
+	if c, err := l.currencies.Resolve(cur); err == nil {
+		return c.Round(share)
+	}
+	return share
+}
+
+// settle 在派彩後檢查紅利狀態：用盡的紅利標記為 depleted，
+// 並回傳流水已達標、餘額應轉為現金的紅利（此時尚未修改達標紅利的狀態）。
+func (l *BonusLedger) settle(bonuses []*Bonus) (completed []*Bonus) {
+	for _, b := range bonuses {
+		switch {
+		case b.Wagered.GreaterThanOrEqual(b.WageringRequired):
+			completed = append(completed, b)
+		case !b.Balance.IsPositive():
+			b.Status = BonusDepleted
+		}
+	}
+	return completed
+}
+
+// save 將紅利的變更寫回鎖定中的紅利帳。
+func (l *BonusLedger) save(ctx context.Context, tx BonusTx, bonuses []*Bonus) error {
+	now := time.Now()
+	for _, b := range bonuses {
+		b.UpdatedAt = now
+		if err := tx.Update(ctx, b); err != nil {
+			return fmt.Errorf("update bonus %d: %w", b.ID, err)
+		}
+	}
+	return nil
+}
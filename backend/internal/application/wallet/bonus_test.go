@@ -0,0 +1,168 @@
+package wallet_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/joe_shih/slot-factory/internal/adapter/wallet/bonus"
+	"github.com/joe_shih/slot-factory/internal/adapter/wallet/mock"
+	"github.com/joe_shih/slot-factory/internal/application/wallet"
+	"github.com/joe_shih/slot-factory/internal/config"
+	"github.com/joe_shih/slot-factory/internal/domain/currency"
+	"github.com/joe_shih/slot-factory/internal/domain/operator"
+	"github.com/shopspring/decimal"
+)
+
+// newBonusService 建立一個使用記憶體錢包與記憶體紅利帳的錢包服務，玩家的起始現金為 cash。
+func newBonusService(t *testing.T, cash string) (*wallet.Service, *bonus.MemoryStore) {
+	t.Helper()
+	payment, err := mock.NewPayment(config.MockWalletConfig{StartingBalances: map[string]string{"TWD": cash}})
+	if err != nil {
+		t.Fatalf("mock.NewPayment: %v", err)
+	}
+	store := bonus.NewMemoryStore()
+	ledger := wallet.NewBonusLedger(store, wallet.SpendBonusFirst, currency.DefaultRegistry(), "default")
+	return wallet.NewService(slog.New(slog.NewTextHandler(io.Discard, nil)), payment, ledger), store
+}
+
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func TestBonusConcurrentDebitsDoNotDoubleSpend(t *testing.T) {
+	svc, _ := newBonusService(t, "0")
+	ctx := context.Background()
+	if _, err := svc.GrantBonus(ctx, "p1", "TWD", dec("10"), dec("10")); err != nil {
+		t.Fatalf("GrantBonus: %v", err)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.Debit(ctx, "p1", "TWD", dec("10")); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if succeeded != 1 {
+		t.Fatalf("%d debits succeeded against a single 10 TWD bonus, want 1", succeeded)
+	}
+}
+
+func TestBonusWageringSpreadsAcrossFundingBonuses(t *testing.T) {
+	svc, store := newBonusService(t, "100")
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := svc.GrantBonus(ctx, "p1", "TWD", dec("5"), dec("10")); err != nil {
+			t.Fatalf("GrantBonus: %v", err)
+		}
+	}
+
+	if _, err := svc.Debit(ctx, "p1", "TWD", dec("12")); err != nil {
+		t.Fatalf("Debit: %v", err)
+	}
+	bonuses, _ := store.ListActive(ctx, "default", "p1", "TWD")
+	if len(bonuses) != 2 {
+		t.Fatalf("got %d active bonuses, want 2", len(bonuses))
+	}
+	// 兩筆紅利各支付 5，現金支付的 2 計入最舊的紅利
+	if !bonuses[0].Wagered.Equal(dec("7")) || !bonuses[1].Wagered.Equal(dec("5")) {
+		t.Fatalf("wagered = %s, %s; want 7, 5", bonuses[0].Wagered, bonuses[1].Wagered)
+	}
+}
+
+func TestBonusPendingStakeSplitsLaterCredit(t *testing.T) {
+	svc, store := newBonusService(t, "10")
+	ctx := context.Background()
+	if _, err := svc.GrantBonus(ctx, "p1", "TWD", dec("10"), dec("10")); err != nil {
+		t.Fatalf("GrantBonus: %v", err)
+	}
+	// 下注 10 全部由紅利支付，之後的派彩應全數回到紅利
+	if _, err := svc.Debit(ctx, "p1", "TWD", dec("10")); err != nil {
+		t.Fatalf("Debit: %v", err)
+	}
+	if _, err := svc.Credit(ctx, "p1", "TWD", dec("30")); err != nil {
+		t.Fatalf("Credit: %v", err)
+	}
+	balances, err := svc.GetBalances(ctx, "p1", "TWD")
+	if err != nil {
+		t.Fatalf("GetBalances: %v", err)
+	}
+	if !balances.Cash.Equal(dec("10")) || !balances.Bonus.Equal(dec("30")) {
+		t.Fatalf("balances = %+v, want cash 10, bonus 30", balances)
+	}
+	bonuses, _ := store.ListActive(ctx, "default", "p1", "TWD")
+	if len(bonuses) != 1 || !bonuses[0].Wagered.Equal(dec("10")) {
+		t.Fatalf("unexpected bonuses after settle: %+v", bonuses)
+	}
+}
+
+func TestGrantBonusValidatesCurrency(t *testing.T) {
+	svc, _ := newBonusService(t, "0")
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		currency string
+		amount   string
+		wantErr  bool
+	}{
+		{name: "ok", currency: "TWD", amount: "10.5"},
+		{name: "lower case code", currency: "twd", amount: "1"},
+		{name: "empty currency", currency: "", amount: "1", wantErr: true},
+		{name: "unknown currency", currency: "XYZ", amount: "1", wantErr: true},
+		{name: "exceeds precision", currency: "TWD", amount: "1.005", wantErr: true},
+		{name: "crypto precision", currency: "BTC", amount: "0.00000001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := svc.GrantBonus(ctx, "p1", tt.currency, dec(tt.amount), dec("1"))
+			if tt.wantErr {
+				if err == nil || err.Code != 400 {
+					t.Fatalf("got %v, want 400", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GrantBonus: %v", err)
+			}
+			if b.Currency != "TWD" && b.Currency != "BTC" {
+				t.Fatalf("currency not normalised: %q", b.Currency)
+			}
+		})
+	}
+}
+
+func TestBonusLedgerIsScopedByOperator(t *testing.T) {
+	svc, _ := newBonusService(t, "0")
+	opA := operator.NewContext(context.Background(), "op-a")
+	if _, err := svc.GrantBonus(opA, "p1", "TWD", dec("10"), dec("1")); err != nil {
+		t.Fatalf("GrantBonus: %v", err)
+	}
+	// 另一個營運商的同名玩家不能使用 op-a 玩家的紅利
+	balances, err := svc.GetBalances(operator.NewContext(context.Background(), "op-b"), "p1", "TWD")
+	if err != nil {
+		t.Fatalf("GetBalances: %v", err)
+	}
+	if !balances.Bonus.IsZero() {
+		t.Fatalf("op-b bonus = %s, want 0", balances.Bonus)
+	}
+	balances, err = svc.GetBalances(opA, "p1", "TWD")
+	if err != nil {
+		t.Fatalf("GetBalances: %v", err)
+	}
+	if !balances.Bonus.Equal(dec("10")) {
+		t.Fatalf("op-a bonus = %s, want 10", balances.Bonus)
+	}
+}
@@ -0,0 +1,145 @@
+package wallet
+
+import (
+	"context"
+	"sort"
+
+	"github.com/joe_shih/slot-factory/internal/domain/operator"
+	"github.com/shopspring/decimal"
+)
+
+// OperatorRouter 是依營運商路由的 Payment 實作。
+//
+// 每個營運商有各自的錢包端點與憑證，OperatorRouter 依 context 中的營運商 ID
+// (operator.NewContext) 選擇對應的 Payment；context 沒有營運商時使用預設營運商。
+// 轉交給底層 Payment 的 context 一律帶有營運商 ID。
+type OperatorRouter struct {
+	payments  map[string]Payment
+	defaultID string
+}
+
+var (
+	_ Payment        = (*OperatorRouter)(nil)
+	_ HealthProvider = (*OperatorRouter)(nil)
+)
+
+// NewOperatorRouter 建立一個依營運商路由的錢包。
+//
+// 參數說明：
+//   - defaultID: string, context 沒有營運商時使用的營運商 ID。
+//   - payments: map[string]Payment, key 為營運商 ID，value 為該營運商的錢包實作。
+//
+// 回傳值：
+//   - *OperatorRouter: 初始化完成的路由錢包。
+func NewOperatorRouter(defaultID string, payments map[string]Payment) *OperatorRouter {
+	return &OperatorRouter{payments: payments, defaultID: defaultID}
+}
+
+// route 取得 context 對應的營運商錢包，並回傳帶有營運商 ID 的 context。
+func (r *OperatorRouter) route(ctx context.Context) (context.Context, string, Payment, *PaymentError) {
+	id, ok := operator.IDFromContext(ctx)
+	if !ok {
+		id = r.defaultID
+		ctx = operator.NewContext(ctx, id)
+	}
+	p, exists := r.payments[id]
+	if !exists {
+		return ctx, id, nil, &PaymentError{Code: 400, Message: "Unknown operator: " + id}
+	}
+	return ctx, id, p, nil
+}
+
+func (r *OperatorRouter) GetBalance(ctx context.Context, playerID string, currency string) (decimal.Decimal, *PaymentError) {
+	ctx, _, p, err := r.route(ctx)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return p.GetBalance(ctx, playerID, currency)
+}
+
+func (r *OperatorRouter) Debit(ctx context.Context, playerID string, currency string, amount decimal.Decimal) (decimal.Decimal, *PaymentError) {
+	ctx, _, p, err := r.route(ctx)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return p.Debit(ctx, playerID, currency, amount)
+}
+
+func (r *OperatorRouter) Credit(ctx context.Context, playerID string, currency string, amount decimal.Decimal) (decimal.Decimal, *PaymentError) {
+	ctx, _, p, err := r.route(ctx)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return p.Credit(ctx, playerID, currency, amount)
+}
+
+func (r *OperatorRouter) DebitAndCredit(ctx context.Context, playerID string, currency string, debitAmount, creditAmount decimal.Decimal) (decimal.Decimal, *PaymentError) {
+	ctx, _, p, err := r.route(ctx)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return p.DebitAndCredit(ctx, playerID, currency, debitAmount, creditAmount)
+}
+
+// GetHistory 回傳玩家的交易紀錄。
+//
+// context 帶有營運商時只查詢該營運商；否則查詢所有營運商，依時間由新到舊合併後取前 limit 筆。
+func (r *OperatorRouter) GetHistory(ctx context.Context, playerID string, limit int) ([]TransactionRecord, *PaymentError) {
+	if id, ok := operator.IDFromContext(ctx); ok {
+		p, exists := r.payments[id]
+		if !exists {
+			return nil, &PaymentError{Code: 400, Message: "Unknown operator: " + id}
+		}
+		return r.historyOf(ctx, id, p, playerID, limit)
+	}
+
+	var all []TransactionRecord
+	for id, p := range r.payments {
+		records, err := r.historyOf(operator.NewContext(ctx, id), id, p, playerID, limit)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, records...)
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].CreatedAt.After(all[j].CreatedAt) })
+	if limit > 0 && len(all) > limit {
+		all = all[:limit]
+	}
+	return all, nil
+}
+
+// historyOf 查詢單一營運商的交易紀錄，並填入營運商 ID。
+func (r *OperatorRouter) historyOf(ctx context.Context, id string, p Payment, playerID string, limit int) ([]TransactionRecord, *PaymentError) {
+	records, err := p.GetHistory(ctx, playerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		if records[i].OperatorID == "" {
+			records[i].OperatorID = id
+		}
+	}
+	return records, nil
+}
+
+// Health 彙整所有營運商錢包的健康狀態，任一營運商不健康即視為不健康。
+func (r *OperatorRouter) Health() HealthStatus {
+	status := HealthStatus{
+		Healthy:   true,
+		State:     "ok",
+		Operators: make(map[string]HealthStatus, len(r.payments)),
+	}
+	for id, p := range r.payments {
+		s := HealthStatus{Healthy: true, State: "unknown"}
+		if hp, ok := p.(HealthProvider); ok {
+			s = hp.Health()
+		}
+		status.Operators[id] = s
+		status.InFlight += s.InFlight
+		if !s.Healthy {
+			status.Healthy = false
+			status.State = "degraded"
+		}
+	}
+	return status
+}
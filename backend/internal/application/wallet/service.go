@@ -1,9 +1,13 @@
 package wallet
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"time"
 
+	"github.com/joe_shih/slot-factory/internal/domain/requestid"
 	"github.com/shopspring/decimal"
 )
 
@@ -12,92 +16,442 @@ type PaymentError struct {
 	Message string
 }
 
+// 錢包層級的錯誤代碼，沿用 HTTP 狀態碼語意。
+const (
+	// CodeTooManyRequests 表示同時進行中的外部錢包請求已達上限，請求被快速拒絕。
+	CodeTooManyRequests = 429
+	// CodeCanceled 表示呼叫端已取消請求，例如玩家斷線或伺服器關閉。
+	CodeCanceled = 499
+	// CodeCircuitOpen 表示外部錢包斷路器開啟中，請求被快速拒絕。
+	CodeCircuitOpen = 503
+	// CodeDeadlineExceeded 表示請求超過呼叫端設定的期限。
+	CodeDeadlineExceeded = 504
+)
+
+// FromContextError 將 context 的取消或逾時錯誤轉換為對應的 PaymentError。
+// 如果 err 不是 context 相關的錯誤（包含 nil），則回傳 nil。
+func FromContextError(err error) *PaymentError {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return &PaymentError{Code: CodeCanceled, Message: "Request canceled"}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &PaymentError{Code: CodeDeadlineExceeded, Message: "Request deadline exceeded"}
+	default:
+		return nil
+	}
+}
+
+type transactionIDKey struct{}
+
+// WithTransactionID 回傳指定交易 ID 的 context，Payment 實作以此作為外部平台的冪等鍵，
+// 重試同一筆異動時帶上相同的 ID 就不會重複入帳。未指定時 Payment 實作每次呼叫都產生新的交易 ID。
+func WithTransactionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, transactionIDKey{}, id)
+}
+
+// TransactionIDFromContext 取得 WithTransactionID 指定的交易 ID，沒有時回傳空字串。
+func TransactionIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(transactionIDKey{}).(string)
+	return id
+}
+
+// HealthStatus 描述錢包底層依賴（例如外部錢包平台）的健康狀態。
+type HealthStatus struct {
+	Healthy             bool   `json:"healthy"`
+	State               string `json:"state"`
+	InFlight            int    `json:"inFlight"`
+	ConsecutiveFailures int    `json:"consecutiveFailures"`
+
+	// Operators 是各營運商錢包的健康狀態，只有依營運商路由時才會填入。
+	Operators map[string]HealthStatus `json:"operators,omitempty"`
+}
+
+// HealthProvider 定義了回報錢包健康狀態的介面，用於健康檢查端點。
+type HealthProvider interface {
+	Health() HealthStatus
+}
+
 // TransactionRecord 代表一筆錢包交易紀錄。
 type TransactionRecord struct {
 	ID              int64           `json:"id"`
+	OperatorID      string          `json:"operatorID,omitempty"`
 	PlayerID        string          `json:"playerID"`
+	Currency        string          `json:"currency"`
 	Amount          decimal.Decimal `json:"amount"`
 	TransactionType string          `json:"transactionType"`
 	BalanceAfter    decimal.Decimal `json:"balanceAfter"`
-	CreatedAt       time.Time       `json:"createdAt"`
+	// RequestID 是觸發交易的客戶端請求 ID，客戶端未提供時為空字串。
+	RequestID string    `json:"requestID,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
 }
 
 // HistoryProvider 定義了讀取交易歷史的介面，用於 API 服務層。
+//
+// ctx 帶有營運商 ID (operator.NewContext) 時只回傳該營運商的紀錄。
 type HistoryProvider interface {
-	GetHistory(playerID string, limit int) ([]TransactionRecord, *PaymentError)
+	GetHistory(ctx context.Context, playerID string, limit int) ([]TransactionRecord, *PaymentError)
 }
 
 // Payment 定義了完整錢包含業務邏輯介面（包含寫入）。
 type Payment interface {
 	HistoryProvider
-	// GetBalance 取得玩家餘額。
-	GetBalance(playerID string) (balance decimal.Decimal, err *PaymentError)
+	// GetBalance 取得玩家指定幣種的餘額。
+	GetBalance(ctx context.Context, playerID string, currency string) (balance decimal.Decimal, err *PaymentError)
 
 	// Debit 扣款。
 	// 如果餘額不足，返回錯誤。
-	Debit(playerID string, amount decimal.Decimal) (newBalance decimal.Decimal, err *PaymentError)
+	Debit(ctx context.Context, playerID string, currency string, amount decimal.Decimal) (newBalance decimal.Decimal, err *PaymentError)
 
 	// Credit 加款。
-	Credit(playerID string, amount decimal.Decimal) (newBalance decimal.Decimal, err *PaymentError)
+	Credit(ctx context.Context, playerID string, currency string, amount decimal.Decimal) (newBalance decimal.Decimal, err *PaymentError)
 
 	// DebitAndCredit 扣款和加款。
-	DebitAndCredit(playerID string, debitAmount decimal.Decimal, creditAmount decimal.Decimal) (newBalance decimal.Decimal, err *PaymentError)
+	DebitAndCredit(ctx context.Context, playerID string, currency string, debitAmount decimal.Decimal, creditAmount decimal.Decimal) (newBalance decimal.Decimal, err *PaymentError)
 }
 
+// Service 是錢包的 use case 層。
+//
+// Context 的處理原則：
+//   - 讀取類操作 (查詢餘額、歷史) 直接使用呼叫端的 ctx，玩家斷線或伺服器關閉時立即取消。
+//   - 會異動金額的操作 (扣款、派彩) 只在送出前檢查 ctx；一旦送出就與取消訊號脫鉤
+//     (保留 ctx 內的值，例如 trace ID)，交由底層逾時控制，避免外部平台已扣款但本地不知道結果。
 type Service struct {
 	payment Payment
+	bonus   *BonusLedger
 	logger  *slog.Logger
 }
 
+// 確保 Service 在編譯時期就實現了 API 層需要的介面。
+var (
+	_ HistoryProvider = (*Service)(nil)
+	_ HealthProvider  = (*Service)(nil)
+	_ BonusProvider   = (*Service)(nil)
+)
+
 // NewService 建立一個新的錢包服務實例。
-func NewService(logger *slog.Logger, payment Payment) *Service {
+//
+// 參數說明：
+//   - logger: *slog.Logger, 用於記錄日誌的 Logger 實例。
+//   - payment: Payment, 管理現金餘額的錢包實作。
+//   - bonus: *BonusLedger, 紅利帳本，傳入 nil 則停用紅利功能，所有扣款皆使用現金。
+//
+// 回傳值：
+//   - *Service: 初始化完成的錢包服務。
+func NewService(logger *slog.Logger, payment Payment, bonus *BonusLedger) *Service {
 	return &Service{
 		payment: payment,
+		bonus:   bonus,
 		logger:  logger.With("component", "wallet_service"),
 	}
 }
 
-func (s *Service) GetBalance(playerID string) (decimal.Decimal, *PaymentError) {
-	balance, err := s.payment.GetBalance(playerID)
+// GetBalances 取得玩家現金與紅利的餘額明細。
+func (s *Service) GetBalances(ctx context.Context, playerID string, currency string) (Balances, *PaymentError) {
+	cash, err := s.GetBalance(ctx, playerID, currency)
 	if err != nil {
-		s.logger.Error("get balance failed", "playerID", playerID, "error", err)
+		return Balances{}, err
+	}
+	bonus := decimal.Zero
+	if s.bonus != nil {
+		_, operatorID := s.bonus.operatorOf(ctx)
+		bonuses, storeErr := s.bonus.store.ListActive(ctx, operatorID, playerID, currency)
+		if storeErr != nil {
+			s.logger.Error("list bonuses failed", "playerID", playerID, "currency", currency, "error", storeErr)
+			return Balances{}, &PaymentError{Code: 500, Message: "Bonus store error"}
+		}
+		bonus = balanceOf(bonuses)
+	}
+	return Balances{Cash: cash, Bonus: bonus, Total: cash.Add(bonus)}, nil
+}
+
+func (s *Service) GetBalance(ctx context.Context, playerID string, currency string) (decimal.Decimal, *PaymentError) {
+	balance, err := s.payment.GetBalance(ctx, playerID, currency)
+	if err != nil {
+		s.logger.Error("get balance failed", "playerID", playerID, "currency", currency, "error", err)
 		return decimal.Zero, err
 	}
 	return balance, nil
 }
 
-func (s *Service) Debit(playerID string, amount decimal.Decimal) (decimal.Decimal, *PaymentError) {
-	newBalance, err := s.payment.Debit(playerID, amount)
+// Debit 扣款。啟用紅利時會依扣款順序分配現金與紅利，並回傳現金與紅利的合計餘額。
+func (s *Service) Debit(ctx context.Context, playerID string, currency string, amount decimal.Decimal) (decimal.Decimal, *PaymentError) {
+	if err := FromContextError(ctx.Err()); err != nil {
+		return decimal.Zero, err
+	}
+	ctx = context.WithoutCancel(ctx)
+	if s.bonus != nil {
+		return s.debitAndCreditWithBonus(ctx, playerID, currency, amount, decimal.Zero, false)
+	}
+	newBalance, err := s.payment.Debit(ctx, playerID, currency, amount)
 	if err != nil {
-		s.logger.Error("debit failed", "playerID", playerID, "amount", amount, "error", err)
+		s.logger.Error("debit failed", "playerID", playerID, "requestID", requestid.FromContext(ctx), "currency", currency, "amount", amount, "error", err)
 		return newBalance, err
 	}
 	return newBalance, nil
 }
 
-func (s *Service) Credit(playerID string, amount decimal.Decimal) (decimal.Decimal, *PaymentError) {
-	newBalance, err := s.payment.Credit(playerID, amount)
+// Credit 加款（派彩）。啟用紅利時，派彩會依先前下注中紅利支付的比例分回紅利，並回傳合計餘額。
+func (s *Service) Credit(ctx context.Context, playerID string, currency string, amount decimal.Decimal) (decimal.Decimal, *PaymentError) {
+	if err := FromContextError(ctx.Err()); err != nil {
+		return decimal.Zero, err
+	}
+	ctx = context.WithoutCancel(ctx)
+	if s.bonus != nil {
+		return s.creditWithBonus(ctx, playerID, currency, amount)
+	}
+	newBalance, err := s.payment.Credit(ctx, playerID, currency, amount)
 	if err != nil {
-		s.logger.Error("credit failed", "playerID", playerID, "amount", amount, "error", err)
+		s.logger.Error("credit failed", "playerID", playerID, "requestID", requestid.FromContext(ctx), "currency", currency, "amount", amount, "error", err)
 		return decimal.Zero, err
 	}
 	return newBalance, nil
 }
 
-func (s *Service) DebitAndCredit(playerID string, debitAmount decimal.Decimal, creditAmount decimal.Decimal) (decimal.Decimal, *PaymentError) {
-	newBalance, err := s.payment.DebitAndCredit(playerID, debitAmount, creditAmount)
+// DebitAndCredit 扣款並派彩。啟用紅利時回傳現金與紅利的合計餘額。
+func (s *Service) DebitAndCredit(ctx context.Context, playerID string, currency string, debitAmount decimal.Decimal, creditAmount decimal.Decimal) (decimal.Decimal, *PaymentError) {
+	if err := FromContextError(ctx.Err()); err != nil {
+		return decimal.Zero, err
+	}
+	ctx = context.WithoutCancel(ctx)
+	if s.bonus != nil {
+		return s.debitAndCreditWithBonus(ctx, playerID, currency, debitAmount, creditAmount, true)
+	}
+	newBalance, err := s.payment.DebitAndCredit(ctx, playerID, currency, debitAmount, creditAmount)
 	if err != nil {
-		s.logger.Error("debit and credit failed", "playerID", playerID, "debitAmount", debitAmount, "creditAmount", creditAmount, "error", err)
+		s.logger.Error("debit and credit failed", "playerID", playerID, "requestID", requestid.FromContext(ctx), "currency", currency, "debitAmount", debitAmount, "creditAmount", creditAmount, "error", err)
 		return newBalance, err
 	}
 	return newBalance, nil
 }
 
-func (s *Service) GetHistory(playerID string, limit int) ([]TransactionRecord, *PaymentError) {
-	records, err := s.payment.GetHistory(playerID, limit)
+// Health 回報底層錢包實作的健康狀態。
+// 如果底層實作沒有提供健康檢查，則一律視為健康。
+func (s *Service) Health() HealthStatus {
+	if hp, ok := s.payment.(HealthProvider); ok {
+		return hp.Health()
+	}
+	return HealthStatus{Healthy: true, State: "unknown"}
+}
+
+func (s *Service) GetHistory(ctx context.Context, playerID string, limit int) ([]TransactionRecord, *PaymentError) {
+	records, err := s.payment.GetHistory(ctx, playerID, limit)
 	if err != nil {
 		s.logger.Error("get history failed", "playerID", playerID, "error", err)
 		return nil, err
 	}
 	return records, nil
 }
+
+// GrantBonus 發放一筆紅利給玩家。
+//
+// 參數說明：
+//   - playerID: string, 玩家 ID。
+//   - currency: string, 紅利幣種。
+//   - amount: decimal.Decimal, 紅利金額，必須為正數。
+//   - wageringMultiplier: decimal.Decimal, 流水倍數，流水要求 = 紅利金額 × 倍數。
+//
+// 回傳值：
+//   - *Bonus: 建立完成的紅利。
+//   - *PaymentError: 如果未啟用紅利、幣種不支援、金額超出幣種精度或參數錯誤，則返回錯誤。
+func (s *Service) GrantBonus(ctx context.Context, playerID string, currency string, amount decimal.Decimal, wageringMultiplier decimal.Decimal) (*Bonus, *PaymentError) {
+	if err := FromContextError(ctx.Err()); err != nil {
+		return nil, err
+	}
+	ctx = context.WithoutCancel(ctx)
+	if s.bonus == nil {
+		return nil, &PaymentError{Code: 400, Message: "Bonus is not enabled"}
+	}
+	if !amount.IsPositive() || wageringMultiplier.IsNegative() {
+		return nil, &PaymentError{Code: 400, Message: "invalid bonus amount or wagering multiplier"}
+	}
+	cur, resolveErr := s.bonus.currencies.Resolve(currency)
+	if currency == "" || resolveErr != nil {
+		return nil, &PaymentError{Code: 400, Message: fmt.Sprintf("unsupported currency: %s", currency)}
+	}
+	if !amount.Equal(amount.Truncate(cur.Precision)) {
+		return nil, &PaymentError{Code: 400, Message: fmt.Sprintf("bonus amount exceeds %s precision of %d decimals", cur.Code, cur.Precision)}
+	}
+	_, operatorID := s.bonus.operatorOf(ctx)
+	now := time.Now()
+	bonus := &Bonus{
+		OperatorID:       operatorID,
+		PlayerID:         playerID,
+		Currency:         cur.Code,
+		Granted:          amount,
+		Balance:          amount,
+		WageringRequired: cur.Round(amount.Mul(wageringMultiplier)),
+		Wagered:          decimal.Zero,
+		Status:           BonusActive,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if err := s.bonus.store.Create(ctx, bonus); err != nil {
+		s.logger.Error("grant bonus failed", "operatorID", operatorID, "playerID", playerID, "currency", currency, "amount", amount, "error", err)
+		return nil, &PaymentError{Code: 500, Message: "Bonus store error"}
+	}
+	s.logger.Info("bonus granted", "operatorID", operatorID, "playerID", playerID, "currency", currency, "amount", amount, "wageringRequired", bonus.WageringRequired)
+	return bonus, nil
+}
+
+// debitAndCreditWithBonus 在啟用紅利時處理扣款（settle 為 false）或扣款並派彩（settle 為 true）。
+//
+// 整個流程在紅利帳鎖定期間完成，其他請求 (包含其他服務實體) 必須等待外部錢包呼叫結束，紅利才不會被重複使用。
+func (s *Service) debitAndCreditWithBonus(ctx context.Context, playerID string, currency string, debitAmount, creditAmount decimal.Decimal, settle bool) (decimal.Decimal, *PaymentError) {
+	var (
+		balance decimal.Decimal
+		err     *PaymentError
+	)
+	ctx, operatorID := s.bonus.operatorOf(ctx)
+	lockErr := s.bonus.store.WithLock(ctx, operatorID, playerID, currency, func(tx BonusTx) error {
+		bonuses, storeErr := tx.ListActive(ctx)
+		if storeErr != nil {
+			s.logger.Error("list bonuses failed", "playerID", playerID, "currency", currency, "error", storeErr)
+			err = &PaymentError{Code: 500, Message: "Bonus store error"}
+			return storeErr
+		}
+		bonusBalance := balanceOf(bonuses)
+
+		cashBalance := decimal.Zero
+		if s.bonus.order == SpendCashFirst {
+			if cashBalance, err = s.GetBalance(ctx, playerID, currency); err != nil {
+				return nil
+			}
+		}
+		fromCash, fromBonus, splitErr := s.bonus.split(debitAmount, bonusBalance, cashBalance)
+		if splitErr != nil {
+			balance, err = cashBalance.Add(bonusBalance), splitErr
+			return nil
+		}
+
+		// 派彩依本次下注中紅利支付的比例分配
+		bonusWin := decimal.Zero
+		if settle {
+			bonusWin = s.bonus.winShare(currency, creditAmount, Stake{Total: debitAmount, Bonus: fromBonus})
+		}
+		cashWin := creditAmount.Sub(bonusWin)
+
+		// 現金部分交給外部錢包
+		var payErr *PaymentError
+		if settle {
+			cashBalance, payErr = s.payment.DebitAndCredit(ctx, playerID, currency, fromCash, cashWin)
+		} else if fromCash.IsPositive() {
+			cashBalance, payErr = s.payment.Debit(ctx, playerID, currency, fromCash)
+		} else {
+			cashBalance, payErr = s.payment.GetBalance(ctx, playerID, currency)
+		}
+		if payErr != nil {
+			s.logger.Error("debit failed", "playerID", playerID, "requestID", requestid.FromContext(ctx), "currency", currency, "cashAmount", fromCash, "bonusAmount", fromBonus, "error", payErr)
+			balance, err = cashBalance.Add(bonusBalance), payErr
+			return nil
+		}
+
+		// 紅利部分在本地帳本處理，外部錢包已經扣款，之後的錯誤只記錄不回傳給玩家
+		s.bonus.consume(bonuses, fromBonus, debitAmount)
+		if settle {
+			if bonusWin.IsPositive() && len(bonuses) > 0 {
+				bonuses[0].Balance = bonuses[0].Balance.Add(bonusWin)
+			}
+			cashBalance = s.releaseBonuses(ctx, playerID, currency, bonuses, cashBalance)
+		} else if stakeErr := s.addStake(ctx, tx, debitAmount, fromBonus); stakeErr != nil {
+			s.logger.Error("[ALARM] save pending stake failed after wallet debit", "playerID", playerID, "currency", currency, "error", stakeErr)
+		}
+		if saveErr := s.bonus.save(ctx, tx, bonuses); saveErr != nil {
+			s.logger.Error("[ALARM] save bonus failed after wallet debit", "playerID", playerID, "currency", currency, "error", saveErr)
+		}
+		balance = cashBalance.Add(balanceOf(bonuses))
+		return nil
+	})
+	if lockErr != nil && err == nil {
+		s.logger.Error("lock bonus ledger failed", "playerID", playerID, "currency", currency, "error", lockErr)
+		return decimal.Zero, &PaymentError{Code: 500, Message: "Bonus store error"}
+	}
+	return balance, err
+}
+
+// creditWithBonus 在啟用紅利時處理派彩，派彩依先前累計下注中紅利支付的比例分回紅利。
+func (s *Service) creditWithBonus(ctx context.Context, playerID string, currency string, amount decimal.Decimal) (decimal.Decimal, *PaymentError) {
+	var (
+		balance decimal.Decimal
+		err     *PaymentError
+	)
+	ctx, operatorID := s.bonus.operatorOf(ctx)
+	lockErr := s.bonus.store.WithLock(ctx, operatorID, playerID, currency, func(tx BonusTx) error {
+		bonuses, storeErr := tx.ListActive(ctx)
+		if storeErr != nil {
+			s.logger.Error("list bonuses failed", "playerID", playerID, "currency", currency, "error", storeErr)
+			err = &PaymentError{Code: 500, Message: "Bonus store error"}
+			return storeErr
+		}
+		stake, storeErr := tx.Stake(ctx)
+		if storeErr != nil {
+			s.logger.Error("load pending stake failed", "playerID", playerID, "currency", currency, "error", storeErr)
+			err = &PaymentError{Code: 500, Message: "Bonus store error"}
+			return storeErr
+		}
+
+		bonusWin := s.bonus.winShare(currency, amount, stake)
+		if len(bonuses) == 0 {
+			// 紅利已結束，派彩全數進入現金
+			bonusWin = decimal.Zero
+		}
+		cashBalance, payErr := s.payment.Credit(ctx, playerID, currency, amount.Sub(bonusWin))
+		if payErr != nil {
+			s.logger.Error("credit failed", "playerID", playerID, "requestID", requestid.FromContext(ctx), "currency", currency, "amount", amount, "error", payErr)
+			err = payErr
+			return nil
+		}
+
+		if bonusWin.IsPositive() {
+			bonuses[0].Balance = bonuses[0].Balance.Add(bonusWin)
+		}
+		cashBalance = s.releaseBonuses(ctx, playerID, currency, bonuses, cashBalance)
+		if stakeErr := tx.SetStake(ctx, Stake{}); stakeErr != nil {
+			s.logger.Error("[ALARM] clear pending stake failed after wallet credit", "playerID", playerID, "currency", currency, "error", stakeErr)
+		}
+		if saveErr := s.bonus.save(ctx, tx, bonuses); saveErr != nil {
+			s.logger.Error("[ALARM] save bonus failed after wallet credit", "playerID", playerID, "currency", currency, "error", saveErr)
+		}
+		balance = cashBalance.Add(balanceOf(bonuses))
+		return nil
+	})
+	if lockErr != nil && err == nil {
+		s.logger.Error("lock bonus ledger failed", "playerID", playerID, "currency", currency, "error", lockErr)
+		return decimal.Zero, &PaymentError{Code: 500, Message: "Bonus store error"}
+	}
+	return balance, err
+}
+
+// addStake 將一筆尚未結算的下注累計到鎖定中的紅利帳。
+func (s *Service) addStake(ctx context.Context, tx BonusTx, total, bonus decimal.Decimal) error {
+	stake, err := tx.Stake(ctx)
+	if err != nil {
+		return err
+	}
+	return tx.SetStake(ctx, Stake{Total: stake.Total.Add(total), Bonus: stake.Bonus.Add(bonus)})
+}
+
+// releaseBonuses 結算紅利狀態，並將流水達標的紅利逐筆轉入現金，回傳最新的現金餘額。
+//
+// 每筆轉入都以紅利 ID 產生自己的交易 ID，不會與同一請求的派彩被平台視為重複交易，
+// 重試時也會沿用相同的 ID 而不會重複入帳。轉入失敗的紅利維持 active，待下次結算時重試。
+func (s *Service) releaseBonuses(ctx context.Context, playerID string, currency string, bonuses []*Bonus, cashBalance decimal.Decimal) decimal.Decimal {
+	for _, b := range s.bonus.settle(bonuses) {
+		if b.Balance.IsPositive() {
+			newBalance, err := s.payment.Credit(WithTransactionID(ctx, releaseTransactionID(b)), playerID, currency, b.Balance)
+			if err != nil {
+				s.logger.Error("[ALARM] release bonus to cash failed", "playerID", playerID, "currency", currency, "bonusID", b.ID, "amount", b.Balance, "error", err)
+				continue
+			}
+			cashBalance = newBalance
+			s.logger.Info("bonus wagering completed", "playerID", playerID, "currency", currency, "bonusID", b.ID, "released", b.Balance)
+		}
+		b.Balance = decimal.Zero
+		b.Status = BonusCompleted
+	}
+	return cashBalance
+}
+
+// releaseTransactionID 回傳紅利轉入現金的交易 ID。
+func releaseTransactionID(b *Bonus) string {
+	return fmt.Sprintf("bonus-release-%d", b.ID)
+}
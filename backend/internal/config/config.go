@@ -2,7 +2,13 @@ package config
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
+	"github.com/joe_shih/slot-factory/internal/domain/currency"
+	"github.com/joe_shih/slot-factory/internal/domain/game"
+	"github.com/joe_shih/slot-factory/internal/domain/operator"
+	"github.com/shopspring/decimal"
 	"github.com/spf13/viper"
 )
 
@@ -11,23 +17,150 @@ type AdapterMode string
 const (
 	ModeMock AdapterMode = "mock"
 	ModeReal AdapterMode = "real"
+	// ModeJWT 在本地驗證營運商簽發的 JWT，不需要每次登入都呼叫營運商。
+	ModeJWT AdapterMode = "jwt"
 )
 
 // AuthConfig 包含驗證服務的設定。
 type AuthConfig struct {
 	Mode AdapterMode `mapstructure:"mode"`
+
+	// Verify 包含 real 模式下營運商 token 驗證端點的設定。
+	Verify AuthVerifyConfig `mapstructure:"verify"`
+
+	// JWT 包含 jwt 模式下本地驗證 JWT 的設定。
+	JWT AuthJWTConfig `mapstructure:"jwt"`
+}
+
+// AuthJWTConfig 包含本地 JWT 驗證的設定。
+//
+// 公鑰可以來自 JWKS 檔案或網址 (支援 RS256 與 ES256，以及 oct 金鑰的 HS256)，
+// HS256 也可以直接使用 HMACSecret，但必須在 Algorithms 中明確列出。金鑰會被快取並定期重新載入以支援金鑰輪替。
+type AuthJWTConfig struct {
+	// JWKSURL 是 JWKS 的網址，與 JWKSFile 擇一設定。
+	JWKSURL string `mapstructure:"jwksUrl"`
+
+	// JWKSFile 是 JWKS 的檔案路徑，替換檔案內容即可輪替金鑰。
+	JWKSFile string `mapstructure:"jwksFile"`
+
+	// HMACSecret 是 HS256 的共享密鑰，可為空。不要寫在設定檔中，應由部署環境注入。
+	HMACSecret string `mapstructure:"hmacSecret"`
+
+	// Algorithms 是允許的簽章演算法，空陣列時預設為 RS256 與 ES256；HS256 必須明確列出。
+	Algorithms []string `mapstructure:"algorithms"`
+
+	// Issuer 是要求的 iss，空字串表示不檢查。
+	Issuer string `mapstructure:"issuer"`
+
+	// Audience 是要求的 aud，空字串表示不檢查。
+	Audience string `mapstructure:"audience"`
+
+	// LeewaySec 是檢查 exp 與 nbf 時允許的時鐘誤差（秒）。
+	LeewaySec int `mapstructure:"leewaySec"`
+
+	// RefreshIntervalSec 是重新載入 JWKS 的週期（秒），0 表示使用預設值 300。
+	RefreshIntervalSec int `mapstructure:"refreshIntervalSec"`
+
+	// MinRefreshIntervalSec 是遇到未知 kid 時兩次強制重新載入的最短間隔（秒），0 表示使用預設值 10。
+	MinRefreshIntervalSec int `mapstructure:"minRefreshIntervalSec"`
+}
+
+// AuthVerifyConfig 包含營運商 token 驗證端點的設定。
+type AuthVerifyConfig struct {
+	// URL 是驗證端點的完整網址，會以 POST {"token": "..."} 呼叫。
+	URL string `mapstructure:"url"`
+
+	// APIKey 是呼叫驗證端點時帶上的 Bearer 金鑰，可為空。
+	APIKey string `mapstructure:"apiKey"`
+
+	// TimeoutMs 是單次請求的逾時時間（毫秒），0 表示使用預設值 3000。
+	TimeoutMs int `mapstructure:"timeoutMs"`
+
+	// MaxRetries 是遇到網路錯誤、429 或 5xx 時的最大重試次數。
+	MaxRetries int `mapstructure:"maxRetries"`
+
+	// RetryWaitMs 是第一次重試前的等待時間（毫秒），之後以指數退避增加，0 表示使用預設值 100。
+	RetryWaitMs int `mapstructure:"retryWaitMs"`
 }
 
 // DatabaseConfig 包含資料庫的設定。
 type DatabaseConfig struct {
 	Driver string `mapstructure:"driver"`
 	DSN    string `mapstructure:"dsn"`
+
+	// Mock 包含 driver 為 "mock" 時記憶體錢包的設定。
+	Mock MockWalletConfig `mapstructure:"mock"`
+}
+
+// MockWalletConfig 包含記憶體錢包的設定，用於本地開發與壓力測試。
+type MockWalletConfig struct {
+	// StartingBalances 是各幣種新玩家的起始餘額，例如 {"TWD": "100000"}；未設定的幣種預設為 100000。
+	StartingBalances map[string]string `mapstructure:"startingBalances"`
+
+	// LatencyMs 是每次呼叫注入的固定延遲（毫秒）。
+	LatencyMs int `mapstructure:"latencyMs"`
+
+	// LatencyJitterMs 是在固定延遲之上額外加入的隨機延遲上限（毫秒）。
+	LatencyJitterMs int `mapstructure:"latencyJitterMs"`
+
+	// FailureRate 是每次呼叫隨機失敗的機率 (0~1)，失敗時回傳 502。
+	FailureRate float64 `mapstructure:"failureRate"`
+
+	// MaxHistory 是每位玩家保留的交易紀錄筆數上限，0 表示使用預設值 1000。
+	MaxHistory int `mapstructure:"maxHistory"`
 }
 
 // ExternalWalletConfig 包含外接錢包 API 的設定。
 type ExternalWalletConfig struct {
 	BaseURL string `mapstructure:"baseUrl"`
 	APIKey  string `mapstructure:"apiKey"`
+
+	// TimeoutMs 是單次外部呼叫的逾時時間（毫秒），0 表示使用預設值 5000。
+	TimeoutMs int `mapstructure:"timeoutMs"`
+
+	// Breaker 包含斷路器與艙壁隔離 (Bulkhead) 的設定。
+	Breaker WalletBreakerConfig `mapstructure:"breaker"`
+
+	// Signing 包含請求簽章與回應驗章的設定，每個營運商可各自設定。
+	Signing WalletSigningConfig `mapstructure:"signing"`
+}
+
+// WalletSigningConfig 包含外接錢包請求簽章的設定。
+type WalletSigningConfig struct {
+	// Scheme 是簽章方案，例如 "bearer"（預設，僅送出 APIKey）或 "hmac-sha256"。
+	Scheme string `mapstructure:"scheme"`
+
+	// KeyID 是送給對方識別金鑰用的 ID，可為空。
+	KeyID string `mapstructure:"keyId"`
+
+	// Secret 是 HMAC 共享密鑰。
+	Secret string `mapstructure:"secret"`
+
+	// ReplayWindowSec 是允許的時間戳記誤差（秒），超出視為重放（預設 30）。
+	ReplayWindowSec int `mapstructure:"replayWindowSec"`
+
+	// AllowUnsignedResponse 為 true 時接受未簽章的回應，僅供過渡期使用。
+	AllowUnsignedResponse bool `mapstructure:"allowUnsignedResponse"`
+}
+
+// WalletBreakerConfig 包含外接錢包斷路器與艙壁隔離的設定。
+//
+// 所有欄位為 0 時皆會套用預設值，讓舊設定檔不需修改即可運作。
+type WalletBreakerConfig struct {
+	// FailureThreshold 是連續失敗幾次後開啟斷路器（預設 5）。
+	FailureThreshold int `mapstructure:"failureThreshold"`
+
+	// SlowCallMs 是慢呼叫的門檻（毫秒），超過此延遲的呼叫即使成功也計入失敗（預設 2000）。
+	SlowCallMs int `mapstructure:"slowCallMs"`
+
+	// OpenSec 是斷路器開啟後，等待多久進入半開狀態試探（秒，預設 10）。
+	OpenSec int `mapstructure:"openSec"`
+
+	// HalfOpenProbes 是半開狀態下允許同時進行的試探請求數（預設 1）。
+	HalfOpenProbes int `mapstructure:"halfOpenProbes"`
+
+	// MaxConcurrent 是同時進行中的外部呼叫上限，超過時快速失敗（預設 64）。
+	MaxConcurrent int `mapstructure:"maxConcurrent"`
 }
 
 // ExternalConfig 包含所有外部服務的設定。
@@ -42,6 +175,61 @@ type RedisConfig struct {
 	DB       int    `mapstructure:"db"`
 }
 
+// BonusConfig 包含紅利 (Bonus) 子餘額的設定。
+type BonusConfig struct {
+	// Enabled 為 true 時啟用紅利帳本。
+	Enabled bool `mapstructure:"enabled"`
+	// SpendOrder 是扣款順序: "bonus_first" (預設) 或 "cash_first"。
+	SpendOrder string `mapstructure:"spendOrder"`
+}
+
+// CurrencyConfig 包含單一幣種的精度、進位規則與下注限制。
+type CurrencyConfig struct {
+	Code      string `mapstructure:"code"`
+	Precision int32  `mapstructure:"precision"`
+	// Rounding 是派彩進位規則: "down" (預設), "half_up", "half_even"。
+	Rounding string `mapstructure:"rounding"`
+	// MinBet 與 MaxBet 以字串表示以避免浮點誤差，空字串表示不限制。
+	MinBet string `mapstructure:"minBet"`
+	MaxBet string `mapstructure:"maxBet"`
+}
+
+// CurrenciesConfig 包含支援的幣種列表與預設幣種。
+type CurrenciesConfig struct {
+	// Default 是使用者資料未帶幣種時使用的幣種。
+	Default string `mapstructure:"default"`
+	// List 是支援的幣種列表，為空時使用內建幣種 (TWD, USD, BTC, ETH, USDT)。
+	List []CurrencyConfig `mapstructure:"list"`
+}
+
+// Registry 根據設定建立幣種註冊表。
+func (c CurrenciesConfig) Registry() (*currency.Registry, error) {
+	if len(c.List) == 0 {
+		return currency.NewRegistry(c.Default, currency.Builtin()...)
+	}
+	list := make([]currency.Currency, 0, len(c.List))
+	for _, cc := range c.List {
+		cur := currency.Currency{
+			Code:      cc.Code,
+			Precision: cc.Precision,
+			Rounding:  currency.RoundingMode(cc.Rounding),
+		}
+		var err error
+		if cc.MinBet != "" {
+			if cur.MinBet, err = decimal.NewFromString(cc.MinBet); err != nil {
+				return nil, fmt.Errorf("invalid minBet for %s: %w", cc.Code, err)
+			}
+		}
+		if cc.MaxBet != "" {
+			if cur.MaxBet, err = decimal.NewFromString(cc.MaxBet); err != nil {
+				return nil, fmt.Errorf("invalid maxBet for %s: %w", cc.Code, err)
+			}
+		}
+		list = append(list, cur)
+	}
+	return currency.NewRegistry(c.Default, list...)
+}
+
 // AppConfig 包含應用程式的所有全域設定。
 //
 // 這是一個聚合設定結構，包含了 WebSocket、資料庫、Redis 與外部服務等所有必要的設定。
@@ -65,6 +253,27 @@ type AppConfig struct {
 	// WriteBufferSize 是寫入緩衝區大小（位元組）。
 	WriteBufferSize int `mapstructure:"writeBufferSize"`
 
+	// SendBufferSize 是每條 WebSocket 連線的發送佇列長度（訊息數），0 表示使用預設值 256。
+	SendBufferSize int `mapstructure:"sendBufferSize"`
+
+	// SendOverflowPolicy 是發送佇列已滿時的處理方式："disconnect"（預設）、"drop_oldest" 或 "drop_newest"。
+	SendOverflowPolicy string `mapstructure:"sendOverflowPolicy"`
+
+	// Compression 包含 WebSocket permessage-deflate 壓縮設定。
+	Compression CompressionConfig `mapstructure:"compression"`
+
+	// Admission 包含 WebSocket 升級時的來源與連線數限制。
+	Admission AdmissionConfig `mapstructure:"admission"`
+
+	// RateLimit 包含每條連線的訊息速率與各操作的頻率限制。
+	RateLimit RateLimitConfig `mapstructure:"rateLimit"`
+
+	// Drain 包含收到 SIGTERM 後排空連線的設定。
+	Drain DrainConfig `mapstructure:"drain"`
+
+	// Heartbeat 包含應用層 ping/pong 的設定。
+	Heartbeat HeartbeatConfig `mapstructure:"heartbeat"`
+
 	// Auth 包含驗證相關設定。
 	Auth AuthConfig `mapstructure:"auth"`
 
@@ -76,11 +285,354 @@ type AppConfig struct {
 
 	// Redis 包含 Redis 快取與 Pub/Sub 設定。
 	Redis RedisConfig `mapstructure:"redis"`
+
+	// Currencies 包含支援的幣種與各幣種的精度、進位規則與下注限制。
+	Currencies CurrenciesConfig `mapstructure:"currencies"`
+
+	// Games 包含各遊戲的設定，key 為遊戲 ID。
+	Games map[string]GameConfig `mapstructure:"games"`
+
+	// Bonus 包含紅利與流水要求的設定。
+	Bonus BonusConfig `mapstructure:"bonus"`
+
+	// Session 包含伺服器簽發的可恢復 session token 設定。
+	Session SessionConfig `mapstructure:"session"`
+
+	// MockPlatform 包含本地模擬錢包平台的設定。
+	MockPlatform MockPlatformConfig `mapstructure:"mockPlatform"`
+
+	// API 包含 REST API 伺服器 (cmd/api) 的設定。
+	API APIConfig `mapstructure:"api"`
+
+	// Operators 包含營運商 (租戶) 的設定。
+	Operators OperatorsConfig `mapstructure:"operators"`
+}
+
+// HeartbeatConfig 包含應用層 ping/pong 的設定。
+//
+// 伺服器定期向已登入的玩家發送 {"action":"ping"}，客戶端以相同的 seq 回應 pong，用於量測往返時間，
+// 並中斷仍回應協定層 ping、但已停止處理訊息的半開連線。只有回應過 pong 的客戶端才會被檢查。
+type HeartbeatConfig struct {
+	// IntervalSec 是發送 ping 的間隔（秒），0 表示停用。
+	IntervalSec int `mapstructure:"intervalSec"`
+
+	// MaxMissed 是連續未回應幾次 ping 後中斷連線，0 表示使用預設值 3。
+	MaxMissed int `mapstructure:"maxMissed"`
+}
+
+// DrainConfig 包含收到 SIGTERM 後排空連線的設定。
+//
+// 排空開始時 /readyz 回報未就緒並拒絕新的 WebSocket 連線，通知客戶端重新連線到其他實體，
+// 等待進行中的局結算 (最多 TimeoutSec 秒) 後才關閉所有連線。k8s 的 terminationGracePeriodSeconds 應大於 TimeoutSec。
+type DrainConfig struct {
+	// TimeoutSec 是等待進行中的局結算的期限（秒），0 表示使用預設值 30。
+	TimeoutSec int `mapstructure:"timeoutSec"`
+
+	// ReconnectDelayMs 是建議客戶端重新連線前等待的時間（毫秒），客戶端應在此範圍內隨機選擇，避免同時湧入其他實體。
+	ReconnectDelayMs int `mapstructure:"reconnectDelayMs"`
+}
+
+// CompressionConfig 包含 WebSocket permessage-deflate 壓縮設定。
+type CompressionConfig struct {
+	// Enabled 啟用壓縮，只有在客戶端也支援時才會壓縮。
+	Enabled bool `mapstructure:"enabled"`
+
+	// Level 是 compress/flate 的壓縮等級 (-2 ~ 9)，0 表示使用預設值 1 (最快)。
+	Level int `mapstructure:"level"`
+
+	// ThresholdBytes 是壓縮的最小訊息大小（位元組），較小的訊息不壓縮直接發送。
+	ThresholdBytes int `mapstructure:"thresholdBytes"`
+}
+
+// AdmissionConfig 包含 WebSocket 升級時的來源與連線數限制。
+type AdmissionConfig struct {
+	// AllowedOrigins 是允許連線的瀏覽器來源，支援 "https://game.example.com"、"https://*.example.com" 與 "*"；空清單表示允許所有來源。
+	AllowedOrigins []string `mapstructure:"allowedOrigins"`
+
+	// MaxConnections 是單一實體的連線數上限，超過時回應 503，0 表示不限制。
+	MaxConnections int `mapstructure:"maxConnections"`
+
+	// MaxConnectionsPerIP 是每個客戶端 IP 的連線數上限，超過時回應 429，0 表示不限制。
+	MaxConnectionsPerIP int `mapstructure:"maxConnectionsPerIP"`
+
+	// ClientIPHeader 是反向代理傳遞客戶端 IP 的標頭（例如 "X-Forwarded-For"），空字串表示使用連線的遠端位址。
+	ClientIPHeader string `mapstructure:"clientIPHeader"`
+
+	// TrustedProxyHops 是伺服器前方會附加 ClientIPHeader 的受信任反向代理層數，客戶端 IP 取標頭右側數來第 N 個位址，0 表示 1。
+	TrustedProxyHops int `mapstructure:"trustedProxyHops"`
+}
+
+// RateLimitConfig 包含每條連線的訊息速率與各操作的頻率限制 (token bucket)。
+type RateLimitConfig struct {
+	// Policy 是超過連線層限制時的處理方式："drop"（預設，丟棄訊息）、"warn"（丟棄並通知客戶端）或 "kick"（中斷連線）。
+	Policy string `mapstructure:"policy"`
+
+	// MessagesPerSec 是每條連線每秒可送出的訊息數，0 表示不限制。
+	MessagesPerSec float64 `mapstructure:"messagesPerSec"`
+
+	// MessageBurst 是可以連續送出的訊息數，0 表示使用 MessagesPerSec。
+	MessageBurst int `mapstructure:"messageBurst"`
+
+	// BytesPerSec 是每條連線每秒可送出的位元組數，0 表示不限制。
+	BytesPerSec float64 `mapstructure:"bytesPerSec"`
+
+	// ByteBurst 是可以連續送出的位元組數，0 表示使用 BytesPerSec（不小於 maxMessageSize）。
+	ByteBurst int `mapstructure:"byteBurst"`
+
+	// ActionPolicy 是超過操作頻率限制時的處理方式，空字串表示與 Policy 相同。
+	ActionPolicy string `mapstructure:"actionPolicy"`
+
+	// Actions 是各操作 (login、play、resume、logout) 的頻率限制，未列出的操作不限制。
+	Actions map[string]ActionRateLimitConfig `mapstructure:"actions"`
+}
+
+// ActionRateLimitConfig 是單一操作的頻率限制。
+type ActionRateLimitConfig struct {
+	// PerSec 是每秒可以執行的次數。
+	PerSec float64 `mapstructure:"perSec"`
+
+	// Burst 是可以連續執行的次數，0 表示使用 PerSec。
+	Burst int `mapstructure:"burst"`
+}
+
+// OperatorsConfig 包含營運商列表的來源與內容。
+type OperatorsConfig struct {
+	// Source 是營運商列表的來源："config"（預設，使用 List）或 "db"（讀取 operators 資料表）。
+	Source string `mapstructure:"source"`
+
+	// Default 是無法從登入資訊判斷營運商時使用的營運商 ID，空字串表示使用 "default"。
+	Default string `mapstructure:"default"`
+
+	// List 是營運商列表。為空時會以全域的 auth 與 external.wallet 設定建立單一的預設營運商。
+	List []OperatorConfig `mapstructure:"list"`
+}
+
+// OperatorConfig 包含單一營運商的串接設定與營運規則。
+type OperatorConfig struct {
+	// ID 是營運商的唯一識別碼，與 token 中的 operatorID 對應。
+	ID string `mapstructure:"id"`
+
+	// Name 是營運商的顯示名稱。
+	Name string `mapstructure:"name"`
+
+	// Auth 是營運商的驗證方式，mode 為空時沿用全域的 auth 設定。
+	Auth AuthConfig `mapstructure:"auth"`
+
+	// Wallet 是營運商的錢包端點與憑證，baseUrl 為空時沿用全域的 external.wallet 設定。
+	Wallet ExternalWalletConfig `mapstructure:"wallet"`
+
+	// Currencies 是允許的幣種代碼，空陣列表示允許所有平台支援的幣種。
+	Currencies []string `mapstructure:"currencies"`
+
+	// DefaultCurrency 是使用者資料未帶幣種時使用的幣種，空字串表示使用平台預設幣種。
+	DefaultCurrency string `mapstructure:"defaultCurrency"`
+
+	// Games 是開放的遊戲 ID，空陣列表示開放所有遊戲。
+	Games []int `mapstructure:"games"`
+
+	// BetLimits 是各幣種的下注限制，key 為幣種代碼，只能收窄平台在 currencies 設定的限制。
+	BetLimits map[string]BetLimitConfig `mapstructure:"betLimits"`
+}
+
+// BetLimitConfig 是單一幣種的下注限制，以字串表示以避免浮點誤差，空字串表示不限制。
+type BetLimitConfig struct {
+	MinBet string `mapstructure:"minBet"`
+	MaxBet string `mapstructure:"maxBet"`
+}
+
+// parse 解析下注限制，空字串解析為 0 (不限制)。
+func (bl BetLimitConfig) parse() (minBet, maxBet decimal.Decimal, err error) {
+	if bl.MinBet != "" {
+		if minBet, err = decimal.NewFromString(bl.MinBet); err != nil {
+			return decimal.Zero, decimal.Zero, fmt.Errorf("invalid minBet: %w", err)
+		}
+	}
+	if bl.MaxBet != "" {
+		if maxBet, err = decimal.NewFromString(bl.MaxBet); err != nil {
+			return decimal.Zero, decimal.Zero, fmt.Errorf("invalid maxBet: %w", err)
+		}
+	}
+	return minBet, maxBet, nil
+}
+
+// GameConfig 包含單一遊戲的設定。
+type GameConfig struct {
+	// BetLimits 是遊戲各幣種的下注限制，key 為幣種代碼，只能收窄平台與營運商的限制。
+	BetLimits map[string]BetLimitConfig `mapstructure:"betLimits"`
+}
+
+// GameBetLimits 回傳遊戲設定的下注限制，沒有設定時回傳 nil。
+func (c AppConfig) GameBetLimits(gameID int) (game.BetLimits, error) {
+	gc, ok := c.Games[strconv.Itoa(gameID)]
+	if !ok || len(gc.BetLimits) == 0 {
+		return nil, nil
+	}
+	limits := make(game.BetLimits, len(gc.BetLimits))
+	for code, bl := range gc.BetLimits {
+		// viper 會將 map 的 key 轉為小寫
+		code = strings.ToUpper(code)
+		minBet, maxBet, err := bl.parse()
+		if err != nil {
+			return nil, fmt.Errorf("game %d %s: %w", gameID, code, err)
+		}
+		limits[code] = game.BetLimit{MinBet: minBet, MaxBet: maxBet}
+	}
+	return limits, nil
+}
+
+// Operator 將營運商設定轉換為 domain 層的營運商。
+func (c OperatorConfig) Operator() (operator.Operator, error) {
+	op := operator.Operator{
+		ID:              c.ID,
+		Name:            c.Name,
+		Currencies:      c.Currencies,
+		DefaultCurrency: c.DefaultCurrency,
+		Games:           c.Games,
+		BetLimits:       make(map[string]operator.BetLimit, len(c.BetLimits)),
+	}
+	for code, bl := range c.BetLimits {
+		// viper 會將 map 的 key 轉為小寫
+		code = strings.ToUpper(code)
+		minBet, maxBet, err := bl.parse()
+		if err != nil {
+			return operator.Operator{}, fmt.Errorf("operator %s %s: %w", c.ID, code, err)
+		}
+		op.BetLimits[code] = operator.BetLimit{MinBet: minBet, MaxBet: maxBet}
+	}
+	return op, nil
+}
+
+// ResolveOperators 補齊營運商列表的預設值並建立營運商註冊表。
+//
+// list 為空時以全域的 auth 與 external.wallet 建立單一的預設營運商，讓單一營運商的部署不需修改設定。
+// 各營運商未設定的驗證方式與錢包端點沿用全域設定。
+//
+// 參數說明：
+//   - list: []OperatorConfig, 營運商列表，來自 Operators.List 或資料庫。
+//
+// 回傳值：
+//   - []OperatorConfig: 補齊預設值後的營運商列表，供建立各營運商的驗證與錢包 adapter。
+//   - *operator.Registry: 營運商註冊表。
+//   - error: 如果營運商設定無效，則返回錯誤。
+func (c AppConfig) ResolveOperators(list []OperatorConfig) ([]OperatorConfig, *operator.Registry, error) {
+	defaultID := c.Operators.Default
+	if defaultID == "" {
+		defaultID = operator.DefaultID
+	}
+	if len(list) == 0 {
+		list = []OperatorConfig{{ID: defaultID}}
+	}
+
+	resolved := make([]OperatorConfig, len(list))
+	ops := make([]operator.Operator, len(list))
+	for i, oc := range list {
+		if oc.Auth.Mode == "" {
+			oc.Auth = c.Auth
+		}
+		if oc.Wallet.BaseURL == "" {
+			oc.Wallet = c.External.Wallet
+		}
+		op, err := oc.Operator()
+		if err != nil {
+			return nil, nil, err
+		}
+		resolved[i] = oc
+		ops[i] = op
+	}
+	registry, err := operator.NewRegistry(defaultID, ops...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resolved, registry, nil
+}
+
+// SessionConfig 包含 session token 的設定。
+type SessionConfig struct {
+	// TTLSec 是 session token 的有效期限（秒），斷線後必須在此期限內 resume，0 表示使用預設值 900。
+	TTLSec int `mapstructure:"ttlSec"`
+
+	// SingleSession 決定同一位玩家重複登入時的處理方式："kick_old"（預設，踢除舊連線）、
+	// "reject_new"（拒絕新登入）或 "off"（不限制）。需要 Redis。
+	SingleSession string `mapstructure:"singleSession"`
+
+	// RegistryTTLSec 是線上登記的存活時間（秒），實體當機後登記會在此時間後自動清除，0 表示使用預設值 30。
+	RegistryTTLSec int `mapstructure:"registryTtlSec"`
+}
+
+// MockPlatformConfig 包含本地模擬錢包平台 (cmd/mockplatform) 的設定。
+//
+// 模擬平台實作了 Proxy 錢包串接的 API，用於本地開發與整合測試，
+// 簽章方式與 API Key 則沿用 External.Wallet 的設定，讓兩端自動一致。
+type MockPlatformConfig struct {
+	// Port 是模擬平台監聽的連接埠（預設 8000）。
+	Port int `mapstructure:"port"`
+
+	// Storage 是儲存方式："memory"（預設）或 "sqlite"。
+	Storage string `mapstructure:"storage"`
+
+	// SQLitePath 是 Storage 為 "sqlite" 時的資料庫檔案路徑。
+	SQLitePath string `mapstructure:"sqlitePath"`
+
+	// StartingBalances 是各幣種新玩家的起始餘額；未設定的幣種預設為 100000。
+	StartingBalances map[string]string `mapstructure:"startingBalances"`
+
+	// LatencyMs 是每個請求注入的固定延遲（毫秒）。
+	LatencyMs int `mapstructure:"latencyMs"`
+
+	// LatencyJitterMs 是在固定延遲之上額外加入的隨機延遲上限（毫秒）。
+	LatencyJitterMs int `mapstructure:"latencyJitterMs"`
+
+	// ErrorRate 是請求在處理前隨機失敗的機率 (0~1)。
+	ErrorRate float64 `mapstructure:"errorRate"`
+
+	// ErrorStatus 是注入失敗時回傳的 HTTP 狀態碼（預設 500）。
+	ErrorStatus int `mapstructure:"errorStatus"`
+
+	// LostResponseRate 是交易已入帳、但回應遺失 (回傳 ErrorStatus) 的機率 (0~1)，用於測試重送與沖正。
+	LostResponseRate float64 `mapstructure:"lostResponseRate"`
+
+	// DuplicateMode 決定收到重複交易 ID 時的行為："replay"（預設，回傳先前結果）、
+	// "reject"（回傳 409）或 "apply"（視為新交易重複入帳，模擬不具冪等性的平台）。
+	DuplicateMode string `mapstructure:"duplicateMode"`
+
+	// AdminKey 是呼叫 /admin 管理端點 (執行期調整故障注入) 時 X-Admin-Key 標頭必須帶上的值，未設定時停用管理端點。
+	AdminKey string `mapstructure:"adminKey"`
 }
 
 // APIConfig 包含 REST API 伺服器的設定。
 type APIConfig struct {
 	Port int `mapstructure:"port"`
+
+	// Auth 包含 REST API 的身分驗證與角色設定。
+	Auth APIAuthConfig `mapstructure:"auth"`
+}
+
+// APIAuthConfig 包含 REST API 的身分驗證設定。
+//
+// 呼叫端可以使用 X-API-Key 標頭 (服務對服務) 或 Authorization: Bearer <JWT> (後台登入的操作人員)，
+// 兩者都會被解析為一個角色：viewer < support < admin。
+type APIAuthConfig struct {
+	// Enabled 為 false 時不驗證身分，所有請求視為匿名 admin，僅供本地開發使用。
+	Enabled bool `mapstructure:"enabled"`
+
+	// Keys 是允許的 API Key 列表。
+	Keys []APIKeyConfig `mapstructure:"keys"`
+
+	// JWT 包含操作人員 JWT 的驗證設定，角色取自 role claim，操作者取自 sub。
+	// 未設定任何金鑰來源時不接受 JWT。
+	JWT AuthJWTConfig `mapstructure:"jwt"`
+}
+
+// APIKeyConfig 是一組 API Key 與其角色。
+type APIKeyConfig struct {
+	// Name 是稽核紀錄中顯示的操作者名稱。
+	Name string `mapstructure:"name"`
+
+	// Key 是金鑰本身。
+	Key string `mapstructure:"key"`
+
+	// Role 是金鑰的角色："viewer"、"support" 或 "admin"。
+	Role string `mapstructure:"role"`
 }
 
 // LoadConfig 從指定路徑載入設定檔。
@@ -104,6 +656,9 @@ func LoadConfig[T any](configPath string, env string) (*T, error) {
 	configName := fmt.Sprintf("config.%s", env)
 	v.SetConfigName(configName)
 	v.SetConfigType("yaml")
+	// 巢狀設定以底線對應環境變數，例如 auth.jwt.hmacSecret 可由 AUTH_JWT_HMACSECRET 覆寫，
+	// 密鑰類的設定應以此方式注入；只有設定檔中存在的 key 才會被覆寫
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
 
 	if err := v.ReadInConfig(); err != nil {
@@ -0,0 +1,157 @@
+package currency
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// RoundingMode 定義了金額進位到幣種精度時使用的規則。
+type RoundingMode string
+
+const (
+	// RoundDown 無條件捨去 (往 0 方向)，派彩時不會多付。
+	RoundDown RoundingMode = "down"
+	// RoundHalfUp 四捨五入。
+	RoundHalfUp RoundingMode = "half_up"
+	// RoundHalfEven 銀行家捨入法。
+	RoundHalfEven RoundingMode = "half_even"
+)
+
+// DefaultCode 是未指定幣種時使用的預設幣種，與 wallets 資料表的預設值一致。
+const DefaultCode = "TWD"
+
+// Currency 描述一個幣種的精度、進位規則與下注限制。
+type Currency struct {
+	// Code 是幣種代碼，例如 "TWD"、"USD"、"BTC"。
+	Code string
+	// Precision 是小數位數。
+	Precision int32
+	// Rounding 是派彩金額的進位規則。
+	Rounding RoundingMode
+	// MinBet 是單次最小下注額，為 0 表示不限制（但仍需大於 0）。
+	MinBet decimal.Decimal
+	// MaxBet 是單次最大下注額，為 0 表示不限制。
+	MaxBet decimal.Decimal
+}
+
+// Round 將金額依照此幣種的精度與進位規則處理。
+func (c Currency) Round(amount decimal.Decimal) decimal.Decimal {
+	switch c.Rounding {
+	case RoundHalfUp:
+		return amount.Round(c.Precision)
+	case RoundHalfEven:
+		return amount.RoundBank(c.Precision)
+	default:
+		return amount.Truncate(c.Precision)
+	}
+}
+
+// ValidateBet 檢查下注金額是否為正數、符合幣種精度，且落在下注限制內。
+func (c Currency) ValidateBet(amount decimal.Decimal) error {
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return fmt.Errorf("bet amount must be positive")
+	}
+	if !amount.Equal(amount.Truncate(c.Precision)) {
+		return fmt.Errorf("bet amount exceeds %s precision of %d decimals", c.Code, c.Precision)
+	}
+	if c.MinBet.IsPositive() && amount.LessThan(c.MinBet) {
+		return fmt.Errorf("bet amount below minimum %s %s", c.MinBet.String(), c.Code)
+	}
+	if c.MaxBet.IsPositive() && amount.GreaterThan(c.MaxBet) {
+		return fmt.Errorf("bet amount above maximum %s %s", c.MaxBet.String(), c.Code)
+	}
+	return nil
+}
+
+// Narrow 以額外的下注限制收窄幣種的限制：最小下注取較大者，最大下注取較小者，為 0 表示不額外限制。
+func (c Currency) Narrow(minBet, maxBet decimal.Decimal) Currency {
+	if minBet.GreaterThan(c.MinBet) {
+		c.MinBet = minBet
+	}
+	if maxBet.IsPositive() && (!c.MaxBet.IsPositive() || maxBet.LessThan(c.MaxBet)) {
+		c.MaxBet = maxBet
+	}
+	return c
+}
+
+// Registry 保存所有支援的幣種。
+type Registry struct {
+	currencies  map[string]Currency
+	defaultCode string
+}
+
+// NewRegistry 建立一個幣種註冊表。
+//
+// 參數說明：
+//   - defaultCode: string, 使用者資料未帶幣種時使用的幣種代碼，空字串時使用 DefaultCode。
+//   - currencies: ...Currency, 支援的幣種列表。
+//
+// 回傳值：
+//   - *Registry: 初始化完成的幣種註冊表。
+//   - error: 如果預設幣種不在列表中，或幣種的進位規則無效，則返回錯誤。
+func NewRegistry(defaultCode string, currencies ...Currency) (*Registry, error) {
+	if defaultCode == "" {
+		defaultCode = DefaultCode
+	}
+	r := &Registry{
+		currencies:  make(map[string]Currency, len(currencies)),
+		defaultCode: strings.ToUpper(defaultCode),
+	}
+	for _, c := range currencies {
+		c.Code = strings.ToUpper(c.Code)
+		switch c.Rounding {
+		case "":
+			c.Rounding = RoundDown
+		case RoundDown, RoundHalfUp, RoundHalfEven:
+		default:
+			return nil, fmt.Errorf("unknown rounding mode %q for currency %s", c.Rounding, c.Code)
+		}
+		r.currencies[c.Code] = c
+	}
+	if _, ok := r.currencies[r.defaultCode]; !ok {
+		return nil, fmt.Errorf("default currency %s is not configured", r.defaultCode)
+	}
+	return r, nil
+}
+
+// DefaultRegistry 回傳內建的幣種註冊表，包含法幣 TWD、USD 與 8 位小數的加密貨幣。
+func DefaultRegistry() *Registry {
+	r, _ := NewRegistry(DefaultCode, Builtin()...)
+	return r
+}
+
+// Builtin 回傳內建支援的幣種列表。
+func Builtin() []Currency {
+	return []Currency{
+		{Code: "TWD", Precision: 2, Rounding: RoundDown},
+		{Code: "USD", Precision: 2, Rounding: RoundDown},
+		{Code: "BTC", Precision: 8, Rounding: RoundDown},
+		{Code: "ETH", Precision: 8, Rounding: RoundDown},
+		{Code: "USDT", Precision: 6, Rounding: RoundDown},
+	}
+}
+
+// List 回傳所有支援的幣種，依代碼排序。
+func (r *Registry) List() []Currency {
+	list := make([]Currency, 0, len(r.currencies))
+	for _, c := range r.currencies {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+	return list
+}
+
+// Resolve 根據幣種代碼取得幣種設定，空字串會回傳預設幣種。
+func (r *Registry) Resolve(code string) (Currency, error) {
+	if code == "" {
+		code = r.defaultCode
+	}
+	c, ok := r.currencies[strings.ToUpper(code)]
+	if !ok {
+		return Currency{}, fmt.Errorf("unsupported currency: %s", code)
+	}
+	return c, nil
+}
@@ -0,0 +1,160 @@
+package currency
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		precision int32
+		amount    string
+		down      string
+		halfUp    string
+		halfEven  string
+	}{
+		{precision: 2, amount: "1.005", down: "1", halfUp: "1.01", halfEven: "1"},
+		{precision: 2, amount: "1.015", down: "1.01", halfUp: "1.02", halfEven: "1.02"},
+		{precision: 2, amount: "2.349", down: "2.34", halfUp: "2.35", halfEven: "2.35"},
+		{precision: 2, amount: "-1.005", down: "-1", halfUp: "-1.01", halfEven: "-1"},
+		{precision: 2, amount: "3.1", down: "3.1", halfUp: "3.1", halfEven: "3.1"},
+		{precision: 8, amount: "0.123456785", down: "0.12345678", halfUp: "0.12345679", halfEven: "0.12345678"},
+		{precision: 8, amount: "0.123456775", down: "0.12345677", halfUp: "0.12345678", halfEven: "0.12345678"},
+		{precision: 8, amount: "0.000000019", down: "0.00000001", halfUp: "0.00000002", halfEven: "0.00000002"},
+		{precision: 8, amount: "0.000000004", down: "0", halfUp: "0", halfEven: "0"},
+	}
+	for _, tt := range tests {
+		for mode, want := range map[RoundingMode]string{RoundDown: tt.down, RoundHalfUp: tt.halfUp, RoundHalfEven: tt.halfEven} {
+			c := Currency{Code: "X", Precision: tt.precision, Rounding: mode}
+			if got := c.Round(dec(tt.amount)); !got.Equal(dec(want)) {
+				t.Errorf("Round(%s) with %d decimals %s = %s, want %s", tt.amount, tt.precision, mode, got, want)
+			}
+		}
+	}
+
+	// 未設定進位規則時無條件捨去
+	if got := (Currency{Precision: 2}).Round(dec("1.999")); !got.Equal(dec("1.99")) {
+		t.Errorf("Round without rounding mode = %s, want 1.99", got)
+	}
+}
+
+func TestValidateBet(t *testing.T) {
+	twd := Currency{Code: "TWD", Precision: 2, MinBet: dec("1"), MaxBet: dec("100")}
+	btc := Currency{Code: "BTC", Precision: 8}
+	tests := []struct {
+		name     string
+		currency Currency
+		amount   string
+		wantErr  bool
+	}{
+		{name: "whole amount", currency: twd, amount: "10"},
+		{name: "within precision", currency: twd, amount: "10.55"},
+		{name: "trailing zeros", currency: twd, amount: "10.5000"},
+		{name: "beyond precision", currency: twd, amount: "10.555", wantErr: true},
+		{name: "zero", currency: twd, amount: "0", wantErr: true},
+		{name: "negative", currency: twd, amount: "-5", wantErr: true},
+		{name: "below minimum", currency: twd, amount: "0.99", wantErr: true},
+		{name: "at minimum", currency: twd, amount: "1"},
+		{name: "at maximum", currency: twd, amount: "100"},
+		{name: "above maximum", currency: twd, amount: "100.01", wantErr: true},
+		{name: "one satoshi", currency: btc, amount: "0.00000001"},
+		{name: "below one satoshi", currency: btc, amount: "0.000000015", wantErr: true},
+		{name: "no limits", currency: btc, amount: "1000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.currency.ValidateBet(dec(tt.amount))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateBet(%s) error = %v, wantErr %v", tt.amount, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNarrow(t *testing.T) {
+	tests := []struct {
+		name      string
+		min, max  string // 幣種原本的限制
+		narrowMin string
+		narrowMax string
+		wantMin   string
+		wantMax   string
+	}{
+		{name: "narrower on both sides", min: "1", max: "100", narrowMin: "5", narrowMax: "50", wantMin: "5", wantMax: "50"},
+		{name: "zero keeps the currency limits", min: "1", max: "100", narrowMin: "0", narrowMax: "0", wantMin: "1", wantMax: "100"},
+		{name: "cannot widen", min: "1", max: "100", narrowMin: "0.5", narrowMax: "500", wantMin: "1", wantMax: "100"},
+		{name: "limits an unlimited maximum", min: "0", max: "0", narrowMin: "0", narrowMax: "200", wantMin: "0", wantMax: "200"},
+		{name: "only the minimum", min: "1", max: "100", narrowMin: "5", narrowMax: "0", wantMin: "5", wantMax: "100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Currency{Code: "TWD", Precision: 2, MinBet: dec(tt.min), MaxBet: dec(tt.max)}
+			got := c.Narrow(dec(tt.narrowMin), dec(tt.narrowMax))
+			if !got.MinBet.Equal(dec(tt.wantMin)) || !got.MaxBet.Equal(dec(tt.wantMax)) {
+				t.Fatalf("Narrow = [%s, %s], want [%s, %s]", got.MinBet, got.MaxBet, tt.wantMin, tt.wantMax)
+			}
+			if !c.MinBet.Equal(dec(tt.min)) || !c.MaxBet.Equal(dec(tt.max)) {
+				t.Fatal("Narrow modified the original currency")
+			}
+		})
+	}
+}
+
+func TestNewRegistry(t *testing.T) {
+	tests := []struct {
+		name        string
+		defaultCode string
+		currencies  []Currency
+		wantErr     bool
+	}{
+		{name: "builtin", defaultCode: "", currencies: Builtin()},
+		{name: "lowercase codes", defaultCode: "usd", currencies: []Currency{{Code: "usd", Precision: 2}}},
+		{name: "every rounding mode", defaultCode: "TWD", currencies: []Currency{
+			{Code: "TWD", Rounding: RoundDown},
+			{Code: "USD", Rounding: RoundHalfUp},
+			{Code: "EUR", Rounding: RoundHalfEven},
+		}},
+		{name: "missing default", defaultCode: "EUR", currencies: Builtin(), wantErr: true},
+		{name: "unknown rounding mode", defaultCode: "TWD", currencies: []Currency{{Code: "TWD", Rounding: "ceil"}}, wantErr: true},
+		{name: "misspelled rounding mode", defaultCode: "TWD", currencies: []Currency{{Code: "TWD", Rounding: "half-up"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRegistry(tt.defaultCode, tt.currencies...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewRegistry error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistryResolve(t *testing.T) {
+	r, err := NewRegistry("twd", Currency{Code: "twd", Precision: 2}, Currency{Code: "BTC", Precision: 8})
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	tests := []struct {
+		code     string
+		wantCode string
+		wantErr  bool
+	}{
+		{code: "", wantCode: "TWD"},
+		{code: "btc", wantCode: "BTC"},
+		{code: "TWD", wantCode: "TWD"},
+		{code: "EUR", wantErr: true},
+	}
+	for _, tt := range tests {
+		c, err := r.Resolve(tt.code)
+		if (err != nil) != tt.wantErr || c.Code != tt.wantCode {
+			t.Fatalf("Resolve(%q) = %q, %v; want %q", tt.code, c.Code, err, tt.wantCode)
+		}
+		if err == nil && c.Rounding != RoundDown {
+			t.Fatalf("Resolve(%q) rounding = %q, want the default %q", tt.code, c.Rounding, RoundDown)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package game
+
+import "fmt"
+
+// GroupPlayers 是所有已登入玩家的連線所在的群組，用於全平台公告。
+const GroupPlayers = "players"
+
+// GameGroup 回傳遊戲房間的群組名稱，目前在該遊戲中的玩家都會在此群組內。
+func GameGroup(gameID int) string {
+	return fmt.Sprintf("game:%d", gameID)
+}
+
+// Broadcaster 將訊息廣播給群組內的所有連線。
+//
+// 群組成員由連線層維護 (GameClient.Join / Leave)，斷線的連線會自動移出群組。
+// 每則訊息對每種編碼 (JSON、MessagePack 等) 只序列化一次，由同一種編碼的連線共用；
+// 發送不會阻塞，慢速的連線依連線層的溢出處理方式處理，不影響其他玩家。
+type Broadcaster interface {
+	// Broadcast 將訊息發送給群組內的所有連線，回傳收到訊息的連線數。
+	Broadcast(group string, message Envelope) int
+	// BroadcastAll 將訊息發送給本實體上的所有連線，包含尚未登入的連線，回傳收到訊息的連線數。
+	BroadcastAll(message Envelope) int
+}
@@ -1,10 +1,66 @@
 package game
 
-import "github.com/shopspring/decimal"
+import (
+	"context"
 
+	"github.com/joe_shih/slot-factory/internal/domain/currency"
+	"github.com/shopspring/decimal"
+)
+
+// IGame 定義了遊戲實作需要提供的行為。
+//
+// AddPlayer 與 Play 會收到與玩家連線綁定的 context，玩家斷線或伺服器關閉時會被取消，
+// 遊戲應將它傳遞給錢包等外部呼叫。
 type IGame interface {
 	ID() int
-	AddPlayer(player *Player)
+	// Info 回傳遊戲目錄顯示用的資訊。
+	Info() Info
+	AddPlayer(ctx context.Context, player *Player)
 	RemovePlayer(player *Player)
-	Play(player *Player, betAmount decimal.Decimal)
-}
\ No newline at end of file
+	Play(ctx context.Context, player *Player, betAmount decimal.Decimal)
+}
+
+// Drainer 是 IGame 的選用介面，由有跨玩家共享輪次的遊戲實作。
+// 服務實體排空連線時，遊戲中心在停止接受新的下注後呼叫 Drain，等待已下注的局開獎並派彩後才關閉連線。
+type Drainer interface {
+	// Drain 阻塞直到所有已下注的局都已結算，ctx 到期時回傳 ctx 的錯誤。
+	Drain(ctx context.Context) error
+}
+
+// 遊戲類型
+const (
+	// TypeSingle 是單人遊戲，每次 Play 即為一局。
+	TypeSingle = "single"
+	// TypeMultiplayer 是多人共享輪次的遊戲。
+	TypeMultiplayer = "multiplayer"
+)
+
+// Info 是遊戲目錄中顯示的遊戲資訊。
+type Info struct {
+	// Name 是遊戲的顯示名稱。
+	Name string `json:"name"`
+	// Type 是遊戲類型，例如 TypeSingle 或 TypeMultiplayer。
+	Type string `json:"type"`
+	// RTP 是理論返還率 (%)。
+	RTP decimal.Decimal `json:"rtp"`
+	// BetLimits 是遊戲自身各幣種的下注限制，key 為幣種代碼，沒有設定的幣種沿用平台限制。
+	BetLimits BetLimits `json:"betLimits,omitempty"`
+}
+
+// BetLimit 是遊戲對單一幣種設定的下注限制，為 0 表示不額外限制。
+type BetLimit struct {
+	MinBet decimal.Decimal `json:"minBet"`
+	MaxBet decimal.Decimal `json:"maxBet"`
+}
+
+// BetLimits 是遊戲各幣種的下注限制，key 為幣種代碼。
+type BetLimits map[string]BetLimit
+
+// Apply 將遊戲的下注限制套用到幣種上。遊戲只能收窄平台 (與營運商) 的限制。
+func (l BetLimits) Apply(c currency.Currency) currency.Currency {
+	limit, ok := l[c.Code]
+	if !ok {
+		return c
+	}
+	return c.Narrow(limit.MinBet, limit.MaxBet)
+}
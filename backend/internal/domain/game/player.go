@@ -1,24 +1,99 @@
 package game
 
 import (
-	"encoding/json"
+	"context"
+	"fmt"
+
+	"github.com/joe_shih/slot-factory/internal/domain/currency"
+	"github.com/joe_shih/slot-factory/internal/domain/operator"
+	"github.com/joe_shih/slot-factory/internal/domain/requestid"
+	"github.com/joe_shih/slot-factory/pkg/codec"
 )
 
 // Envelope 是所有 WebSocket 訊息的通用外層結構。
 type Envelope struct {
-	Action  string `json:"action"`
-	Payload any    `json:"payload,omitempty"`
+	Action string `json:"action"`
+	// RequestID 是客戶端在請求中帶上的 requestId，只出現在直接回應該請求的訊息，廣播訊息不會帶上。
+	RequestID string `json:"requestId,omitempty"`
+	Payload   any    `json:"payload,omitempty"`
+}
+
+// MarshalProto 依 slot.proto 將訊息編碼為 Envelope，Payload 必須是 nil 或實作 codec.ProtoMessage。
+func (e Envelope) MarshalProto() ([]byte, error) {
+	var payload codec.ProtoMessage
+	if e.Payload != nil {
+		var ok bool
+		if payload, ok = e.Payload.(codec.ProtoMessage); !ok {
+			return nil, fmt.Errorf("payload %T of action %q has no protobuf encoding", e.Payload, e.Action)
+		}
+	}
+	return codec.AppendEnvelope(nil, e.Action, e.RequestID, payload), nil
+}
+
+// ActionError 是標準錯誤訊息的 action。
+const ActionError = "error"
+
+// 標準錯誤訊息的錯誤代碼。
+const (
+	// ErrCodeInvalidMessage 表示訊息無法解析。
+	ErrCodeInvalidMessage = "invalid_message"
+	// ErrCodeInvalidRequestID 表示 requestId 超過長度上限。
+	ErrCodeInvalidRequestID = "invalid_request_id"
+	// ErrCodeInvalidPayload 表示 data 的格式不符合 action 的要求。
+	ErrCodeInvalidPayload = "invalid_payload"
+	// ErrCodeUnknownAction 表示不支援的 action。
+	ErrCodeUnknownAction = "unknown_action"
+	// ErrCodeNotLoggedIn 表示尚未登入就送出需要登入的 action。
+	ErrCodeNotLoggedIn = "not_logged_in"
+	// ErrCodeGameNotFound 表示玩家不在任何遊戲中，或遊戲不存在。
+	ErrCodeGameNotFound = "game_not_found"
+)
+
+// ErrorPayload 是標準錯誤訊息的內容。
+type ErrorPayload struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// AppendProto 依 slot.proto 的 Error 編碼。
+func (p ErrorPayload) AppendProto(b []byte) []byte {
+	b = codec.AppendString(b, 1, p.Code)
+	b = codec.AppendString(b, 2, p.Message)
+	return codec.AppendString(b, 3, p.RequestID)
+}
+
+// 確保 Envelope 可以用 Protobuf codec 編碼。
+var (
+	_ codec.ProtoMarshaler = Envelope{}
+	_ codec.ProtoMessage   = ErrorPayload{}
+)
+
+// NewErrorEnvelope 建立標準錯誤訊息，requestID 會同時放在外層與內容中。
+func NewErrorEnvelope(requestID, code, message string) Envelope {
+	return Envelope{
+		Action:    ActionError,
+		RequestID: requestID,
+		Payload:   ErrorPayload{Code: code, Message: message, RequestID: requestID},
+	}
 }
 
 // GameClient 定義了與客戶端通訊所需實現的介面。
 // 這將核心領域 (domain) 與具體的網路實現 (如 WebSocket) 分離。
 type GameClient interface {
 	GetID() string
-	SendMessage(message string) error
+	// Context 返回與連線生命週期綁定的 context，連線中斷時會被取消。
+	Context() context.Context
+	// SendMessage 發送訊息給客戶端，編碼方式 (JSON、MessagePack 等) 由連線層依客戶端協商決定。
+	SendMessage(message Envelope) error
 	Kick(reason string) error
 	GetTag(key string) (value any, exists bool)
 	SetTag(key string, value any)
 	GetIP() string
+	// Join 將連線加入具名群組，之後 Broadcaster 對該群組的廣播都會發送給此連線。斷線時會自動離開所有群組。
+	Join(group string)
+	// Leave 將連線移出具名群組。
+	Leave(group string)
 }
 
 // Player 代表一個玩家實體。
@@ -27,14 +102,29 @@ type Player struct {
 	ID string
 	// Name 是玩家的名稱。
 	Name string
+	// Currency 是玩家錢包使用的幣種，決定金額精度、派彩進位規則與下注限制。
+	Currency currency.Currency
+	// Language 是玩家的語系，由驗證服務提供。
+	Language string
+	// OperatorID 是玩家所屬營運商的識別碼。
+	OperatorID string
+	// Operator 是玩家所屬的營運商，決定可用的幣種、遊戲與下注限制；未啟用營運商模型時為 nil。
+	Operator *operator.Operator
+	// Country 是玩家所在國家的代碼。
+	Country string
 	// client 是指向實現了 GameClient 介面的連線物件。
 	client GameClient
 }
 
-// SendMessage 將一個 Envelope 結構序列化為 JSON 字串，並透過 GameClient 發送出去。
+// SendMessage 透過 GameClient 將一個 Envelope 發送給玩家，由連線層依客戶端選擇的編碼序列化。
 func (p *Player) SendMessage(message Envelope) error {
-	data, _ := json.MarshalIndent(message, "", "  ")
-	return p.client.SendMessage(string(data))
+	return p.client.SendMessage(message)
+}
+
+// Reply 將 ctx 中的請求 ID (requestid.NewContext) 帶入訊息後發送給玩家，用於直接回應客戶端請求的訊息。
+func (p *Player) Reply(ctx context.Context, message Envelope) error {
+	message.RequestID = requestid.FromContext(ctx)
+	return p.client.SendMessage(message)
 }
 
 // Kick 透過 GameClient 踢出玩家連線。
@@ -52,16 +142,45 @@ func (p *Player) GetTag(key string) (value any, exists bool) {
 	return p.client.GetTag(key)
 }
 
+// Context 透過 GameClient 取得與連線生命週期綁定的 context，並帶上玩家所屬的營運商，
+// 讓錢包呼叫可以路由到營運商各自的端點。
+func (p *Player) Context() context.Context {
+	return operator.NewContext(p.client.Context(), p.OperatorID)
+}
+
+// Join 透過 GameClient 將玩家的連線加入具名群組。
+func (p *Player) Join(group string) {
+	p.client.Join(group)
+}
+
+// Leave 透過 GameClient 將玩家的連線移出具名群組。
+func (p *Player) Leave(group string) {
+	p.client.Leave(group)
+}
+
+// Key 回傳玩家在平台上的唯一識別："營運商ID:玩家ID"。玩家 ID 只在營運商內唯一，
+// 以玩家為 key 的狀態 (遊戲座位、線上登記等) 必須同時區分營運商。
+func (p *Player) Key() string {
+	return p.OperatorID + ":" + p.ID
+}
+
+// SameClient 判斷兩個 Player 是否附加在同一個連線上。
+// 同一玩家在其他連線重新登入時會建立新的 Player，遊戲可以藉此分辨舊連線的離開通知。
+func (p *Player) SameClient(other *Player) bool {
+	return other != nil && p.client == other.client
+}
+
 // IP 透過 GameClient 從連線讀取IP
 func (p *Player) IP() string {
 	return p.client.GetIP()
 }
 
 // NewPlayer 創建一個新的 Player 實例。
-func NewPlayer(id string, name string, client GameClient) *Player {
+func NewPlayer(id string, name string, cur currency.Currency, client GameClient) *Player {
 	return &Player{
-		ID:     id,
-		Name:   name,
-		client: client,
+		ID:       id,
+		Name:     name,
+		Currency: cur,
+		client:   client,
 	}
 }
@@ -0,0 +1,16 @@
+package operator
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext 回傳帶有營運商 ID 的 context，錢包等外部呼叫會依此路由到營運商各自的端點。
+func NewContext(ctx context.Context, operatorID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, operatorID)
+}
+
+// IDFromContext 取得 context 中的營運商 ID，沒有或為空字串時 ok 為 false。
+func IDFromContext(ctx context.Context) (id string, ok bool) {
+	id, _ = ctx.Value(contextKey{}).(string)
+	return id, id != ""
+}
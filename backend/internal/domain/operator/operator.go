@@ -0,0 +1,137 @@
+package operator
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/joe_shih/slot-factory/internal/domain/currency"
+	"github.com/shopspring/decimal"
+)
+
+// DefaultID 是未設定營運商列表時使用的預設營運商 ID。
+const DefaultID = "default"
+
+// ErrUnknownOperator 表示營運商不存在或未啟用。
+var ErrUnknownOperator = errors.New("unknown operator")
+
+// BetLimit 是營運商對單一幣種設定的下注限制，為 0 表示不額外限制。
+type BetLimit struct {
+	MinBet decimal.Decimal
+	MaxBet decimal.Decimal
+}
+
+// Operator 代表一個營運商 (租戶)，決定其玩家可以使用的幣種、遊戲與下注限制。
+//
+// 錢包端點、憑證與驗證方式屬於串接細節，由 adapter 依營運商 ID 各自建立。
+type Operator struct {
+	// ID 是營運商的唯一識別碼，與 token 中的 operatorID 對應。
+	ID string
+	// Name 是營運商的顯示名稱。
+	Name string
+	// Currencies 是允許的幣種代碼，空陣列表示允許所有平台支援的幣種。
+	Currencies []string
+	// DefaultCurrency 是使用者資料未帶幣種時使用的幣種，空字串表示使用平台預設幣種。
+	DefaultCurrency string
+	// Games 是開放的遊戲 ID，空陣列表示開放所有遊戲。
+	Games []int
+	// BetLimits 是各幣種的下注限制，key 為幣種代碼。
+	BetLimits map[string]BetLimit
+}
+
+// AllowsCurrency 判斷營運商是否允許使用指定幣種。
+func (o *Operator) AllowsCurrency(code string) bool {
+	if len(o.Currencies) == 0 {
+		return true
+	}
+	return slices.Contains(o.Currencies, strings.ToUpper(code))
+}
+
+// AllowsGame 判斷營運商是否開放指定遊戲。
+func (o *Operator) AllowsGame(gameID int) bool {
+	return len(o.Games) == 0 || slices.Contains(o.Games, gameID)
+}
+
+// ApplyLimits 將營運商的下注限制套用到幣種上。
+//
+// 營運商只能收窄平台的限制：最小下注取較大者，最大下注取較小者。
+func (o *Operator) ApplyLimits(c currency.Currency) currency.Currency {
+	limit, ok := o.BetLimits[c.Code]
+	if !ok {
+		return c
+	}
+	return c.Narrow(limit.MinBet, limit.MaxBet)
+}
+
+// Registry 保存所有營運商。
+type Registry struct {
+	operators map[string]*Operator
+	defaultID string
+}
+
+// NewRegistry 建立一個營運商註冊表。
+//
+// 參數說明：
+//   - defaultID: string, 使用者資料未帶營運商時使用的營運商 ID，空字串時使用 DefaultID。
+//   - operators: ...Operator, 營運商列表。
+//
+// 回傳值：
+//   - *Registry: 初始化完成的營運商註冊表。
+//   - error: 如果 ID 重複或預設營運商不在列表中，則返回錯誤。
+func NewRegistry(defaultID string, operators ...Operator) (*Registry, error) {
+	if defaultID == "" {
+		defaultID = DefaultID
+	}
+	r := &Registry{
+		operators: make(map[string]*Operator, len(operators)),
+		defaultID: defaultID,
+	}
+	for _, op := range operators {
+		if op.ID == "" {
+			return nil, fmt.Errorf("operator id is required")
+		}
+		if _, exists := r.operators[op.ID]; exists {
+			return nil, fmt.Errorf("duplicate operator id: %s", op.ID)
+		}
+		codes := make([]string, len(op.Currencies))
+		for i, code := range op.Currencies {
+			codes[i] = strings.ToUpper(code)
+		}
+		op.Currencies = codes
+		op.DefaultCurrency = strings.ToUpper(op.DefaultCurrency)
+		r.operators[op.ID] = &op
+	}
+	if _, ok := r.operators[r.defaultID]; !ok {
+		return nil, fmt.Errorf("default operator %s is not configured", r.defaultID)
+	}
+	return r, nil
+}
+
+// Resolve 根據營運商 ID 取得營運商，空字串會回傳預設營運商。
+func (r *Registry) Resolve(id string) (*Operator, error) {
+	if id == "" {
+		id = r.defaultID
+	}
+	op, ok := r.operators[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownOperator, id)
+	}
+	return op, nil
+}
+
+// DefaultID 回傳預設營運商的 ID。
+func (r *Registry) DefaultID() string {
+	return r.defaultID
+}
+
+// List 回傳所有營運商，依 ID 排序。
+func (r *Registry) List() []*Operator {
+	list := make([]*Operator, 0, len(r.operators))
+	for _, op := range r.operators {
+		list = append(list, op)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
@@ -0,0 +1,24 @@
+// Package requestid 在 context 中傳遞客戶端提供的請求 ID，
+// 讓回應、日誌與錢包交易紀錄可以對應到同一個客戶端請求。
+package requestid
+
+import "context"
+
+// MaxLength 是請求 ID 的長度上限，與 wallet_transactions.request_id 欄位一致。
+const MaxLength = 64
+
+type contextKey struct{}
+
+// NewContext 回傳帶有請求 ID 的 context，id 為空字串時直接回傳 ctx。
+func NewContext(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext 取得 context 中的請求 ID，沒有時回傳空字串。
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
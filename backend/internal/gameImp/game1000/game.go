@@ -1,11 +1,14 @@
 package game1000
 
 import (
+	"context"
 	"log/slog"
 	"math/rand/v2"
 
 	"github.com/joe_shih/slot-factory/internal/application/wallet"
 	"github.com/joe_shih/slot-factory/internal/domain/game"
+	"github.com/joe_shih/slot-factory/internal/domain/requestid"
+	"github.com/joe_shih/slot-factory/pkg/codec"
 	"github.com/shopspring/decimal"
 )
 
@@ -14,11 +17,31 @@ const (
 	ActionPlayResult = "play_result"
 )
 
+// 確保此遊戲送出的訊息內容都有 slot.proto 的編碼。
+var (
+	_ codec.ProtoMessage = balanceResult{}
+	_ codec.ProtoMessage = playResult{}
+)
+
 // balanceResult 是此遊戲的餘額訊息結構。
 type balanceResult struct {
-	Success bool            `json:"success"`
-	Error   string          `json:"error,omitempty"`
-	Balance decimal.Decimal `json:"balance"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	// Balance 是現金與紅利的合計餘額。
+	Balance      decimal.Decimal `json:"balance"`
+	CashBalance  decimal.Decimal `json:"cashBalance"`
+	BonusBalance decimal.Decimal `json:"bonusBalance"`
+	Currency     string          `json:"currency"`
+}
+
+// AppendProto 依 slot.proto 的 Game1000Balance 編碼。
+func (r balanceResult) AppendProto(b []byte) []byte {
+	b = codec.AppendBool(b, 1, r.Success)
+	b = codec.AppendString(b, 2, r.Error)
+	b = codec.AppendString(b, 3, r.Balance.String())
+	b = codec.AppendString(b, 4, r.CashBalance.String())
+	b = codec.AppendString(b, 5, r.BonusBalance.String())
+	return codec.AppendString(b, 6, r.Currency)
 }
 
 // playResult 是此遊戲的結果訊息結構。
@@ -29,6 +52,18 @@ type playResult struct {
 	WinAmount decimal.Decimal `json:"winAmount"`
 	Dice      int             `json:"dice"`
 	Balance   decimal.Decimal `json:"balance"`
+	Currency  string          `json:"currency"`
+}
+
+// AppendProto 依 slot.proto 的 Game1000PlayResult 編碼。
+func (r playResult) AppendProto(b []byte) []byte {
+	b = codec.AppendBool(b, 1, r.Success)
+	b = codec.AppendString(b, 2, r.Error)
+	b = codec.AppendString(b, 3, r.BetAmount.String())
+	b = codec.AppendString(b, 4, r.WinAmount.String())
+	b = codec.AppendInt64(b, 5, int64(r.Dice))
+	b = codec.AppendString(b, 6, r.Balance.String())
+	return codec.AppendString(b, 7, r.Currency)
 }
 
 // Game 實作一個簡單的單人骰子遊戲 (game.IGame 介面)。
@@ -37,14 +72,16 @@ type Game struct {
 	id            int
 	logger        *slog.Logger
 	walletService *wallet.Service
+	betLimits     game.BetLimits // 遊戲自身的下注限制，只能收窄玩家幣種的限制
 }
 
-// NewGame 創建一個新的 1000 骰子遊戲實例。
-func NewGame(logger *slog.Logger, walletService *wallet.Service) game.IGame {
+// NewGame 創建一個新的 1000 骰子遊戲實例，betLimits 為 nil 時沿用玩家幣種的下注限制。
+func NewGame(logger *slog.Logger, walletService *wallet.Service, betLimits game.BetLimits) game.IGame {
 	return &Game{
 		id:            1000,
 		logger:        logger.With("gameID", 1000),
 		walletService: walletService,
+		betLimits:     betLimits,
 	}
 }
 
@@ -53,23 +90,35 @@ func (g *Game) ID() int {
 	return g.id
 }
 
+// Info 返回遊戲目錄資訊。骰出 1 (機率 1/6) 贏得 6 倍賭注，理論返還率為 100%。
+func (g *Game) Info() game.Info {
+	return game.Info{Name: "Lucky Dice", Type: game.TypeSingle, RTP: decimal.NewFromInt(100), BetLimits: g.betLimits}
+}
+
 // AddPlayer 在單人遊戲中，此方法僅發送歡迎訊息，不需將玩家存儲在遊戲狀態中。
-func (g *Game) AddPlayer(player *game.Player) {
-	balance, error := g.walletService.GetBalance(player.ID)
+func (g *Game) AddPlayer(ctx context.Context, player *game.Player) {
+	currencyCode := player.Currency.Code
+	balances, error := g.walletService.GetBalances(ctx, player.ID, currencyCode)
 	if error != nil {
 		g.logger.Error("get balance failed", "playerID", player.ID, "error", error)
-		err := player.SendMessage(game.Envelope{
+		err := player.Reply(ctx, game.Envelope{
 			Action:  ActionGetBalance,
-			Payload: balanceResult{Error: error.Message},
+			Payload: balanceResult{Error: error.Message, Currency: currencyCode},
 		})
 		if err != nil {
 			g.logger.Error("send message failed", "error", err, "playerID", player.ID)
 		}
 		return
 	}
-	err := player.SendMessage(game.Envelope{
-		Action:  ActionGetBalance,
-		Payload: balanceResult{Success: true, Balance: balance},
+	err := player.Reply(ctx, game.Envelope{
+		Action: ActionGetBalance,
+		Payload: balanceResult{
+			Success:      true,
+			Balance:      balances.Total,
+			CashBalance:  balances.Cash,
+			BonusBalance: balances.Bonus,
+			Currency:     currencyCode,
+		},
 	})
 	if err != nil {
 		g.logger.Error("send message failed", "error", err, "playerID", player.ID)
@@ -84,28 +133,42 @@ func (g *Game) RemovePlayer(player *game.Player) {
 
 // Play 處理玩家的遊玩請求，執行一次完整的單人遊戲流程。
 // 遊戲邏輯：骰出一個 1~6 的數字，如果結果為 1，玩家贏得 6 倍賭注。
-func (g *Game) Play(player *game.Player, betAmount decimal.Decimal) {
+func (g *Game) Play(ctx context.Context, player *game.Player, betAmount decimal.Decimal) {
 	var result playResult
+	cur := g.betLimits.Apply(player.Currency)
+
+	if err := cur.ValidateBet(betAmount); err != nil {
+		sendErr := player.Reply(ctx, game.Envelope{
+			Action:  ActionPlayResult,
+			Payload: playResult{Error: err.Error(), BetAmount: betAmount, Currency: cur.Code},
+		})
+		if sendErr != nil {
+			g.logger.Error("send message failed", "error", sendErr, "playerID", player.ID)
+		}
+		return
+	}
 
 	// 執行遊戲核心邏輯
 	dice := rand.IntN(6) + 1 // 產生1到6的隨機數
 	winAmount := decimal.Zero
 	if dice == 1 {
-		winAmount = betAmount.Mul(decimal.NewFromInt(6))
+		// 派彩依幣種精度與進位規則處理
+		winAmount = cur.Round(betAmount.Mul(decimal.NewFromInt(6)))
 	}
 	result = playResult{
 		Success:   true,
 		BetAmount: betAmount,
 		WinAmount: winAmount,
 		Dice:      dice,
+		Currency:  cur.Code,
 	}
 
-	newBalance, err := g.walletService.DebitAndCredit(player.ID, betAmount, winAmount)
+	newBalance, err := g.walletService.DebitAndCredit(ctx, player.ID, cur.Code, betAmount, winAmount)
 	if err != nil {
-		g.logger.Error("debit and credit failed", "playerID", player.ID, "betAmount", betAmount, "winAmount", winAmount, "error", err)
-		err := player.SendMessage(game.Envelope{
+		g.logger.Error("debit and credit failed", "playerID", player.ID, "requestID", requestid.FromContext(ctx), "currency", cur.Code, "betAmount", betAmount, "winAmount", winAmount, "error", err)
+		err := player.Reply(ctx, game.Envelope{
 			Action:  ActionPlayResult,
-			Payload: playResult{Error: err.Message, Balance: newBalance},
+			Payload: playResult{Error: err.Message, Balance: newBalance, Currency: cur.Code},
 		})
 		if err != nil {
 			g.logger.Error("send message failed", "error", err, "playerID", player.ID)
@@ -114,8 +177,8 @@ func (g *Game) Play(player *game.Player, betAmount decimal.Decimal) {
 	}
 	result.Balance = newBalance
 
-	// 將結果包裝在標準的 Envelope 中發送給客戶端
-	_ = player.SendMessage(game.Envelope{
+	// 將結果包裝在標準的 Envelope 中發送給客戶端，並帶上下注請求的 requestId
+	_ = player.Reply(ctx, game.Envelope{
 		Action:  ActionPlayResult,
 		Payload: result,
 	})
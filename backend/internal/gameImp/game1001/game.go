@@ -1,6 +1,7 @@
 package game1001
 
 import (
+	"context"
 	"log/slog"
 	"math/rand/v2"
 	"sync"
@@ -8,44 +9,59 @@ import (
 
 	"github.com/joe_shih/slot-factory/internal/application/wallet"
 	"github.com/joe_shih/slot-factory/internal/domain/game"
+	"github.com/joe_shih/slot-factory/internal/domain/operator"
+	"github.com/joe_shih/slot-factory/internal/domain/requestid"
 	"github.com/shopspring/decimal"
 )
 
-// betInfo 記錄了單一玩家的下注資訊。
+// betInfo 記錄了單一玩家本局已扣款、尚未開獎派彩的下注。
+// 下注與座位分開記錄：玩家在開獎前離開時，下注仍會在開獎時結算，Drain 也會等待它。
 type betInfo struct {
+	player    *game.Player // 下注時的玩家，用於派彩時的營運商、幣種與玩家 ID
 	betAmount decimal.Decimal
 }
 
-// gamePlayer 擴展了 domain.Player，加入了遊戲內的特定資料，如下注資訊。
-type gamePlayer struct {
-	*game.Player
-	betInfo betInfo
-}
-
 // Game 實作一個多人輪盤遊戲 (game.IGame 介面)。
 // 遊戲邏輯：每隔一段時間開獎，開出數字 1~10，開中 1 且有下注的玩家贏得10倍彩金。
 type Game struct {
 	id            int
-	players       map[string]*gamePlayer // 使用玩家 ID 作為 key
-	mu            sync.RWMutex           // 使用讀寫鎖保護 players map
+	players       map[string]*game.Player // 使用 game.Player.Key() (營運商ID:玩家ID) 作為 key
+	bets          map[string]*betInfo     // 本局尚未派彩的下注，key 與 players 相同
+	mu            sync.RWMutex            // 使用讀寫鎖保護 players、bets 與下列局數、計數
+	round         uint64                  // 已開獎的局數，用於辨識扣款期間已經開獎的下注
+	debiting      int                     // 扣款中 (不持有鎖) 的下注數
+	settling      int                     // 已開獎、派彩中 (不持有鎖) 的下注數
 	state         state
 	countdown     int
-	ticker        *time.Ticker  // 遊戲主循環的計時器
-	stopCh        chan struct{} // 用於停止遊戲主循環
+	ticker        *time.Ticker       // 遊戲主循環的計時器
+	stopCh        chan struct{}      // 用於停止遊戲主循環
+	ctx           context.Context    // 遊戲生命週期的 context，用於派彩等不隸屬於單一玩家的錢包呼叫
+	cancel        context.CancelFunc // 停止遊戲時取消 ctx
 	logger        *slog.Logger
 	walletService *wallet.Service
+	broadcaster   game.Broadcaster
+	group         string         // 房間群組，玩家加入遊戲後由遊戲中心加入，離開前移出
+	betLimits     game.BetLimits // 遊戲自身的下注限制，只能收窄玩家幣種的限制
 }
 
-// NewGame 創建一個新的 1001 輪盤遊戲實例。
-func NewGame(logger *slog.Logger, walletService *wallet.Service) game.IGame {
+// NewGame 創建一個新的 1001 輪盤遊戲實例，房間內的通知透過 broadcaster 向遊戲房間群組廣播。
+// betLimits 為 nil 時沿用玩家幣種的下注限制。
+func NewGame(logger *slog.Logger, walletService *wallet.Service, broadcaster game.Broadcaster, betLimits game.BetLimits) game.IGame {
+	ctx, cancel := context.WithCancel(context.Background())
 	game := &Game{
 		id:            1001,
-		players:       make(map[string]*gamePlayer),
+		ctx:           ctx,
+		cancel:        cancel,
+		players:       make(map[string]*game.Player),
+		bets:          make(map[string]*betInfo),
 		state:         StateWaiting,
 		ticker:        time.NewTicker(1 * time.Second),
 		stopCh:        make(chan struct{}),
 		logger:        logger.With("gameID", 1001),
 		walletService: walletService,
+		broadcaster:   broadcaster,
+		group:         game.GameGroup(1001),
+		betLimits:     betLimits,
 	}
 	game.startLoop()
 	return game
@@ -56,47 +72,46 @@ func (g *Game) ID() int {
 	return g.id
 }
 
+// Info 返回遊戲目錄資訊。開出 1 (機率 1/10) 時贏得 10 倍賭注，理論返還率為 100%。
+func (g *Game) Info() game.Info {
+	return game.Info{Name: "Lucky Wheel", Type: game.TypeMultiplayer, RTP: decimal.NewFromInt(100), BetLimits: g.betLimits}
+}
+
 // AddPlayer 將一個新玩家加入遊戲，並觸發狀態同步。
-func (g *Game) AddPlayer(player *game.Player) {
+func (g *Game) AddPlayer(ctx context.Context, player *game.Player) {
 	g.mu.Lock()
-	// 1. 建立遊戲玩家實例並加入列表
-	gp := &gamePlayer{
-		Player:  player,
-		betInfo: betInfo{betAmount: decimal.Zero},
-	}
-	g.players[player.ID] = gp
-	g.logger.Info("player added", "playerID", player.ID)
+	// 1. 將玩家加入列表 (重新加入的玩家沿用本局尚未派彩的下注)
+	g.players[player.Key()] = player
+	g.logger.Info("player added", "operatorID", player.OperatorID, "playerID", player.ID)
 
 	// 2. 準備新玩家和現有玩家的資訊
-	newPlayerInfo := PlayerInfo{ID: gp.ID, Name: gp.Name, BetAmount: gp.betInfo.betAmount}
+	newPlayerInfo := g.playerInfoLocked(player)
 
 	// 複製一份當前玩家列表以在解鎖後使用
-	playersToNotify := make([]*gamePlayer, 0, len(g.players)-1)
 	currentPlayerListForNewPlayer := make([]PlayerInfo, 0, len(g.players)-1)
-	for _, p := range g.players {
-		if p.ID != player.ID { // 不包含新玩家自己
-			playersToNotify = append(playersToNotify, p)
-			currentPlayerListForNewPlayer = append(currentPlayerListForNewPlayer, PlayerInfo{ID: p.ID, Name: p.Name, BetAmount: p.betInfo.betAmount})
+	for key, p := range g.players {
+		if key != player.Key() { // 不包含新玩家自己
+			currentPlayerListForNewPlayer = append(currentPlayerListForNewPlayer, g.playerInfoLocked(p))
 		}
 	}
 	currentState := g.state
 	currentCountdown := g.countdown
 	g.mu.Unlock() // !!! 在廣播前解鎖
 
-	// 3. 廣播 player_joined 給所有已在房內的玩家
-	g.broadcast(playersToNotify, game.Envelope{
+	// 3. 廣播 player_joined 給所有已在房內的玩家 (新玩家在 AddPlayer 返回後才加入房間群組)
+	g.broadcaster.Broadcast(g.group, game.Envelope{
 		Action:  string(ActionPlayerJoined),
 		Payload: PayloadPlayerJoined{Player: newPlayerInfo},
 	})
 
-	// 4. 發送 player_list 給新加入的玩家
-	_ = player.SendMessage(game.Envelope{
+	// 4. 發送 player_list 給新加入的玩家 (回應登入請求，帶上 requestId)
+	_ = player.Reply(ctx, game.Envelope{
 		Action:  string(ActionPlayerList),
 		Payload: PayloadPlayerList{Players: currentPlayerListForNewPlayer},
 	})
 
 	// 5. 發送當前遊戲狀態給新玩家
-	_ = player.SendMessage(game.Envelope{
+	_ = player.Reply(ctx, game.Envelope{
 		Action: string(ActionStateUpdate),
 		Payload: PayloadStateUpdate{
 			State:     currentState,
@@ -105,53 +120,88 @@ func (g *Game) AddPlayer(player *game.Player) {
 	})
 }
 
+// playerInfoLocked 回傳廣播給前端的玩家資訊，必須在持有鎖的情況下調用。
+func (g *Game) playerInfoLocked(p *game.Player) PlayerInfo {
+	betAmount := decimal.Zero
+	if bet, ok := g.bets[p.Key()]; ok {
+		betAmount = bet.betAmount
+	}
+	return PlayerInfo{ID: p.ID, OperatorID: p.OperatorID, Name: p.Name, BetAmount: betAmount, Currency: p.Currency.Code}
+}
+
 // RemovePlayer 從遊戲中移除一個玩家。
+// 玩家已由新的連線重新加入時，舊連線的離開不會移除新連線的座位。
+// 本局已下注的金額不會隨座位移除，仍會在開獎時派彩。
 func (g *Game) RemovePlayer(player *game.Player) {
 	g.mu.Lock()
-	delete(g.players, player.ID)
-	// 複製玩家列表用於廣播
-	allPlayers := g.getAllPlayers_unsafe()
+	current, ok := g.players[player.Key()]
+	if !ok || !current.SameClient(player) {
+		g.mu.Unlock()
+		g.logger.Info("ignore remove of stale connection", "playerID", player.ID)
+		return
+	}
+	delete(g.players, player.Key())
 	g.mu.Unlock() // 解鎖
 
-	// 廣播玩家離開的訊息
-	g.broadcast(allPlayers, game.Envelope{
+	// 廣播玩家離開的訊息 (離開的玩家已先被移出房間群組)
+	g.broadcaster.Broadcast(g.group, game.Envelope{
 		Action:  string(ActionPlayerLeft),
-		Payload: PayloadPlayerLeft{PlayerID: player.ID},
+		Payload: PayloadPlayerLeft{PlayerID: player.ID, OperatorID: player.OperatorID},
 	})
 	g.logger.Info("player removed", "playerID", player.ID)
 }
 
 // Play 處理玩家的下注請求。
-func (g *Game) Play(player *game.Player, betAmount decimal.Decimal) {
+//
+// 扣款期間不持有鎖，外部錢包變慢時不會拖住房間內的其他玩家與遊戲主循環。
+// 扣款完成前已經開獎時，這筆下注沒有參與開獎，會退款並回覆下注失敗。
+func (g *Game) Play(ctx context.Context, player *game.Player, betAmount decimal.Decimal) {
 	g.mu.Lock()
-	gamePlayer, ok := g.players[player.ID]
+	gamePlayer, ok := g.players[player.Key()]
 	if !ok {
 		g.mu.Unlock()
 		return
 	}
+	key := gamePlayer.Key()
 
 	if g.state != StateBetting {
 		g.mu.Unlock() // 解鎖後再發訊息
-		err := gamePlayer.SendMessage(game.Envelope{Action: string(ActionBetResult), Payload: PayloadBetResult{Success: false, Error: "not in betting state"}})
+		err := gamePlayer.Reply(ctx, game.Envelope{Action: string(ActionBetResult), Payload: PayloadBetResult{Success: false, Error: "not in betting state"}})
 		if err != nil {
 			g.logger.Error("send message failed", "error", err, "playerID", player.ID)
 		}
 		return
 	}
-	if betAmount.LessThanOrEqual(decimal.Zero) {
+	if err := g.betLimits.Apply(gamePlayer.Currency).ValidateBet(betAmount); err != nil {
 		g.mu.Unlock() // 解鎖後再發訊息
-		err := gamePlayer.SendMessage(game.Envelope{Action: string(ActionBetResult), Payload: PayloadBetResult{Success: false, Error: "bet amount must be positive"}})
+		err := gamePlayer.Reply(ctx, game.Envelope{Action: string(ActionBetResult), Payload: PayloadBetResult{Success: false, Error: err.Error(), Currency: gamePlayer.Currency.Code}})
 		if err != nil {
 			g.logger.Error("send message failed", "error", err, "playerID", player.ID)
 		}
 		return
 	}
 
+	// 記下扣款開始時的局數後解鎖，扣款期間其他玩家與主循環可以繼續運作
+	round := g.round
+	g.debiting++
+	g.mu.Unlock()
+
 	// 嘗試扣款
-	balance, err := g.walletService.Debit(player.ID, betAmount)
+	balance, err := g.walletService.Debit(ctx, player.ID, gamePlayer.Currency.Code, betAmount)
+	g.mu.Lock()
+	if err == nil && g.round != round {
+		g.mu.Unlock()
+		// 退款完成前仍計入扣款中，Drain 會等待退款
+		g.refund(ctx, gamePlayer, betAmount)
+		g.mu.Lock()
+		g.debiting--
+		g.mu.Unlock()
+		return
+	}
+	g.debiting--
 	if err != nil {
 		g.mu.Unlock() // 解鎖後再發訊息
-		err := gamePlayer.SendMessage(game.Envelope{
+		err := gamePlayer.Reply(ctx, game.Envelope{
 			Action: string(ActionBetResult),
 			Payload: PayloadBetResult{
 				Success: false,
@@ -161,37 +211,42 @@ func (g *Game) Play(player *game.Player, betAmount decimal.Decimal) {
 		if err != nil {
 			g.logger.Error("send message failed", "error", err, "playerID", player.ID)
 		}
-		g.logger.Error("payment debit failed", "playerID", player.ID, "error", err)
+		g.logger.Error("payment debit failed", "playerID", player.ID, "requestID", requestid.FromContext(ctx), "error", err)
 		return
 	}
 
 	// 更新玩家下注總額
-	gamePlayer.betInfo.betAmount = gamePlayer.betInfo.betAmount.Add(betAmount)
+	bet, ok := g.bets[key]
+	if !ok {
+		bet = &betInfo{player: gamePlayer, betAmount: decimal.Zero}
+		g.bets[key] = bet
+	}
+	bet.betAmount = bet.betAmount.Add(betAmount)
 
 	// 準備廣播資訊
 	betResultPayload := PayloadBetResult{
 		Success:  true,
-		TotalBet: gamePlayer.betInfo.betAmount,
+		TotalBet: bet.betAmount,
 		Balance:  balance,
+		Currency: gamePlayer.Currency.Code,
 	}
 	playerBetPayload := PayloadPlayerBet{
 		PlayerID:  gamePlayer.ID,
 		BetAmount: betAmount,
-		TotalBet:  gamePlayer.betInfo.betAmount,
+		TotalBet:  bet.betAmount,
+		Currency:  gamePlayer.Currency.Code,
 	}
 
-	// 複製玩家列表用於廣播
-	allPlayers := g.getAllPlayers_unsafe()
 	g.mu.Unlock() // !!! 解鎖
 
 	// 回傳個人下注結果
-	_ = gamePlayer.SendMessage(game.Envelope{
+	_ = gamePlayer.Reply(ctx, game.Envelope{
 		Action:  string(ActionBetResult),
 		Payload: betResultPayload,
 	})
 
 	// 廣播玩家下注活動
-	g.broadcast(allPlayers, game.Envelope{
+	g.broadcaster.Broadcast(g.group, game.Envelope{
 		Action:  string(ActionPlayerBet),
 		Payload: playerBetPayload,
 	})
@@ -229,6 +284,8 @@ func (g *Game) tick() {
 	case StateWaiting:
 		g.setState(StateBetting, 10)
 	case StateBetting:
+		// 先停止接受下注再開獎，開獎與派彩期間 (不持有鎖) 送達的下注會被拒絕
+		g.state = StateWaiting
 		// rollWheel 包含自己的鎖管理，所以這裡要先解鎖
 		g.mu.Unlock()
 		g.rollWheel()
@@ -253,10 +310,9 @@ func (g *Game) setState(next state, duration int) {
 			Countdown: g.countdown,
 		},
 	}
-	allPlayers := g.getAllPlayers_unsafe()
 
-	// 在 goroutine 中廣播，避免阻塞當前狀態機
-	go g.broadcast(allPlayers, msg)
+	// 廣播只將訊息放入各連線的發送佇列，不會阻塞狀態機
+	g.broadcaster.Broadcast(g.group, msg)
 }
 
 // rollWheel 執行開獎邏輯並廣播結果。
@@ -271,66 +327,104 @@ func (g *Game) rollWheel() {
 		Payload: PayloadOpening{Number: number},
 	}
 	// 廣播開獎號碼
-	allPlayers := g.getAllPlayers_unsafe()
-	g.broadcast(allPlayers, openingMsg)
-
-	g.mu.Lock() // 加鎖以安全地遍歷和修改玩家
-	// 遍歷玩家，計算並發送輸贏結果
-	for _, p := range g.players {
-		if p.betInfo.betAmount.GreaterThan(decimal.Zero) {
-			betAmount := p.betInfo.betAmount
-			winAmount := decimal.Zero
-			if isWin {
-				winAmount = betAmount.Mul(decimal.NewFromInt(10))
-			}
-			// 派彩
-			newBalance, err := g.walletService.Credit(p.ID, winAmount)
-			if err != nil {
-				g.logger.Error("payment credit failed", "playerID", p.ID, "amount", winAmount, "error", err)
-				// TODO: 處理派彩失敗的情況 (例如重試佇列)
-			}
-			// 在 goroutine 中發送個人訊息，避免阻塞
-			go func() {
-				_ = p.SendMessage(game.Envelope{
-					Action: string(ActionWinResult),
-					Payload: PayloadWinResult{
-						BetAmount: betAmount,
-						WinAmount: winAmount,
-						Balance:   newBalance,
-					},
-				})
-			}()
+	g.broadcaster.Broadcast(g.group, openingMsg)
+
+	// 在鎖內取出本局的下注 (包含已離開的玩家)，派彩期間不持有鎖，錢包變慢時不會拖住房間與主循環
+	g.mu.Lock()
+	bets := g.bets
+	g.bets = make(map[string]*betInfo)
+	g.round++
+	g.settling += len(bets)
+	g.mu.Unlock()
+
+	for key, bet := range bets {
+		p := bet.player
+		betAmount := bet.betAmount
+		winAmount := decimal.Zero
+		if isWin {
+			// 派彩依幣種精度與進位規則處理
+			winAmount = p.Currency.Round(betAmount.Mul(decimal.NewFromInt(10)))
+		}
+		// 派彩
+		// 派彩依玩家所屬營運商路由到對應的錢包
+		newBalance, err := g.walletService.Credit(operator.NewContext(g.ctx, p.OperatorID), p.ID, p.Currency.Code, winAmount)
+		if err != nil {
+			g.logger.Error("payment credit failed", "playerID", p.ID, "amount", winAmount, "error", err)
+			// TODO: 處理派彩失敗的情況 (例如重試佇列)
 		}
-		// 重置玩家下注額
-		p.betInfo.betAmount = decimal.Zero
-	}
 
-	g.mu.Unlock() // 解鎖
+		// 重新加鎖套用結算結果：結果發送給目前的座位 (玩家可能已由新的連線重新加入)，已離開的玩家只派彩不通知
+		g.mu.Lock()
+		seat, ok := g.players[key]
+		g.settling--
+		g.mu.Unlock()
+		if ok {
+			// SendMessage 只將訊息放入連線的發送佇列，不會阻塞
+			_ = seat.SendMessage(game.Envelope{
+				Action: string(ActionWinResult),
+				Payload: PayloadWinResult{
+					BetAmount: betAmount,
+					WinAmount: winAmount,
+					Balance:   newBalance,
+					Currency:  p.Currency.Code,
+				},
+			})
+		}
+	}
 }
 
-// broadcast 將訊息發送給指定的玩家列表。
-func (g *Game) broadcast(players []*gamePlayer, message game.Envelope) {
-	for _, p := range players {
-		go func() {
-			_ = p.SendMessage(message) // 使用 goroutine 非阻塞地發送
-		}()
+// refund 退還扣款完成前已經開獎的下注，並回覆下注失敗。
+// 玩家可能已經斷線，退款不隨請求的 context 取消。
+func (g *Game) refund(ctx context.Context, player *game.Player, betAmount decimal.Decimal) {
+	balance, payErr := g.walletService.Credit(context.WithoutCancel(ctx), player.ID, player.Currency.Code, betAmount)
+	if payErr != nil {
+		g.logger.Error("[ALARM] refund of late bet failed", "playerID", player.ID, "requestID", requestid.FromContext(ctx), "amount", betAmount, "error", payErr)
+	}
+	err := player.Reply(ctx, game.Envelope{
+		Action:  string(ActionBetResult),
+		Payload: PayloadBetResult{Success: false, Error: "not in betting state", Balance: balance, Currency: player.Currency.Code},
+	})
+	if err != nil {
+		g.logger.Error("send message failed", "error", err, "playerID", player.ID)
 	}
 }
 
-// getAllPlayers_unsafe 返回所有玩家的列表，呼叫前必須持有鎖。
-func (g *Game) getAllPlayers_unsafe() []*gamePlayer {
-	players := make([]*gamePlayer, 0, len(g.players))
-	for _, p := range g.players {
-		players = append(players, p)
+// drainPollInterval 是 Drain 檢查已下注的局是否已結算的間隔。
+const drainPollInterval = 100 * time.Millisecond
+
+// Drain 等待所有已下注的玩家在開獎後完成派彩 (game.Drainer 介面)。
+// 遊戲中心在呼叫前已停止接受新的下注，因此最多等待一局。
+func (g *Game) Drain(ctx context.Context) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		if !g.hasPendingBets() {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
-	return players
+}
+
+// hasPendingBets 判斷是否有已下注但尚未開獎派彩的局，包含已離開的玩家的下注與扣款、派彩中的下注。
+func (g *Game) hasPendingBets() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.bets) > 0 || g.debiting > 0 || g.settling > 0
 }
 
 // Stop 停止遊戲的主循環。
 func (g *Game) Stop() {
 	close(g.stopCh)
 	g.ticker.Stop()
+	g.cancel()
 }
 
 // 確保 Game 類型在編譯時期就實現了 IGame 接口。
 var _ game.IGame = (*Game)(nil)
+
+// 確保排空連線時會等待已下注的局結算。
+var _ game.Drainer = (*Game)(nil)
@@ -0,0 +1,272 @@
+package game1001
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joe_shih/slot-factory/internal/adapter/wallet/mock"
+	"github.com/joe_shih/slot-factory/internal/application/wallet"
+	"github.com/joe_shih/slot-factory/internal/config"
+	"github.com/joe_shih/slot-factory/internal/domain/currency"
+	"github.com/joe_shih/slot-factory/internal/domain/game"
+	"github.com/shopspring/decimal"
+)
+
+// fakeClient 是記錄收到訊息的 game.GameClient。
+type fakeClient struct {
+	mu   sync.Mutex
+	sent []game.Envelope
+}
+
+func (c *fakeClient) GetID() string            { return "c1" }
+func (c *fakeClient) Context() context.Context { return context.Background() }
+func (c *fakeClient) Kick(string) error        { return nil }
+func (c *fakeClient) GetTag(string) (any, bool) {
+	return nil, false
+}
+func (c *fakeClient) SetTag(string, any) {}
+func (c *fakeClient) GetIP() string      { return "127.0.0.1" }
+func (c *fakeClient) Join(string)        {}
+func (c *fakeClient) Leave(string)       {}
+
+func (c *fakeClient) SendMessage(message game.Envelope) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, message)
+	return nil
+}
+
+// nopBroadcaster 是不發送任何訊息的 game.Broadcaster。
+type nopBroadcaster struct{}
+
+func (nopBroadcaster) Broadcast(string, game.Envelope) int { return 0 }
+func (nopBroadcaster) BroadcastAll(game.Envelope) int      { return 0 }
+
+// gatedPayment 模擬變慢的錢包：gate 不為 nil 的操作會先通知 started，再等到 gate 關閉才送出。
+type gatedPayment struct {
+	wallet.Payment
+	started    chan struct{}
+	debitGate  chan struct{}
+	creditGate chan struct{}
+}
+
+func (p *gatedPayment) wait(gate chan struct{}) {
+	if gate != nil {
+		p.started <- struct{}{}
+		<-gate
+	}
+}
+
+func (p *gatedPayment) Debit(ctx context.Context, playerID string, currency string, amount decimal.Decimal) (decimal.Decimal, *wallet.PaymentError) {
+	p.wait(p.debitGate)
+	return p.Payment.Debit(ctx, playerID, currency, amount)
+}
+
+func (p *gatedPayment) Credit(ctx context.Context, playerID string, currency string, amount decimal.Decimal) (decimal.Decimal, *wallet.PaymentError) {
+	p.wait(p.creditGate)
+	return p.Payment.Credit(ctx, playerID, currency, amount)
+}
+
+// newTestGame 建立一個不啟動主循環、處於下注階段的遊戲，玩家的起始餘額為 1000 TWD。
+func newTestGame(t *testing.T) (*Game, *gatedPayment) {
+	t.Helper()
+	mockPayment, err := mock.NewPayment(config.MockWalletConfig{StartingBalances: map[string]string{"TWD": "1000"}})
+	if err != nil {
+		t.Fatalf("mock.NewPayment: %v", err)
+	}
+	payment := &gatedPayment{Payment: mockPayment, started: make(chan struct{}, 1)}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return &Game{
+		id:            1001,
+		ctx:           ctx,
+		cancel:        cancel,
+		players:       make(map[string]*game.Player),
+		bets:          make(map[string]*betInfo),
+		state:         StateBetting,
+		logger:        logger,
+		walletService: wallet.NewService(logger, payment, nil),
+		broadcaster:   nopBroadcaster{},
+		group:         game.GameGroup(1001),
+	}, payment
+}
+
+// newTestPlayer 建立營運商 default 的玩家，回傳玩家與其連線。
+func newTestPlayer(t *testing.T, id string) (*game.Player, *fakeClient) {
+	t.Helper()
+	twd, err := currency.DefaultRegistry().Resolve("TWD")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	client := &fakeClient{}
+	player := game.NewPlayer(id, "Player "+id, twd, client)
+	player.OperatorID = "default"
+	return player, client
+}
+
+func TestDrainWaitsForBetsOfPlayersWhoLeft(t *testing.T) {
+	g, payment := newTestGame(t)
+	player, _ := newTestPlayer(t, "p1")
+	ctx := context.Background()
+
+	g.AddPlayer(ctx, player)
+	g.Play(ctx, player, decimal.NewFromInt(10))
+	g.RemovePlayer(player)
+
+	if !g.hasPendingBets() {
+		t.Fatal("bet of a player who left is no longer pending")
+	}
+	drainCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := g.Drain(drainCtx); err == nil {
+		t.Fatal("Drain returned before the bet was settled")
+	}
+
+	g.rollWheel()
+	if err := g.Drain(ctx); err != nil {
+		t.Fatalf("Drain after settlement: %v", err)
+	}
+	// 派彩仍會進到已離開玩家的錢包：輸了餘額為 990，贏了為 1090
+	balance, perr := payment.GetBalance(ctx, "p1", "TWD")
+	if perr != nil {
+		t.Fatalf("GetBalance: %v", perr)
+	}
+	if !balance.Equal(decimal.NewFromInt(990)) && !balance.Equal(decimal.NewFromInt(1090)) {
+		t.Fatalf("balance = %s, want 990 or 1090", balance)
+	}
+}
+
+func TestRejoinKeepsPendingBet(t *testing.T) {
+	g, _ := newTestGame(t)
+	player, _ := newTestPlayer(t, "p1")
+	ctx := context.Background()
+
+	g.AddPlayer(ctx, player)
+	g.Play(ctx, player, decimal.NewFromInt(10))
+	g.RemovePlayer(player)
+
+	rejoined, client := newTestPlayer(t, "p1")
+	g.AddPlayer(ctx, rejoined)
+	g.Play(ctx, rejoined, decimal.NewFromInt(5))
+
+	g.mu.RLock()
+	total := g.bets[rejoined.Key()].betAmount
+	g.mu.RUnlock()
+	if !total.Equal(decimal.NewFromInt(15)) {
+		t.Fatalf("total bet = %s, want 15", total)
+	}
+
+	g.rollWheel()
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	last := client.sent[len(client.sent)-1]
+	if last.Action != string(ActionWinResult) {
+		t.Fatalf("last message = %s, want %s on the new connection", last.Action, ActionWinResult)
+	}
+	if got := last.Payload.(PayloadWinResult).BetAmount; !got.Equal(decimal.NewFromInt(15)) {
+		t.Fatalf("settled bet = %s, want 15", got)
+	}
+}
+
+// lastMessage 回傳連線最後收到的訊息。
+func (c *fakeClient) lastMessage(t *testing.T) game.Envelope {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.sent) == 0 {
+		t.Fatal("no message sent")
+	}
+	return c.sent[len(c.sent)-1]
+}
+
+// within 在 timeout 內執行 fn，逾時代表 fn 被鎖住。
+func within(t *testing.T, timeout time.Duration, what string, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		t.Fatalf("%s blocked while a wallet call was in flight", what)
+	}
+}
+
+func TestSlowDebitDoesNotBlockRoom(t *testing.T) {
+	g, payment := newTestGame(t)
+	payment.debitGate = make(chan struct{})
+	p1, client := newTestPlayer(t, "p1")
+	p2, _ := newTestPlayer(t, "p2")
+	ctx := context.Background()
+	g.AddPlayer(ctx, p1)
+
+	played := make(chan struct{})
+	go func() {
+		defer close(played)
+		g.Play(ctx, p1, decimal.NewFromInt(10))
+	}()
+	<-payment.started
+
+	within(t, time.Second, "AddPlayer", func() { g.AddPlayer(ctx, p2) })
+	if !g.hasPendingBets() {
+		t.Fatal("bet being debited is not pending, Drain would not wait for it")
+	}
+	// 扣款期間倒數結束並開獎
+	within(t, time.Second, "tick", g.tick)
+
+	close(payment.debitGate)
+	<-played
+	// 扣款完成時已經開獎，這筆下注沒有參與開獎，必須退款
+	balance, err := payment.GetBalance(ctx, "p1", "TWD")
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	if !balance.Equal(decimal.NewFromInt(1000)) {
+		t.Fatalf("balance = %s, want the late bet refunded (1000)", balance)
+	}
+	last := client.lastMessage(t)
+	if result, ok := last.Payload.(PayloadBetResult); last.Action != string(ActionBetResult) || !ok || result.Success {
+		t.Fatalf("last message = %+v, want a failed bet_result", last)
+	}
+	if g.hasPendingBets() {
+		t.Fatal("refunded bet is still pending")
+	}
+}
+
+func TestSlowCreditDoesNotBlockRoom(t *testing.T) {
+	g, payment := newTestGame(t)
+	p1, client := newTestPlayer(t, "p1")
+	p2, _ := newTestPlayer(t, "p2")
+	ctx := context.Background()
+	g.AddPlayer(ctx, p1)
+	g.Play(ctx, p1, decimal.NewFromInt(10))
+
+	payment.creditGate = make(chan struct{})
+	rolled := make(chan struct{})
+	go func() {
+		defer close(rolled)
+		g.rollWheel()
+	}()
+	<-payment.started
+
+	within(t, time.Second, "AddPlayer", func() { g.AddPlayer(ctx, p2) })
+	if !g.hasPendingBets() {
+		t.Fatal("bet being settled is not pending, Drain would not wait for it")
+	}
+	close(payment.creditGate)
+	<-rolled
+
+	if err := g.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if last := client.lastMessage(t); last.Action != string(ActionWinResult) {
+		t.Fatalf("last message = %s, want %s", last.Action, ActionWinResult)
+	}
+}
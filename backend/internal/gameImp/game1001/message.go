@@ -1,6 +1,9 @@
 package game1001
 
-import "github.com/shopspring/decimal"
+import (
+	"github.com/joe_shih/slot-factory/pkg/codec"
+	"github.com/shopspring/decimal"
+)
 
 // ActionType 定義了此遊戲內部使用的訊息動作類型。
 type ActionType string
@@ -18,11 +21,34 @@ const (
 
 // --- Payloads ---
 
+// 確保此遊戲送出的訊息內容都有 slot.proto 的編碼。
+var (
+	_ codec.ProtoMessage = PayloadPlayerList{}
+	_ codec.ProtoMessage = PayloadPlayerJoined{}
+	_ codec.ProtoMessage = PayloadPlayerLeft{}
+	_ codec.ProtoMessage = PayloadStateUpdate{}
+	_ codec.ProtoMessage = PayloadPlayerBet{}
+	_ codec.ProtoMessage = PayloadBetResult{}
+	_ codec.ProtoMessage = PayloadOpening{}
+	_ codec.ProtoMessage = PayloadWinResult{}
+)
+
 // PlayerInfo 定義了廣播給前端的玩家資訊。
 type PlayerInfo struct {
-	ID        string          `json:"id"`
-	Name      string          `json:"name"`
-	BetAmount decimal.Decimal `json:"betAmount"`
+	ID         string          `json:"id"`
+	OperatorID string          `json:"operatorId"`
+	Name       string          `json:"name"`
+	BetAmount  decimal.Decimal `json:"betAmount"`
+	Currency   string          `json:"currency"`
+}
+
+// AppendProto 依 slot.proto 的 Game1001PlayerInfo 編碼。
+func (p PlayerInfo) AppendProto(b []byte) []byte {
+	b = codec.AppendString(b, 1, p.ID)
+	b = codec.AppendString(b, 2, p.OperatorID)
+	b = codec.AppendString(b, 3, p.Name)
+	b = codec.AppendString(b, 4, p.BetAmount.String())
+	return codec.AppendString(b, 5, p.Currency)
 }
 
 // PayloadPlayerList 是發送給新玩家的當前玩家列表。
@@ -30,14 +56,34 @@ type PayloadPlayerList struct {
 	Players []PlayerInfo `json:"players"`
 }
 
+// AppendProto 依 slot.proto 的 Game1001PlayerList 編碼。
+func (p PayloadPlayerList) AppendProto(b []byte) []byte {
+	for _, player := range p.Players {
+		b = codec.AppendMessage(b, 1, player)
+	}
+	return b
+}
+
 // PayloadPlayerJoined 是廣播給房間內所有人的新玩家資訊。
 type PayloadPlayerJoined struct {
 	Player PlayerInfo `json:"player"`
 }
 
+// AppendProto 依 slot.proto 的 Game1001PlayerJoined 編碼。
+func (p PayloadPlayerJoined) AppendProto(b []byte) []byte {
+	return codec.AppendMessage(b, 1, p.Player)
+}
+
 // PayloadPlayerLeft 是廣播給房間內所有人的離開玩家資訊。
 type PayloadPlayerLeft struct {
-	PlayerID string `json:"playerId"`
+	PlayerID   string `json:"playerId"`
+	OperatorID string `json:"operatorId"`
+}
+
+// AppendProto 依 slot.proto 的 Game1001PlayerLeft 編碼。
+func (p PayloadPlayerLeft) AppendProto(b []byte) []byte {
+	b = codec.AppendString(b, 1, p.PlayerID)
+	return codec.AppendString(b, 2, p.OperatorID)
 }
 
 // PayloadStateUpdate 廣播遊戲狀態變更。
@@ -46,11 +92,26 @@ type PayloadStateUpdate struct {
 	Countdown int   `json:"countdown"`
 }
 
+// AppendProto 依 slot.proto 的 Game1001StateUpdate 編碼。
+func (p PayloadStateUpdate) AppendProto(b []byte) []byte {
+	b = codec.AppendString(b, 1, string(p.State))
+	return codec.AppendInt64(b, 2, int64(p.Countdown))
+}
+
 // PayloadPlayerBet 廣播玩家的下注活動。
 type PayloadPlayerBet struct {
 	PlayerID  string          `json:"playerId"`
 	BetAmount decimal.Decimal `json:"betAmount"`
 	TotalBet  decimal.Decimal `json:"totalBet"`
+	Currency  string          `json:"currency"`
+}
+
+// AppendProto 依 slot.proto 的 Game1001PlayerBet 編碼。
+func (p PayloadPlayerBet) AppendProto(b []byte) []byte {
+	b = codec.AppendString(b, 1, p.PlayerID)
+	b = codec.AppendString(b, 2, p.BetAmount.String())
+	b = codec.AppendString(b, 3, p.TotalBet.String())
+	return codec.AppendString(b, 4, p.Currency)
 }
 
 // PayloadBetResult 是伺服器回傳給下注玩家的個人結果。
@@ -59,6 +120,16 @@ type PayloadBetResult struct {
 	Error    string          `json:"error,omitempty"`
 	TotalBet decimal.Decimal `json:"totalBet,omitempty"`
 	Balance  decimal.Decimal `json:"balance,omitempty"`
+	Currency string          `json:"currency,omitempty"`
+}
+
+// AppendProto 依 slot.proto 的 Game1001BetResult 編碼。
+func (p PayloadBetResult) AppendProto(b []byte) []byte {
+	b = codec.AppendBool(b, 1, p.Success)
+	b = codec.AppendString(b, 2, p.Error)
+	b = codec.AppendString(b, 3, p.TotalBet.String())
+	b = codec.AppendString(b, 4, p.Balance.String())
+	return codec.AppendString(b, 5, p.Currency)
 }
 
 // PayloadOpening 廣播開獎結果。
@@ -66,9 +137,23 @@ type PayloadOpening struct {
 	Number int `json:"number"`
 }
 
+// AppendProto 依 slot.proto 的 Game1001Opening 編碼。
+func (p PayloadOpening) AppendProto(b []byte) []byte {
+	return codec.AppendInt64(b, 1, int64(p.Number))
+}
+
 // PayloadWinResult 廣播給贏家的中獎訊息。
 type PayloadWinResult struct {
 	BetAmount decimal.Decimal `json:"betAmount"`
 	WinAmount decimal.Decimal `json:"winAmount"`
 	Balance   decimal.Decimal `json:"balance"`
+	Currency  string          `json:"currency"`
+}
+
+// AppendProto 依 slot.proto 的 Game1001WinResult 編碼。
+func (p PayloadWinResult) AppendProto(b []byte) []byte {
+	b = codec.AppendString(b, 1, p.BetAmount.String())
+	b = codec.AppendString(b, 2, p.WinAmount.String())
+	b = codec.AppendString(b, 3, p.Balance.String())
+	return codec.AppendString(b, 4, p.Currency)
 }
@@ -0,0 +1,453 @@
+package mockplatform
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	walletProxy "github.com/joe_shih/slot-factory/internal/adapter/wallet/proxy"
+	"github.com/joe_shih/slot-factory/internal/config"
+	"github.com/shopspring/decimal"
+)
+
+// DuplicateMode 決定收到重複交易 ID 時的行為。
+type DuplicateMode string
+
+const (
+	// DuplicateReplay 回傳先前的交易結果，不重複入帳（冪等）。
+	DuplicateReplay DuplicateMode = "replay"
+	// DuplicateReject 回傳 409 Conflict。
+	DuplicateReject DuplicateMode = "reject"
+	// DuplicateApply 視為新交易再次入帳，模擬不具冪等性的平台。
+	DuplicateApply DuplicateMode = "apply"
+)
+
+// Faults 是可在執行期調整的故障注入設定。
+type Faults struct {
+	LatencyMs        int           `json:"latencyMs"`
+	LatencyJitterMs  int           `json:"latencyJitterMs"`
+	ErrorRate        float64       `json:"errorRate"`
+	ErrorStatus      int           `json:"errorStatus"`
+	LostResponseRate float64       `json:"lostResponseRate"`
+	DuplicateMode    DuplicateMode `json:"duplicateMode"`
+}
+
+// validate 檢查設定值並補上預設值。
+func (f *Faults) validate() error {
+	if f.ErrorRate < 0 || f.ErrorRate > 1 {
+		return fmt.Errorf("errorRate must be between 0 and 1, got %v", f.ErrorRate)
+	}
+	if f.LostResponseRate < 0 || f.LostResponseRate > 1 {
+		return fmt.Errorf("lostResponseRate must be between 0 and 1, got %v", f.LostResponseRate)
+	}
+	if f.ErrorStatus == 0 {
+		f.ErrorStatus = http.StatusInternalServerError
+	}
+	switch f.DuplicateMode {
+	case "":
+		f.DuplicateMode = DuplicateReplay
+	case DuplicateReplay, DuplicateReject, DuplicateApply:
+	default:
+		return fmt.Errorf("unknown duplicateMode: %s", f.DuplicateMode)
+	}
+	return nil
+}
+
+// Server 是模擬的無縫錢包 (Seamless Wallet) 平台，實作了 Proxy 錢包串接的 API：
+//
+//	GET  /balance/:playerID?currency=
+//	POST /debit     {playerID, currency, amount, transactionID, requestID?}
+//	POST /credit    {playerID, currency, amount, transactionID, requestID?}
+//	POST /spin      {playerID, currency, debitAmount, creditAmount, transactionID, requestID?}
+//	POST /rollback  {playerID, type, transactionID}
+//	POST /auth/verify {token}
+//
+// 交易以玩家、交易類型與 Proxy 錢包產生的交易 ID (transactionID) 識別，同一組識別重複送出時依 DuplicateMode 處理。
+// 客戶端的請求 ID (requestID) 只記錄在日誌中，不參與交易識別。
+//
+// 另外提供 GET/PUT /admin/faults 讓整合測試在執行期調整延遲與故障注入，
+// 管理端點以 X-Admin-Key 標頭驗證，不經過錢包驗證與故障注入；未設定 AdminKey 時停用管理端點。
+type Server struct {
+	store  Store
+	logger *slog.Logger
+
+	apiKey   string
+	adminKey string
+	signer   *walletProxy.HMACSigner // 非 nil 時使用 HMAC 驗章與簽章
+
+	faultsMu sync.RWMutex
+	faults   Faults
+
+	engine *gin.Engine
+}
+
+// NewServer 建立一個模擬錢包平台。
+//
+// 參數說明：
+//   - store: Store, 帳戶與交易的儲存實作。
+//   - cfg: config.MockPlatformConfig, 延遲、故障注入與重複請求行為的設定。
+//   - walletCfg: config.ExternalWalletConfig, 與 Proxy 錢包共用的 API Key 與簽章設定。
+//   - nonces: walletProxy.NonceStore, 啟用 HMAC 時記錄已使用的請求 Nonce，傳入 nil 則使用記憶體儲存。
+//   - logger: *slog.Logger, 日誌記錄器。
+//
+// 回傳值：
+//   - *Server: 初始化完成的模擬平台。
+//   - error: 如果故障注入設定或簽章方案無效，則返回錯誤。
+func NewServer(store Store, cfg config.MockPlatformConfig, walletCfg config.ExternalWalletConfig, nonces walletProxy.NonceStore, logger *slog.Logger) (*Server, error) {
+	faults := Faults{
+		LatencyMs:        cfg.LatencyMs,
+		LatencyJitterMs:  cfg.LatencyJitterMs,
+		ErrorRate:        cfg.ErrorRate,
+		ErrorStatus:      cfg.ErrorStatus,
+		LostResponseRate: cfg.LostResponseRate,
+		DuplicateMode:    DuplicateMode(cfg.DuplicateMode),
+	}
+	if err := faults.validate(); err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		store:    store,
+		logger:   logger,
+		apiKey:   walletCfg.APIKey,
+		adminKey: cfg.AdminKey,
+		faults:   faults,
+	}
+	switch walletCfg.Signing.Scheme {
+	case "", walletProxy.SchemeBearer:
+	case walletProxy.SchemeHMACSHA256:
+		if walletCfg.Signing.Secret == "" {
+			return nil, errors.New("hmac-sha256 signing requires a secret")
+		}
+		s.signer = walletProxy.NewHMACSigner(walletCfg.Signing.KeyID, walletCfg.Signing.Secret,
+			time.Duration(walletCfg.Signing.ReplayWindowSec)*time.Second)
+		if nonces != nil {
+			s.signer.SetNonceStore(nonces)
+		}
+	default:
+		return nil, fmt.Errorf("mock platform does not support signing scheme: %s", walletCfg.Signing.Scheme)
+	}
+
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	admin := engine.Group("/admin", s.authenticateAdmin)
+	{
+		admin.GET("/faults", s.handleGetFaults)
+		admin.PUT("/faults", s.handleSetFaults)
+	}
+	api := engine.Group("/", s.authenticate, s.injectFaults)
+	{
+		api.GET("/balance/:playerID", s.handleBalance)
+		api.POST("/debit", s.handleDebit)
+		api.POST("/credit", s.handleCredit)
+		api.POST("/spin", s.handleSpin)
+		api.POST("/rollback", s.handleRollback)
+	}
+	// 驗證端點與錢包的簽章無關，因此不經過 authenticate
+	engine.POST("/auth/verify", s.injectFaults, s.handleVerifyToken)
+	s.engine = engine
+	return s, nil
+}
+
+// Handler 回傳平台的 http.Handler，可直接掛到 http.Server 或 httptest.Server。
+func (s *Server) Handler() http.Handler {
+	return s.engine
+}
+
+// Faults 回傳目前的故障注入設定。
+func (s *Server) Faults() Faults {
+	s.faultsMu.RLock()
+	defer s.faultsMu.RUnlock()
+	return s.faults
+}
+
+// SetFaults 在執行期替換故障注入設定。
+func (s *Server) SetFaults(f Faults) error {
+	if err := f.validate(); err != nil {
+		return err
+	}
+	s.faultsMu.Lock()
+	defer s.faultsMu.Unlock()
+	s.faults = f
+	return nil
+}
+
+// --- 回應格式 ---
+
+// apiResponse 與 Proxy 錢包解析的回應格式一致。
+type apiResponse struct {
+	Balance       decimal.Decimal `json:"balance"`
+	Status        string          `json:"status"`
+	Message       string          `json:"message,omitempty"`
+	TransactionID string          `json:"transactionID,omitempty"`
+}
+
+// respond 輸出 JSON 回應，啟用 HMAC 時附加回應簽章。
+func (s *Server) respond(c *gin.Context, status int, resp apiResponse) {
+	body, err := json.Marshal(resp)
+	if err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if s.signer != nil {
+		s.signer.SignResponse(c.Writer, c.Request, status, body)
+	}
+	c.Data(status, "application/json", body)
+}
+
+// fail 輸出錯誤回應並中止後續 handler。
+func (s *Server) fail(c *gin.Context, status int, message string) {
+	s.respond(c, status, apiResponse{Status: "error", Message: message})
+	c.Abort()
+}
+
+// --- Middleware ---
+
+// authenticate 驗證 Bearer API Key 或 HMAC 簽章。
+func (s *Server) authenticate(c *gin.Context) {
+	if s.signer != nil {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			s.fail(c, http.StatusBadRequest, "cannot read body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		if err := s.signer.VerifyRequest(c.Request, body); err != nil {
+			s.fail(c, http.StatusUnauthorized, err.Error())
+			return
+		}
+		return
+	}
+	if s.apiKey != "" && c.GetHeader("Authorization") != "Bearer "+s.apiKey {
+		s.fail(c, http.StatusUnauthorized, "invalid api key")
+	}
+}
+
+// HeaderAdminKey 是呼叫管理端點時必須帶上的標頭。
+const HeaderAdminKey = "X-Admin-Key"
+
+// authenticateAdmin 驗證管理端點的 X-Admin-Key，未設定 AdminKey 時拒絕所有管理請求。
+func (s *Server) authenticateAdmin(c *gin.Context) {
+	if s.adminKey == "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin api is disabled"})
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.GetHeader(HeaderAdminKey)), []byte(s.adminKey)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin key"})
+	}
+}
+
+// injectFaults 依設定注入延遲與處理前的隨機失敗。
+func (s *Server) injectFaults(c *gin.Context) {
+	f := s.Faults()
+	delay := time.Duration(f.LatencyMs) * time.Millisecond
+	if f.LatencyJitterMs > 0 {
+		delay += rand.N(time.Duration(f.LatencyJitterMs) * time.Millisecond)
+	}
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-c.Request.Context().Done():
+			c.Abort()
+			return
+		}
+	}
+	if f.ErrorRate > 0 && rand.Float64() < f.ErrorRate {
+		s.fail(c, f.ErrorStatus, "injected failure")
+	}
+}
+
+// --- Handlers ---
+
+type walletRequest struct {
+	TransactionID string          `json:"transactionID" binding:"required"`
+	RequestID     string          `json:"requestID"`
+	PlayerID      string          `json:"playerID" binding:"required"`
+	Currency      string          `json:"currency" binding:"required"`
+	Amount        decimal.Decimal `json:"amount"`
+	DebitAmount   decimal.Decimal `json:"debitAmount"`
+	CreditAmount  decimal.Decimal `json:"creditAmount"`
+}
+
+type rollbackRequest struct {
+	PlayerID      string `json:"playerID" binding:"required"`
+	Type          string `json:"type" binding:"required"`
+	TransactionID string `json:"transactionID" binding:"required"`
+}
+
+// transactionID 以玩家、交易類型與呼叫端的交易 ID 組合出 Store 的交易 ID，
+// 讓不同玩家或交易類型的交易不會因呼叫端重複使用 ID 而互相覆蓋。
+func transactionID(playerID, txType, id string) string {
+	return playerID + ":" + txType + ":" + id
+}
+
+func (s *Server) handleBalance(c *gin.Context) {
+	currency := c.Query("currency")
+	if currency == "" {
+		s.fail(c, http.StatusBadRequest, "currency is required")
+		return
+	}
+	balance, err := s.store.Balance(c.Request.Context(), c.Param("playerID"), currency)
+	if err != nil {
+		s.logger.Error("balance failed", "error", err)
+		s.fail(c, http.StatusInternalServerError, "store error")
+		return
+	}
+	s.respond(c, http.StatusOK, apiResponse{Balance: balance, Status: "ok"})
+}
+
+func (s *Server) handleDebit(c *gin.Context) {
+	s.handleTransaction(c, TxDebit, func(req walletRequest) (decimal.Decimal, decimal.Decimal) {
+		return req.Amount, decimal.Zero
+	})
+}
+
+func (s *Server) handleCredit(c *gin.Context) {
+	s.handleTransaction(c, TxCredit, func(req walletRequest) (decimal.Decimal, decimal.Decimal) {
+		return decimal.Zero, req.Amount
+	})
+}
+
+func (s *Server) handleSpin(c *gin.Context) {
+	s.handleTransaction(c, TxSpin, func(req walletRequest) (decimal.Decimal, decimal.Decimal) {
+		return req.DebitAmount, req.CreditAmount
+	})
+}
+
+// handleTransaction 處理扣款、派彩與下注派彩合併的共用流程，包含重複交易與遺失回應的模擬。
+func (s *Server) handleTransaction(c *gin.Context, txType string, amounts func(walletRequest) (debit, credit decimal.Decimal)) {
+	var req walletRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	debit, credit := amounts(req)
+	if debit.IsNegative() || credit.IsNegative() {
+		s.fail(c, http.StatusBadRequest, "amount must not be negative")
+		return
+	}
+
+	f := s.Faults()
+	tx := &Transaction{
+		ID:       transactionID(req.PlayerID, txType, req.TransactionID),
+		PlayerID: req.PlayerID,
+		Currency: req.Currency,
+		Type:     txType,
+		Debit:    debit,
+		Credit:   credit,
+	}
+	if f.DuplicateMode == DuplicateApply {
+		// 不具冪等性的平台會忽略交易 ID，每次都產生新的交易
+		tx.ID = ""
+	}
+
+	err := s.store.Apply(c.Request.Context(), tx)
+	var dup *DuplicateError
+	switch {
+	case errors.As(err, &dup):
+		if f.DuplicateMode == DuplicateReject {
+			s.fail(c, http.StatusConflict, "duplicate transaction")
+			return
+		}
+		s.respond(c, http.StatusOK, apiResponse{
+			Balance:       dup.Existing.BalanceAfter,
+			Status:        "ok",
+			Message:       "duplicate transaction replayed",
+			TransactionID: dup.Existing.ID,
+		})
+		return
+	case errors.Is(err, ErrInsufficientBalance):
+		s.fail(c, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		s.logger.Error("apply transaction failed", "error", err, "type", txType, "requestID", req.RequestID)
+		s.fail(c, http.StatusInternalServerError, "store error")
+		return
+	}
+
+	if f.LostResponseRate > 0 && rand.Float64() < f.LostResponseRate {
+		s.logger.Info("injected lost response", "transactionID", tx.ID, "type", txType, "requestID", req.RequestID)
+		s.fail(c, f.ErrorStatus, "injected lost response")
+		return
+	}
+	s.respond(c, http.StatusOK, apiResponse{Balance: tx.BalanceAfter, Status: "ok", TransactionID: tx.ID})
+}
+
+func (s *Server) handleRollback(c *gin.Context) {
+	var req rollbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		s.fail(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	id := transactionID(req.PlayerID, req.Type, req.TransactionID)
+	tx, balance, err := s.store.Rollback(c.Request.Context(), id)
+	if errors.Is(err, ErrTransactionNotFound) {
+		s.fail(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		s.logger.Error("rollback failed", "error", err, "transactionID", id)
+		s.fail(c, http.StatusInternalServerError, "store error")
+		return
+	}
+	s.respond(c, http.StatusOK, apiResponse{Balance: balance, Status: "ok", TransactionID: tx.ID})
+}
+
+type verifyTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// handleVerifyToken 模擬營運商的 token 驗證端點。
+//
+// token 格式為 "玩家ID" 或 "玩家ID:幣種"；以 "expired" 開頭回傳過期，以 "invalid" 開頭或為空則回傳無效。
+func (s *Server) handleVerifyToken(c *gin.Context) {
+	var req verifyTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "token_invalid", "message": err.Error()})
+		return
+	}
+	switch {
+	case strings.HasPrefix(req.Token, "expired"):
+		c.JSON(http.StatusUnauthorized, gin.H{"code": "token_expired", "message": "token has expired"})
+		return
+	case req.Token == "", strings.HasPrefix(req.Token, "invalid"):
+		c.JSON(http.StatusUnauthorized, gin.H{"code": "token_invalid", "message": "token is not recognized"})
+		return
+	}
+	userID, currency, _ := strings.Cut(req.Token, ":")
+	c.JSON(http.StatusOK, gin.H{
+		"userID":     userID,
+		"name":       "Player" + userID,
+		"currency":   currency,
+		"language":   "zh-TW",
+		"operatorID": "mock-operator",
+		"country":    "TW",
+	})
+}
+
+func (s *Server) handleGetFaults(c *gin.Context) {
+	c.JSON(http.StatusOK, s.Faults())
+}
+
+func (s *Server) handleSetFaults(c *gin.Context) {
+	var f Faults
+	if err := c.ShouldBindJSON(&f); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.SetFaults(f); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, s.Faults())
+}
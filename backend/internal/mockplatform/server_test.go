@@ -0,0 +1,227 @@
+package mockplatform_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joe_shih/slot-factory/internal/adapter/wallet/bonus"
+	walletProxy "github.com/joe_shih/slot-factory/internal/adapter/wallet/proxy"
+	"github.com/joe_shih/slot-factory/internal/application/wallet"
+	"github.com/joe_shih/slot-factory/internal/config"
+	"github.com/joe_shih/slot-factory/internal/domain/currency"
+	"github.com/joe_shih/slot-factory/internal/domain/requestid"
+	"github.com/joe_shih/slot-factory/internal/mockplatform"
+	"github.com/shopspring/decimal"
+)
+
+const testAdminKey = "test-admin-key"
+
+// newPlatform 啟動模擬平台，並建立以相同錢包設定連線的 ProxyPayment。
+func newPlatform(t *testing.T, walletCfg config.ExternalWalletConfig) (*mockplatform.Server, *httptest.Server, *walletProxy.ProxyPayment) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	server, err := mockplatform.NewServer(mockplatform.NewMemoryStore(nil), config.MockPlatformConfig{AdminKey: testAdminKey}, walletCfg, nil, logger)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	srv := httptest.NewServer(server.Handler())
+	t.Cleanup(srv.Close)
+
+	walletCfg.BaseURL = srv.URL
+	payment, err := walletProxy.NewPayment(nil, "test-op", walletCfg, logger)
+	if err != nil {
+		t.Fatalf("NewPayment: %v", err)
+	}
+	return server, srv, payment
+}
+
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func balanceOf(t *testing.T, payment *walletProxy.ProxyPayment, playerID string) decimal.Decimal {
+	t.Helper()
+	balance, err := payment.GetBalance(context.Background(), playerID, "TWD")
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	return balance
+}
+
+func TestProxyPaymentAgainstMockPlatform(t *testing.T) {
+	tests := []struct {
+		name      string
+		walletCfg config.ExternalWalletConfig
+	}{
+		{name: "bearer", walletCfg: config.ExternalWalletConfig{APIKey: "k"}},
+		{name: "hmac", walletCfg: config.ExternalWalletConfig{Signing: config.WalletSigningConfig{
+			Scheme: walletProxy.SchemeHMACSHA256, KeyID: "k1", Secret: "s",
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, payment := newPlatform(t, tt.walletCfg)
+			ctx := context.Background()
+			if _, err := payment.Debit(requestid.NewContext(ctx, "r1"), "p1", "TWD", dec("10")); err != nil {
+				t.Fatalf("Debit: %v", err)
+			}
+			if _, err := payment.Credit(requestid.NewContext(ctx, "r1"), "p1", "TWD", dec("4")); err != nil {
+				t.Fatalf("Credit: %v", err)
+			}
+			balance, err := payment.DebitAndCredit(requestid.NewContext(ctx, "r2"), "p1", "TWD", dec("1"), dec("3"))
+			if err != nil {
+				t.Fatalf("DebitAndCredit: %v", err)
+			}
+			if !balance.Equal(dec("99996")) {
+				t.Fatalf("balance = %s, want 99996", balance)
+			}
+		})
+	}
+}
+
+func TestProxyPaymentChargesEveryCallWithSameRequestID(t *testing.T) {
+	_, _, payment := newPlatform(t, config.ExternalWalletConfig{})
+	// 請求 ID 由客戶端提供，重複送出相同的 ID 仍是不同的下注，不能被當作重複交易
+	ctx := requestid.NewContext(context.Background(), "same")
+
+	for i := 0; i < 2; i++ {
+		if _, err := payment.Debit(ctx, "p1", "TWD", dec("10")); err != nil {
+			t.Fatalf("Debit %d: %v", i, err)
+		}
+	}
+	if got := balanceOf(t, payment, "p1"); !got.Equal(dec("99980")) {
+		t.Fatalf("p1 balance = %s, want both debits (99980)", got)
+	}
+}
+
+func TestMockPlatformDeduplicatesByTransactionID(t *testing.T) {
+	_, srv, payment := newPlatform(t, config.ExternalWalletConfig{})
+	post := func(path, body string) int {
+		t.Helper()
+		resp, err := http.Post(srv.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	for i := 0; i < 2; i++ {
+		if status := post("/debit", `{"playerID":"p1","currency":"TWD","amount":"10","transactionID":"tx-1"}`); status != http.StatusOK {
+			t.Fatalf("debit %d: status %d", i, status)
+		}
+	}
+	if got := balanceOf(t, payment, "p1"); !got.Equal(dec("99990")) {
+		t.Fatalf("p1 balance = %s, want a single debit (99990)", got)
+	}
+	// 不同玩家相同的交易 ID 必須視為不同交易
+	if status := post("/debit", `{"playerID":"p2","currency":"TWD","amount":"10","transactionID":"tx-1"}`); status != http.StatusOK {
+		t.Fatalf("debit p2: status %d", status)
+	}
+	if got := balanceOf(t, payment, "p2"); !got.Equal(dec("99990")) {
+		t.Fatalf("p2 balance = %s, want 99990", got)
+	}
+	if status := post("/debit", `{"playerID":"p1","currency":"TWD","amount":"10","requestID":"r1"}`); status != http.StatusBadRequest {
+		t.Fatalf("debit without transactionID: status %d, want 400", status)
+	}
+}
+
+func TestProxyPaymentRollsBackLostDebit(t *testing.T) {
+	server, _, payment := newPlatform(t, config.ExternalWalletConfig{})
+	if err := server.SetFaults(mockplatform.Faults{LostResponseRate: 1}); err != nil {
+		t.Fatalf("SetFaults: %v", err)
+	}
+
+	// 沒有客戶端請求 ID 的交易同樣要沖正
+	ctx := context.Background()
+	if _, err := payment.Debit(ctx, "p1", "TWD", dec("10")); err == nil {
+		t.Fatal("Debit succeeded although the response was lost")
+	}
+	if _, err := payment.DebitAndCredit(requestid.NewContext(ctx, "lost-spin"), "p1", "TWD", dec("5"), dec("1")); err == nil {
+		t.Fatal("DebitAndCredit succeeded although the response was lost")
+	}
+	if err := server.SetFaults(mockplatform.Faults{}); err != nil {
+		t.Fatalf("SetFaults: %v", err)
+	}
+	if got := balanceOf(t, payment, "p1"); !got.Equal(dec("100000")) {
+		t.Fatalf("balance = %s, want lost transactions rolled back (100000)", got)
+	}
+}
+
+func TestMockPlatformAdminRequiresKey(t *testing.T) {
+	_, srv, _ := newPlatform(t, config.ExternalWalletConfig{})
+	tests := []struct {
+		name string
+		key  string
+		want int
+	}{
+		{name: "missing key", key: "", want: http.StatusUnauthorized},
+		{name: "wrong key", key: "nope", want: http.StatusUnauthorized},
+		{name: "valid key", key: testAdminKey, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"/admin/faults", nil)
+			if tt.key != "" {
+				req.Header.Set(mockplatform.HeaderAdminKey, tt.key)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("GET /admin/faults: %v", err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestMockPlatformAdminDisabledWithoutKey(t *testing.T) {
+	server, err := mockplatform.NewServer(mockplatform.NewMemoryStore(nil), config.MockPlatformConfig{}, config.ExternalWalletConfig{}, nil,
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/admin/faults", nil)
+	req.Header.Set(mockplatform.HeaderAdminKey, "")
+	server.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403", rec.Code)
+	}
+}
+
+func TestBonusReleaseIsNotReplayedAsTheWinCredit(t *testing.T) {
+	_, _, payment := newPlatform(t, config.ExternalWalletConfig{})
+	store := bonus.NewMemoryStore()
+	ledger := wallet.NewBonusLedger(store, wallet.SpendBonusFirst, currency.DefaultRegistry(), "default")
+	svc := wallet.NewService(slog.New(slog.NewTextHandler(io.Discard, nil)), payment, ledger)
+	ctx := requestid.NewContext(context.Background(), "r1")
+
+	if _, err := svc.GrantBonus(ctx, "p1", "TWD", dec("10"), dec("1")); err != nil {
+		t.Fatalf("GrantBonus: %v", err)
+	}
+	// 流水要求 10；下注 15：紅利支付 10、現金支付 5
+	if _, err := svc.Debit(ctx, "p1", "TWD", dec("15")); err != nil {
+		t.Fatalf("Debit: %v", err)
+	}
+	// 同一個請求的派彩 30：現金分到 10，紅利分到 20 並因流水達標轉入現金，兩筆都是 credit
+	if _, err := svc.Credit(ctx, "p1", "TWD", dec("30")); err != nil {
+		t.Fatalf("Credit: %v", err)
+	}
+
+	if got := balanceOf(t, payment, "p1"); !got.Equal(dec("100025")) {
+		t.Fatalf("cash balance = %s, want the win and the released bonus paid (100025)", got)
+	}
+	if bonuses, _ := store.ListActive(ctx, "default", "p1", "TWD"); len(bonuses) != 0 {
+		t.Fatalf("bonus still active after release: %+v", bonuses[0])
+	}
+}
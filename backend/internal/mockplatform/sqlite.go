@@ -0,0 +1,199 @@
+package mockplatform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// accountModel 對應 SQLite 的 platform_accounts 表。
+type accountModel struct {
+	PlayerID string          `gorm:"column:player_id;primaryKey"`
+	Currency string          `gorm:"column:currency;primaryKey"`
+	Balance  decimal.Decimal `gorm:"column:balance"`
+}
+
+func (accountModel) TableName() string {
+	return "platform_accounts"
+}
+
+// transactionModel 對應 SQLite 的 platform_transactions 表。
+type transactionModel struct {
+	ID           string          `gorm:"column:id;primaryKey"`
+	PlayerID     string          `gorm:"column:player_id;index"`
+	Currency     string          `gorm:"column:currency"`
+	Type         string          `gorm:"column:type"`
+	Debit        decimal.Decimal `gorm:"column:debit"`
+	Credit       decimal.Decimal `gorm:"column:credit"`
+	BalanceAfter decimal.Decimal `gorm:"column:balance_after"`
+	RolledBack   bool            `gorm:"column:rolled_back"`
+	CreatedAt    time.Time       `gorm:"column:created_at"`
+}
+
+func (transactionModel) TableName() string {
+	return "platform_transactions"
+}
+
+// SQLiteStore 是 Store 的 SQLite 實作，重啟後資料仍會保留。
+//
+// 使用 mattn/go-sqlite3，需要在 CGO_ENABLED=1 的環境下編譯。
+type SQLiteStore struct {
+	db       *gorm.DB
+	starting StartingBalances
+}
+
+var _ Store = (*SQLiteStore)(nil)
+
+// NewSQLiteStore 開啟 (或建立) SQLite 資料庫並建立所需的資料表。
+//
+// 參數說明：
+//   - path: string, 資料庫檔案路徑。
+//   - starting: StartingBalances, 新開戶時的起始餘額。
+//
+// 回傳值：
+//   - *SQLiteStore: 初始化完成的 SQLite 儲存。
+//   - error: 如果無法開啟資料庫或建立資料表，則返回錯誤。
+func NewSQLiteStore(path string, starting StartingBalances) (*SQLiteStore, error) {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite %s: %w", path, err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	// SQLite 同時只允許一個寫入者，單一連線可避免 "database is locked"
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&accountModel{}, &transactionModel{}); err != nil {
+		return nil, fmt.Errorf("migrate sqlite: %w", err)
+	}
+	return &SQLiteStore{db: db, starting: starting}, nil
+}
+
+func (s *SQLiteStore) Balance(ctx context.Context, playerID string, currency string) (decimal.Decimal, error) {
+	var balance decimal.Decimal
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		acc, err := s.account(tx, playerID, currency)
+		if err != nil {
+			return err
+		}
+		balance = acc.Balance
+		return nil
+	})
+	return balance, err
+}
+
+func (s *SQLiteStore) Apply(ctx context.Context, t *Transaction) error {
+	if t.ID == "" {
+		t.ID = uuid.NewString()
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing transactionModel
+		err := tx.Where("id = ?", t.ID).Take(&existing).Error
+		if err == nil {
+			return &DuplicateError{Existing: existing.toTransaction()}
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		acc, err := s.account(tx, t.PlayerID, t.Currency)
+		if err != nil {
+			return err
+		}
+		if acc.Balance.LessThan(t.Debit) {
+			return ErrInsufficientBalance
+		}
+		acc.Balance = acc.Balance.Sub(t.Debit).Add(t.Credit)
+		if err := tx.Save(acc).Error; err != nil {
+			return err
+		}
+		t.BalanceAfter = acc.Balance
+		t.CreatedAt = time.Now()
+		return tx.Create(toTransactionModel(t)).Error
+	})
+}
+
+func (s *SQLiteStore) Rollback(ctx context.Context, id string) (*Transaction, decimal.Decimal, error) {
+	var (
+		result  *Transaction
+		balance decimal.Decimal
+	)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var m transactionModel
+		if err := tx.Where("id = ?", id).Take(&m).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTransactionNotFound
+			}
+			return err
+		}
+		acc, err := s.account(tx, m.PlayerID, m.Currency)
+		if err != nil {
+			return err
+		}
+		if !m.RolledBack {
+			acc.Balance = acc.Balance.Add(m.Debit).Sub(m.Credit)
+			if err := tx.Save(acc).Error; err != nil {
+				return err
+			}
+			m.RolledBack = true
+			if err := tx.Model(&m).Update("rolled_back", true).Error; err != nil {
+				return err
+			}
+		}
+		result = m.toTransaction()
+		balance = acc.Balance
+		return nil
+	})
+	if err != nil {
+		return nil, decimal.Zero, err
+	}
+	return result, balance, nil
+}
+
+// account 取得玩家帳戶，不存在時以起始餘額開戶。
+func (s *SQLiteStore) account(tx *gorm.DB, playerID string, currency string) (*accountModel, error) {
+	acc := &accountModel{PlayerID: playerID, Currency: currency, Balance: s.starting.For(currency)}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(acc).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("player_id = ? AND currency = ?", playerID, currency).Take(acc).Error; err != nil {
+		return nil, err
+	}
+	return acc, nil
+}
+
+func (m transactionModel) toTransaction() *Transaction {
+	return &Transaction{
+		ID:           m.ID,
+		PlayerID:     m.PlayerID,
+		Currency:     m.Currency,
+		Type:         m.Type,
+		Debit:        m.Debit,
+		Credit:       m.Credit,
+		BalanceAfter: m.BalanceAfter,
+		RolledBack:   m.RolledBack,
+		CreatedAt:    m.CreatedAt,
+	}
+}
+
+func toTransactionModel(t *Transaction) *transactionModel {
+	return &transactionModel{
+		ID:           t.ID,
+		PlayerID:     t.PlayerID,
+		Currency:     t.Currency,
+		Type:         t.Type,
+		Debit:        t.Debit,
+		Credit:       t.Credit,
+		BalanceAfter: t.BalanceAfter,
+		RolledBack:   t.RolledBack,
+		CreatedAt:    t.CreatedAt,
+	}
+}
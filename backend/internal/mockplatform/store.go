@@ -0,0 +1,170 @@
+package mockplatform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// 交易類型，對應平台的 /debit、/credit 與 /spin 端點。
+const (
+	TxDebit  = "debit"
+	TxCredit = "credit"
+	TxSpin   = "spin"
+)
+
+var (
+	// ErrInsufficientBalance 表示玩家餘額不足以扣款。
+	ErrInsufficientBalance = errors.New("balance is not enough")
+	// ErrTransactionNotFound 表示找不到指定的交易。
+	ErrTransactionNotFound = errors.New("transaction not found")
+)
+
+// DuplicateError 表示交易 ID 已經被處理過，Existing 為先前的交易結果。
+type DuplicateError struct {
+	Existing *Transaction
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("duplicate transaction: %s", e.Existing.ID)
+}
+
+// defaultStartingBalance 是未針對幣種設定起始餘額時使用的預設值。
+var defaultStartingBalance = decimal.NewFromInt(100000)
+
+// StartingBalances 是各幣種新玩家的起始餘額，key 為大寫幣種代碼。
+type StartingBalances map[string]decimal.Decimal
+
+// ParseStartingBalances 將設定檔中的字串金額轉換為 StartingBalances。
+func ParseStartingBalances(raw map[string]string) (StartingBalances, error) {
+	balances := make(StartingBalances, len(raw))
+	for cur, amount := range raw {
+		d, err := decimal.NewFromString(amount)
+		if err != nil {
+			return nil, fmt.Errorf("invalid starting balance for %s: %w", cur, err)
+		}
+		balances[strings.ToUpper(cur)] = d
+	}
+	return balances, nil
+}
+
+// For 回傳指定幣種的起始餘額，未設定時為 100000。
+func (b StartingBalances) For(currency string) decimal.Decimal {
+	if d, ok := b[strings.ToUpper(currency)]; ok {
+		return d
+	}
+	return defaultStartingBalance
+}
+
+// Transaction 是平台端的一筆錢包交易。
+type Transaction struct {
+	ID           string          `json:"transactionID"`
+	PlayerID     string          `json:"playerID"`
+	Currency     string          `json:"currency"`
+	Type         string          `json:"type"`
+	Debit        decimal.Decimal `json:"debit"`
+	Credit       decimal.Decimal `json:"credit"`
+	BalanceAfter decimal.Decimal `json:"balanceAfter"`
+	RolledBack   bool            `json:"rolledBack"`
+	CreatedAt    time.Time       `json:"createdAt"`
+}
+
+// Store 定義了模擬平台的帳戶與交易儲存。
+type Store interface {
+	// Balance 回傳玩家指定幣種的餘額，帳戶不存在時以起始餘額開戶。
+	Balance(ctx context.Context, playerID string, currency string) (decimal.Decimal, error)
+
+	// Apply 原子地扣除 tx.Debit、加上 tx.Credit 並寫入交易紀錄，成功後填入 ID 與 BalanceAfter。
+	// tx.ID 為空時自動產生；已存在時回傳 *DuplicateError 且不異動餘額。
+	Apply(ctx context.Context, tx *Transaction) error
+
+	// Rollback 沖正指定交易並回傳沖正後的交易與玩家餘額。已沖正的交易重複呼叫不會再次異動。
+	Rollback(ctx context.Context, id string) (*Transaction, decimal.Decimal, error)
+}
+
+// MemoryStore 是 Store 的記憶體實作，程式結束後資料即消失。
+type MemoryStore struct {
+	mu       sync.Mutex
+	starting StartingBalances
+	balances map[string]decimal.Decimal // key: 玩家ID:幣種
+	txs      map[string]*Transaction
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore 建立一個記憶體儲存。
+func NewMemoryStore(starting StartingBalances) *MemoryStore {
+	return &MemoryStore{
+		starting: starting,
+		balances: make(map[string]decimal.Decimal),
+		txs:      make(map[string]*Transaction),
+	}
+}
+
+func (s *MemoryStore) Balance(_ context.Context, playerID string, currency string) (decimal.Decimal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.balanceLocked(playerID, currency), nil
+}
+
+func (s *MemoryStore) Apply(_ context.Context, tx *Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tx.ID == "" {
+		tx.ID = uuid.NewString()
+	}
+	if existing, ok := s.txs[tx.ID]; ok {
+		copied := *existing
+		return &DuplicateError{Existing: &copied}
+	}
+	balance := s.balanceLocked(tx.PlayerID, tx.Currency)
+	if balance.LessThan(tx.Debit) {
+		return ErrInsufficientBalance
+	}
+	balance = balance.Sub(tx.Debit).Add(tx.Credit)
+	s.balances[accountKey(tx.PlayerID, tx.Currency)] = balance
+	tx.BalanceAfter = balance
+	tx.CreatedAt = time.Now()
+	copied := *tx
+	s.txs[tx.ID] = &copied
+	return nil
+}
+
+func (s *MemoryStore) Rollback(_ context.Context, id string) (*Transaction, decimal.Decimal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, ok := s.txs[id]
+	if !ok {
+		return nil, decimal.Zero, ErrTransactionNotFound
+	}
+	balance := s.balanceLocked(tx.PlayerID, tx.Currency)
+	if !tx.RolledBack {
+		balance = balance.Add(tx.Debit).Sub(tx.Credit)
+		s.balances[accountKey(tx.PlayerID, tx.Currency)] = balance
+		tx.RolledBack = true
+	}
+	copied := *tx
+	return &copied, balance, nil
+}
+
+// balanceLocked 取得餘額，帳戶不存在時開戶。呼叫前必須持有鎖。
+func (s *MemoryStore) balanceLocked(playerID string, currency string) decimal.Decimal {
+	key := accountKey(playerID, currency)
+	balance, ok := s.balances[key]
+	if !ok {
+		balance = s.starting.For(currency)
+		s.balances[key] = balance
+	}
+	return balance
+}
+
+// accountKey 組合玩家 ID 與幣種作為 key。
+func accountKey(playerID string, currency string) string {
+	return playerID + ":" + currency
+}
@@ -0,0 +1,112 @@
+// Package codec 定義 WebSocket 訊息的編碼方式，由客戶端在連線時以 Sec-WebSocket-Protocol 子協定選擇。
+//
+// JSON 與 MessagePack 以 encoding/json 的規則描述訊息：欄位名稱、omitempty 與自訂的 MarshalJSON
+// (例如 decimal 以字串表示) 在兩種編碼下都相同，差別只在傳輸格式。
+// Protobuf 則以 slot.proto 定義的訊息型別編碼，訊息型別須實作 ProtoMarshaler。
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Codec 是一種訊息編碼方式。
+type Codec interface {
+	// Subprotocol 是客戶端協商時使用的 Sec-WebSocket-Protocol 名稱。
+	Subprotocol() string
+	// Binary 表示編碼結果應以 binary frame 發送，否則以 text frame 發送。
+	Binary() bool
+	// Encode 將 v 編碼為傳輸格式。
+	Encode(v any) ([]byte, error)
+	// ToJSON 將客戶端送來的訊息轉換為等價的 JSON，讓上層只需處理一種格式。
+	ToJSON(data []byte) ([]byte, error)
+}
+
+// 支援的子協定名稱。
+const (
+	SubprotocolJSON     = "slot.v1.json"
+	SubprotocolMsgpack  = "slot.v1.msgpack"
+	SubprotocolProtobuf = "slot.v1.protobuf"
+)
+
+var (
+	// JSON 是精簡 (無縮排) 的 JSON 編碼，也是客戶端未指定子協定時的預設值。
+	JSON Codec = jsonCodec{}
+	// Msgpack 是 MessagePack 編碼，以 binary frame 發送。
+	Msgpack Codec = msgpackCodec{}
+	// Protobuf 是以 slot.proto 定義的訊息型別編碼的 Protocol Buffers，以 binary frame 發送。
+	Protobuf Codec = protobufCodec{}
+)
+
+// all 依伺服器偏好順序列出所有 codec。
+var all = []Codec{JSON, Msgpack, Protobuf}
+
+// Subprotocols 依伺服器偏好順序回傳所有支援的子協定名稱，用於 WebSocket 升級時的協商。
+func Subprotocols() []string {
+	names := make([]string, len(all))
+	for i, c := range all {
+		names[i] = c.Subprotocol()
+	}
+	return names
+}
+
+// ForSubprotocol 回傳子協定對應的 codec，空字串或不支援的子協定回傳 JSON。
+func ForSubprotocol(name string) Codec {
+	for _, c := range all {
+		if c.Subprotocol() == name {
+			return c
+		}
+	}
+	return JSON
+}
+
+// jsonCodec 是精簡的 JSON 編碼。
+type jsonCodec struct{}
+
+func (jsonCodec) Subprotocol() string { return SubprotocolJSON }
+
+func (jsonCodec) Binary() bool { return false }
+
+func (jsonCodec) Encode(v any) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) ToJSON(data []byte) ([]byte, error) { return data, nil }
+
+// normalize 依 encoding/json 的規則將 v 轉為由 map、slice、字串、數字與布林組成的通用結構，
+// 整數維持為 int64，其餘數字為 float64。
+func normalize(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return convertNumbers(generic), nil
+}
+
+// convertNumbers 將 json.Number 轉為 int64 或 float64。
+func convertNumbers(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, item := range t {
+			t[k] = convertNumbers(item)
+		}
+		return t
+	case []any:
+		for i, item := range t {
+			t[i] = convertNumbers(item)
+		}
+		return t
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	default:
+		return v
+	}
+}
@@ -0,0 +1,44 @@
+package codec
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/ugorji/go/codec"
+)
+
+// msgpackHandle 設定 MessagePack 的編解碼：字串以 str 型別傳輸，map 解碼為 map[string]any 以便轉回 JSON。
+var msgpackHandle = func() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{}
+	h.WriteExt = true
+	h.RawToString = true
+	h.MapType = reflect.TypeOf(map[string]any(nil))
+	return h
+}()
+
+// msgpackCodec 是 MessagePack 編碼。
+type msgpackCodec struct{}
+
+func (msgpackCodec) Subprotocol() string { return SubprotocolMsgpack }
+
+func (msgpackCodec) Binary() bool { return true }
+
+func (msgpackCodec) Encode(v any) ([]byte, error) {
+	generic, err := normalize(v)
+	if err != nil {
+		return nil, err
+	}
+	var out []byte
+	if err := codec.NewEncoderBytes(&out, msgpackHandle).Encode(generic); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (msgpackCodec) ToJSON(data []byte) ([]byte, error) {
+	var generic any
+	if err := codec.NewDecoderBytes(data, msgpackHandle).Decode(&generic); err != nil {
+		return nil, err
+	}
+	return json.Marshal(generic)
+}
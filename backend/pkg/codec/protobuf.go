@@ -0,0 +1,234 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// protobufCodec 是以 slot.proto 定義的訊息表示的 Protocol Buffers 編碼。
+//
+// 伺服器訊息必須實作 ProtoMarshaler，由訊息型別自行依 slot.proto 編碼，不經過 JSON。
+// 客戶端請求依 action 以 requestMessages 中對應的請求訊息解碼，再轉換為等價的 JSON 交給上層。
+// 沒有 protoc 產生的程式碼，直接以 protowire 編解碼，客戶端可以用 slot.proto 產生程式碼。
+type protobufCodec struct{}
+
+// ProtoMarshaler 是可以編碼為 slot.proto 中 Envelope 的訊息。
+type ProtoMarshaler interface {
+	MarshalProto() ([]byte, error)
+}
+
+// ProtoMessage 是 slot.proto 中定義的 payload 訊息。
+type ProtoMessage interface {
+	// AppendProto 將訊息依 slot.proto 編碼後附加到 b。
+	AppendProto(b []byte) []byte
+}
+
+// Envelope 的欄位編號，與 slot.proto 一致。
+const (
+	envelopeAction    protowire.Number = 1
+	envelopeRequestID protowire.Number = 2
+	envelopePayload   protowire.Number = 3
+	envelopeData      protowire.Number = 4
+)
+
+// fieldKind 是請求訊息欄位的 proto 型別。
+type fieldKind int
+
+const (
+	kindString fieldKind = iota
+	kindInt64
+	kindUint64
+)
+
+// requestField 是請求訊息的一個欄位，name 為對應的 JSON 欄位名稱。
+type requestField struct {
+	name string
+	kind fieldKind
+}
+
+// requestMessages 是各 action 的請求訊息 (Envelope.data) 欄位，與 slot.proto 一致。
+// 不在表中的 action 沒有 data，收到的 data 會被忽略。
+var requestMessages = map[string]map[protowire.Number]requestField{
+	"login": {
+		1: {name: "sid", kind: kindString},
+		2: {name: "gameId", kind: kindInt64},
+		3: {name: "operatorId", kind: kindString},
+	},
+	"resume": {1: {name: "sessionToken", kind: kindString}},
+	"play":   {1: {name: "betAmount", kind: kindString}},
+	"ping":   {1: {name: "clientTime", kind: kindInt64}},
+	"pong":   {1: {name: "seq", kind: kindUint64}},
+}
+
+func (protobufCodec) Subprotocol() string { return SubprotocolProtobuf }
+
+func (protobufCodec) Binary() bool { return true }
+
+func (protobufCodec) Encode(v any) ([]byte, error) {
+	m, ok := v.(ProtoMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("codec: %T has no protobuf encoding", v)
+	}
+	return m.MarshalProto()
+}
+
+// AppendEnvelope 將伺服器訊息依 slot.proto 的 Envelope 編碼後附加到 b，payload 為 nil 時省略。
+func AppendEnvelope(b []byte, action, requestID string, payload ProtoMessage) []byte {
+	b = AppendString(b, envelopeAction, action)
+	b = AppendString(b, envelopeRequestID, requestID)
+	if payload != nil {
+		b = AppendMessage(b, envelopePayload, payload)
+	}
+	return b
+}
+
+// AppendString 附加 string 欄位，空字串依 proto3 的規則省略。
+func AppendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+// AppendInt64 附加 int64 欄位，0 依 proto3 的規則省略。
+func AppendInt64(b []byte, num protowire.Number, v int64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(v))
+}
+
+// AppendUint64 附加 uint64 欄位，0 依 proto3 的規則省略。
+func AppendUint64(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// AppendBool 附加 bool 欄位，false 依 proto3 的規則省略。
+func AppendBool(b []byte, num protowire.Number, v bool) []byte {
+	if !v {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, 1)
+}
+
+// AppendMessage 附加巢狀訊息欄位，空的訊息也會寫入，讓客戶端可以分辨欄位是否存在。
+func AppendMessage(b []byte, num protowire.Number, m ProtoMessage) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m.AppendProto(nil))
+}
+
+func (protobufCodec) ToJSON(data []byte) ([]byte, error) {
+	var (
+		action, requestID string
+		body              []byte
+		hasBody           bool
+	)
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+		switch {
+		case (num == envelopeAction || num == envelopeRequestID) && typ == protowire.BytesType:
+			s, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			if !utf8.ValidString(s) {
+				return nil, fmt.Errorf("codec: envelope field %d is not valid UTF-8", num)
+			}
+			data = data[n:]
+			if num == envelopeAction {
+				action = s
+			} else {
+				requestID = s
+			}
+		case num == envelopeData && typ == protowire.BytesType:
+			b, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			body, hasBody = b, true
+		default:
+			// 略過未知的欄位 (包含只有伺服器會送出的 payload)，保留新增欄位時的相容性
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+
+	m := map[string]any{"action": action}
+	if requestID != "" {
+		m["requestId"] = requestID
+	}
+	// data 要在讀完整個 Envelope 後才解碼，因為欄位順序不保證 action 在 data 之前
+	if fields, ok := requestMessages[action]; ok && hasBody {
+		value, err := parseRequest(body, fields)
+		if err != nil {
+			return nil, fmt.Errorf("codec: invalid %s data: %w", action, err)
+		}
+		m["data"] = value
+	}
+	return json.Marshal(m)
+}
+
+// parseRequest 依欄位表解碼請求訊息，轉為以 JSON 欄位名稱為 key 的 map。
+// 整數以 json.Number 保留，避免經過 float64 失去精度。
+func parseRequest(b []byte, fields map[protowire.Number]requestField) (map[string]any, error) {
+	out := make(map[string]any)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		field, ok := fields[num]
+		switch {
+		case ok && field.kind == kindString && typ == protowire.BytesType:
+			s, n := protowire.ConsumeString(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			if !utf8.ValidString(s) {
+				return nil, fmt.Errorf("field %q is not valid UTF-8", field.name)
+			}
+			b = b[n:]
+			out[field.name] = s
+		case ok && field.kind != kindString && typ == protowire.VarintType:
+			x, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+			if field.kind == kindInt64 {
+				out[field.name] = json.Number(strconv.FormatInt(int64(x), 10))
+			} else {
+				out[field.name] = json.Number(strconv.FormatUint(x, 10))
+			}
+		case ok:
+			return nil, fmt.Errorf("field %q has wire type %d", field.name, typ)
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,224 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// testPlayer 對應 slot.proto 的 Game1001PlayerInfo 的前兩個欄位。
+type testPlayer struct {
+	ID        string
+	BetAmount string
+}
+
+func (p testPlayer) AppendProto(b []byte) []byte {
+	b = AppendString(b, 1, p.ID)
+	return AppendString(b, 4, p.BetAmount)
+}
+
+// testList 對應 slot.proto 的 Game1001PlayerList。
+type testList struct {
+	Players []testPlayer
+}
+
+func (l testList) AppendProto(b []byte) []byte {
+	for _, p := range l.Players {
+		b = AppendMessage(b, 1, p)
+	}
+	return b
+}
+
+// testEnvelope 是實作 ProtoMarshaler 的伺服器訊息。
+type testEnvelope struct {
+	action, requestID string
+	payload           ProtoMessage
+}
+
+func (e testEnvelope) MarshalProto() ([]byte, error) {
+	return AppendEnvelope(nil, e.action, e.requestID, e.payload), nil
+}
+
+// fields 將一層 protobuf 訊息解碼為欄位編號對應的原始值，varint 為 uint64，length-delimited 為 []byte。
+func fields(t *testing.T, b []byte) map[protowire.Number][]any {
+	t.Helper()
+	out := make(map[protowire.Number][]any)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("ConsumeTag: %v", protowire.ParseError(n))
+		}
+		b = b[n:]
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				t.Fatalf("ConsumeVarint: %v", protowire.ParseError(n))
+			}
+			out[num] = append(out[num], v)
+			b = b[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				t.Fatalf("ConsumeBytes: %v", protowire.ParseError(n))
+			}
+			out[num] = append(out[num], v)
+			b = b[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+	}
+	return out
+}
+
+func TestProtobufEncodesTypedEnvelope(t *testing.T) {
+	msg := testEnvelope{action: "player_list", requestID: "r1", payload: testList{Players: []testPlayer{
+		{ID: "p1", BetAmount: "12345678901234567890.12345678"},
+		{ID: "p2"},
+	}}}
+	data, err := Protobuf.Encode(msg)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	envelope := fields(t, data)
+	if got := string(envelope[envelopeAction][0].([]byte)); got != "player_list" {
+		t.Fatalf("action = %q", got)
+	}
+	if got := string(envelope[envelopeRequestID][0].([]byte)); got != "r1" {
+		t.Fatalf("request_id = %q", got)
+	}
+	players := fields(t, envelope[envelopePayload][0].([]byte))[1]
+	if len(players) != 2 {
+		t.Fatalf("players = %d, want 2", len(players))
+	}
+	first := fields(t, players[0].([]byte))
+	if got := string(first[4][0].([]byte)); got != "12345678901234567890.12345678" {
+		t.Fatalf("bet_amount = %q, want the exact decimal string", got)
+	}
+	if second := fields(t, players[1].([]byte)); len(second[4]) != 0 {
+		t.Fatalf("empty bet_amount was written: %v", second[4])
+	}
+
+	// 沒有 payload 時省略欄位
+	data, err = Protobuf.Encode(testEnvelope{action: "logout"})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if _, ok := fields(t, data)[envelopePayload]; ok {
+		t.Fatal("payload written for a message without payload")
+	}
+}
+
+func TestProtobufRejectsUntypedMessage(t *testing.T) {
+	if _, err := Protobuf.Encode(map[string]any{"action": "x"}); err == nil {
+		t.Fatal("Encode accepted a value without a protobuf encoding")
+	}
+}
+
+// request 編碼客戶端送出的 Envelope，data 為 nil 時省略。
+// data 刻意放在 action 之前，確認解碼不依賴欄位順序。
+func request(action, requestID string, data []byte) []byte {
+	var b []byte
+	if data != nil {
+		b = protowire.AppendTag(b, envelopeData, protowire.BytesType)
+		b = protowire.AppendBytes(b, data)
+	}
+	b = AppendString(b, envelopeAction, action)
+	return AppendString(b, envelopeRequestID, requestID)
+}
+
+func TestProtobufDecodesClientRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{
+			name: "login",
+			data: request("login", "r1", AppendString(AppendInt64(AppendString(nil, 1, "sid-1"), 2, 1001), 3, "op")),
+			want: `{"action":"login","requestId":"r1","data":{"sid":"sid-1","gameId":1001,"operatorId":"op"}}`,
+		},
+		{
+			name: "play keeps the decimal string",
+			data: request("play", "r2", AppendString(nil, 1, "10.50")),
+			want: `{"action":"play","requestId":"r2","data":{"betAmount":"10.50"}}`,
+		},
+		{
+			name: "pong keeps large integers",
+			data: request("pong", "", AppendUint64(nil, 1, 9007199254740993)),
+			want: `{"action":"pong","data":{"seq":9007199254740993}}`,
+		},
+		{
+			name: "empty data",
+			data: request("resume", "", []byte{}),
+			want: `{"action":"resume","data":{}}`,
+		},
+		{
+			name: "logout without data",
+			data: request("logout", "", nil),
+			want: `{"action":"logout"}`,
+		},
+		{
+			name: "unknown action drops data",
+			data: request("dance", "r3", AppendString(nil, 1, "x")),
+			want: `{"action":"dance","requestId":"r3"}`,
+		},
+		{
+			name: "unknown fields are skipped",
+			data: request("play", "", AppendInt64(AppendString(nil, 1, "1"), 9, 7)),
+			want: `{"action":"play","data":{"betAmount":"1"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Protobuf.ToJSON(tt.data)
+			if err != nil {
+				t.Fatalf("ToJSON: %v", err)
+			}
+			if !jsonEqual(t, got, []byte(tt.want)) {
+				t.Fatalf("ToJSON = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtobufRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "truncated", data: []byte{0x0a, 0x05, 'p'}},
+		{name: "invalid utf-8", data: []byte{0x0a, 0x01, 0xff}},
+		{name: "invalid utf-8 in data", data: request("play", "", []byte{0x0a, 0x01, 0xff})},
+		{name: "wrong wire type in data", data: request("play", "", AppendInt64(nil, 1, 10))},
+		{name: "truncated data", data: request("login", "", []byte{0x0a, 0x05, 's'})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Protobuf.ToJSON(tt.data); err == nil {
+				t.Fatal("ToJSON succeeded, want error")
+			}
+		})
+	}
+}
+
+// jsonEqual 比較兩份 JSON 是否等價，數字以原始文字比較。
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	decode := func(data []byte) any {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			t.Fatalf("decode %s: %v", data, err)
+		}
+		return v
+	}
+	x, y := decode(a), decode(b)
+	xs, _ := json.Marshal(x)
+	ys, _ := json.Marshal(y)
+	return string(xs) == string(ys)
+}
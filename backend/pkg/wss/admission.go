@@ -0,0 +1,233 @@
+package wss
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Admission 在建立 WebSocket 連線前檢查升級請求，可依標頭、IP 或 query 參數 (例如 token) 決定是否放行。
+// 回傳 nil 表示放行；回傳 *AdmissionError 可指定 HTTP 狀態碼，其他錯誤一律以 403 Forbidden 拒絕。
+//
+// @param r - 升級請求。
+// @param ip - 依 Config.ClientIPHeader 解析出的客戶端 IP。
+// @return error - 拒絕連線的原因。
+type Admission func(r *http.Request, ip string) error
+
+// AdmissionError 是拒絕升級請求時的錯誤，Status 與 Message 會直接作為 HTTP 回應。
+type AdmissionError struct {
+	// Status 是回應的 HTTP 狀態碼。
+	Status int
+	// Message 是回應的內容，會顯示給客戶端，不應包含內部資訊。
+	Message string
+}
+
+func (e *AdmissionError) Error() string {
+	return fmt.Sprintf("wss: upgrade rejected (%d): %s", e.Status, e.Message)
+}
+
+// Reject 建立一個以指定狀態碼拒絕升級請求的錯誤，供 Admission 使用。
+//
+// @param status - 回應的 HTTP 狀態碼。
+// @param message - 回應的內容。
+// @return error - *AdmissionError。
+func Reject(status int, message string) error {
+	return &AdmissionError{Status: status, Message: message}
+}
+
+// connectionRetryAfterSec 是連線數已達上限或伺服器排空中時 Retry-After 標頭建議的重試秒數。
+const connectionRetryAfterSec = 5
+
+// 拒絕升級請求的原因，作為 slot_ws_upgrade_rejected_total 的 reason 標籤。
+const (
+	rejectOrigin    = "origin"
+	rejectAdmission = "admission"
+	rejectPerIP     = "per_ip_limit"
+	rejectCapacity  = "capacity"
+	rejectDraining  = "draining"
+)
+
+// originMatcher 比對升級請求的 Origin 是否在允許清單中。
+//
+// 清單項目可以是完整來源 (https://game.example.com)、以 *. 開頭的子網域萬用字元
+// (https://*.example.com，不含 example.com 本身) 或單獨的 * (允許所有來源)。
+// 項目未指定 scheme 時 (例如 *.example.com) 不限制 scheme；以 :* 結尾時 (例如 http://localhost:*) 不限制 port。
+type originMatcher struct {
+	any      bool
+	patterns []originPattern
+}
+
+// originPattern 是解析後的一個允許來源。
+type originPattern struct {
+	scheme string // 空字串表示不限制
+	host   string // anyPort 為 false 時含 port；wildcard 為 true 時是萬用字元之後的網域後綴 (例如 .example.com)
+	// wildcard 表示比對子網域。
+	wildcard bool
+	// anyPort 表示不限制 port。
+	anyPort bool
+}
+
+// newOriginMatcher 解析允許的來源清單，清單為空時允許所有來源。
+//
+// @param origins - 允許的來源清單。
+// @return *originMatcher - 來源比對器。
+// @return error - 清單中有無法解析的項目時回傳錯誤。
+func newOriginMatcher(origins []string) (*originMatcher, error) {
+	m := &originMatcher{any: len(origins) == 0}
+	for _, origin := range origins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		if origin == "*" {
+			m.any = true
+			continue
+		}
+		var p originPattern
+		host := origin
+		if scheme, rest, ok := strings.Cut(origin, "://"); ok {
+			p.scheme = scheme
+			host = rest
+		}
+		if strings.HasPrefix(host, "*.") {
+			p.wildcard = true
+			host = host[1:]
+		}
+		if rest, ok := strings.CutSuffix(host, ":*"); ok {
+			p.anyPort = true
+			host = rest
+		}
+		if host == "" || host == "." || strings.ContainsAny(host, "*/") {
+			return nil, fmt.Errorf("wss: invalid allowed origin %q", origin)
+		}
+		p.host = host
+		m.patterns = append(m.patterns, p)
+	}
+	return m, nil
+}
+
+// allow 判斷請求是否來自允許的來源。
+// 沒有 Origin 標頭的請求不是來自瀏覽器 (瀏覽器一定會帶上 Origin)，不受來源限制。
+func (m *originMatcher) allow(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if m.any || origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	for _, p := range m.patterns {
+		if p.scheme != "" && p.scheme != scheme {
+			continue
+		}
+		host := strings.ToLower(u.Host)
+		if p.anyPort {
+			host = strings.ToLower(u.Hostname())
+		}
+		if p.wildcard {
+			if strings.HasSuffix(host, p.host) {
+				return true
+			}
+			continue
+		}
+		if host == p.host {
+			return true
+		}
+	}
+	return false
+}
+
+// connectionLimiter 限制全域與每個 IP 的連線數。
+// 名額在升級前保留，連線結束時釋放，避免同時湧入的請求在升級期間超過上限。
+type connectionLimiter struct {
+	maxTotal int // 0 表示不限制
+	maxPerIP int // 0 表示不限制
+
+	mu    sync.Mutex
+	total int
+	perIP map[string]int
+}
+
+// newConnectionLimiter 建立連線數限制器。
+//
+// @param maxTotal - 全域連線數上限，0 表示不限制。
+// @param maxPerIP - 每個 IP 的連線數上限，0 表示不限制。
+// @return *connectionLimiter - 連線數限制器。
+func newConnectionLimiter(maxTotal, maxPerIP int) *connectionLimiter {
+	return &connectionLimiter{
+		maxTotal: maxTotal,
+		maxPerIP: maxPerIP,
+		perIP:    make(map[string]int),
+	}
+}
+
+// acquire 為指定 IP 保留一個連線名額，超過上限時回傳拒絕的原因。
+// 成功時呼叫端必須在連線結束後呼叫 release。
+func (l *connectionLimiter) acquire(ip string) (reason string, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.maxTotal > 0 && l.total >= l.maxTotal {
+		return rejectCapacity, false
+	}
+	if l.maxPerIP > 0 && l.perIP[ip] >= l.maxPerIP {
+		return rejectPerIP, false
+	}
+	l.total++
+	l.perIP[ip]++
+	return "", true
+}
+
+// release 歸還 acquire 保留的連線名額。
+func (l *connectionLimiter) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.total--
+	if l.perIP[ip] <= 1 {
+		delete(l.perIP, ip)
+		return
+	}
+	l.perIP[ip]--
+}
+
+// clientIP 解析升級請求的客戶端 IP。
+// header 為空時使用連線的遠端位址；否則從該標頭 (例如 X-Forwarded-For) 的右側數來第 trustedHops 個位址取得。
+//
+// 每個反向代理都會把它看到的對端位址附加到標頭的最右側，最左側的位址則可由客戶端任意偽造，
+// 因此只信任由 trustedHops 個受信任的代理附加的部分：最外層受信任的代理附加的位址就是客戶端 IP。
+// trustedHops 小於 1 時視為 1 (只有一層代理)。標頭不存在、位址數不足或不是合法 IP 時退回遠端位址。
+func clientIP(r *http.Request, header string, trustedHops int) string {
+	if header != "" {
+		if trustedHops < 1 {
+			trustedHops = 1
+		}
+		var hops []string
+		for _, value := range r.Header.Values(header) {
+			for _, hop := range strings.Split(value, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+		if len(hops) >= trustedHops {
+			if ip := net.ParseIP(strings.Trim(hops[len(hops)-trustedHops], "[]")); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return strings.Trim(r.RemoteAddr, "[]")
+	}
+	return host
+}
+
+// rejectUpgrade 以指定的狀態碼回應被拒絕的升級請求並記錄指標。
+func (s *Server) rejectUpgrade(w http.ResponseWriter, r *http.Request, ip, reason string, status int, message string) {
+	upgradeRejectedTotal.WithLabelValues(reason).Inc()
+	s.logger.Debug("websocket upgrade rejected", "reason", reason, "ip", ip, "origin", r.Header.Get("Origin"), "status", status)
+	if status == http.StatusServiceUnavailable || status == http.StatusTooManyRequests {
+		w.Header().Set("Retry-After", strconv.Itoa(connectionRetryAfterSec))
+	}
+	http.Error(w, message, status)
+}
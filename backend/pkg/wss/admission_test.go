@@ -0,0 +1,43 @@
+package wss
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		values []string
+		hops   int
+		want   string
+	}{
+		{name: "no header configured", values: []string{"1.1.1.1"}, want: "10.0.0.9"},
+		{name: "header missing", header: "X-Forwarded-For", want: "10.0.0.9"},
+		{name: "single proxy uses rightmost", header: "X-Forwarded-For", values: []string{"6.6.6.6, 1.1.1.1"}, hops: 1, want: "1.1.1.1"},
+		{name: "zero hops means one", header: "X-Forwarded-For", values: []string{"6.6.6.6, 1.1.1.1"}, want: "1.1.1.1"},
+		{name: "two proxies", header: "X-Forwarded-For", values: []string{"6.6.6.6, 1.1.1.1, 172.16.0.2"}, hops: 2, want: "1.1.1.1"},
+		{name: "repeated headers", header: "X-Forwarded-For", values: []string{"6.6.6.6", "1.1.1.1, 172.16.0.2"}, hops: 2, want: "1.1.1.1"},
+		{name: "fewer entries than hops", header: "X-Forwarded-For", values: []string{"1.1.1.1"}, hops: 2, want: "10.0.0.9"},
+		{name: "not an ip", header: "X-Forwarded-For", values: []string{"1.1.1.1, unknown"}, hops: 1, want: "10.0.0.9"},
+		{name: "ipv6", header: "X-Forwarded-For", values: []string{"[2001:db8::1]"}, hops: 1, want: "2001:db8::1"},
+		{name: "x-real-ip", header: "X-Real-IP", values: []string{"1.1.1.1"}, want: "1.1.1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/ws", nil)
+			r.RemoteAddr = "10.0.0.9:5000"
+			for _, v := range tt.values {
+				name := tt.header
+				if name == "" {
+					name = "X-Forwarded-For"
+				}
+				r.Header.Add(name, v)
+			}
+			if got := clientIP(r, tt.header, tt.hops); got != tt.want {
+				t.Fatalf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package wss
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const benchGroup = "bench"
+
+// benchSubscriber 將每條連線加入 benchGroup，並計算收到的訊息數。
+type benchSubscriber struct {
+	connected atomic.Int64
+	received  atomic.Int64
+	notify    chan struct{}
+}
+
+func (s *benchSubscriber) OnConnect(client Client) {
+	client.Join(benchGroup)
+	s.connected.Add(1)
+}
+
+func (s *benchSubscriber) OnDisconnect(Client) {}
+
+func (s *benchSubscriber) OnMessage(Client, []byte) {
+	s.received.Add(1)
+	if s.notify != nil {
+		s.notify <- struct{}{}
+	}
+}
+
+// newBenchServer 啟動 WebSocket 伺服器並建立 n 條持續讀取並丟棄訊息的客戶端連線。
+func newBenchServer(b *testing.B, n int, sub *benchSubscriber) (*Server, []*websocket.Conn) {
+	b.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	server := NewServer(ctx, &Config{
+		WriteWait:      10 * time.Second,
+		PongWait:       time.Minute,
+		MaxMessageSize: 4096,
+		SendBufferSize: 1024,
+		OverflowPolicy: OverflowDropOldest,
+	}, logger)
+	server.Register(sub)
+	srv := httptest.NewServer(server)
+	b.Cleanup(func() {
+		cancel()
+		srv.Close()
+	})
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	conns := make([]*websocket.Conn, n)
+	for i := range conns {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			b.Fatalf("dial: %v", err)
+		}
+		b.Cleanup(func() { _ = conn.Close() })
+		conns[i] = conn
+		go func() {
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+	}
+	for sub.connected.Load() < int64(n) {
+		time.Sleep(time.Millisecond)
+	}
+	return server, conns
+}
+
+// BenchmarkBroadcast 量測向群組廣播時，編碼一次並放入每條連線發送佇列的成本。
+func BenchmarkBroadcast(b *testing.B) {
+	payload := []byte(`{"action":"state_update","payload":{"state":"betting","countdown":10}}`)
+	encode := func(string) ([]byte, bool, error) { return payload, false, nil }
+	for _, n := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("clients=%d", n), func(b *testing.B) {
+			server, _ := newBenchServer(b, n, &benchSubscriber{})
+			b.ReportAllocs()
+			for b.Loop() {
+				if sent := server.Broadcast(benchGroup, encode); sent != n {
+					b.Fatalf("broadcast sent to %d connections, want %d", sent, n)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkDispatch 量測客戶端訊息經由讀取迴圈與 dispatch goroutine 交給 Subscriber 的完整路徑。
+func BenchmarkDispatch(b *testing.B) {
+	for _, n := range []int{1, 16} {
+		b.Run(fmt.Sprintf("clients=%d", n), func(b *testing.B) {
+			sub := &benchSubscriber{notify: make(chan struct{}, 1024)}
+			_, conns := newBenchServer(b, n, sub)
+			message := []byte(`{"action":"play","requestId":"r1","data":{"betAmount":"10"}}`)
+			b.ReportAllocs()
+			b.ResetTimer()
+			go func() {
+				for i := 0; i < b.N; i++ {
+					if err := conns[i%n].WriteMessage(websocket.TextMessage, message); err != nil {
+						b.Errorf("write: %v", err)
+						return
+					}
+				}
+			}()
+			for i := 0; i < b.N; i++ {
+				<-sub.notify
+			}
+		})
+	}
+}
@@ -1,18 +1,36 @@
 package wss
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+)
 
 // Client 定義了客戶端連線對外暴露的行為。
 // 業務邏輯層將依賴此介面，而非具體的 Connection 實作。
 type Client interface {
 	// ID 返回客戶端的唯一標識符。
 	ID() string
-	// SendMessage 發送文字訊息給客戶端。
+	// Context 返回與連線生命週期綁定的 context，連線中斷或伺服器關閉時會被取消。
+	Context() context.Context
+	// SendMessage 發送文字訊息給客戶端，不會阻塞。
+	// 連線關閉後回傳 ErrConnectionClosed，發送佇列已滿時依 Config.OverflowPolicy 可能回傳 *OverflowError。
 	SendMessage(message string) error
+	// SendBinary 以 binary frame 發送二進位訊息給客戶端，行為與 SendMessage 相同。
+	SendBinary(data []byte) error
+	// Subprotocol 返回升級時協商出的 Sec-WebSocket-Protocol，未協商時為空字串。
+	Subprotocol() string
+	// Dropped 返回此連線因發送佇列已滿而丟棄的訊息數量。
+	Dropped() uint64
+	// Join 將連線加入具名群組，之後 Server.Broadcast 該群組的訊息都會發送給此連線。斷線時會自動離開所有群組。
+	Join(group string)
+	// Leave 將連線移出具名群組。
+	Leave(group string)
 	// Kick 中斷與客戶端的連線。
 	Kick(reason string) error
-	// RemoteAddr 返回客戶端的網路位址。
+	// RemoteAddr 返回客戶端的網路位址。位於反向代理之後時是代理的位址。
 	RemoteAddr() string
+	// ClientIP 返回升級時依 Config.ClientIPHeader 與 Config.TrustedProxyHops 解析出的客戶端 IP，與連線數限制使用的 IP 相同。
+	ClientIP() string
 	// Headers 返回客戶端升級請求時的 HTTP 標頭。
 	Headers() http.Header
 	// UserAgent 返回客戶端的 User-Agent。
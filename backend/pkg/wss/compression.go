@@ -0,0 +1,66 @@
+package wss
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// countingConn 統計寫入網路的位元組數，用於計算壓縮率。
+type countingConn struct {
+	net.Conn
+	written atomic.Uint64
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.written.Add(uint64(n))
+	return n, err
+}
+
+// countingResponseWriter 在升級時以 countingConn 包裝被接管 (hijack) 的連線。
+type countingResponseWriter struct {
+	http.ResponseWriter
+	conn *countingConn
+}
+
+func (w *countingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("wss: response does not implement http.Hijacker")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	w.conn = &countingConn{Conn: conn}
+	return w.conn, rw, nil
+}
+
+// offersDeflate 判斷客戶端是否在升級請求中提出 permessage-deflate 擴充。
+func offersDeflate(r *http.Request) bool {
+	for _, ext := range r.Header.Values("Sec-WebSocket-Extensions") {
+		if strings.Contains(strings.ToLower(ext), "permessage-deflate") {
+			return true
+		}
+	}
+	return false
+}
+
+// observeCompression 記錄一個 frame 的壓縮結果。wire 為 0 表示無法取得寫入網路的位元組數。
+func observeCompression(compressed bool, raw int, wire uint64) {
+	if !compressed {
+		compressionFramesTotal.WithLabelValues("below_threshold").Inc()
+		return
+	}
+	compressionFramesTotal.WithLabelValues("compressed").Inc()
+	if wire == 0 {
+		return
+	}
+	compressionRawBytesTotal.Add(float64(raw))
+	compressionWireBytesTotal.Add(float64(wire))
+	compressionRatio.Observe(float64(raw) / float64(wire))
+}
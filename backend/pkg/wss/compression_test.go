@@ -0,0 +1,241 @@
+package wss
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestOffersDeflate(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   bool
+	}{
+		{name: "no header"},
+		{name: "deflate", values: []string{"permessage-deflate"}, want: true},
+		{name: "with parameters", values: []string{"permessage-deflate; client_max_window_bits"}, want: true},
+		{name: "case insensitive", values: []string{"PerMessage-Deflate"}, want: true},
+		{name: "among other extensions", values: []string{"x-webkit-deflate-frame, permessage-deflate"}, want: true},
+		{name: "repeated headers", values: []string{"x-custom", "permessage-deflate"}, want: true},
+		{name: "other extension only", values: []string{"x-webkit-deflate-frame"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/ws", nil)
+			for _, v := range tt.values {
+				r.Header.Add("Sec-WebSocket-Extensions", v)
+			}
+			if got := offersDeflate(r); got != tt.want {
+				t.Fatalf("offersDeflate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// counterValue 讀取 counter 目前的值。
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := c.Write(&m); err != nil {
+		t.Fatalf("read counter: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+// compressionCounters 是壓縮相關指標的快照。
+type compressionCounters struct {
+	belowThreshold, compressed, raw, wire float64
+}
+
+func readCompressionCounters(t *testing.T) compressionCounters {
+	return compressionCounters{
+		belowThreshold: counterValue(t, compressionFramesTotal.WithLabelValues("below_threshold")),
+		compressed:     counterValue(t, compressionFramesTotal.WithLabelValues("compressed")),
+		raw:            counterValue(t, compressionRawBytesTotal),
+		wire:           counterValue(t, compressionWireBytesTotal),
+	}
+}
+
+func (c compressionCounters) sub(o compressionCounters) compressionCounters {
+	return compressionCounters{
+		belowThreshold: c.belowThreshold - o.belowThreshold,
+		compressed:     c.compressed - o.compressed,
+		raw:            c.raw - o.raw,
+		wire:           c.wire - o.wire,
+	}
+}
+
+func TestObserveCompression(t *testing.T) {
+	tests := []struct {
+		name       string
+		compressed bool
+		raw        int
+		wire       uint64
+		want       compressionCounters
+	}{
+		{name: "below threshold", raw: 100, wire: 102, want: compressionCounters{belowThreshold: 1}},
+		{name: "compressed", compressed: true, raw: 1000, wire: 200, want: compressionCounters{compressed: 1, raw: 1000, wire: 200}},
+		// 無法取得寫入網路的位元組數時只計算 frame 數，避免扭曲壓縮率
+		{name: "unknown wire bytes", compressed: true, raw: 1000, want: compressionCounters{compressed: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := readCompressionCounters(t)
+			observeCompression(tt.compressed, tt.raw, tt.wire)
+			if got := readCompressionCounters(t).sub(before); got != tt.want {
+				t.Fatalf("counter delta = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// recordingConn 記錄從網路讀到的所有位元組，用於檢查 frame 是否被壓縮。
+type recordingConn struct {
+	net.Conn
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (c *recordingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.mu.Lock()
+	c.buf.Write(p[:n])
+	c.mu.Unlock()
+	return n, err
+}
+
+// wireFrame 是從網路位元組解析出的 frame 標頭。
+type wireFrame struct {
+	opcode     byte
+	compressed bool // RSV1，permessage-deflate 壓縮的訊息
+	length     int
+}
+
+// frames 解析升級回應之後伺服器送出的 frame。伺服器送出的 frame 不經過遮罩。
+func (c *recordingConn) frames(t *testing.T) []wireFrame {
+	t.Helper()
+	c.mu.Lock()
+	data := bytes.Clone(c.buf.Bytes())
+	c.mu.Unlock()
+	end := bytes.Index(data, []byte("\r\n\r\n"))
+	if end < 0 {
+		t.Fatal("upgrade response not found")
+	}
+	data = data[end+4:]
+
+	var frames []wireFrame
+	for len(data) >= 2 {
+		f := wireFrame{opcode: data[0] & 0x0f, compressed: data[0]&0x40 != 0}
+		n, header := int(data[1]&0x7f), 2
+		switch n {
+		case 126:
+			n, header = int(binary.BigEndian.Uint16(data[2:])), 4
+		case 127:
+			n, header = int(binary.BigEndian.Uint64(data[2:])), 10
+		}
+		f.length = n
+		frames = append(frames, f)
+		data = data[header+n:]
+	}
+	return frames
+}
+
+// sendOnConnect 在客戶端連線後依序送出 messages。
+type sendOnConnect struct {
+	messages []string
+}
+
+func (s *sendOnConnect) OnConnect(client Client) {
+	for _, m := range s.messages {
+		_ = client.SendMessage(m)
+	}
+}
+func (s *sendOnConnect) OnDisconnect(Client)      {}
+func (s *sendOnConnect) OnMessage(Client, []byte) {}
+
+func TestCompressionThresholdOnUpgradedConnection(t *testing.T) {
+	small := `{"action":"pong"}`
+	large := strings.Repeat(`{"action":"bet_result","payload":{"win":"0"}},`, 40)
+
+	tests := []struct {
+		name           string
+		clientDeflate  bool
+		wantCompressed []bool
+	}{
+		{name: "negotiated", clientDeflate: true, wantCompressed: []bool{false, true}},
+		{name: "client without deflate", wantCompressed: []bool{false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var recorder *recordingConn
+			dialer := &websocket.Dialer{
+				EnableCompression: tt.clientDeflate,
+				NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+					conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+					if err != nil {
+						return nil, err
+					}
+					recorder = &recordingConn{Conn: conn}
+					return recorder, nil
+				},
+			}
+			before := readCompressionCounters(t)
+			conn := dialTestServerWith(t, &Config{EnableCompression: true, CompressionThreshold: 256}, &sendOnConnect{messages: []string{small, large}}, dialer)
+
+			for _, want := range []string{small, large} {
+				_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+				_, got, err := conn.ReadMessage()
+				if err != nil {
+					t.Fatalf("read: %v", err)
+				}
+				if string(got) != want {
+					t.Fatalf("message = %q, want %q", got, want)
+				}
+			}
+
+			frames := recorder.frames(t)
+			if len(frames) != len(tt.wantCompressed) {
+				t.Fatalf("received %d frames, want %d: %+v", len(frames), len(tt.wantCompressed), frames)
+			}
+			for i, f := range frames {
+				if f.opcode != websocket.TextMessage || f.compressed != tt.wantCompressed[i] {
+					t.Fatalf("frame %d = %+v, want a text frame with compressed %v", i, f, tt.wantCompressed[i])
+				}
+			}
+			if tt.clientDeflate && frames[1].length >= len(large) {
+				t.Fatalf("compressed frame carries %d bytes, want fewer than %d", frames[1].length, len(large))
+			}
+
+			// 指標在 writePump 寫完 frame 之後才更新
+			want := compressionCounters{}
+			if tt.clientDeflate {
+				want = compressionCounters{belowThreshold: 1, compressed: 1, raw: float64(len(large))}
+			}
+			deadline := time.Now().Add(time.Second)
+			for {
+				got := readCompressionCounters(t).sub(before)
+				if got.belowThreshold == want.belowThreshold && got.compressed == want.compressed && got.raw == want.raw {
+					if tt.clientDeflate && (got.wire <= float64(frames[1].length) || got.wire >= got.raw) {
+						t.Fatalf("wire bytes = %v, want the compressed frame including its header", got.wire)
+					}
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("counter delta = %+v, want %+v", got, want)
+				}
+				time.Sleep(time.Millisecond)
+			}
+		})
+	}
+}
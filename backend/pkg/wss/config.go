@@ -2,6 +2,39 @@ package wss
 
 import "time"
 
+// OverflowPolicy 決定連線的發送佇列已滿時如何處理新的訊息。
+type OverflowPolicy string
+
+const (
+	// OverflowDisconnect 丟棄新的訊息並中斷跟不上的連線 (預設)，客戶端可重新連線後恢復狀態。
+	OverflowDisconnect OverflowPolicy = "disconnect"
+	// OverflowDropOldest 丟棄佇列中最舊的訊息，讓新的訊息入列。
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+	// OverflowDropNewest 丟棄新的訊息，保留佇列中的訊息。
+	OverflowDropNewest OverflowPolicy = "drop_newest"
+)
+
+// RateLimitPolicy 決定客戶端送出的訊息超過速率限制時如何處理。
+type RateLimitPolicy string
+
+const (
+	// RateLimitDrop 丟棄超過限制的訊息 (預設)。
+	RateLimitDrop RateLimitPolicy = "drop"
+	// RateLimitWarn 丟棄超過限制的訊息，並通知實現 RateLimitSubscriber 的 Subscriber 警告客戶端。
+	RateLimitWarn RateLimitPolicy = "warn"
+	// RateLimitKick 以 1008 (policy violation) 中斷超過限制的連線。
+	RateLimitKick RateLimitPolicy = "kick"
+)
+
+const (
+	// defaultSendBufferSize 是未設定 SendBufferSize 時每條連線的發送佇列長度。
+	defaultSendBufferSize = 256
+	// defaultInboundBufferSize 是未設定 InboundBufferSize 時每條連線等待處理的訊息佇列長度。
+	defaultInboundBufferSize = 64
+	// defaultCompressionLevel 是未設定 CompressionLevel 時的壓縮等級 (flate.BestSpeed)。
+	defaultCompressionLevel = 1
+)
+
 // Config 定義了 WebSocket 伺服器的所有可設定參數。
 type Config struct {
 	WriteWait       time.Duration // 寫入操作的超時時間
@@ -10,4 +43,54 @@ type Config struct {
 	MaxMessageSize  int64         // 允許接收的最大訊息大小
 	ReadBufferSize  int           // 讀取緩衝區的大小
 	WriteBufferSize int           // 寫入緩衝區的大小
+
+	SendBufferSize int            // 每條連線的發送佇列長度，0 表示使用預設值 256
+	OverflowPolicy OverflowPolicy // 發送佇列已滿時的處理方式，空字串表示 OverflowDisconnect
+
+	InboundBufferSize int // 每條連線等待處理的訊息佇列長度，0 表示使用預設值 64
+
+	// Subprotocols 是伺服器支援的 Sec-WebSocket-Protocol，依偏好順序排列。
+	// 依伺服器的偏好順序選用第一個客戶端也要求的子協定；客戶端未要求時不協商子協定。
+	Subprotocols []string
+
+	// EnableCompression 啟用 permessage-deflate，只有在客戶端也提出此擴充時才會壓縮。
+	EnableCompression bool
+	// CompressionLevel 是 compress/flate 的壓縮等級 (-2 ~ 9)，0 表示使用預設值 1 (flate.BestSpeed)。
+	CompressionLevel int
+	// CompressionThreshold 是壓縮的最小訊息大小 (位元組)，較小的訊息不壓縮直接發送，0 表示全部壓縮。
+	CompressionThreshold int
+
+	// AllowedOrigins 是允許建立連線的瀏覽器來源，支援完整來源 (https://game.example.com)、
+	// 子網域萬用字元 (https://*.example.com) 與 *；空清單表示允許所有來源。沒有 Origin 標頭的非瀏覽器客戶端不受限制。
+	AllowedOrigins []string
+	// MaxConnections 是伺服器的連線數上限，超過時以 503 拒絕升級，0 表示不限制。
+	MaxConnections int
+	// MaxConnectionsPerIP 是每個客戶端 IP 的連線數上限，超過時以 429 拒絕升級，0 表示不限制。
+	MaxConnectionsPerIP int
+	// ClientIPHeader 是解析客戶端 IP 的標頭 (例如 X-Forwarded-For 或 X-Real-IP)，空字串表示使用連線的遠端位址。
+	// 只應在伺服器位於會覆寫此標頭的反向代理之後時設定，否則客戶端可偽造 IP 繞過限制。
+	ClientIPHeader string
+	// TrustedProxyHops 是伺服器前方會附加 ClientIPHeader 的受信任反向代理層數，客戶端 IP 取標頭右側數來第 TrustedProxyHops 個位址，
+	// 更左側的位址可由客戶端偽造而不採用。0 表示 1 (只有一層代理)。
+	TrustedProxyHops int
+
+	// MessagesPerSecond 是每條連線每秒可送出的訊息數 (token bucket 的補充速率)，0 表示不限制。
+	MessagesPerSecond float64
+	// MessageBurst 是訊息數的 bucket 容量，允許短時間的突發，0 表示使用 MessagesPerSecond (至少 1)。
+	MessageBurst int
+	// BytesPerSecond 是每條連線每秒可送出的位元組數，0 表示不限制。
+	BytesPerSecond float64
+	// ByteBurst 是位元組數的 bucket 容量，0 表示使用 BytesPerSecond；不會小於 MaxMessageSize，確保單則最大訊息仍可送出。
+	ByteBurst int
+	// RateLimitPolicy 是超過速率限制時的處理方式，空字串表示 RateLimitDrop。
+	RateLimitPolicy RateLimitPolicy
+}
+
+// ValidateAllowedOrigins 檢查 AllowedOrigins 的格式，供啟動時提早發現設定錯誤。
+//
+// @param origins - 允許的來源清單。
+// @return error - 清單中有無法解析的項目時回傳錯誤。
+func ValidateAllowedOrigins(origins []string) error {
+	_, err := newOriginMatcher(origins)
+	return err
 }
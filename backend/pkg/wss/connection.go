@@ -1,9 +1,11 @@
 package wss
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,15 +15,39 @@ import (
 // connection 是 Client 介面的具體實現，負責管理底層 WebSocket 連線。
 type connection struct {
 	id         string
+	ctx        context.Context
+	cancel     context.CancelFunc
 	hub        *hub
 	conn       *websocket.Conn
-	send       chan []byte
+	cfg        *Config
+	send       chan frame
+	inbound    chan inboundEvent
 	mu         sync.Mutex
 	remoteAddr string
+	clientIP   string
 	headers    http.Header
 	tags       map[string]any
 	tagsMutex  sync.RWMutex
 	logger     *slog.Logger
+
+	// sendMu 保護 send 佇列的入列與關閉，closed 之後 SendMessage 一律回傳 ErrConnectionClosed。
+	sendMu     sync.Mutex
+	closed     bool
+	sendClosed bool
+	dropped    atomic.Uint64
+
+	// compress 表示已協商 permessage-deflate，wire 統計寫入網路的位元組數。
+	compress bool
+	wire     *countingConn
+
+	// release 歸還升級前保留的連線名額，在 readPump 結束時呼叫。
+	release func()
+	// limiter 限制客戶端送出的訊息速率，nil 表示不限制。
+	limiter *inboundLimiter
+
+	// groups 是連線加入的群組，由 hub.groups 的鎖保護；groupsClosed 表示連線已註銷，不再加入群組。
+	groups       map[string]struct{}
+	groupsClosed bool
 }
 
 // 確保 connection 類型在編譯時期就實現了 Client 接口。
@@ -32,19 +58,26 @@ var _ Client = (*connection)(nil)
 // @param hub - 指向 hub 的指標，用於註冊和訊息傳遞。
 // @param conn - 底層的 websocket 連線。
 // @param r - 建立連線時的 HTTP 請求，用於獲取標頭和遠端位址。
+// @param cfg - WebSocket 伺服器的設定參數，決定發送佇列長度與溢出處理方式。
 // @param logger - 用於記錄日誌的 slog 實例。
 // @return *connection - 一個初始化完成的連線實例。
-func newConnection(hub *hub, conn *websocket.Conn, r *http.Request, logger *slog.Logger) *connection {
+func newConnection(hub *hub, conn *websocket.Conn, r *http.Request, cfg *Config, logger *slog.Logger) *connection {
 	clientID := generateClientID()
+	ctx, cancel := context.WithCancel(hub.ctx)
 	return &connection{
 		id:         clientID,
+		ctx:        ctx,
+		cancel:     cancel,
 		hub:        hub,
 		conn:       conn,
-		send:       make(chan []byte, 256),
+		cfg:        cfg,
+		send:       make(chan frame, cfg.SendBufferSize),
+		inbound:    make(chan inboundEvent, cfg.InboundBufferSize),
 		remoteAddr: r.RemoteAddr,
 		headers:    r.Header.Clone(), // 複製標頭以確保安全
 		tags:       make(map[string]any),
 		logger:     logger.With("clientID", clientID),
+		limiter:    newInboundLimiter(cfg),
 	}
 }
 
@@ -53,17 +86,124 @@ func (c *connection) ID() string {
 	return c.id
 }
 
-// SendMessage 將一則訊息放入發送佇列，由 writePump 異步發送。
+// Context 返回與連線生命週期綁定的 context。
+// 當讀取迴圈結束 (連線中斷) 或 hub 的 context 被取消 (伺服器關閉) 時會被取消。
+func (c *connection) Context() context.Context {
+	return c.ctx
+}
+
+// SendMessage 將一則文字訊息放入發送佇列，由 writePump 以 text frame 異步發送。
+//
+// 此方法不會阻塞：連線關閉後回傳 ErrConnectionClosed；佇列已滿時依 Config.OverflowPolicy 處理，
+// 丟棄新訊息或中斷連線時回傳 *OverflowError，丟棄最舊的訊息時新訊息仍會入列並回傳 nil。
+// 被丟棄的訊息會計入 Dropped。
 func (c *connection) SendMessage(message string) error {
-	c.send <- []byte(message)
-	return nil
+	return c.enqueue(frame{messageType: websocket.TextMessage, data: []byte(message)})
+}
+
+// SendBinary 將一則二進位訊息放入發送佇列，由 writePump 以 binary frame 異步發送。
+// 佇列與溢出處理方式與 SendMessage 相同。
+func (c *connection) SendBinary(data []byte) error {
+	return c.enqueue(frame{messageType: websocket.BinaryMessage, data: data})
 }
 
-// Kick 立即中斷與客戶端的連線。
+// Subprotocol 返回升級時協商出的 Sec-WebSocket-Protocol，未協商時為空字串。
+func (c *connection) Subprotocol() string {
+	return c.conn.Subprotocol()
+}
+
+// enqueue 將 frame 放入發送佇列，佇列已滿時套用溢出處理方式。
+func (c *connection) enqueue(f frame) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.closed {
+		return ErrConnectionClosed
+	}
+	select {
+	case c.send <- f:
+		return nil
+	default:
+	}
+
+	switch c.cfg.OverflowPolicy {
+	case OverflowDropOldest:
+		select {
+		case <-c.send:
+			c.recordDrop()
+		default:
+		}
+		// 持有 sendMu 時只有 writePump 會取出訊息，剛空出的位置不會被搶走
+		c.send <- f
+		return nil
+	case OverflowDropNewest:
+		return &OverflowError{ClientID: c.id, Policy: OverflowDropNewest, Dropped: c.recordDrop()}
+	default:
+		dropped := c.dropped.Add(1)
+		c.closed = true
+		c.logger.Warn("disconnecting slow consumer", "dropped", dropped, "buffer", cap(c.send))
+		go c.disconnect(websocket.CloseTryAgainLater, "slow consumer")
+		return &OverflowError{ClientID: c.id, Policy: OverflowDisconnect, Dropped: dropped}
+	}
+}
+
+// recordDrop 累計丟棄的訊息數量，第一次丟棄時記錄警告，回傳累計數量。
+func (c *connection) recordDrop() uint64 {
+	dropped := c.dropped.Add(1)
+	if dropped == 1 {
+		c.logger.Warn("send buffer full, dropping messages", "policy", c.cfg.OverflowPolicy, "buffer", cap(c.send))
+	}
+	return dropped
+}
+
+// Dropped 返回此連線因發送佇列已滿而丟棄的訊息數量。
+func (c *connection) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
+// closeSend 關閉發送佇列，通知 writePump 結束。可重複呼叫。
+func (c *connection) closeSend() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.closed = true
+	if !c.sendClosed {
+		c.sendClosed = true
+		close(c.send)
+	}
+}
+
+// disconnect 在寫入逾時內以指定的關閉代碼送出關閉訊息後直接關閉底層連線，不等待發送佇列清空。
+// readPump 會因此結束並向 hub 註銷連線。
+func (c *connection) disconnect(code int, reason string) {
+	deadline := time.Now().Add(c.cfg.WriteWait)
+	msg := websocket.FormatCloseMessage(code, reason)
+	if err := c.conn.WriteControl(websocket.CloseMessage, msg, deadline); err != nil {
+		c.logger.Debug("write close message failed", "error", err)
+	}
+	if err := c.conn.Close(); err != nil {
+		c.logger.Debug("close connection failed", "error", err)
+	}
+}
+
+// Kick 中斷與客戶端的連線。
+// 關閉訊息排在發送佇列的最後，已排入的訊息 (例如說明踢線原因的錯誤訊息) 會先送出；之後的 SendMessage 一律回傳 ErrConnectionClosed。
+// 佇列已關閉或已滿時直接送出關閉訊息。
 func (c *connection) Kick(reason string) error {
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, reason)
+	c.sendMu.Lock()
+	if !c.closed {
+		select {
+		case c.send <- frame{messageType: websocket.CloseMessage, data: msg}:
+			c.closed = true
+			c.sendMu.Unlock()
+			return nil
+		default:
+		}
+	}
+	c.sendMu.Unlock()
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	return c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, reason))
+	return c.conn.WriteMessage(websocket.CloseMessage, msg)
 }
 
 // RemoteAddr 返回客戶端的網路位址。
@@ -71,6 +211,11 @@ func (c *connection) RemoteAddr() string {
 	return c.remoteAddr
 }
 
+// ClientIP 返回升級時依 Config.ClientIPHeader 與 Config.TrustedProxyHops 解析出的客戶端 IP。
+func (c *connection) ClientIP() string {
+	return c.clientIP
+}
+
 // Headers 返回客戶端升級請求時的 HTTP 標頭。
 func (c *connection) Headers() http.Header {
 	return c.headers
@@ -96,17 +241,55 @@ func (c *connection) GetTag(key string) (value any, exists bool) {
 	return
 }
 
-// readPump 將來自 WebSocket 連線的訊息泵送到 hub。
+// inboundEvent 是 readPump 交給 dispatch goroutine 的事件：客戶端訊息或速率限制通知。
+type inboundEvent struct {
+	message []byte
+	// rateLimited 不為 nil 時此事件是速率限制通知，message 為空。
+	rateLimited *rateLimitNotice
+}
+
+// rateLimitNotice 是 RateLimitSubscriber.OnRateLimited 的參數。
+type rateLimitNotice struct {
+	limit      string
+	retryAfter time.Duration
+}
+
+// dispatch 依序將此連線的事件交給 hub 的 Subscriber：先是連線事件，接著是 readPump 讀到的每一則訊息與速率限制通知，
+// 最後在 readPump 結束後送出斷線事件。每條連線有自己的 dispatch goroutine，確保同一個客戶端的事件不會亂序。
+func (c *connection) dispatch() {
+	c.hub.dispatchConnect(c)
+	for event := range c.inbound {
+		// 連線已中斷時丟棄尚未處理的訊息，避免以已取消的 context 執行下注等操作
+		if c.ctx.Err() != nil {
+			continue
+		}
+		if n := event.rateLimited; n != nil {
+			c.hub.dispatchRateLimited(c, n.limit, n.retryAfter)
+			continue
+		}
+		c.hub.dispatchMessage(c, event.message)
+	}
+	c.hub.dispatchDisconnect(c)
+}
+
+// readPump 將來自 WebSocket 連線的訊息泵送到此連線的 dispatch goroutine。
 // 它會持續讀取客戶端訊息，直到連線關閉或發生錯誤。
+// 待處理的訊息佇列已滿時會暫停讀取，對該客戶端形成背壓，不影響其他連線。
 //
 // @param cfg - WebSocket 伺服器的設定參數。
 func (c *connection) readPump(cfg *Config) {
 	defer func() {
-		c.hub.unregister <- c
+		c.cancel()
+		close(c.inbound)
+		c.hub.unregister(c)
+		if c.release != nil {
+			c.release()
+		}
 		err := c.conn.Close()
 		if err != nil {
 			c.logger.Warn("read pump failed on closing connection", "error", err)
 		}
+		c.hub.open.Add(-1)
 	}()
 	c.conn.SetReadLimit(cfg.MaxMessageSize)
 	_ = c.conn.SetReadDeadline(time.Now().Add(cfg.PongWait))
@@ -119,8 +302,47 @@ func (c *connection) readPump(cfg *Config) {
 			}
 			break
 		}
-		c.hub.inbound <- &clientMessage{client: c, message: message}
+		event := inboundEvent{message: message}
+		if c.limiter != nil {
+			if notice, ok := c.admit(cfg, len(message)); !ok {
+				if cfg.RateLimitPolicy == RateLimitKick {
+					c.disconnect(websocket.ClosePolicyViolation, "rate limit exceeded")
+					return
+				}
+				if notice == nil {
+					continue
+				}
+				// 警告與一般訊息走同一個佇列，由 dispatch goroutine 依序交給 Subscriber
+				event = inboundEvent{rateLimited: notice}
+			}
+		}
+		select {
+		case c.inbound <- event:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// admit 檢查訊息是否在速率限制內，超過時記錄指標。回傳 false 表示訊息應被丟棄；
+// RateLimitPolicy 為 RateLimitWarn 且距離上次警告已超過間隔時，同時回傳要交給 Subscriber 的速率限制通知。
+func (c *connection) admit(cfg *Config, size int) (*rateLimitNotice, bool) {
+	now := time.Now()
+	limit, retryAfter, ok := c.limiter.allow(now, size)
+	if ok {
+		return nil, true
+	}
+	rateLimitedTotal.WithLabelValues(limit, string(cfg.RateLimitPolicy)).Inc()
+	switch cfg.RateLimitPolicy {
+	case RateLimitKick:
+		c.logger.Warn("kicking client for exceeding rate limit", "limit", limit)
+	case RateLimitWarn:
+		if c.limiter.shouldWarn(now) {
+			c.logger.Warn("client exceeded rate limit", "limit", limit, "retryAfter", retryAfter)
+			return &rateLimitNotice{limit: limit, retryAfter: retryAfter}, false
+		}
 	}
+	return nil, false
 }
 
 // writePump 將來自 hub 的訊息泵送到 WebSocket 連線。
@@ -155,13 +377,30 @@ func (c *connection) writePump(cfg *Config) {
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
+			if message.messageType == websocket.CloseMessage {
+				// Kick 排入的關閉訊息，送出後結束連線
+				if err := c.conn.WriteMessage(websocket.CloseMessage, message.data); err != nil {
+					c.logger.Debug("write pump failed on writing close message", "error", err)
+				}
+				c.mu.Unlock()
+				return
+			}
+
+			// 小於門檻的訊息壓縮效益低，直接發送
+			compressed := c.compress && len(message.data) >= c.cfg.CompressionThreshold
+			var wireBefore uint64
+			if c.compress {
+				c.conn.EnableWriteCompression(compressed)
+				wireBefore = c.wire.written.Load()
+			}
+
+			w, err := c.conn.NextWriter(message.messageType)
 			if err != nil {
 				c.logger.Warn("write pump failed on getting next writer", "error", err)
 				c.mu.Unlock()
 				return
 			}
-			_, err = w.Write(message)
+			_, err = w.Write(message.data)
 			if err != nil {
 				c.logger.Warn("write pump failed on writing message", "error", err)
 				c.mu.Unlock()
@@ -173,6 +412,9 @@ func (c *connection) writePump(cfg *Config) {
 				c.mu.Unlock()
 				return
 			}
+			if c.compress {
+				observeCompression(compressed, len(message.data), c.wire.written.Load()-wireBefore)
+			}
 			c.mu.Unlock()
 		case <-ticker.C:
 			c.mu.Lock()
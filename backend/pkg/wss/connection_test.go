@@ -0,0 +1,140 @@
+package wss
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestConnection 建立一條未啟動 readPump 與 writePump 的伺服器端連線，發送佇列不會被取出，
+// 回傳連線與對應的客戶端連線。
+func newTestConnection(t *testing.T, policy OverflowPolicy, bufferSize int) (*connection, *websocket.Conn) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	cfg := &Config{WriteWait: time.Second, SendBufferSize: bufferSize, OverflowPolicy: policy}
+	h := newHub(ctx, logger)
+
+	upgraded := make(chan *connection, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		upgraded <- newConnection(h, conn, r, cfg, logger)
+	}))
+	t.Cleanup(func() {
+		cancel()
+		srv.Close()
+	})
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	c := <-upgraded
+	t.Cleanup(func() { _ = c.conn.Close() })
+	return c, client
+}
+
+// queued 取出發送佇列中所有訊息的內容。
+func queued(c *connection) []string {
+	var got []string
+	for {
+		select {
+		case f := <-c.send:
+			got = append(got, string(f.data))
+		default:
+			return got
+		}
+	}
+}
+
+func TestEnqueueOverflowPolicy(t *testing.T) {
+	tests := []struct {
+		policy     OverflowPolicy
+		wantErr    bool
+		wantQueued []string
+	}{
+		{policy: OverflowDropOldest, wantQueued: []string{"m3", "m4", "m5"}},
+		{policy: OverflowDropNewest, wantErr: true, wantQueued: []string{"m1", "m2", "m3"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			c, _ := newTestConnection(t, tt.policy, 3)
+			for _, m := range []string{"m1", "m2", "m3"} {
+				if err := c.SendMessage(m); err != nil {
+					t.Fatalf("SendMessage(%s) with room in the buffer: %v", m, err)
+				}
+			}
+			for i, m := range []string{"m4", "m5"} {
+				err := c.SendMessage(m)
+				if !tt.wantErr {
+					if err != nil {
+						t.Fatalf("SendMessage(%s) = %v, want nil", m, err)
+					}
+					continue
+				}
+				var overflow *OverflowError
+				if !errors.As(err, &overflow) || !errors.Is(err, ErrSendBufferFull) {
+					t.Fatalf("SendMessage(%s) = %v, want *OverflowError", m, err)
+				}
+				if overflow.Policy != tt.policy || overflow.Dropped != uint64(i+1) || overflow.ClientID != c.ID() {
+					t.Fatalf("overflow error = %+v", overflow)
+				}
+			}
+
+			if got := c.Dropped(); got != 2 {
+				t.Fatalf("Dropped = %d, want 2", got)
+			}
+			if got := queued(c); strings.Join(got, ",") != strings.Join(tt.wantQueued, ",") {
+				t.Fatalf("queued = %v, want %v", got, tt.wantQueued)
+			}
+			// 丟棄訊息不會中斷連線
+			if err := c.SendMessage("m6"); err != nil {
+				t.Fatalf("SendMessage after overflow: %v", err)
+			}
+		})
+	}
+}
+
+func TestEnqueueOverflowDisconnect(t *testing.T) {
+	c, client := newTestConnection(t, OverflowDisconnect, 2)
+	for _, m := range []string{"m1", "m2"} {
+		if err := c.SendMessage(m); err != nil {
+			t.Fatalf("SendMessage(%s) with room in the buffer: %v", m, err)
+		}
+	}
+	if c.closed {
+		t.Fatal("connection closed before the buffer overflowed")
+	}
+
+	err := c.SendMessage("m3")
+	var overflow *OverflowError
+	if !errors.As(err, &overflow) || overflow.Policy != OverflowDisconnect || overflow.Dropped != 1 {
+		t.Fatalf("SendMessage on full buffer = %v, want a disconnect *OverflowError", err)
+	}
+	if err := c.SendMessage("m4"); !errors.Is(err, ErrConnectionClosed) {
+		t.Fatalf("SendMessage after disconnect = %v, want ErrConnectionClosed", err)
+	}
+	if got := queued(c); strings.Join(got, ",") != "m1,m2" {
+		t.Fatalf("queued = %v, want [m1 m2]", got)
+	}
+
+	// 客戶端收到 CloseTryAgainLater，佇列中的訊息不會送出
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = client.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseTryAgainLater) {
+		t.Fatalf("client read = %v, want close %d", err, websocket.CloseTryAgainLater)
+	}
+}
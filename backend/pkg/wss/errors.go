@@ -0,0 +1,32 @@
+package wss
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrConnectionClosed 表示連線已關閉，訊息不會被發送。
+	ErrConnectionClosed = errors.New("wss: connection closed")
+	// ErrSendBufferFull 表示連線的發送佇列已滿，訊息被丟棄。
+	ErrSendBufferFull = errors.New("wss: send buffer full")
+)
+
+// OverflowError 是發送佇列已滿時 SendMessage 回傳的錯誤，可用 errors.Is(err, ErrSendBufferFull) 判斷。
+type OverflowError struct {
+	// ClientID 是發生溢出的連線 ID。
+	ClientID string
+	// Policy 是套用的溢出處理方式。
+	Policy OverflowPolicy
+	// Dropped 是此連線累計丟棄的訊息數量 (包含這一則)。
+	Dropped uint64
+}
+
+func (e *OverflowError) Error() string {
+	return fmt.Sprintf("wss: send buffer full for client %s (policy %s, %d dropped)", e.ClientID, e.Policy, e.Dropped)
+}
+
+// Unwrap 讓 errors.Is 可以比對 ErrSendBufferFull。
+func (e *OverflowError) Unwrap() error {
+	return ErrSendBufferFull
+}
@@ -0,0 +1,182 @@
+package wss
+
+import (
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// 廣播的範圍，作為 slot_ws_broadcast_recipients 的 scope 標籤。
+const (
+	broadcastScopeGroup = "group"
+	broadcastScopeAll   = "all"
+)
+
+// Encoder 將一則廣播訊息依連線協商的子協定編碼。
+// 同一次廣播中每種子協定只會編碼一次，編碼結果由該子協定的所有連線共用，不會為每條連線重新序列化。
+//
+// @param subprotocol - 連線協商出的 Sec-WebSocket-Protocol，未協商時為空字串。
+// @return data - 編碼後的訊息，發送期間不可再被修改。
+// @return binary - 是否以 binary frame 發送，false 時以 text frame 發送。
+// @return err - 編碼失敗時回傳錯誤，該子協定的連線都不會收到此訊息。
+type Encoder func(subprotocol string) (data []byte, binary bool, err error)
+
+// groupRegistry 維護具名群組 (例如遊戲房間、大廳) 與其成員。
+// 連線可以同時加入多個群組，斷線時會自動離開所有群組。
+type groupRegistry struct {
+	mu      sync.RWMutex
+	members map[string]map[*connection]struct{}
+}
+
+// newGroupRegistry 建立空的群組表。
+func newGroupRegistry() *groupRegistry {
+	return &groupRegistry{members: make(map[string]map[*connection]struct{})}
+}
+
+// join 將連線加入群組，已離線的連線不會被加入。
+func (g *groupRegistry) join(c *connection, name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if c.groupsClosed {
+		return
+	}
+	members := g.members[name]
+	if members == nil {
+		members = make(map[*connection]struct{})
+		g.members[name] = members
+	}
+	members[c] = struct{}{}
+	if c.groups == nil {
+		c.groups = make(map[string]struct{})
+	}
+	c.groups[name] = struct{}{}
+}
+
+// leave 將連線移出群組，群組沒有成員時一併移除。
+func (g *groupRegistry) leave(c *connection, name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.remove(c, name)
+}
+
+// leaveAll 將連線移出所有群組，之後 join 不再有作用。在連線註銷時呼叫。
+func (g *groupRegistry) leaveAll(c *connection) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	c.groupsClosed = true
+	for name := range c.groups {
+		g.remove(c, name)
+	}
+}
+
+// remove 將連線移出群組，呼叫前必須持有寫鎖。
+func (g *groupRegistry) remove(c *connection, name string) {
+	delete(c.groups, name)
+	members := g.members[name]
+	delete(members, c)
+	if len(members) == 0 {
+		delete(g.members, name)
+	}
+}
+
+// snapshot 回傳群組目前成員的快照，讓廣播不必在持有鎖的情況下入列。
+func (g *groupRegistry) snapshot(name string) []*connection {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	members := g.members[name]
+	list := make([]*connection, 0, len(members))
+	for c := range members {
+		list = append(list, c)
+	}
+	return list
+}
+
+// size 回傳群組目前的成員數。
+func (g *groupRegistry) size(name string) int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.members[name])
+}
+
+// fanOut 將訊息放入每條連線的發送佇列，每種子協定只呼叫一次 encode。
+// 入列不會阻塞，佇列已滿的連線依 Config.OverflowPolicy 處理，不影響其他連線。
+// 回傳成功入列的連線數。
+func (h *hub) fanOut(scope string, clients []*connection, encode Encoder) int {
+	frames := make(map[string]*frame, 1)
+	sent := 0
+	for _, c := range clients {
+		subprotocol := c.Subprotocol()
+		f, ok := frames[subprotocol]
+		if !ok {
+			data, binary, err := encode(subprotocol)
+			if err != nil {
+				h.logger.Error("encode broadcast message failed", "subprotocol", subprotocol, "error", err)
+			} else {
+				messageType := websocket.TextMessage
+				if binary {
+					messageType = websocket.BinaryMessage
+				}
+				f = &frame{messageType: messageType, data: data}
+			}
+			frames[subprotocol] = f
+		}
+		if f == nil {
+			continue
+		}
+		if c.enqueue(*f) == nil {
+			sent++
+		}
+	}
+	broadcastRecipients.WithLabelValues(scope).Observe(float64(sent))
+	return sent
+}
+
+// snapshot 回傳目前所有連線的快照。
+func (h *hub) snapshot() []*connection {
+	list := make([]*connection, 0, h.size())
+	for i := range h.shards {
+		shard := &h.shards[i]
+		shard.mu.Lock()
+		for c := range shard.clients {
+			list = append(list, c)
+		}
+		shard.mu.Unlock()
+	}
+	return list
+}
+
+// Broadcast 將訊息發送給群組內的所有連線。
+// 訊息對每種子協定只編碼一次，各連線共用同一份資料；發送不會阻塞，慢速連線依 Config.OverflowPolicy 處理。
+//
+// @param group - 群組名稱，群組不存在時不會發送任何訊息。
+// @param encode - 依子協定編碼訊息的函式。
+// @return int - 成功放入發送佇列的連線數。
+func (s *Server) Broadcast(group string, encode Encoder) int {
+	return s.hub.fanOut(broadcastScopeGroup, s.hub.groups.snapshot(group), encode)
+}
+
+// BroadcastAll 將訊息發送給所有連線，行為與 Broadcast 相同。
+//
+// @param encode - 依子協定編碼訊息的函式。
+// @return int - 成功放入發送佇列的連線數。
+func (s *Server) BroadcastAll(encode Encoder) int {
+	return s.hub.fanOut(broadcastScopeAll, s.hub.snapshot(), encode)
+}
+
+// GroupSize 回傳群組目前的成員數。
+//
+// @param group - 群組名稱。
+// @return int - 成員數，群組不存在時為 0。
+func (s *Server) GroupSize(group string) int {
+	return s.hub.groups.size(group)
+}
+
+// Join 將連線加入群組，重複加入沒有作用。連線中斷後加入不會有作用。
+func (c *connection) Join(group string) {
+	c.hub.groups.join(c, group)
+}
+
+// Leave 將連線移出群組，未加入時沒有作用。
+func (c *connection) Leave(group string) {
+	c.hub.groups.leave(c, group)
+}
@@ -2,18 +2,38 @@ package wss
 
 import (
 	"context"
+	"hash/fnv"
 	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// hub 維護一組活躍的客戶端，並將事件分派給所有已註冊的 Subscriber
+// hubShardCount 是連線表的分片數量，降低大量連線同時註冊與註銷時的鎖競爭。
+const hubShardCount = 64
+
+// hubShard 是連線表的一個分片。
+type hubShard struct {
+	mu      sync.Mutex
+	clients map[*connection]struct{}
+	closed  bool
+}
+
+// hub 維護一組活躍的客戶端，並將事件分派給所有已註冊的 Subscriber。
+//
+// 連線表依連線 ID 分片，各分片各自持有鎖。事件不經過共用的事件迴圈，而是由每條連線自己的
+// dispatch goroutine 依序呼叫 Subscriber：同一個客戶端的連線、訊息與斷線事件保持順序，
+// 單一客戶端的處理過慢 (例如等待錢包回應) 也不會拖慢其他客戶端。
 type hub struct {
-	clients     map[*connection]bool
-	register    chan *connection
-	unregister  chan *connection
-	inbound     chan *clientMessage
+	shards      [hubShardCount]hubShard
+	count       atomic.Int64
 	subscribers []Subscriber
+	groups      *groupRegistry
 	ctx         context.Context
 	logger      *slog.Logger
+
+	// open 是底層連線尚未關閉的數量，踢除後直到讀取迴圈結束才會減少。
+	open atomic.Int64
 }
 
 // newHub 創建一個新的 hub 實例。
@@ -22,18 +42,20 @@ type hub struct {
 // @param logger - 用於記錄日誌的 slog 實例。
 // @return *hub - 一個初始化完成的 hub 實例。
 func newHub(ctx context.Context, logger *slog.Logger) *hub {
-	return &hub{
-		register:    make(chan *connection),
-		unregister:  make(chan *connection),
-		inbound:     make(chan *clientMessage),
-		clients:     make(map[*connection]bool),
+	h := &hub{
 		subscribers: make([]Subscriber, 0),
+		groups:      newGroupRegistry(),
 		ctx:         ctx,
 		logger:      logger,
 	}
+	for i := range h.shards {
+		h.shards[i].clients = make(map[*connection]struct{})
+	}
+	return h
 }
 
 // registerSubscriber 註冊一個新的事件處理器 (Subscriber)。
+// 必須在開始接受連線前呼叫，之後 subscribers 只會被讀取。
 //
 // @param subscriber - 實現了 Subscriber 介面的事件處理器。
 func (h *hub) registerSubscriber(subscriber Subscriber) {
@@ -42,43 +64,98 @@ func (h *hub) registerSubscriber(subscriber Subscriber) {
 	}
 }
 
-// run 啟動 hub 的主事件迴圈。
-// 這個迴圈會處理客戶端的註冊、註銷、訊息傳遞以及優雅關閉的邏輯。
+// shardOf 回傳連線所屬的分片。
+func (h *hub) shardOf(client *connection) *hubShard {
+	f := fnv.New32a()
+	_, _ = f.Write([]byte(client.id))
+	return &h.shards[f.Sum32()%hubShardCount]
+}
+
+// register 將連線加入連線表。hub 已關閉時回傳 false。
+func (h *hub) register(client *connection) bool {
+	shard := h.shardOf(client)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if shard.closed {
+		return false
+	}
+	shard.clients[client] = struct{}{}
+	h.count.Add(1)
+	h.open.Add(1)
+	h.logger.Info("client registered", "clientID", client.ID())
+	return true
+}
+
+// unregister 將連線移出連線表與所有群組，並關閉其發送佇列。可重複呼叫。
+func (h *hub) unregister(client *connection) {
+	h.groups.leaveAll(client)
+	shard := h.shardOf(client)
+	shard.mu.Lock()
+	_, ok := shard.clients[client]
+	delete(shard.clients, client)
+	shard.mu.Unlock()
+	if ok {
+		h.count.Add(-1)
+		h.logger.Info("client unregistered", "clientID", client.ID())
+	}
+	client.closeSend()
+}
+
+// size 回傳目前的連線數。
+func (h *hub) size() int {
+	return int(h.count.Load())
+}
+
+// dispatchConnect 通知所有 Subscriber 有新的連線。
+func (h *hub) dispatchConnect(client *connection) {
+	for _, subscriber := range h.subscribers {
+		subscriber.OnConnect(client)
+	}
+}
+
+// dispatchMessage 將客戶端訊息交給所有 Subscriber。
+func (h *hub) dispatchMessage(client *connection, message []byte) {
+	h.logger.Debug("message received from client", "clientID", client.ID())
+	for _, subscriber := range h.subscribers {
+		subscriber.OnMessage(client, message)
+	}
+}
+
+// dispatchRateLimited 通知實現 RateLimitSubscriber 的 Subscriber 客戶端超過速率限制。
+func (h *hub) dispatchRateLimited(client *connection, limit string, retryAfter time.Duration) {
+	for _, subscriber := range h.subscribers {
+		if s, ok := subscriber.(RateLimitSubscriber); ok {
+			s.OnRateLimited(client, limit, retryAfter)
+		}
+	}
+}
+
+// dispatchDisconnect 通知所有 Subscriber 連線已中斷。
+func (h *hub) dispatchDisconnect(client *connection) {
+	for _, subscriber := range h.subscribers {
+		subscriber.OnDisconnect(client)
+	}
+}
+
+// run 等待 hub 的 context 被取消，接著踢除所有連線並拒絕新的連線。
 func (h *hub) run() {
-	for {
-		select {
-		case client := <-h.register:
-			h.clients[client] = true
-			h.logger.Info("client registered", "clientID", client.ID())
-			for _, subscriber := range h.subscribers {
-				subscriber.OnConnect(client)
-			}
-		case client := <-h.unregister:
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-				h.logger.Info("client unregistered", "clientID", client.ID())
-				for _, subscriber := range h.subscribers {
-					subscriber.OnDisconnect(client)
-				}
-			}
-		case msg := <-h.inbound:
-			h.logger.Debug("message received from client", "clientID", msg.client.ID())
-			for _, subscriber := range h.subscribers {
-				subscriber.OnMessage(msg.client, msg.message)
-			}
-		case <-h.ctx.Done():
-			// Context 被取消，開始關閉程序
-			h.logger.Info("hub shutting down")
-			for client := range h.clients {
-				err := client.Kick("Server is shutting down.")
-				if err != nil {
-					h.logger.Error("kick client failed", "error", err, "clientID", client.ID())
-				}
-				delete(h.clients, client)
-				close(client.send)
+	<-h.ctx.Done()
+	h.logger.Info("hub shutting down")
+	for i := range h.shards {
+		shard := &h.shards[i]
+		shard.mu.Lock()
+		shard.closed = true
+		clients := shard.clients
+		shard.clients = make(map[*connection]struct{})
+		shard.mu.Unlock()
+
+		for client := range clients {
+			err := client.Kick("Server is shutting down.")
+			if err != nil {
+				h.logger.Error("kick client failed", "error", err, "clientID", client.ID())
 			}
-			return // 結束 run 迴圈
+			h.count.Add(-1)
+			client.closeSend()
 		}
 	}
 }
@@ -1,7 +1,7 @@
 package wss
 
-// clientMessage 是一個內部結構，用於將客戶端和其發送的訊息綁定在一起。
-type clientMessage struct {
-	client  *connection
-	message []byte
+// frame 是發送佇列中的一則訊息，messageType 為 websocket.TextMessage 或 websocket.BinaryMessage。
+type frame struct {
+	messageType int
+	data        []byte
 }
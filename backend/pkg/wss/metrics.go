@@ -0,0 +1,46 @@
+package wss
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus 指標，描述 WebSocket 連線的傳輸狀態。
+var (
+	compressionFramesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "slot_ws_compression_frames_total",
+		Help: "Number of outbound frames on connections with permessage-deflate, by result (compressed or below_threshold).",
+	}, []string{"result"})
+
+	compressionRawBytesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "slot_ws_compression_raw_bytes_total",
+		Help: "Uncompressed payload bytes of compressed outbound frames.",
+	})
+
+	compressionWireBytesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "slot_ws_compression_wire_bytes_total",
+		Help: "Bytes written to the network for compressed outbound frames, including frame headers.",
+	})
+
+	compressionRatio = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "slot_ws_compression_ratio",
+		Help:    "Per-frame compression ratio (uncompressed payload bytes / bytes on the wire).",
+		Buckets: []float64{1, 1.5, 2, 3, 4, 6, 8, 12, 16, 24},
+	})
+
+	upgradeRejectedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "slot_ws_upgrade_rejected_total",
+		Help: "Number of rejected WebSocket upgrade requests, by reason (draining, origin, admission, per_ip_limit or capacity).",
+	}, []string{"reason"})
+
+	rateLimitedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "slot_ws_rate_limited_total",
+		Help: "Number of inbound messages rejected by per-connection rate limits, by limit (messages or bytes) and policy.",
+	}, []string{"limit", "policy"})
+
+	broadcastRecipients = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "slot_ws_broadcast_recipients",
+		Help:    "Number of connections a broadcast message was queued to, by scope (group or all).",
+		Buckets: prometheus.ExponentialBuckets(1, 4, 9),
+	}, []string{"scope"})
+)
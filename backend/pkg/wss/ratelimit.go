@@ -0,0 +1,102 @@
+package wss
+
+import (
+	"math"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// 超過的速率限制，作為 RateLimitSubscriber.OnRateLimited 的 limit 參數與指標的 limit 標籤。
+const (
+	// LimitMessages 表示超過每秒訊息數限制。
+	LimitMessages = "messages"
+	// LimitBytes 表示超過每秒位元組數限制。
+	LimitBytes = "bytes"
+)
+
+// rateLimitWarnInterval 是同一條連線兩次速率限制警告之間的最短間隔，避免洪水攻擊時警告本身佔滿發送佇列。
+const rateLimitWarnInterval = time.Second
+
+// RateLimitSubscriber 是 Subscriber 的選用介面。
+// Config.RateLimitPolicy 為 RateLimitWarn 時，實現此介面的 Subscriber 會在客戶端超過速率限制時收到通知，
+// 由業務層以客戶端的訊息格式送出警告。同一條連線每秒最多通知一次。
+type RateLimitSubscriber interface {
+	// OnRateLimited 當客戶端的訊息因超過速率限制被丟棄時被呼叫。
+	// 此方法與 OnMessage 一樣由連線的 dispatch goroutine 依序呼叫，不會與同一條連線的其他事件同時執行。
+	//
+	// @param client 超過限制的客戶端。
+	// @param limit 超過的限制 (LimitMessages 或 LimitBytes)。
+	// @param retryAfter 建議客戶端等待的時間。
+	OnRateLimited(client Client, limit string, retryAfter time.Duration)
+}
+
+// inboundLimiter 以 token bucket 限制單一連線送出的訊息數與位元組數，只由 readPump 使用。
+type inboundLimiter struct {
+	messages *rate.Limiter // nil 表示不限制
+	bytes    *rate.Limiter // nil 表示不限制
+	lastWarn time.Time
+}
+
+// newInboundLimiter 依設定建立連線的速率限制器，未設定任何限制時回傳 nil。
+//
+// @param cfg - WebSocket 伺服器的設定參數，burst 必須已由 NewServer 補上預設值。
+// @return *inboundLimiter - 速率限制器。
+func newInboundLimiter(cfg *Config) *inboundLimiter {
+	if cfg.MessagesPerSecond <= 0 && cfg.BytesPerSecond <= 0 {
+		return nil
+	}
+	l := &inboundLimiter{}
+	if cfg.MessagesPerSecond > 0 {
+		l.messages = rate.NewLimiter(rate.Limit(cfg.MessagesPerSecond), cfg.MessageBurst)
+	}
+	if cfg.BytesPerSecond > 0 {
+		l.bytes = rate.NewLimiter(rate.Limit(cfg.BytesPerSecond), cfg.ByteBurst)
+	}
+	return l
+}
+
+// allow 判斷一則大小為 size 的訊息是否在限制內，放行時扣除 token。
+// 超過限制時不扣除任何 token，並回傳超過的限制與可以再送出的等待時間。
+func (l *inboundLimiter) allow(now time.Time, size int) (limit string, retryAfter time.Duration, ok bool) {
+	var msg *rate.Reservation
+	if l.messages != nil {
+		msg = l.messages.ReserveN(now, 1)
+		if delay := msg.DelayFrom(now); delay > 0 {
+			msg.CancelAt(now)
+			return LimitMessages, delay, false
+		}
+	}
+	if l.bytes != nil {
+		r := l.bytes.ReserveN(now, size)
+		delay := r.DelayFrom(now)
+		if !r.OK() || delay > 0 {
+			r.CancelAt(now)
+			if msg != nil {
+				msg.CancelAt(now)
+			}
+			if !r.OK() {
+				delay = time.Second
+			}
+			return LimitBytes, delay, false
+		}
+	}
+	return "", 0, true
+}
+
+// shouldWarn 判斷距離上次警告是否已超過 rateLimitWarnInterval，是則記錄本次警告時間。
+func (l *inboundLimiter) shouldWarn(now time.Time) bool {
+	if now.Sub(l.lastWarn) < rateLimitWarnInterval {
+		return false
+	}
+	l.lastWarn = now
+	return true
+}
+
+// rateLimitBurst 回傳 token bucket 的容量：未設定時使用每秒速率 (至少 1)，且不小於 floor。
+func rateLimitBurst(burst int, perSecond float64, floor int) int {
+	if burst <= 0 {
+		burst = int(math.Ceil(perSecond))
+	}
+	return max(burst, floor, 1)
+}
@@ -0,0 +1,202 @@
+package wss
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialTestServer 以 cfg 啟動 WebSocket 伺服器並註冊 sub，回傳一條已建立的客戶端連線。
+func dialTestServer(t *testing.T, cfg *Config, sub Subscriber) *websocket.Conn {
+	t.Helper()
+	return dialTestServerWith(t, cfg, sub, websocket.DefaultDialer)
+}
+
+// dialTestServerWith 與 dialTestServer 相同，但以 dialer 建立客戶端連線。
+func dialTestServerWith(t *testing.T, cfg *Config, sub Subscriber, dialer *websocket.Dialer) *websocket.Conn {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	if cfg.WriteWait == 0 {
+		cfg.WriteWait = 10 * time.Second
+	}
+	if cfg.PongWait == 0 {
+		cfg.PongWait = time.Minute
+	}
+	if cfg.MaxMessageSize == 0 {
+		cfg.MaxMessageSize = 4096
+	}
+	server := NewServer(ctx, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	server.Register(sub)
+	srv := httptest.NewServer(server)
+	t.Cleanup(func() {
+		cancel()
+		srv.Close()
+	})
+
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// orderSubscriber 依序記錄收到的訊息與速率限制通知，並檢查同一條連線的事件不會同時執行。
+type orderSubscriber struct {
+	inFlight   atomic.Int32
+	overlapped atomic.Bool
+	release    chan struct{} // 第一則訊息會等到 release 關閉才返回
+	events     chan string
+	once       sync.Once
+}
+
+func (s *orderSubscriber) OnConnect(Client)    {}
+func (s *orderSubscriber) OnDisconnect(Client) {}
+
+func (s *orderSubscriber) enter() func() {
+	if s.inFlight.Add(1) > 1 {
+		s.overlapped.Store(true)
+	}
+	return func() { s.inFlight.Add(-1) }
+}
+
+func (s *orderSubscriber) OnMessage(_ Client, message []byte) {
+	defer s.enter()()
+	s.once.Do(func() { <-s.release })
+	s.events <- string(message)
+}
+
+func (s *orderSubscriber) OnRateLimited(_ Client, limit string, _ time.Duration) {
+	defer s.enter()()
+	s.events <- "rate_limited:" + limit
+}
+
+func TestRateLimitNoticeIsDispatchedInOrder(t *testing.T) {
+	sub := &orderSubscriber{release: make(chan struct{}), events: make(chan string, 4)}
+	conn := dialTestServer(t, &Config{
+		MessagesPerSecond: 0.001,
+		MessageBurst:      1,
+		RateLimitPolicy:   RateLimitWarn,
+	}, sub)
+
+	for _, m := range []string{"first", "dropped"} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(m)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	// 第一則訊息仍在處理中時，速率限制通知不能先送達 Subscriber
+	select {
+	case got := <-sub.events:
+		t.Fatalf("received %q while the first message was still being handled", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(sub.release)
+
+	for _, want := range []string{"first", "rate_limited:" + LimitMessages} {
+		select {
+		case got := <-sub.events:
+			if got != want {
+				t.Fatalf("event = %q, want %q", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+	if sub.overlapped.Load() {
+		t.Fatal("OnRateLimited ran concurrently with OnMessage")
+	}
+}
+
+func TestInboundLimiterAllow(t *testing.T) {
+	t0 := time.Unix(1_700_000_000, 0)
+	steps := []struct {
+		at        time.Duration
+		size      int
+		wantLimit string // 空字串表示放行
+		wantRetry time.Duration
+	}{
+		{at: 0, size: 10},
+		// 位元組不足時退還已預留的訊息 token
+		{at: 0, size: 95, wantLimit: LimitBytes, wantRetry: 50 * time.Millisecond},
+		{at: 0, size: 90},
+		{at: 0, size: 1, wantLimit: LimitMessages, wantRetry: 500 * time.Millisecond},
+		// 超過 bucket 容量的訊息永遠無法放行，建議等待 1 秒
+		{at: 500 * time.Millisecond, size: 101, wantLimit: LimitBytes, wantRetry: time.Second},
+		{at: 500 * time.Millisecond, size: 50},
+		{at: 500 * time.Millisecond, size: 1, wantLimit: LimitMessages, wantRetry: 500 * time.Millisecond},
+	}
+
+	l := newInboundLimiter(&Config{MessagesPerSecond: 2, MessageBurst: 2, BytesPerSecond: 100, ByteBurst: 100})
+	for i, step := range steps {
+		limit, retryAfter, ok := l.allow(t0.Add(step.at), step.size)
+		if ok != (step.wantLimit == "") || limit != step.wantLimit || retryAfter != step.wantRetry {
+			t.Fatalf("step %d (%d bytes at %s): allow = %q, %s, %v; want %q, %s",
+				i, step.size, step.at, limit, retryAfter, ok, step.wantLimit, step.wantRetry)
+		}
+	}
+}
+
+func TestNewInboundLimiter(t *testing.T) {
+	if l := newInboundLimiter(&Config{}); l != nil {
+		t.Fatal("limiter created without any limit configured")
+	}
+	// 只限制訊息數時不檢查位元組數
+	l := newInboundLimiter(&Config{MessagesPerSecond: 1, MessageBurst: 1})
+	if _, _, ok := l.allow(time.Unix(0, 0), 1<<20); !ok {
+		t.Fatal("large message rejected without a bytes limit")
+	}
+}
+
+func TestInboundLimiterShouldWarn(t *testing.T) {
+	t0 := time.Unix(1_700_000_000, 0)
+	steps := []struct {
+		at   time.Duration
+		want bool
+	}{
+		{at: 0, want: true},
+		{at: 10 * time.Millisecond, want: false},
+		{at: 999 * time.Millisecond, want: false},
+		{at: time.Second, want: true},
+		{at: 1500 * time.Millisecond, want: false},
+		{at: 2100 * time.Millisecond, want: true},
+	}
+	l := &inboundLimiter{}
+	for _, step := range steps {
+		if got := l.shouldWarn(t0.Add(step.at)); got != step.want {
+			t.Fatalf("shouldWarn at %s = %v, want %v", step.at, got, step.want)
+		}
+	}
+}
+
+func TestRateLimitBurst(t *testing.T) {
+	tests := []struct {
+		name      string
+		burst     int
+		perSecond float64
+		floor     int
+		want      int
+	}{
+		{name: "configured burst", burst: 5, perSecond: 20, want: 5},
+		{name: "defaults to rate", perSecond: 20, want: 20},
+		{name: "fractional rate rounds up", perSecond: 2.5, want: 3},
+		{name: "at least one", perSecond: 0.1, want: 1},
+		{name: "floor raises configured burst", burst: 100, perSecond: 1000, floor: 4096, want: 4096},
+		{name: "floor raises default burst", perSecond: 1000, floor: 4096, want: 4096},
+		{name: "burst above floor", burst: 8192, perSecond: 1000, floor: 4096, want: 8192},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rateLimitBurst(tt.burst, tt.perSecond, tt.floor); got != tt.want {
+				t.Fatalf("rateLimitBurst(%d, %v, %d) = %d, want %d", tt.burst, tt.perSecond, tt.floor, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,17 +2,25 @@ package wss
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
 // Server 是 websocket package 對外的主要門面 (Facade)，並實現了 http.Handler 介面。
 type Server struct {
-	hub    *hub
-	cfg    *Config
-	logger *slog.Logger
+	hub       *hub
+	cfg       *Config
+	logger    *slog.Logger
+	origins   *originMatcher
+	limiter   *connectionLimiter
+	admission Admission
+	// draining 為 true 時拒絕新的升級請求，已建立的連線不受影響。
+	draining atomic.Bool
 }
 
 // 確保 Server 實現了 http.Handler 介面
@@ -29,47 +37,185 @@ func NewServer(ctx context.Context, cfg *Config, logger *slog.Logger) *Server {
 	if cfg.PingPeriod == 0 && cfg.PongWait > 0 {
 		cfg.PingPeriod = (cfg.PongWait * 9) / 10
 	}
+	if cfg.SendBufferSize <= 0 {
+		cfg.SendBufferSize = defaultSendBufferSize
+	}
+	if cfg.InboundBufferSize <= 0 {
+		cfg.InboundBufferSize = defaultInboundBufferSize
+	}
+	if cfg.CompressionLevel == 0 {
+		cfg.CompressionLevel = defaultCompressionLevel
+	}
+	if cfg.OverflowPolicy == "" {
+		cfg.OverflowPolicy = OverflowDisconnect
+	}
+	if cfg.MessagesPerSecond > 0 {
+		cfg.MessageBurst = rateLimitBurst(cfg.MessageBurst, cfg.MessagesPerSecond, 0)
+	}
+	if cfg.BytesPerSecond > 0 {
+		cfg.ByteBurst = rateLimitBurst(cfg.ByteBurst, cfg.BytesPerSecond, int(cfg.MaxMessageSize))
+	}
+	if cfg.RateLimitPolicy == "" {
+		cfg.RateLimitPolicy = RateLimitDrop
+	}
+
+	logger = logger.With("component", "wss_server")
+	origins, err := newOriginMatcher(cfg.AllowedOrigins)
+	if err != nil {
+		// 設定錯誤時拒絕所有瀏覽器來源，避免意外對所有網站開放
+		logger.Error("invalid allowed origins, rejecting all browser origins", "error", err)
+		origins = &originMatcher{}
+	}
 
 	h := newHub(ctx, logger.With("component", "hub"))
 	go h.run()
 	return &Server{
-		hub:    h,
-		cfg:    cfg,
-		logger: logger.With("component", "wss_server"),
+		hub:     h,
+		cfg:     cfg,
+		logger:  logger,
+		origins: origins,
+		limiter: newConnectionLimiter(cfg.MaxConnections, cfg.MaxConnectionsPerIP),
 	}
 }
 
+// ConnectionCount 回傳目前的連線數。
+func (s *Server) ConnectionCount() int {
+	return s.hub.size()
+}
+
 // Register 將一個業務邏輯處理器 (Subscriber) 註冊到 WebSocket 伺服器。
+// 必須在開始接受連線前呼叫。
 //
 // @param subscriber - 實現了 Subscriber 介面的事件處理器。
 func (s *Server) Register(subscriber Subscriber) {
 	s.hub.registerSubscriber(subscriber)
 }
 
+// SetAdmission 設定升級前的准入檢查，在來源檢查之後、保留連線名額之前執行。
+// 必須在開始接受連線前呼叫。
+//
+// @param admission - 准入檢查函式，nil 表示不檢查。
+func (s *Server) SetAdmission(admission Admission) {
+	s.admission = admission
+}
+
+// Drain 讓伺服器進入排空模式：之後的升級請求一律以 503 Service Unavailable (附 Retry-After) 拒絕，
+// 已建立的連線不受影響，直到伺服器的 context 被取消時才會被關閉。
+// 用於滾動更新時，讓負載平衡將新的連線導向其他實體。
+func (s *Server) Drain() {
+	if !s.draining.Swap(true) {
+		s.logger.Warn("draining: rejecting new websocket connections", "connections", s.hub.size())
+	}
+}
+
+// Draining 回傳伺服器是否處於排空模式。
+func (s *Server) Draining() bool {
+	return s.draining.Load()
+}
+
+// waitPollInterval 是 Wait 檢查連線是否都已關閉的間隔。
+const waitPollInterval = 50 * time.Millisecond
+
+// Wait 等待所有連線的底層連線都已關閉，讓關閉訊息在程式結束前送出。
+// 應在伺服器的 context 被取消後呼叫。
+//
+// @param ctx - 等待的期限。
+// @return error - 期限到期時仍有連線未關閉，回傳 ctx 的錯誤。
+func (s *Server) Wait(ctx context.Context) error {
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+	for s.hub.open.Load() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
 // ServeHTTP 實現 http.Handler 介面，處理 WebSocket 的升級請求。
+// 升級前依序檢查排空模式、來源、准入檢查與連線數上限，未通過時以對應的 HTTP 狀態碼拒絕，不會建立連線。
 //
 // @param w - http.ResponseWriter，用於寫入 HTTP 回應。
 // @param r - *http.Request，收到的 HTTP 請求。
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ip := clientIP(r, s.cfg.ClientIPHeader, s.cfg.TrustedProxyHops)
+	if s.draining.Load() {
+		s.rejectUpgrade(w, r, ip, rejectDraining, http.StatusServiceUnavailable, "server is draining")
+		return
+	}
+	if !s.origins.allow(r) {
+		s.rejectUpgrade(w, r, ip, rejectOrigin, http.StatusForbidden, "origin not allowed")
+		return
+	}
+	if s.admission != nil {
+		if err := s.admission(r, ip); err != nil {
+			status, message := http.StatusForbidden, "forbidden"
+			var admissionErr *AdmissionError
+			if errors.As(err, &admissionErr) {
+				status, message = admissionErr.Status, admissionErr.Message
+			}
+			s.rejectUpgrade(w, r, ip, rejectAdmission, status, message)
+			return
+		}
+	}
+	if reason, ok := s.limiter.acquire(ip); !ok {
+		if reason == rejectCapacity {
+			s.rejectUpgrade(w, r, ip, reason, http.StatusServiceUnavailable, "server is at capacity")
+		} else {
+			s.rejectUpgrade(w, r, ip, reason, http.StatusTooManyRequests, "too many connections from this address")
+		}
+		return
+	}
+	release := func() { s.limiter.release(ip) }
+
 	upgrader := websocket.Upgrader{
 		ReadBufferSize:  s.cfg.ReadBufferSize,
 		WriteBufferSize: s.cfg.WriteBufferSize,
+		Subprotocols:    s.cfg.Subprotocols,
 		CheckOrigin: func(r *http.Request) bool {
-			// 在生產環境中，這裡應該有更嚴格的來源檢查
+			// 來源已在升級前依 AllowedOrigins 檢查
 			return true
 		},
+		EnableCompression: s.cfg.EnableCompression,
+	}
+
+	// 協商 permessage-deflate 時統計寫入網路的位元組數，用於計算壓縮率
+	compress := s.cfg.EnableCompression && offersDeflate(r)
+	var counter *countingResponseWriter
+	if compress {
+		counter = &countingResponseWriter{ResponseWriter: w}
+		w = counter
 	}
 
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
+		release()
 		s.logger.Warn("websocket upgrade failed", "error", err)
 		return
 	}
 
 	clientLogger := s.logger.With("component", "client")
-	client := newConnection(s.hub, conn, r, clientLogger)
-	client.hub.register <- client
+	client := newConnection(s.hub, conn, r, s.cfg, clientLogger)
+	client.release = release
+	client.clientIP = ip
+	if compress {
+		client.compress = true
+		client.wire = counter.conn
+		if err := conn.SetCompressionLevel(s.cfg.CompressionLevel); err != nil {
+			s.logger.Warn("invalid compression level, using default", "level", s.cfg.CompressionLevel, "error", err)
+		}
+	}
+	if !client.hub.register(client) {
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "Server is shutting down."), time.Now().Add(s.cfg.WriteWait))
+		_ = conn.Close()
+		client.cancel()
+		release()
+		return
+	}
 
+	go client.dispatch()
 	go client.writePump(s.cfg)
 	go client.readPump(s.cfg)
 }
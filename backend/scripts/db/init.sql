@@ -5,12 +5,14 @@ USE slot_factory;
 -- 玩家錢包表
 CREATE TABLE IF NOT EXISTS wallets (
     id BIGINT AUTO_INCREMENT PRIMARY KEY,
-    player_id VARCHAR(255) NOT NULL UNIQUE COMMENT '玩家唯一標識',
-    balance DECIMAL(18, 4) NOT NULL DEFAULT 0.0000 COMMENT '錢包餘額',
+    operator_id VARCHAR(64) NOT NULL DEFAULT 'default' COMMENT '營運商 ID',
+    player_id VARCHAR(255) NOT NULL COMMENT '玩家 ID (只在營運商內唯一)',
+    balance DECIMAL(30, 8) NOT NULL DEFAULT 0 COMMENT '錢包餘額 (8 位小數以支援加密貨幣)',
     currency VARCHAR(10) NOT NULL DEFAULT 'TWD' COMMENT '幣種',
     version BIGINT NOT NULL DEFAULT 0 COMMENT '樂觀鎖版本號 (用於併發控制)',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_operator_player_currency (operator_id, player_id, currency) COMMENT '每個營運商的每位玩家每個幣種一個錢包',
     INDEX idx_player_id (player_id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='玩家錢包表';
 
@@ -18,10 +20,73 @@ CREATE TABLE IF NOT EXISTS wallets (
 -- 雖然 K8s 上為了省資源可能不寫入，但 Schema 還是要先定義好
 CREATE TABLE IF NOT EXISTS wallet_transactions (
     id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    operator_id VARCHAR(64) NOT NULL DEFAULT 'default' COMMENT '營運商 ID',
     player_id VARCHAR(255) NOT NULL,
-    amount DECIMAL(18, 4) NOT NULL COMMENT '變動金額',
+    currency VARCHAR(10) NOT NULL DEFAULT 'TWD' COMMENT '幣種',
+    amount DECIMAL(30, 8) NOT NULL COMMENT '變動金額',
     transaction_type VARCHAR(20) NOT NULL COMMENT '交易類型: SPIN, DEPOSIT, WITHDRAW',
-    balance_after DECIMAL(18, 4) NOT NULL COMMENT '變動後餘額',
+    balance_after DECIMAL(30, 8) NOT NULL COMMENT '變動後餘額',
+    request_id VARCHAR(64) NOT NULL DEFAULT '' COMMENT '觸發交易的客戶端請求 ID',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    INDEX idx_player_id_created (player_id, created_at)
+    INDEX idx_player_id_created (player_id, created_at),
+    INDEX idx_operator_player_created (operator_id, player_id, created_at)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='錢包流水表';
+
+-- 紅利表 (Bonus)
+-- 紅利與現金分開管理，流水 (wagered) 達到要求 (wagering_required) 後剩餘紅利轉為現金
+CREATE TABLE IF NOT EXISTS wallet_bonuses (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    operator_id VARCHAR(64) NOT NULL DEFAULT 'default' COMMENT '營運商 ID',
+    player_id VARCHAR(255) NOT NULL,
+    currency VARCHAR(10) NOT NULL DEFAULT 'TWD' COMMENT '幣種',
+    granted DECIMAL(30, 8) NOT NULL COMMENT '發放金額',
+    balance DECIMAL(30, 8) NOT NULL COMMENT '剩餘紅利',
+    wagering_required DECIMAL(30, 8) NOT NULL COMMENT '流水要求',
+    wagered DECIMAL(30, 8) NOT NULL DEFAULT 0 COMMENT '已完成流水',
+    status VARCHAR(20) NOT NULL DEFAULT 'active' COMMENT '狀態: active, completed, depleted',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_operator_player_currency_status (operator_id, player_id, currency, status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='紅利表';
+
+-- 紅利未結算下注表
+-- 記錄多人遊戲已扣款、尚未派彩的下注中由紅利支付的部分，派彩時依比例分回紅利；
+-- 每個營運商的每位玩家每個幣種一列，同時作為紅利帳的列鎖 (SELECT ... FOR UPDATE)，避免多個實體重複使用同一筆紅利
+CREATE TABLE IF NOT EXISTS wallet_bonus_stakes (
+    operator_id VARCHAR(64) NOT NULL DEFAULT 'default' COMMENT '營運商 ID',
+    player_id VARCHAR(255) NOT NULL,
+    currency VARCHAR(10) NOT NULL COMMENT '幣種',
+    total DECIMAL(30, 8) NOT NULL DEFAULT 0 COMMENT '未結算的下注總額',
+    bonus DECIMAL(30, 8) NOT NULL DEFAULT 0 COMMENT '其中由紅利支付的金額',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (operator_id, player_id, currency)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='紅利未結算下注表';
+
+-- 管理操作稽核紀錄 (REST API 的 /admin 路由)
+CREATE TABLE IF NOT EXISTS admin_audit_logs (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    actor VARCHAR(255) NOT NULL COMMENT '操作者: API Key 名稱或 JWT sub',
+    role VARCHAR(20) NOT NULL COMMENT '角色: viewer, support, admin',
+    method VARCHAR(20) NOT NULL COMMENT '驗證方式: api_key, jwt, anonymous',
+    action VARCHAR(255) NOT NULL COMMENT '操作，例如 POST /api/v1/admin/kick_all',
+    params TEXT COMMENT '請求參數 (JSON)',
+    remote_ip VARCHAR(64) NOT NULL DEFAULT '',
+    status INT NOT NULL COMMENT '回應的 HTTP 狀態碼',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_actor_created (actor, created_at),
+    INDEX idx_created (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='管理操作稽核紀錄';
+
+-- 營運商表 (operators.source 為 "db" 時使用)
+-- settings 為 JSON，欄位名稱與設定檔的 operators.list 相同，例如
+-- {"auth": {"mode": "jwt", "jwt": {"jwksUrl": "..."}}, "wallet": {"baseUrl": "...", "apiKey": "..."},
+--  "currencies": ["TWD"], "games": [1000, 1001], "betLimits": {"TWD": {"minBet": "1", "maxBet": "50000"}}}
+CREATE TABLE IF NOT EXISTS operators (
+    id VARCHAR(64) PRIMARY KEY COMMENT '營運商 ID，與 token 中的 operatorID 對應',
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    enabled TINYINT(1) NOT NULL DEFAULT 1,
+    settings TEXT COMMENT '串接設定與營運規則 (JSON)',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='營運商表';
+
+INSERT IGNORE INTO operators (id, name, settings) VALUES ('default', 'Default Operator', '{}');